@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	lhv1 "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	"github.com/urfave/cli/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/provisioner"
+)
+
+func listCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "list",
+		Aliases: []string{"ls"},
+		Usage:   "List block devices",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "node",
+				Usage: "Only list block devices on the given node",
+			},
+			&cli.StringFlag{
+				Name:  "state",
+				Usage: "Only list block devices in the given state, options are Active, Inactive, or Unknown",
+			},
+			&cli.StringSliceFlag{
+				Name:  "tag",
+				Usage: "Only list block devices with all the given tags",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			client, err := newClient(c)
+			if err != nil {
+				return err
+			}
+			opts := metav1.ListOptions{}
+			if node := c.String("node"); node != "" {
+				opts.LabelSelector = labels.SelectorFromSet(map[string]string{corev1.LabelHostname: node}).String()
+			}
+			bdList, err := client.BlockDevices(c.String("namespace")).List(c.Context, opts)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "NAME\tNODE\tDEVPATH\tTYPE\tSIZE\tSTATE\tPHASE\tPROVISIONER\tTAGS")
+			for i := range bdList.Items {
+				bd := &bdList.Items[i]
+				if state := c.String("state"); state != "" && !strings.EqualFold(string(bd.Status.State), state) {
+					continue
+				}
+				if !hasAllTags(bd, c.StringSlice("tag")) {
+					continue
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					bd.Name,
+					bd.Spec.NodeName,
+					bd.Status.DeviceStatus.DevPath,
+					bd.Status.DeviceStatus.Details.DeviceType,
+					formatBytes(bd.Status.DeviceStatus.Capacity.SizeBytes),
+					bd.Status.State,
+					bd.Status.ProvisionPhase,
+					provisionerName(bd),
+					strings.Join(bd.Spec.Tags, ","))
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func describeCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "describe",
+		Usage:     "Show the details of a block device",
+		ArgsUsage: "<blockdevice>",
+		Action: func(c *cli.Context) error {
+			if err := requireArgs(c, 1); err != nil {
+				return err
+			}
+			client, err := newClient(c)
+			if err != nil {
+				return err
+			}
+			bd, err := client.BlockDevices(c.String("namespace")).Get(c.Context, c.Args().First(), metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			return describeBlockDevice(bd)
+		},
+	}
+}
+
+func describeBlockDevice(bd *diskv1.BlockDevice) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	status := bd.Status.DeviceStatus
+	details := status.Details
+	fmt.Fprintf(w, "Name:\t%s\n", bd.Name)
+	fmt.Fprintf(w, "Node:\t%s\n", bd.Spec.NodeName)
+	fmt.Fprintf(w, "DevPath:\t%s\n", status.DevPath)
+	fmt.Fprintf(w, "State:\t%s\n", bd.Status.State)
+	fmt.Fprintf(w, "Provision:\t%t\n", bd.Spec.Provision)
+	fmt.Fprintf(w, "ProvisionPhase:\t%s\n", bd.Status.ProvisionPhase)
+	fmt.Fprintf(w, "Provisioner:\t%s\n", provisionerName(bd))
+	fmt.Fprintf(w, "Tags:\t%s\n", strings.Join(bd.Spec.Tags, ","))
+	fmt.Fprintf(w, "Size:\t%s\n", formatBytes(status.Capacity.SizeBytes))
+	fmt.Fprintf(w, "Details:\n")
+	fmt.Fprintf(w, "  DeviceType:\t%s\n", details.DeviceType)
+	fmt.Fprintf(w, "  DriveType:\t%s\n", details.DriveType)
+	fmt.Fprintf(w, "  StorageController:\t%s\n", details.StorageController)
+	fmt.Fprintf(w, "  Vendor:\t%s\n", details.Vendor)
+	fmt.Fprintf(w, "  Model:\t%s\n", details.Model)
+	fmt.Fprintf(w, "  SerialNumber:\t%s\n", details.SerialNumber)
+	fmt.Fprintf(w, "  WWN:\t%s\n", details.WWN)
+	fmt.Fprintf(w, "  BusPath:\t%s\n", details.BusPath)
+	fmt.Fprintf(w, "  UUID:\t%s\n", details.UUID)
+	if fs := status.FileSystem; fs != nil {
+		fmt.Fprintf(w, "FileSystem:\n")
+		fmt.Fprintf(w, "  Type:\t%s\n", fs.Type)
+		fmt.Fprintf(w, "  MountPoint:\t%s\n", fs.MountPoint)
+		fmt.Fprintf(w, "  Corrupted:\t%t\n", fs.Corrupted)
+		if fs.LastFormattedAt != nil {
+			fmt.Fprintf(w, "  LastFormattedAt:\t%s\n", fs.LastFormattedAt.Format(time.RFC3339))
+		}
+	}
+	if len(bd.Status.Conditions) > 0 {
+		fmt.Fprintf(w, "Conditions:\n")
+		fmt.Fprintf(w, "  TYPE\tSTATUS\tREASON\tMESSAGE\n")
+		for _, cond := range bd.Status.Conditions {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", cond.Type, cond.Status, cond.Reason, cond.Message)
+		}
+	}
+	return w.Flush()
+}
+
+func provisionCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "provision",
+		Usage:     "Provision a block device with the given provisioner",
+		ArgsUsage: "<blockdevice>",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "longhorn-v1",
+				Usage: "Provision the block device as a Longhorn V1 data engine disk",
+			},
+			&cli.BoolFlag{
+				Name:  "longhorn-v2",
+				Usage: "Provision the block device as a Longhorn V2 data engine disk",
+			},
+			&cli.StringFlag{
+				Name:  "disk-driver",
+				Usage: "The disk driver of the Longhorn V2 data engine disk, options are auto or aio",
+			},
+			&cli.StringFlag{
+				Name:  "lvm",
+				Usage: "Provision the block device to the given LVM volume group",
+			},
+			&cli.StringSliceFlag{
+				Name:  "lvm-parameter",
+				Usage: "The parameters for the LVM volume group, e.g. --type dm-thin",
+			},
+			&cli.BoolFlag{
+				Name:  "force-format",
+				Usage: "Force format the block device for Longhorn V1 even if it already contains a filesystem",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if err := requireArgs(c, 1); err != nil {
+				return err
+			}
+			info, err := provisionerFromFlags(c)
+			if err != nil {
+				return err
+			}
+			bd, err := updateBlockDevice(c, c.Args().First(), func(bd *diskv1.BlockDevice) error {
+				return provisionBlockDevice(bd, info, c.Bool("force-format"))
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("blockdevice %s provisioned by %s\n", bd.Name, provisionerName(bd))
+			return nil
+		},
+	}
+}
+
+// provisionBlockDevice sets the block device to be provisioned by the given provisioner
+func provisionBlockDevice(bd *diskv1.BlockDevice, info *diskv1.ProvisionerInfo, forceFormat bool) error {
+	if bd.Status.State != diskv1.BlockDeviceActive {
+		return fmt.Errorf("block device %s is %s, only active block devices can be provisioned", bd.Name, bd.Status.State)
+	}
+	if bd.Status.ProvisionPhase != diskv1.ProvisionPhaseUnprovisioned && bd.Spec.Provisioner != nil &&
+		provisionerName(bd) != provisionerNameOf(info) {
+		return fmt.Errorf("block device %s is already provisioned by %s, unprovision it first", bd.Name, provisionerName(bd))
+	}
+	bd.Spec.Provision = true
+	bd.Spec.Provisioner = info
+	if info.Longhorn != nil && info.Longhorn.EngineVersion == provisioner.TypeLonghornV1 {
+		if bd.Spec.FileSystem == nil {
+			bd.Spec.FileSystem = &diskv1.FilesystemInfo{}
+		}
+		// Longhorn V1 disks need a filesystem, format the device if it has none
+		fs := bd.Status.DeviceStatus.FileSystem
+		if forceFormat || fs == nil || fs.Type == "" || fs.Corrupted {
+			bd.Spec.FileSystem.ForceFormatted = true
+		}
+	}
+	return nil
+}
+
+func provisionerFromFlags(c *cli.Context) (*diskv1.ProvisionerInfo, error) {
+	var infos []*diskv1.ProvisionerInfo
+	if c.Bool("longhorn-v1") {
+		infos = append(infos, &diskv1.ProvisionerInfo{
+			Longhorn: &diskv1.LonghornProvisionerInfo{EngineVersion: provisioner.TypeLonghornV1},
+		})
+	}
+	if c.Bool("longhorn-v2") {
+		infos = append(infos, &diskv1.ProvisionerInfo{
+			Longhorn: &diskv1.LonghornProvisionerInfo{
+				EngineVersion: provisioner.TypeLonghornV2,
+				DiskDriver:    lhv1.DiskDriver(c.String("disk-driver")),
+			},
+		})
+	}
+	if vg := c.String("lvm"); vg != "" {
+		infos = append(infos, &diskv1.ProvisionerInfo{
+			LVM: &diskv1.LVMProvisionerInfo{
+				VgName:     vg,
+				Parameters: c.StringSlice("lvm-parameter"),
+			},
+		})
+	}
+	if len(infos) != 1 {
+		return nil, fmt.Errorf("exactly one of --longhorn-v1, --longhorn-v2 or --lvm must be specified")
+	}
+	if c.String("disk-driver") != "" && !c.Bool("longhorn-v2") {
+		return nil, fmt.Errorf("--disk-driver is only supported with --longhorn-v2")
+	}
+	return infos[0], nil
+}
+
+func unprovisionCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "unprovision",
+		Usage:     "Unprovision a block device",
+		ArgsUsage: "<blockdevice>",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "wait",
+				Usage: "Wait until the block device is unprovisioned",
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Value: 5 * time.Minute,
+				Usage: "The maximum time to wait for the block device to be unprovisioned",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if err := requireArgs(c, 1); err != nil {
+				return err
+			}
+			name := c.Args().First()
+			if _, err := updateBlockDevice(c, name, unprovisionBlockDevice); err != nil {
+				return err
+			}
+			if !c.Bool("wait") {
+				fmt.Printf("blockdevice %s is unprovisioning\n", name)
+				return nil
+			}
+
+			client, err := newClient(c)
+			if err != nil {
+				return err
+			}
+			bds := client.BlockDevices(c.String("namespace"))
+			err = wait.PollUntilContextTimeout(c.Context, 2*time.Second, c.Duration("timeout"), true, func(ctx context.Context) (bool, error) {
+				bd, err := bds.Get(ctx, name, metav1.GetOptions{})
+				if err != nil {
+					return false, err
+				}
+				return bd.Status.ProvisionPhase == diskv1.ProvisionPhaseUnprovisioned, nil
+			})
+			if err != nil {
+				return fmt.Errorf("failed to wait for blockdevice %s to be unprovisioned: %w", name, err)
+			}
+			fmt.Printf("blockdevice %s unprovisioned\n", name)
+			return nil
+		},
+	}
+}
+
+// unprovisionBlockDevice sets the block device to be unprovisioned
+func unprovisionBlockDevice(bd *diskv1.BlockDevice) error {
+	bd.Spec.Provision = false
+	if bd.Spec.FileSystem != nil {
+		// keep the deprecated field aligned, see the blockdevice mutator
+		bd.Spec.FileSystem.Provisioned = false
+	}
+	return nil
+}
+
+func formatCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "format",
+		Usage:     "Force format a block device, it is applied when the device is provisioned by Longhorn V1",
+		ArgsUsage: "<blockdevice>",
+		Action: func(c *cli.Context) error {
+			if err := requireArgs(c, 1); err != nil {
+				return err
+			}
+			bd, err := updateBlockDevice(c, c.Args().First(), formatBlockDevice)
+			if err != nil {
+				return err
+			}
+			fs := bd.Status.DeviceStatus.FileSystem
+			if fs != nil && fs.LastFormattedAt != nil && !fs.Corrupted {
+				fmt.Printf("blockdevice %s was already formatted at %s, it will not be formatted again\n", bd.Name, fs.LastFormattedAt.Format(time.RFC3339))
+				return nil
+			}
+			fmt.Printf("blockdevice %s marked for formatting\n", bd.Name)
+			return nil
+		},
+	}
+}
+
+// formatBlockDevice marks the unprovisioned block device for formatting
+func formatBlockDevice(bd *diskv1.BlockDevice) error {
+	if bd.Status.ProvisionPhase == diskv1.ProvisionPhaseProvisioned {
+		return fmt.Errorf("block device %s is provisioned, unprovision it first", bd.Name)
+	}
+	if bd.Spec.FileSystem == nil {
+		bd.Spec.FileSystem = &diskv1.FilesystemInfo{}
+	}
+	bd.Spec.FileSystem.ForceFormatted = true
+	return nil
+}
+
+func provisionerName(bd *diskv1.BlockDevice) string {
+	if bd.Spec.Provisioner == nil {
+		return ""
+	}
+	return provisionerNameOf(bd.Spec.Provisioner)
+}
+
+func provisionerNameOf(info *diskv1.ProvisionerInfo) string {
+	switch {
+	case info.LVM != nil:
+		return fmt.Sprintf("%s(%s)", provisioner.TypeLVM, info.LVM.VgName)
+	case info.Longhorn != nil && info.Longhorn.EngineVersion != "":
+		return info.Longhorn.EngineVersion
+	case info.Longhorn != nil:
+		return provisioner.TypeLonghornV1
+	}
+	return ""
+}
+
+func hasAllTags(bd *diskv1.BlockDevice, tags []string) bool {
+	for _, tag := range tags {
+		if !slices.Contains(bd.Spec.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func formatBytes(size uint64) string {
+	return resource.NewQuantity(int64(size), resource.BinarySI).String()
+}
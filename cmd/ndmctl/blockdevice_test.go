@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	lhv1 "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/provisioner"
+)
+
+// newCommandContext returns the context of the command parsing the given arguments
+func newCommandContext(t *testing.T, cmd *cli.Command, args ...string) *cli.Context {
+	set := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	for _, f := range cmd.Flags {
+		require.NoError(t, f.Apply(set))
+	}
+	require.NoError(t, set.Parse(args))
+	return cli.NewContext(cli.NewApp(), set, nil)
+}
+
+func newTestBlockDevice(name string) *diskv1.BlockDevice {
+	return &diskv1.BlockDevice{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "longhorn-system"},
+		Status: diskv1.BlockDeviceStatus{
+			State:          diskv1.BlockDeviceActive,
+			ProvisionPhase: diskv1.ProvisionPhaseUnprovisioned,
+		},
+	}
+}
+
+func TestProvisionerFromFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected *diskv1.ProvisionerInfo
+		err      string
+	}{
+		{
+			name: "longhorn v1",
+			args: []string{"--longhorn-v1"},
+			expected: &diskv1.ProvisionerInfo{
+				Longhorn: &diskv1.LonghornProvisionerInfo{EngineVersion: provisioner.TypeLonghornV1},
+			},
+		},
+		{
+			name: "longhorn v2 with a disk driver",
+			args: []string{"--longhorn-v2", "--disk-driver", "aio"},
+			expected: &diskv1.ProvisionerInfo{
+				Longhorn: &diskv1.LonghornProvisionerInfo{EngineVersion: provisioner.TypeLonghornV2, DiskDriver: lhv1.DiskDriverAio},
+			},
+		},
+		{
+			name: "lvm with parameters",
+			args: []string{"--lvm", "vg01", "--lvm-parameter", "--type", "--lvm-parameter", "dm-thin"},
+			expected: &diskv1.ProvisionerInfo{
+				LVM: &diskv1.LVMProvisionerInfo{VgName: "vg01", Parameters: []string{"--type", "dm-thin"}},
+			},
+		},
+		{
+			name: "no provisioner",
+			err:  "exactly one of --longhorn-v1, --longhorn-v2 or --lvm must be specified",
+		},
+		{
+			name: "multiple provisioners",
+			args: []string{"--longhorn-v1", "--lvm", "vg01"},
+			err:  "exactly one of --longhorn-v1, --longhorn-v2 or --lvm must be specified",
+		},
+		{
+			name: "disk driver without longhorn v2",
+			args: []string{"--longhorn-v1", "--disk-driver", "aio"},
+			err:  "--disk-driver is only supported with --longhorn-v2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := provisionerFromFlags(newCommandContext(t, provisionCommand(), tt.args...))
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, info)
+		})
+	}
+}
+
+func TestProvisionBlockDevice(t *testing.T) {
+	longhornV1 := &diskv1.ProvisionerInfo{Longhorn: &diskv1.LonghornProvisionerInfo{EngineVersion: provisioner.TypeLonghornV1}}
+	lvm := &diskv1.ProvisionerInfo{LVM: &diskv1.LVMProvisionerInfo{VgName: "vg01"}}
+
+	// a Longhorn V1 disk without a filesystem is formatted
+	bd := newTestBlockDevice("bd-1")
+	require.NoError(t, provisionBlockDevice(bd, longhornV1, false))
+	assert.True(t, bd.Spec.Provision)
+	assert.Equal(t, longhornV1, bd.Spec.Provisioner)
+	assert.True(t, bd.Spec.FileSystem.ForceFormatted)
+
+	// an existing filesystem is kept unless forced
+	bd = newTestBlockDevice("bd-1")
+	bd.Status.DeviceStatus.FileSystem = &diskv1.FilesystemStatus{Type: "ext4"}
+	require.NoError(t, provisionBlockDevice(bd, longhornV1, false))
+	assert.False(t, bd.Spec.FileSystem.ForceFormatted)
+	require.NoError(t, provisionBlockDevice(bd, longhornV1, true))
+	assert.True(t, bd.Spec.FileSystem.ForceFormatted)
+
+	// the other provisioners don't need a filesystem
+	bd = newTestBlockDevice("bd-1")
+	require.NoError(t, provisionBlockDevice(bd, lvm, false))
+	assert.Nil(t, bd.Spec.FileSystem)
+
+	bd = newTestBlockDevice("bd-1")
+	bd.Status.State = diskv1.BlockDeviceInactive
+	assert.EqualError(t, provisionBlockDevice(bd, longhornV1, false), "block device bd-1 is Inactive, only active block devices can be provisioned")
+
+	bd = newTestBlockDevice("bd-1")
+	bd.Spec.Provisioner = lvm
+	bd.Status.ProvisionPhase = diskv1.ProvisionPhaseProvisioned
+	assert.EqualError(t, provisionBlockDevice(bd, longhornV1, false), "block device bd-1 is already provisioned by LVM(vg01), unprovision it first")
+	assert.NoError(t, provisionBlockDevice(bd, lvm, false), "the same provisioner can be set again")
+}
+
+func TestUnprovisionBlockDevice(t *testing.T) {
+	bd := newTestBlockDevice("bd-1")
+	bd.Spec.Provision = true
+	bd.Spec.FileSystem = &diskv1.FilesystemInfo{Provisioned: true}
+	require.NoError(t, unprovisionBlockDevice(bd))
+	assert.False(t, bd.Spec.Provision)
+	assert.False(t, bd.Spec.FileSystem.Provisioned)
+}
+
+func TestFormatBlockDevice(t *testing.T) {
+	bd := newTestBlockDevice("bd-1")
+	require.NoError(t, formatBlockDevice(bd))
+	assert.True(t, bd.Spec.FileSystem.ForceFormatted)
+
+	bd = newTestBlockDevice("bd-1")
+	bd.Status.ProvisionPhase = diskv1.ProvisionPhaseProvisioned
+	assert.EqualError(t, formatBlockDevice(bd), "block device bd-1 is provisioned, unprovision it first")
+}
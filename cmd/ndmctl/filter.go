@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/filter"
+)
+
+func filterCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "filter",
+		Usage: "Explain the disk filter verdicts",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "explain",
+				Usage: "Show how the filters treated every disk found on the nodes",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "node",
+						Usage: "Only show the disks on the given node",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					inventories, err := listInventories(c)
+					if err != nil {
+						return err
+					}
+
+					w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+					fmt.Fprintln(w, "NODE\tDEVICE\tVERDICT\tFILTER\tRULES")
+					for _, inventory := range inventories {
+						for _, verdict := range inventory.Status.FilterVerdicts {
+							printVerdict(w, inventory.Spec.NodeName, verdict)
+						}
+					}
+					return w.Flush()
+				},
+			},
+			{
+				Name:      "preview",
+				Usage:     "Show the disks which would be newly excluded by a filters.yaml",
+				ArgsUsage: "<filters.yaml>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "node",
+						Usage: "Only show the disks on the given node",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if err := requireArgs(c, 1); err != nil {
+						return err
+					}
+					content, err := os.ReadFile(c.Args().First())
+					if err != nil {
+						return err
+					}
+					loader := filter.NewConfigMapLoader(nil, "", "", "", "", "")
+					configs, err := loader.ParseFilterConfigs(string(content))
+					if err != nil {
+						return err
+					}
+					inventories, err := listInventories(c)
+					if err != nil {
+						return err
+					}
+					coreClient, err := newCoreClient(c)
+					if err != nil {
+						return err
+					}
+
+					w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+					fmt.Fprintln(w, "NODE\tDEVICE\tVERDICT\tFILTER\tRULES")
+					for _, inventory := range inventories {
+						node, err := coreClient.Nodes().Get(c.Context, inventory.Spec.NodeName, metav1.GetOptions{})
+						if err != nil {
+							return err
+						}
+						verdicts, err := filter.PreviewExclusions(configs, inventory.Spec.NodeName, node.Labels, inventory.Status.FilterVerdicts)
+						if err != nil {
+							return err
+						}
+						for _, verdict := range verdicts {
+							printVerdict(w, inventory.Spec.NodeName, verdict)
+						}
+					}
+					return w.Flush()
+				},
+			},
+		},
+	}
+}
+
+func listInventories(c *cli.Context) ([]diskv1.NodeDiskInventory, error) {
+	client, err := newClient(c)
+	if err != nil {
+		return nil, err
+	}
+	inventoryList, err := client.NodeDiskInventories().List(c.Context, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	inventories := []diskv1.NodeDiskInventory{}
+	for _, inventory := range inventoryList.Items {
+		if node := c.String("node"); node != "" && inventory.Spec.NodeName != node {
+			continue
+		}
+		inventories = append(inventories, inventory)
+	}
+	return inventories, nil
+}
+
+func printVerdict(w *tabwriter.Writer, nodeName string, verdict diskv1.DiskFilterVerdict) {
+	result := "Accepted"
+	if verdict.Excluded {
+		result = "Excluded"
+	} else if verdict.AutoProvisioned {
+		result = "AutoProvisioned"
+	}
+	rules := verdict.Rules
+	if verdict.Reason != "" {
+		rules = verdict.Reason
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", nodeName, verdict.DevPath, result, verdict.Filter, rules)
+}
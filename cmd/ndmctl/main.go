@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	ctrllh "github.com/harvester/harvester/pkg/generated/controllers/longhorn.io"
+	"github.com/rancher/wrangler/v3/pkg/generated/controllers/core"
+	ctlstorage "github.com/rancher/wrangler/v3/pkg/generated/controllers/storage"
+	"github.com/rancher/wrangler/v3/pkg/kubeconfig"
+	"github.com/rancher/wrangler/v3/pkg/start"
+	"github.com/urfave/cli/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/generated/clientset/versioned"
+	ctldiskv1 "github.com/harvester/node-disk-manager/pkg/generated/clientset/versioned/typed/harvesterhci.io/v1beta1"
+	ctldisk "github.com/harvester/node-disk-manager/pkg/generated/controllers/harvesterhci.io"
+	"github.com/harvester/node-disk-manager/pkg/version"
+	"github.com/harvester/node-disk-manager/pkg/webhook/blockdevice"
+)
+
+func main() {
+	app := cli.NewApp()
+	app.Name = "ndmctl"
+	app.Version = version.FriendlyVersion()
+	app.Usage = "ndmctl helps to inspect and operate on the disks managed by node-disk-manager."
+	app.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:    "kubeconfig",
+			EnvVars: []string{"KUBECONFIG"},
+			Usage:   "Kube config for accessing k8s cluster",
+		},
+		&cli.StringFlag{
+			Name:        "namespace",
+			Aliases:     []string{"n"},
+			Value:       "longhorn-system",
+			DefaultText: "longhorn-system",
+			EnvVars:     []string{"NDM_NAMESPACE"},
+			Usage:       "The namespace of the block devices and volume groups",
+		},
+	}
+	app.Commands = []*cli.Command{
+		listCommand(),
+		describeCommand(),
+		provisionCommand(),
+		unprovisionCommand(),
+		formatCommand(),
+		tagCommand(),
+		volumeGroupCommand(),
+		filterCommand(),
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func newClient(c *cli.Context) (ctldiskv1.HarvesterhciV1beta1Interface, error) {
+	kubeConfig, err := kubeconfig.GetNonInteractiveClientConfig(c.String("kubeconfig")).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client config: %w", err)
+	}
+	clientset, err := versioned.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+	return clientset.HarvesterhciV1beta1(), nil
+}
+
+func newCoreClient(c *cli.Context) (typedcorev1.CoreV1Interface, error) {
+	kubeConfig, err := kubeconfig.GetNonInteractiveClientConfig(c.String("kubeconfig")).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client config: %w", err)
+	}
+	client, err := typedcorev1.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create core client: %w", err)
+	}
+	return client, nil
+}
+
+// newBlockDeviceValidator returns the validator of the webhook, backed by caches of
+// the storage classes, volumes and Longhorn resources it looks up
+func newBlockDeviceValidator(c *cli.Context) (*blockdevice.Validator, error) {
+	kubeConfig, err := kubeconfig.GetNonInteractiveClientConfig(c.String("kubeconfig")).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client config: %w", err)
+	}
+	disks, err := ctldisk.NewFactoryFromConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	storageFactory, err := ctlstorage.NewFactoryFromConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	coreFactory, err := core.NewFactoryFromConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	lhFactory, err := ctrllh.NewFactoryFromConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	validator := blockdevice.NewBlockdeviceValidator(
+		disks.Harvesterhci().V1beta1().BlockDevice().Cache(),
+		storageFactory.Storage().V1().StorageClass().Cache(),
+		coreFactory.Core().V1().PersistentVolume().Cache(),
+		lhFactory.Longhorn().V1beta2().Volume().Cache(),
+		lhFactory.Longhorn().V1beta2().BackingImage().Cache(),
+		lhFactory.Longhorn().V1beta2().Node().Cache(),
+		lhFactory.Longhorn().V1beta2().Replica().Cache(),
+	)
+	// the caches are synced once started
+	if err := start.All(c.Context, 1, disks, storageFactory, coreFactory, lhFactory); err != nil {
+		return nil, fmt.Errorf("failed to sync the caches for the validation: %w", err)
+	}
+	return validator, nil
+}
+
+// updateBlockDevice applies the mutate function on the latest block device and
+// validates it with the webhook rules before submitting, retrying on conflicts.
+func updateBlockDevice(c *cli.Context, name string, mutate func(bd *diskv1.BlockDevice) error) (*diskv1.BlockDevice, error) {
+	client, err := newClient(c)
+	if err != nil {
+		return nil, err
+	}
+	validator, err := newBlockDeviceValidator(c)
+	if err != nil {
+		return nil, err
+	}
+	return applyBlockDeviceUpdate(c.Context, client.BlockDevices(c.String("namespace")), name, mutate,
+		func(oldBd, newBd *diskv1.BlockDevice) error {
+			return validator.Update(nil, oldBd, newBd)
+		})
+}
+
+// applyBlockDeviceUpdate is the client side of updateBlockDevice, the mutated block
+// device is only submitted if the validate function accepts it.
+func applyBlockDeviceUpdate(ctx context.Context, bds ctldiskv1.BlockDeviceInterface, name string,
+	mutate func(bd *diskv1.BlockDevice) error, validate func(oldBd, newBd *diskv1.BlockDevice) error) (*diskv1.BlockDevice, error) {
+	var updated *diskv1.BlockDevice
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		bd, err := bds.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		bdCpy := bd.DeepCopy()
+		if err := mutate(bdCpy); err != nil {
+			return err
+		}
+		if err := validate(bd, bdCpy); err != nil {
+			return err
+		}
+		updated, err = bds.Update(ctx, bdCpy, metav1.UpdateOptions{})
+		return err
+	})
+	return updated, err
+}
+
+func requireArgs(c *cli.Context, n int) error {
+	if c.NArg() < n {
+		return fmt.Errorf("%s requires at least %d argument(s), usage: %s %s", c.Command.Name, n, c.Command.Name, c.Command.ArgsUsage)
+	}
+	return nil
+}
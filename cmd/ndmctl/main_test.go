@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/generated/clientset/versioned/fake"
+)
+
+func acceptAll(_, _ *diskv1.BlockDevice) error {
+	return nil
+}
+
+func TestApplyBlockDeviceUpdate(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newTestBlockDevice("bd-1"))
+	bds := clientset.HarvesterhciV1beta1().BlockDevices("longhorn-system")
+
+	// a conflict is retried on the latest block device
+	conflicts := 1
+	clientset.PrependReactor("update", "blockdevices", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicts == 0 {
+			return false, nil, nil
+		}
+		conflicts--
+		return true, nil, apierrors.NewConflict(diskv1.Resource("blockdevices"), "bd-1", fmt.Errorf("the object has been modified"))
+	})
+	mutations := 0
+	updated, err := applyBlockDeviceUpdate(context.TODO(), bds, "bd-1", func(bd *diskv1.BlockDevice) error {
+		mutations++
+		bd.Spec.Tags = append(bd.Spec.Tags, "ssd")
+		return nil
+	}, acceptAll)
+	require.NoError(t, err)
+	assert.Equal(t, 2, mutations)
+	assert.Equal(t, []string{"ssd"}, updated.Spec.Tags)
+
+	// the block device is only submitted if it is valid
+	_, err = applyBlockDeviceUpdate(context.TODO(), bds, "bd-1", formatBlockDevice, func(oldBd, newBd *diskv1.BlockDevice) error {
+		assert.Nil(t, oldBd.Spec.FileSystem, "the validation compares with the stored block device")
+		return fmt.Errorf("rejected")
+	})
+	assert.EqualError(t, err, "rejected")
+	bd, err := bds.Get(context.TODO(), "bd-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, bd.Spec.FileSystem)
+
+	// so is a mutation which fails
+	bd.Status.ProvisionPhase = diskv1.ProvisionPhaseProvisioned
+	_, err = bds.Update(context.TODO(), bd, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	_, err = applyBlockDeviceUpdate(context.TODO(), bds, "bd-1", formatBlockDevice, acceptAll)
+	assert.EqualError(t, err, "block device bd-1 is provisioned, unprovision it first")
+
+	_, err = applyBlockDeviceUpdate(context.TODO(), bds, "bd-2", formatBlockDevice, acceptAll)
+	assert.True(t, apierrors.IsNotFound(err))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/urfave/cli/v2"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+func tagCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "tag",
+		Usage: "Manage the tags of a block device",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "add",
+				Usage:     "Add tags to a block device",
+				ArgsUsage: "<blockdevice> <tag>...",
+				Action: func(c *cli.Context) error {
+					if err := requireArgs(c, 2); err != nil {
+						return err
+					}
+					return updateTags(c, func(tags []string, tag string) []string {
+						if slices.Contains(tags, tag) {
+							return tags
+						}
+						return append(tags, tag)
+					})
+				},
+			},
+			{
+				Name:      "rm",
+				Aliases:   []string{"remove"},
+				Usage:     "Remove tags from a block device",
+				ArgsUsage: "<blockdevice> <tag>...",
+				Action: func(c *cli.Context) error {
+					if err := requireArgs(c, 2); err != nil {
+						return err
+					}
+					return updateTags(c, func(tags []string, tag string) []string {
+						return slices.DeleteFunc(tags, func(t string) bool { return t == tag })
+					})
+				},
+			},
+		},
+	}
+}
+
+func updateTags(c *cli.Context, update func(tags []string, tag string) []string) error {
+	args := c.Args().Slice()
+	bd, err := updateBlockDevice(c, args[0], func(bd *diskv1.BlockDevice) error {
+		for _, tag := range args[1:] {
+			bd.Spec.Tags = update(bd.Spec.Tags, tag)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("blockdevice %s tags: %v\n", bd.Name, bd.Spec.Tags)
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+func volumeGroupCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "vg",
+		Usage: "Inspect LVM volume groups",
+		Subcommands: []*cli.Command{
+			{
+				Name:    "list",
+				Aliases: []string{"ls"},
+				Usage:   "List LVM volume groups",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "node",
+						Usage: "Only list volume groups on the given node",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					client, err := newClient(c)
+					if err != nil {
+						return err
+					}
+					vgList, err := client.LVMVolumeGroups(c.String("namespace")).List(c.Context, metav1.ListOptions{})
+					if err != nil {
+						return err
+					}
+
+					w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+					fmt.Fprintln(w, "NAME\tVGNAME\tNODE\tDESIRED\tSTATUS\tDEVICES")
+					for i := range vgList.Items {
+						vg := &vgList.Items[i]
+						if node := c.String("node"); node != "" && vg.Spec.NodeName != node {
+							continue
+						}
+						fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\n",
+							vg.Name, vg.Spec.VgName, vg.Spec.NodeName, vg.Spec.DesiredState, vgStatus(vg), len(vg.Spec.Devices))
+					}
+					return w.Flush()
+				},
+			},
+			{
+				Name:      "describe",
+				Usage:     "Show the details of an LVM volume group",
+				ArgsUsage: "<volumegroup>",
+				Action: func(c *cli.Context) error {
+					if err := requireArgs(c, 1); err != nil {
+						return err
+					}
+					client, err := newClient(c)
+					if err != nil {
+						return err
+					}
+					vg, err := client.LVMVolumeGroups(c.String("namespace")).Get(c.Context, c.Args().First(), metav1.GetOptions{})
+					if err != nil {
+						return err
+					}
+					return describeVolumeGroup(vg)
+				},
+			},
+		},
+	}
+}
+
+func describeVolumeGroup(vg *diskv1.LVMVolumeGroup) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", vg.Name)
+	fmt.Fprintf(w, "VGName:\t%s\n", vg.Spec.VgName)
+	fmt.Fprintf(w, "Node:\t%s\n", vg.Spec.NodeName)
+	fmt.Fprintf(w, "DesiredState:\t%s\n", vg.Spec.DesiredState)
+	fmt.Fprintf(w, "Status:\t%s\n", vgStatus(vg))
+	fmt.Fprintf(w, "Parameters:\t%s\n", vg.Spec.Parameters)
+	fmt.Fprintf(w, "Devices:\n")
+	for _, name := range sortedKeys(vg.Spec.Devices) {
+		fmt.Fprintf(w, "  %s\t%s\n", name, vg.Spec.Devices[name])
+	}
+	if vg.Status != nil {
+		fmt.Fprintf(w, "TargetType:\t%s\n", vg.Status.VGTargetType)
+		if len(vg.Status.VGConditions) > 0 {
+			fmt.Fprintf(w, "Conditions:\n")
+			fmt.Fprintf(w, "  TYPE\tSTATUS\tREASON\tMESSAGE\n")
+			for _, cond := range vg.Status.VGConditions {
+				fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", cond.Type, cond.Status, cond.Reason, strings.TrimSpace(cond.Message))
+			}
+		}
+	}
+	return w.Flush()
+}
+
+func vgStatus(vg *diskv1.LVMVolumeGroup) diskv1.VGStatus {
+	if vg.Status == nil {
+		return diskv1.VGStatusUnknown
+	}
+	return vg.Status.Status
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
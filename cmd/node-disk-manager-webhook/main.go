@@ -25,6 +25,7 @@ import (
 	ctldiskv1 "github.com/harvester/node-disk-manager/pkg/generated/controllers/harvesterhci.io/v1beta1"
 	"github.com/harvester/node-disk-manager/pkg/webhook/blockdevice"
 	"github.com/harvester/node-disk-manager/pkg/webhook/configmap"
+	"github.com/harvester/node-disk-manager/pkg/webhook/nodediskmanagerconfig"
 	"github.com/harvester/node-disk-manager/pkg/webhook/storageclass"
 )
 
@@ -33,6 +34,7 @@ const webhookName = "harvester-node-disk-manager-webhook"
 type resourceCaches struct {
 	bdCache             ctldiskv1.BlockDeviceCache
 	lvmVGCache          ctldiskv1.LVMVolumeGroupCache
+	ndmConfigCache      ctldiskv1.NodeDiskManagerConfigCache
 	storageClassCache   ctlstoragev1.StorageClassCache
 	pvCache             ctlcorev1.PersistentVolumeCache
 	lhVolumeCache       lhv1beta2.VolumeCache
@@ -127,11 +129,13 @@ func runWebhookServer(ctx context.Context, cfg *rest.Config, options *config.Opt
 	bdValidator := blockdevice.NewBlockdeviceValidator(resourceCaches.bdCache, resourceCaches.storageClassCache, resourceCaches.pvCache,
 		resourceCaches.lhVolumeCache, resourceCaches.lhBackingImageCache, resourceCaches.lhNodeCache, resourceCaches.lhReplicaCache)
 	scValidator := storageclass.NewStorageClassValidator(resourceCaches.lvmVGCache)
-	cmValidator := configmap.NewConfigMapValidator()
+	cmValidator := configmap.NewConfigMapValidator(resourceCaches.ndmConfigCache)
+	ndmConfigValidator := nodediskmanagerconfig.NewNodeDiskManagerConfigValidator()
 	var validators = []admission.Validator{
 		bdValidator,
 		scValidator,
 		cmValidator,
+		ndmConfigValidator,
 	}
 
 	if err := webhookServer.RegisterMutators(mutators...); err != nil {
@@ -174,6 +178,7 @@ func newCaches(ctx context.Context, cfg *rest.Config, threadiness int) (*resourc
 	resourceCaches := &resourceCaches{
 		bdCache:             disks.Harvesterhci().V1beta1().BlockDevice().Cache(),
 		lvmVGCache:          disks.Harvesterhci().V1beta1().LVMVolumeGroup().Cache(),
+		ndmConfigCache:      disks.Harvesterhci().V1beta1().NodeDiskManagerConfig().Cache(),
 		storageClassCache:   storageFactory.Storage().V1().StorageClass().Cache(),
 		pvCache:             coreFactory.Core().V1().PersistentVolume().Cache(),
 		lhVolumeCache:       lhFactory.Longhorn().V1beta2().Volume().Cache(),
@@ -13,21 +13,29 @@ import (
 
 	"github.com/ehazlett/simplelog"
 	ctlharvester "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io"
+	"github.com/rancher/lasso/pkg/cache"
+	"github.com/rancher/lasso/pkg/client"
 	k8scorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core"
 	"github.com/rancher/wrangler/v3/pkg/kubeconfig"
+	"github.com/rancher/wrangler/v3/pkg/schemes"
 	"github.com/rancher/wrangler/v3/pkg/signals"
 	"github.com/rancher/wrangler/v3/pkg/start"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/rest"
 
 	"github.com/harvester/node-disk-manager/pkg/block"
 	blockdevicev1 "github.com/harvester/node-disk-manager/pkg/controller/blockdevice"
+	inventoryv1 "github.com/harvester/node-disk-manager/pkg/controller/inventory"
 	nodev1 "github.com/harvester/node-disk-manager/pkg/controller/node"
 	volumegroupv1 "github.com/harvester/node-disk-manager/pkg/controller/volumegroup"
 	"github.com/harvester/node-disk-manager/pkg/data"
 	"github.com/harvester/node-disk-manager/pkg/filter"
 	ctldisk "github.com/harvester/node-disk-manager/pkg/generated/controllers/harvesterhci.io"
 	ctllonghorn "github.com/harvester/node-disk-manager/pkg/generated/controllers/longhorn.io"
+	"github.com/harvester/node-disk-manager/pkg/metrics"
 	"github.com/harvester/node-disk-manager/pkg/option"
 	"github.com/harvester/node-disk-manager/pkg/udev"
 	"github.com/harvester/node-disk-manager/pkg/utils"
@@ -133,6 +141,69 @@ func main() {
 			Value:       false,
 			Destination: &opt.InjectUdevMonitorError,
 		},
+		&cli.BoolFlag{
+			Name:        "dry-run",
+			EnvVars:     []string{"NDM_DRY_RUN"},
+			Usage:       "Report the disk operations as events instead of applying them",
+			Value:       false,
+			Destination: &opt.DryRun,
+		},
+		&cli.DurationFlag{
+			Name:        "rescan-interval",
+			EnvVars:     []string{"NDM_RESCAN_INTERVAL"},
+			Usage:       "Specify the period of the full disk scan and of the block device resync, 0 disables them",
+			Value:       30 * time.Minute,
+			DefaultText: "30m",
+			Destination: &opt.RescanInterval,
+		},
+		&cli.StringFlag{
+			Name:        "udev-rules-file",
+			EnvVars:     []string{"NDM_UDEV_RULES_FILE"},
+			Usage:       "Specify a JSON file of rules matching the udev events to handle, the rules of the NodeDiskManagerConfig or the ConfigMap take precedence",
+			Destination: &opt.UdevRulesFile,
+		},
+		&cli.BoolFlag{
+			Name:        "cordon-readonly-disks",
+			EnvVars:     []string{"NDM_CORDON_READONLY_DISKS"},
+			Usage:       "Disable the Longhorn scheduling on the provisioned disks whose filesystem turned read-only",
+			Value:       true,
+			Destination: &opt.CordonReadOnlyDisks,
+		},
+		&cli.BoolFlag{
+			Name:        "persist-mounts",
+			EnvVars:     []string{"NDM_PERSIST_MOUNTS"},
+			Usage:       "Persist the mounts of the provisioned Longhorn V1 disks in systemd mount units on the host, so they are mounted on boot. Requires the host /proc",
+			Value:       false,
+			Destination: &opt.PersistMounts,
+		},
+		&cli.StringFlag{
+			Name:        "metrics-listen-address",
+			EnvVars:     []string{"NDM_METRICS_LISTEN_ADDRESS"},
+			Usage:       "Address to serve the Prometheus metrics on, e.g. `:9100`, disabled if empty",
+			Destination: &opt.MetricsAddress,
+		},
+		&cli.UintFlag{
+			Name:        "fs-pressure-threshold",
+			EnvVars:     []string{"NDM_FS_PRESSURE_THRESHOLD"},
+			Usage:       "Percentage of used space from which a provisioned disk has the FilesystemPressure condition, 0 disables it",
+			Value:       90,
+			Destination: &opt.FilesystemPressureThreshold,
+		},
+		&cli.UintFlag{
+			Name:        "inode-pressure-threshold",
+			EnvVars:     []string{"NDM_INODE_PRESSURE_THRESHOLD"},
+			Usage:       "Percentage of used inodes from which a provisioned disk has the FilesystemPressure condition, 0 disables it",
+			Value:       90,
+			Destination: &opt.InodePressureThreshold,
+		},
+		&cli.DurationFlag{
+			Name:        "iostats-window",
+			EnvVars:     []string{"NDM_IOSTATS_WINDOW"},
+			Usage:       "Specify the window the I/O statistics of the disks are sampled over, 0 disables them",
+			Value:       time.Minute,
+			DefaultText: "1m",
+			Destination: &opt.IOStatsWindow,
+		},
 	}
 
 	app.Action = func(_ *cli.Context) error {
@@ -188,6 +259,9 @@ func run(opt *option.Option) error {
 	if opt.NodeName == "" || opt.Namespace == "" {
 		return errors.New("either node name or namespace is empty")
 	}
+	if opt.FilesystemPressureThreshold > 100 || opt.InodePressureThreshold > 100 {
+		return errors.New("the filesystem pressure thresholds must be percentages between 0 and 100")
+	}
 
 	ctx := signals.SetupSignalContext()
 
@@ -202,7 +276,7 @@ func run(opt *option.Option) error {
 		return fmt.Errorf("failed to find kubeconfig: %v", err)
 	}
 
-	// Initialize built-in resources (e.g., ConfigMap)
+	// Initialize built-in resources (e.g., NodeDiskManagerConfig)
 	if err := data.Init(kubeConfig); err != nil {
 		return fmt.Errorf("failed to initialize built-in resources: %v", err)
 	}
@@ -227,18 +301,32 @@ func run(opt *option.Option) error {
 		return fmt.Errorf("error creating core/v1 factory access: %s", err.Error())
 	}
 
+	nodeFactory, err := newNodeFactory(kubeConfig, opt.NodeName)
+	if err != nil {
+		return fmt.Errorf("error creating core/v1 factory access for node %s: %s", opt.NodeName, err.Error())
+	}
+
 	configmap := corev1.Core().V1().ConfigMap()
+	coreNodes := nodeFactory.Core().V1().Node()
+	ndmConfigs := disks.Harvesterhci().V1beta1().NodeDiskManagerConfig()
 
 	// Create ConfigMapLoader for dynamic configuration reloading
 	// The env variables are used as fallback when ConfigMap is not available or empty
 	configMapLoader := filter.NewConfigMapLoader(
-		configmap,
+		configmap.Cache(),
 		opt.NodeName,
 		opt.VendorFilter,
 		opt.PathFilter,
 		opt.LabelFilter,
 		opt.AutoProvisionFilter,
 	)
+	configMapLoader.SetNodeCache(coreNodes.Cache())
+	configMapLoader.SetNDMConfigCache(ndmConfigs.Cache())
+
+	recorder, err := utils.NewEventRecorder(kubeConfig, opt.NodeName)
+	if err != nil {
+		return fmt.Errorf("error creating event recorder: %s", err.Error())
+	}
 
 	terminatedChannel := make(chan bool, 1)
 
@@ -247,6 +335,7 @@ func run(opt *option.Option) error {
 	upgrades := harvesters.Harvesterhci().V1beta1().Upgrade()
 	bds := disks.Harvesterhci().V1beta1().BlockDevice()
 	lvmVGs := disks.Harvesterhci().V1beta1().LVMVolumeGroup()
+	inventories := disks.Harvesterhci().V1beta1().NodeDiskInventory()
 	nodes := lhs.Longhorn().V1beta2().Node()
 	scanner := blockdevicev1.NewScanner(
 		opt.NodeName,
@@ -258,9 +347,27 @@ func run(opt *option.Option) error {
 		cond,
 		false,
 		&terminatedChannel,
+		recorder,
+		opt.DryRun,
+		opt.RescanInterval,
 	)
 
+	// register to monitor the UDEV events, similar to run `udevadm monitor -u`
+	udevMonitor, err := udev.NewUdev(opt, scanner)
+	if err != nil {
+		return err
+	}
+
 	start := func(ctx context.Context) {
+		// the initial scan runs on registering the block device controller,
+		// so the caches read by the ConfigMapLoader must be synced before
+		if err := corev1.Sync(ctx); err != nil {
+			logrus.Fatalf("failed to sync core/v1 caches, %s", err.Error())
+		}
+		if err := disks.Sync(ctx); err != nil {
+			logrus.Fatalf("failed to sync node-disk-manager caches, %s", err.Error())
+		}
+
 		if err := blockdevicev1.Register(
 			ctx,
 			nodes,
@@ -268,6 +375,8 @@ func run(opt *option.Option) error {
 			bds,
 			lvmVGs,
 			configmap,
+			ndmConfigs,
+			coreNodes,
 			block,
 			opt,
 			scanner,
@@ -275,15 +384,21 @@ func run(opt *option.Option) error {
 			logrus.Fatalf("failed to register block device controller, %s", err.Error())
 		}
 
-		if err := nodev1.Register(ctx, nodes, bds, opt); err != nil {
+		if err := nodev1.Register(ctx, nodes, bds, inventories, ndmConfigs, recorder, opt); err != nil {
 			logrus.Fatalf("failed to register ndm node controller, %s", err.Error())
 		}
 
-		if err := volumegroupv1.Register(ctx, lvmVGs, opt); err != nil {
+		if err := volumegroupv1.Register(ctx, lvmVGs, recorder, opt); err != nil {
 			logrus.Fatalf("failed to register ndm volume group controller, %s", err.Error())
 		}
 
-		if err := start.All(ctx, opt.Threadiness, disks, lhs, corev1); err != nil {
+		if err := inventoryv1.Register(ctx, inventories, bds, scanner, opt); err != nil {
+			logrus.Fatalf("failed to register ndm node disk inventory controller, %s", err.Error())
+		}
+
+		udevMonitor.Register(ctx, configmap, ndmConfigs)
+
+		if err := start.All(ctx, opt.Threadiness, disks, lhs, corev1, nodeFactory); err != nil {
 			logrus.Fatalf("error starting, %s", err.Error())
 		}
 
@@ -291,8 +406,11 @@ func run(opt *option.Option) error {
 		// 1. support for filtering out disks from adding as custom resources
 		// 2. add node actions, i.e. block device rescan
 
-		// register to monitor the UDEV events, similar to run `udevadm monitor -u`
-		go udev.NewUdev(opt, scanner).Monitor(ctx)
+		go udevMonitor.Monitor(ctx)
+
+		if opt.MetricsAddress != "" {
+			go metrics.Serve(ctx, opt.MetricsAddress)
+		}
 	}
 
 	start(ctx)
@@ -307,3 +425,22 @@ func run(opt *option.Option) error {
 	<-terminatedChannel
 	return nil
 }
+
+// newNodeFactory returns a core/v1 factory whose caches only hold the Node the agent runs on,
+// so the agents don't each cache every Node of the cluster for the labels of their own node
+func newNodeFactory(kubeConfig *rest.Config, nodeName string) (*k8scorev1.Factory, error) {
+	clientFactory, err := client.NewSharedClientFactory(kubeConfig, &client.SharedClientFactoryOptions{
+		Scheme: schemes.All,
+	})
+	if err != nil {
+		return nil, err
+	}
+	cacheFactory := cache.NewSharedCachedFactory(clientFactory, &cache.SharedCacheFactoryOptions{
+		DefaultTweakList: func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", nodeName).String()
+		},
+	})
+	return k8scorev1.NewFactoryFromConfigWithOptions(kubeConfig, &k8scorev1.FactoryOptions{
+		SharedCacheFactory: cacheFactory,
+	})
+}
@@ -10,7 +10,15 @@ import (
 var (
 	DeviceMounted    condition.Cond = "Mounted"
 	DeviceFormatting condition.Cond = "Formatting"
+	DeviceResizing   condition.Cond = "Resizing"
 	DiskAddedToNode  condition.Cond = "AddedToNode"
+	IdentityConflict condition.Cond = "IdentityConflict"
+	// FilesystemHealthy is false when the filesystem of a provisioned disk needs a repair
+	FilesystemHealthy condition.Cond = "FilesystemHealthy"
+	// MountPersisted is true when the mount of a provisioned disk is persisted on the host
+	MountPersisted condition.Cond = "MountPersisted"
+	// FilesystemPressure is true when the space or the inodes of a provisioned disk run low
+	FilesystemPressure condition.Cond = "FilesystemPressure"
 )
 
 // +genclient
@@ -49,6 +57,13 @@ type BlockDeviceSpec struct {
 	// a bool for the device to be provisioned
 	// +kubebuilder:default:=false
 	Provision bool `json:"provision,omitempty"`
+
+	// the identity key trusted to identify the disk when another device reports the same identity,
+	// options are "UUID", "WWN" and "DeviceID" (Vendor+Model+Serial+BusPath). Setting a key which
+	// is not in conflict resolves the IdentityConflict condition and unblocks the provisioning.
+	// +kubebuilder:validation:Enum:="";UUID;WWN;DeviceID
+	// +optional
+	TrustedIdentity IdentityKey `json:"trustedIdentity,omitempty"`
 }
 
 type BlockDeviceStatus struct {
@@ -67,8 +82,65 @@ type BlockDeviceStatus struct {
 	// +optional
 	DeviceStatus DeviceStatus `json:"deviceStatus,omitempty"`
 
+	// a bool indicating whether NDM suspended the Longhorn scheduling on the disk until it is remounted
+	// +optional
+	SchedulingSuspended bool `json:"schedulingSuspended,omitempty"`
+
 	// The current Tags of the blockdevice
 	Tags []string `json:"tags,omitempty"`
+
+	// the effective block-layer settings of a tuned disk
+	// +optional
+	Tuning *TuningStatus `json:"tuning,omitempty"`
+
+	// a coarse summary of the I/O of an active disk, refreshed hourly or on a significant change of utilization
+	// +optional
+	IOStats *IOStatsSummary `json:"ioStats,omitempty"`
+}
+
+// IOStatsSummary summarizes the I/O of a disk over a sampling window of /proc/diskstats
+type IOStatsSummary struct {
+	ReadIOPS int64 `json:"readIOPS"`
+
+	WriteIOPS int64 `json:"writeIOPS"`
+
+	ReadBytesPerSecond int64 `json:"readBytesPerSecond"`
+
+	WriteBytesPerSecond int64 `json:"writeBytesPerSecond"`
+
+	// the average time of the reads completed in the window
+	ReadLatencyMicroseconds int64 `json:"readLatencyMicroseconds"`
+
+	// the average time of the writes completed in the window
+	WriteLatencyMicroseconds int64 `json:"writeLatencyMicroseconds"`
+
+	// the percentage of the window the disk was busy with I/O
+	UtilizationPercent int64 `json:"utilizationPercent"`
+
+	// the sampling window
+	Window metav1.Duration `json:"window"`
+
+	// the end of the sampling window
+	LastUpdated metav1.Time `json:"lastUpdated"`
+}
+
+// TuningStatus reports the queue attributes of a disk read back after applying its tuning profile
+type TuningStatus struct {
+	// +optional
+	Scheduler string `json:"scheduler,omitempty"`
+
+	// +optional
+	ReadAheadKB int64 `json:"readAheadKB,omitempty"`
+
+	// +optional
+	NrRequests int64 `json:"nrRequests,omitempty"`
+
+	// +optional
+	WriteCache WriteCacheMode `json:"writeCache,omitempty"`
+
+	// the time the profile was last written to the disk
+	// +optional
+	LastApplied *metav1.Time `json:"lastApplied,omitempty"`
 }
 
 type ProvisionerInfo struct {
@@ -117,6 +189,16 @@ type FilesystemInfo struct {
 
 	// a bool indicating whether the filesystem is manually repaired of not
 	Repaired bool `json:"repaired,omitempty"`
+
+	// a bool requesting NDM to repair the unmounted filesystem with e2fsck or xfs_repair.
+	// It is reset once the repair is accepted, the outcome is reported in status.deviceStatus.fileSystem.lastRepair
+	// +optional
+	Repair bool `json:"repair,omitempty"`
+
+	// extra ext4 mount options of a LonghornV1 disk, e.g. noatime, discard or commit=30.
+	// Only the options which can be changed by a remount are allowed, a change is applied by remounting the disk.
+	// +optional
+	MountOptions []string `json:"mountOptions,omitempty"`
 }
 
 type DeviceStatus struct {
@@ -145,6 +227,10 @@ type DeviceCapcity struct {
 
 	// the size of the physical blocks used on the disk, in bytes
 	PhysicalBlockSizeBytes uint64 `json:"physicalBlockSizeBytes"`
+
+	// the size of the logical blocks addressed by the kernel, in bytes, e.g. 512 for a 512e disk or 4096 for a 4Kn disk
+	// +optional
+	LogicalBlockSizeBytes uint64 `json:"logicalBlockSizeBytes,omitempty"`
 }
 
 type DeviceDetails struct {
@@ -194,6 +280,34 @@ type DeviceDetails struct {
 
 	// a string containing the disk label
 	Label string `json:"label,omitempty"`
+
+	// the firmware revision of the disk
+	// +optional
+	FirmwareRevision string `json:"firmwareRevision,omitempty"`
+
+	// the rotation rate of an ATA hard disk drive, unset for the other disks
+	// +optional
+	RotationRateRPM uint32 `json:"rotationRateRPM,omitempty"`
+
+	// the negotiated speed of the link of a SATA or NVMe disk, e.g. "6.0 Gbps" or "8.0 GT/s PCIe x4"
+	// +optional
+	LinkSpeed string `json:"linkSpeed,omitempty"`
+
+	// the granularity of the discard requests, in bytes
+	// +optional
+	DiscardGranularityBytes uint64 `json:"discardGranularityBytes,omitempty"`
+
+	// the largest discard request, in bytes, unset when the disk doesn't support discard
+	// +optional
+	DiscardMaxBytes uint64 `json:"discardMaxBytes,omitempty"`
+
+	// the zoned model of the disk, options are "none", "host-aware" or "host-managed"
+	// +optional
+	ZonedModel string `json:"zonedModel,omitempty"`
+
+	// a bool indicating the kernel flushes a volatile write cache of the disk
+	// +optional
+	WriteCacheEnabled bool `json:"writeCacheEnabled,omitempty"`
 }
 
 type FilesystemStatus struct {
@@ -211,6 +325,84 @@ type FilesystemStatus struct {
 
 	// indicating whether the filesystem is corrupted or not
 	Corrupted bool `json:"corrupted,omitempty"`
+
+	// the outcome of the last repair requested with spec.fileSystem.repair
+	// +optional
+	LastRepair *FilesystemRepairStatus `json:"lastRepair,omitempty"`
+
+	// the directory the disk is mounted under, set when the disk is provisioned.
+	// Empty means /var/lib/harvester/extra-disks
+	// +optional
+	MountRoot string `json:"mountRoot,omitempty"`
+
+	// the extra mount options applied to the mounted filesystem
+	// +optional
+	MountOptions []string `json:"mountOptions,omitempty"`
+
+	// the outcome of the last scheduled fstrim
+	// +optional
+	LastTrim *FilesystemTrimStatus `json:"lastTrim,omitempty"`
+
+	// the usage of the filesystem mounted at the mount point of a provisioned disk
+	// +optional
+	Usage *FilesystemUsage `json:"usage,omitempty"`
+}
+
+type FilesystemUsage struct {
+	// the size of the filesystem in bytes
+	TotalBytes int64 `json:"totalBytes"`
+
+	// the bytes used by the files and the filesystem itself
+	UsedBytes int64 `json:"usedBytes"`
+
+	// the bytes available to unprivileged users
+	AvailableBytes int64 `json:"availableBytes"`
+
+	// the number of inodes of the filesystem
+	TotalInodes int64 `json:"totalInodes"`
+
+	// the number of inodes in use
+	UsedInodes int64 `json:"usedInodes"`
+
+	// the time the usage was read
+	LastUpdated metav1.Time `json:"lastUpdated"`
+}
+
+type FilesystemTrimStatus struct {
+	// the time the trim finished
+	Time metav1.Time `json:"time"`
+
+	// the bytes fstrim reported as trimmed
+	TrimmedBytes int64 `json:"trimmedBytes"`
+
+	// a bool indicating whether fstrim succeeded
+	Succeeded bool `json:"succeeded"`
+
+	// the error of a failed trim
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+type FilesystemRepairStatus struct {
+	// the time the repair finished, or started while it is in progress
+	Time metav1.Time `json:"time"`
+
+	// a bool indicating whether the repair is running, the outcome is reported once it finished
+	// +optional
+	InProgress bool `json:"inProgress,omitempty"`
+
+	// the repair command, e.g. "e2fsck -fy /dev/sdb"
+	Command string `json:"command"`
+
+	// the exit code of the repair command, or -1 if it could not run
+	ExitCode int `json:"exitCode"`
+
+	// the last lines of the output of the repair command
+	// +optional
+	Output string `json:"output,omitempty"`
+
+	// a bool indicating whether the filesystem was repaired, the disk is only mounted back on success
+	Succeeded bool `json:"succeeded"`
 }
 
 type StorageController string
@@ -241,6 +433,10 @@ const (
 	DriveTypeSSD DriveType = "SSD"
 )
 
+// ZonedModelHostManaged is the zoned model of the disks only accepting sequential writes
+// in their zones, e.g. host-managed SMR HDDs and ZNS NVMe namespaces
+const ZonedModelHostManaged = "host-managed"
+
 type BlockDeviceState string
 
 const (
@@ -254,6 +450,17 @@ const (
 	BlockDeviceUnknown BlockDeviceState = "Unknown"
 )
 
+type IdentityKey string
+
+const (
+	// IdentityKeyUUID identifies a disk by its filesystem or LVM UUID
+	IdentityKeyUUID IdentityKey = "UUID"
+	// IdentityKeyWWN identifies a disk by its World Wide Name
+	IdentityKeyWWN IdentityKey = "WWN"
+	// IdentityKeyDeviceID identifies a disk by its Vendor+Model+Serial+BusPath
+	IdentityKeyDeviceID IdentityKey = "DeviceID"
+)
+
 type BlockDeviceType string
 
 const (
@@ -0,0 +1,262 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=ndi;ndis,scope=Cluster
+// +kubebuilder:printcolumn:name="Devices",type="integer",JSONPath=`.status.devices`
+// +kubebuilder:printcolumn:name="Provisioned",type="integer",JSONPath=`.status.provisionedDevices`
+// +kubebuilder:printcolumn:name="Inactive",type="integer",JSONPath=`.status.inactiveDevices`
+// +kubebuilder:printcolumn:name="Corrupted",type="integer",JSONPath=`.status.corruptedDevices`
+// +kubebuilder:printcolumn:name="TotalBytes",type="integer",JSONPath=`.status.capacity.totalBytes`
+// +kubebuilder:printcolumn:name="FreeBytes",type="integer",JSONPath=`.status.capacity.freeBytes`
+// +kubebuilder:printcolumn:name="LastFullScan",type="date",JSONPath=`.status.lastFullScan.time`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:subresource:status
+
+// NodeDiskInventory is a per-node summary of the block devices managed by NDM.
+// It is named after the node and maintained by the NDM agent running there.
+type NodeDiskInventory struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              NodeDiskInventorySpec   `json:"spec"`
+	Status            NodeDiskInventoryStatus `json:"status,omitempty"`
+}
+
+type NodeDiskInventorySpec struct {
+	// name of the node this inventory describes
+	// +kubebuilder:validation:Required
+	NodeName string `json:"nodeName"`
+}
+
+type NodeDiskInventoryStatus struct {
+	// the number of block devices on the node
+	Devices int `json:"devices"`
+
+	// the number of active block devices on the node
+	ActiveDevices int `json:"activeDevices"`
+
+	// the number of inactive block devices on the node
+	InactiveDevices int `json:"inactiveDevices"`
+
+	// the number of block devices with a corrupted filesystem
+	CorruptedDevices int `json:"corruptedDevices"`
+
+	// the number of provisioned block devices on the node
+	ProvisionedDevices int `json:"provisionedDevices"`
+
+	// the raw capacity of all active block devices on the node
+	Capacity CapacitySummary `json:"capacity"`
+
+	// the raw capacity grouped by drive type, e.g. "HDD", "SSD"
+	// +optional
+	ByDriveType map[string]CapacitySummary `json:"byDriveType,omitempty"`
+
+	// the raw capacity grouped by provisioner, e.g. "LonghornV1", "LVM"
+	// +optional
+	ByProvisioner map[string]CapacitySummary `json:"byProvisioner,omitempty"`
+
+	// the raw capacity grouped by device tag
+	// +optional
+	ByTag map[string]CapacitySummary `json:"byTag,omitempty"`
+
+	// whether the agent runs in dry-run mode, in which case the verdicts and scans are
+	// only a preview and none of the resulting changes were applied
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// the verdicts of the disk filters for every disk seen by the last scan
+	// +optional
+	FilterVerdicts []DiskFilterVerdict `json:"filterVerdicts,omitempty"`
+
+	// the last scan of the disks on the node, either a full scan or a scan of some devices
+	// +optional
+	LastScan *ScanRecord `json:"lastScan,omitempty"`
+
+	// the last full scan of the disks on the node, i.e. the last time the block devices
+	// of the node were reconciled with all the disks found
+	// +optional
+	LastFullScan *ScanRecord `json:"lastFullScan,omitempty"`
+
+	// the last time the inventory was recomputed
+	// +optional
+	LastUpdated *metav1.Time `json:"lastUpdated,omitempty"`
+}
+
+// ScanRecord describes a scan of the disks on a node
+type ScanRecord struct {
+	// the time the scan finished
+	Time metav1.Time `json:"time"`
+
+	// what triggered the scan, e.g. "periodic full scan" or "udev add event",
+	// the reasons of the requests coalesced into the scan are joined
+	Reason string `json:"reason"`
+
+	// whether every disk of the node was scanned
+	Full bool `json:"full"`
+
+	// the devices scanned, when it is not a full scan
+	// +optional
+	Devices []string `json:"devices,omitempty"`
+
+	// the error which aborted the scan
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// DiskFilterVerdict records how the disk filters treated a disk found by the scanner
+type DiskFilterVerdict struct {
+	// the device path of the disk, e.g. /dev/sda
+	DevPath string `json:"devPath"`
+
+	// whether the disk is excluded from NDM, no block device is created for excluded disks
+	Excluded bool `json:"excluded"`
+
+	// whether the disk matches the auto-provision filters
+	// +optional
+	AutoProvisioned bool `json:"autoProvisioned,omitempty"`
+
+	// the name of the filter which excluded or auto-provisioned the disk
+	// +optional
+	Filter string `json:"filter,omitempty"`
+
+	// the rules of the filter which excluded or auto-provisioned the disk
+	// +optional
+	Rules string `json:"rules,omitempty"`
+
+	// the reason why the disk is excluded when it is not excluded by a filter,
+	// e.g. it is a device mapper device
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// the properties of the disk which the filters match against
+	Disk FilteredDisk `json:"disk"`
+}
+
+// FilteredDisk is the subset of the disk properties used by the disk filters.
+// It allows to evaluate a new filter configuration against the known disks.
+type FilteredDisk struct {
+	// +optional
+	Vendor string `json:"vendor,omitempty"`
+
+	// +optional
+	Model string `json:"model,omitempty"`
+
+	// +optional
+	SerialNumber string `json:"serialNumber,omitempty"`
+
+	// +optional
+	WWN string `json:"wwn,omitempty"`
+
+	// +optional
+	SizeBytes uint64 `json:"sizeBytes,omitempty"`
+
+	// +optional
+	BusPath string `json:"busPath,omitempty"`
+
+	// +optional
+	DriveType string `json:"driveType,omitempty"`
+
+	// +optional
+	IsRemovable bool `json:"isRemovable,omitempty"`
+
+	// +optional
+	Label string `json:"label,omitempty"`
+
+	// +optional
+	MountPoint string `json:"mountPoint,omitempty"`
+
+	// the filesystem type of the disk
+	// +optional
+	FileSystem string `json:"fileSystem,omitempty"`
+
+	// +optional
+	IsReadOnly bool `json:"isReadOnly,omitempty"`
+
+	// +optional
+	PhysicalBlockSizeBytes uint64 `json:"physicalBlockSizeBytes,omitempty"`
+
+	// +optional
+	LogicalBlockSizeBytes uint64 `json:"logicalBlockSizeBytes,omitempty"`
+
+	// +optional
+	StorageController string `json:"storageController,omitempty"`
+
+	// +optional
+	UUID string `json:"uuid,omitempty"`
+
+	// +optional
+	PtUUID string `json:"ptUUID,omitempty"`
+
+	// +optional
+	FirmwareRevision string `json:"firmwareRevision,omitempty"`
+
+	// +optional
+	RotationRateRPM uint32 `json:"rotationRateRPM,omitempty"`
+
+	// +optional
+	LinkSpeed string `json:"linkSpeed,omitempty"`
+
+	// +optional
+	DiscardGranularity uint64 `json:"discardGranularity,omitempty"`
+
+	// +optional
+	DiscardMaxBytes uint64 `json:"discardMaxBytes,omitempty"`
+
+	// +optional
+	ZonedModel string `json:"zonedModel,omitempty"`
+
+	// +optional
+	WriteCacheEnabled bool `json:"writeCacheEnabled,omitempty"`
+
+	// +optional
+	Partitions []FilteredPartition `json:"partitions,omitempty"`
+}
+
+// FilteredPartition is the subset of the partition properties used by the disk filters
+type FilteredPartition struct {
+	Name string `json:"name"`
+
+	// +optional
+	Label string `json:"label,omitempty"`
+
+	// +optional
+	PartType string `json:"partType,omitempty"`
+
+	// +optional
+	MountPoint string `json:"mountPoint,omitempty"`
+
+	// +optional
+	SizeBytes uint64 `json:"sizeBytes,omitempty"`
+
+	// +optional
+	UUID string `json:"uuid,omitempty"`
+
+	// +optional
+	FsUUID string `json:"fsUUID,omitempty"`
+
+	// the filesystem type of the partition
+	// +optional
+	FileSystem string `json:"fileSystem,omitempty"`
+
+	// +optional
+	IsReadOnly bool `json:"isReadOnly,omitempty"`
+}
+
+type CapacitySummary struct {
+	// the number of devices in this group
+	Devices int `json:"devices"`
+
+	// the total raw capacity of the devices in this group
+	TotalBytes uint64 `json:"totalBytes"`
+
+	// the raw capacity of provisioned devices in this group
+	ProvisionedBytes uint64 `json:"provisionedBytes"`
+
+	// the raw capacity of unprovisioned devices in this group
+	FreeBytes uint64 `json:"freeBytes"`
+}
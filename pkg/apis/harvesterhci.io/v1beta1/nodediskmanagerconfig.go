@@ -0,0 +1,314 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=ndmconfig;ndmconfigs,scope=Cluster
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:subresource:status
+
+// NodeDiskManagerConfig holds the disk filter and auto-provision rules of NDM.
+// NDM only reads the config named "default". Every NDM agent reports the rules
+// it applied on its node in the status.
+type NodeDiskManagerConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              NodeDiskManagerConfigSpec   `json:"spec"`
+	Status            NodeDiskManagerConfigStatus `json:"status,omitempty"`
+}
+
+type NodeDiskManagerConfigSpec struct {
+	// the disk filter rules, the rules of every matching entry are merged
+	// +optional
+	Filters []DiskFilterRule `json:"filters,omitempty"`
+
+	// the auto-provision rules, the rules of every matching entry are merged
+	// +optional
+	AutoProvision []AutoProvisionRule `json:"autoProvision,omitempty"`
+
+	// the rules matching the udev events handled by NDM, an event is handled if it matches
+	// the rule of any entry matching the node. The rules take precedence over the ConfigMap.
+	// +optional
+	UdevRules []UdevRule `json:"udevRules,omitempty"`
+
+	// the directories the LonghornV1 disks are mounted under, the last matching entry wins.
+	// A disk keeps the directory it was provisioned with.
+	// +optional
+	MountRoots []MountRootRule `json:"mountRoots,omitempty"`
+
+	// the fstrim schedules of the mounted LonghornV1 SSD disks, the last entry matching a disk wins
+	// +optional
+	Trim []TrimRule `json:"trim,omitempty"`
+
+	// the block-layer tuning profiles of the disks, the fields set by the last entry matching a disk win
+	// +optional
+	Tuning []TuningRule `json:"tuning,omitempty"`
+}
+
+// NodeTarget selects the nodes an entry applies to. At least one of the
+// fields must be set, and a node must match all of the set fields.
+type NodeTarget struct {
+	// glob pattern of the node names, e.g. "*" or "storage-*"
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
+
+	// label selector of the nodes
+	// +optional
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+}
+
+// DiskFilterRule excludes disks from NDM on the selected nodes
+type DiskFilterRule struct {
+	NodeTarget `json:",inline"`
+
+	// device paths to exclude, e.g. /dev/sdb
+	// +optional
+	ExcludeDevices []string `json:"excludeDevices,omitempty"`
+
+	// filesystem or partition labels to exclude, glob patterns are allowed
+	// +optional
+	ExcludeLabels []string `json:"excludeLabels,omitempty"`
+
+	// vendors to exclude
+	// +optional
+	ExcludeVendors []string `json:"excludeVendors,omitempty"`
+
+	// mount paths to exclude
+	// +optional
+	ExcludePaths []string `json:"excludePaths,omitempty"`
+
+	// CEL expressions evaluated against the disk, a disk is excluded if any evaluates to true
+	// +optional
+	ExcludeExpressions []string `json:"excludeExpressions,omitempty"`
+
+	// the include fields form an allow-list, when any is set only the disks matching one of them are managed
+	// +optional
+	IncludeWWNs []string `json:"includeWWNs,omitempty"`
+
+	// +optional
+	IncludeSerials []string `json:"includeSerials,omitempty"`
+
+	// +optional
+	IncludeModels []string `json:"includeModels,omitempty"`
+
+	// +optional
+	IncludeBusPaths []string `json:"includeBusPaths,omitempty"`
+
+	// +optional
+	IncludeSizeRange *DiskSizeRange `json:"includeSizeRange,omitempty"`
+}
+
+// DiskSizeRange is an inclusive range of disk sizes, either bound can be omitted
+type DiskSizeRange struct {
+	// the minimum size as a quantity, e.g. 1Ti
+	// +optional
+	Min string `json:"min,omitempty"`
+
+	// the maximum size as a quantity, e.g. 4Ti
+	// +optional
+	Max string `json:"max,omitempty"`
+}
+
+// AutoProvisionRule provisions the matching disks on the selected nodes
+type AutoProvisionRule struct {
+	NodeTarget `json:",inline"`
+
+	// device paths to auto-provision, glob patterns are allowed
+	// +optional
+	Devices []string `json:"devices,omitempty"`
+
+	// CEL expressions evaluated against the disk, a disk is auto-provisioned if any evaluates to true
+	// +optional
+	Expressions []string `json:"expressions,omitempty"`
+
+	// only LonghornV1 is supported for now
+	// +optional
+	Provisioner string `json:"provisioner,omitempty"`
+
+	// +optional
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// UdevRule matches the udev events handled by NDM on the selected nodes
+type UdevRule struct {
+	NodeTarget  `json:",inline"`
+	UdevMatcher `json:",inline"`
+}
+
+// UdevMatcher matches an udev event if both the action and every env entry match,
+// an empty matcher matches every event
+type UdevMatcher struct {
+	// regular expression of the event action, e.g. add|remove
+	// +optional
+	Action string `json:"action,omitempty"`
+
+	// regular expressions of the event environment variables, e.g. DEVNAME: ^/dev/sd
+	// +optional
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// MountRootRule sets the directory the LonghornV1 disks of the selected nodes are mounted under
+type MountRootRule struct {
+	NodeTarget `json:",inline"`
+
+	// absolute path of the directory, e.g. /mnt/extra-disks
+	Path string `json:"path"`
+}
+
+// TrimRule schedules fstrim on the disks of the selected nodes
+type TrimRule struct {
+	NodeTarget   `json:",inline"`
+	TrimSchedule `json:",inline"`
+}
+
+// TrimSchedule schedules fstrim on the mounted LonghornV1 SSD disks supporting discard
+type TrimSchedule struct {
+	// the interval between two trims of a disk, e.g. 168h. Zero disables the trim of the matching disks
+	Interval metav1.Duration `json:"interval"`
+
+	// names of the block devices to trim, every disk matches if both the names and the tags are empty
+	// +optional
+	BlockDevices []string `json:"blockDevices,omitempty"`
+
+	// tags of the block devices to trim, a disk with any of the tags matches
+	// +optional
+	Tags []string `json:"tags,omitempty"`
+}
+
+// TuningRule tunes the block layer of the disks of the selected nodes
+type TuningRule struct {
+	NodeTarget    `json:",inline"`
+	TuningProfile `json:",inline"`
+}
+
+// TuningProfile sets the queue attributes of the matching disks in /sys/block/<disk>/queue,
+// the attributes not set are left as is
+type TuningProfile struct {
+	// names of the block devices to tune, every disk matches if both the names and the tags are empty
+	// +optional
+	BlockDevices []string `json:"blockDevices,omitempty"`
+
+	// tags of the block devices to tune, a disk with any of the tags matches
+	// +optional
+	Tags []string `json:"tags,omitempty"`
+
+	// the I/O scheduler, e.g. none, mq-deadline, bfq or kyber
+	// +optional
+	Scheduler string `json:"scheduler,omitempty"`
+
+	// the read-ahead in KiB
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	ReadAheadKB *int64 `json:"readAheadKB,omitempty"`
+
+	// the queue depth of the scheduler, i.e. nr_requests
+	// +optional
+	// +kubebuilder:validation:Minimum:=1
+	NrRequests *int64 `json:"nrRequests,omitempty"`
+
+	// the write cache mode the kernel assumes for the disk, WriteThrough requires the blockDevices to be named
+	// +optional
+	// +kubebuilder:validation:Enum:=WriteBack;WriteThrough
+	WriteCache WriteCacheMode `json:"writeCache,omitempty"`
+}
+
+type WriteCacheMode string
+
+const (
+	// WriteCacheBack makes the kernel flush the volatile cache of the disk
+	WriteCacheBack WriteCacheMode = "WriteBack"
+	// WriteCacheThrough makes the kernel skip the cache flushes, only for disks with a non-volatile cache
+	WriteCacheThrough WriteCacheMode = "WriteThrough"
+)
+
+type NodeDiskManagerConfigStatus struct {
+	// the rules applied by the NDM agent of every node
+	// +optional
+	Nodes []NodeConfigStatus `json:"nodes,omitempty"`
+}
+
+// NodeConfigStatus is the effective configuration of a node, i.e. the merged
+// rules of all entries matching the node
+type NodeConfigStatus struct {
+	NodeName string `json:"nodeName"`
+
+	// the generation of the config the rules were computed from
+	ObservedGeneration int64 `json:"observedGeneration"`
+
+	// the merged filter rules
+	// +optional
+	Filters EffectiveFilterRules `json:"filters,omitempty"`
+
+	// the merged auto-provision rules
+	// +optional
+	AutoProvision EffectiveAutoProvisionRules `json:"autoProvision,omitempty"`
+
+	// the udev rules of the entries matching the node, in order
+	// +optional
+	UdevRules []UdevMatcher `json:"udevRules,omitempty"`
+
+	// the directory the LonghornV1 disks provisioned next are mounted under,
+	// empty means /var/lib/harvester/extra-disks
+	// +optional
+	MountRoot string `json:"mountRoot,omitempty"`
+
+	// the trim schedules of the entries matching the node, in order
+	// +optional
+	Trim []TrimSchedule `json:"trim,omitempty"`
+
+	// the tuning profiles of the entries matching the node, in order
+	// +optional
+	Tuning []TuningProfile `json:"tuning,omitempty"`
+
+	// the errors found while applying the config, the invalid rules are ignored
+	// +optional
+	Errors []string `json:"errors,omitempty"`
+
+	// the time a full scan of the node applied the rules
+	// +optional
+	LastApplied *metav1.Time `json:"lastApplied,omitempty"`
+}
+
+type EffectiveFilterRules struct {
+	// +optional
+	ExcludeDevices string `json:"excludeDevices,omitempty"`
+
+	// +optional
+	ExcludeVendors string `json:"excludeVendors,omitempty"`
+
+	// +optional
+	ExcludePaths string `json:"excludePaths,omitempty"`
+
+	// +optional
+	ExcludeLabels string `json:"excludeLabels,omitempty"`
+
+	// +optional
+	ExcludeExpressions []string `json:"excludeExpressions,omitempty"`
+
+	// +optional
+	IncludeWWNs []string `json:"includeWWNs,omitempty"`
+
+	// +optional
+	IncludeSerials []string `json:"includeSerials,omitempty"`
+
+	// +optional
+	IncludeModels []string `json:"includeModels,omitempty"`
+
+	// +optional
+	IncludeBusPaths []string `json:"includeBusPaths,omitempty"`
+
+	// +optional
+	IncludeSizeRanges []DiskSizeRange `json:"includeSizeRanges,omitempty"`
+}
+
+type EffectiveAutoProvisionRules struct {
+	// +optional
+	Devices string `json:"devices,omitempty"`
+
+	// +optional
+	Expressions []string `json:"expressions,omitempty"`
+}
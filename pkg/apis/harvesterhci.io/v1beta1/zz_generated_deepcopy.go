@@ -22,9 +22,44 @@ limitations under the License.
 package v1beta1
 
 import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoProvisionRule) DeepCopyInto(out *AutoProvisionRule) {
+	*out = *in
+	in.NodeTarget.DeepCopyInto(&out.NodeTarget)
+	if in.Devices != nil {
+		in, out := &in.Devices, &out.Devices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Expressions != nil {
+		in, out := &in.Expressions, &out.Expressions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Params != nil {
+		in, out := &in.Params, &out.Params
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoProvisionRule.
+func (in *AutoProvisionRule) DeepCopy() *AutoProvisionRule {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoProvisionRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BlockDevice) DeepCopyInto(out *BlockDevice) {
 	*out = *in
@@ -92,7 +127,7 @@ func (in *BlockDeviceSpec) DeepCopyInto(out *BlockDeviceSpec) {
 	if in.FileSystem != nil {
 		in, out := &in.FileSystem, &out.FileSystem
 		*out = new(FilesystemInfo)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.Tags != nil {
 		in, out := &in.Tags, &out.Tags
@@ -131,6 +166,16 @@ func (in *BlockDeviceStatus) DeepCopyInto(out *BlockDeviceStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Tuning != nil {
+		in, out := &in.Tuning, &out.Tuning
+		*out = new(TuningStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IOStats != nil {
+		in, out := &in.IOStats, &out.IOStats
+		*out = new(IOStatsSummary)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -144,6 +189,22 @@ func (in *BlockDeviceStatus) DeepCopy() *BlockDeviceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacitySummary) DeepCopyInto(out *CapacitySummary) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacitySummary.
+func (in *CapacitySummary) DeepCopy() *CapacitySummary {
+	if in == nil {
+		return nil
+	}
+	out := new(CapacitySummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Condition) DeepCopyInto(out *Condition) {
 	*out = *in
@@ -215,9 +276,181 @@ func (in *DeviceStatus) DeepCopy() *DeviceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskFilterRule) DeepCopyInto(out *DiskFilterRule) {
+	*out = *in
+	in.NodeTarget.DeepCopyInto(&out.NodeTarget)
+	if in.ExcludeDevices != nil {
+		in, out := &in.ExcludeDevices, &out.ExcludeDevices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeLabels != nil {
+		in, out := &in.ExcludeLabels, &out.ExcludeLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeVendors != nil {
+		in, out := &in.ExcludeVendors, &out.ExcludeVendors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludePaths != nil {
+		in, out := &in.ExcludePaths, &out.ExcludePaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeExpressions != nil {
+		in, out := &in.ExcludeExpressions, &out.ExcludeExpressions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeWWNs != nil {
+		in, out := &in.IncludeWWNs, &out.IncludeWWNs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeSerials != nil {
+		in, out := &in.IncludeSerials, &out.IncludeSerials
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeModels != nil {
+		in, out := &in.IncludeModels, &out.IncludeModels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeBusPaths != nil {
+		in, out := &in.IncludeBusPaths, &out.IncludeBusPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeSizeRange != nil {
+		in, out := &in.IncludeSizeRange, &out.IncludeSizeRange
+		*out = new(DiskSizeRange)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskFilterRule.
+func (in *DiskFilterRule) DeepCopy() *DiskFilterRule {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskFilterRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskFilterVerdict) DeepCopyInto(out *DiskFilterVerdict) {
+	*out = *in
+	in.Disk.DeepCopyInto(&out.Disk)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskFilterVerdict.
+func (in *DiskFilterVerdict) DeepCopy() *DiskFilterVerdict {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskFilterVerdict)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskSizeRange) DeepCopyInto(out *DiskSizeRange) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskSizeRange.
+func (in *DiskSizeRange) DeepCopy() *DiskSizeRange {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskSizeRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EffectiveAutoProvisionRules) DeepCopyInto(out *EffectiveAutoProvisionRules) {
+	*out = *in
+	if in.Expressions != nil {
+		in, out := &in.Expressions, &out.Expressions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EffectiveAutoProvisionRules.
+func (in *EffectiveAutoProvisionRules) DeepCopy() *EffectiveAutoProvisionRules {
+	if in == nil {
+		return nil
+	}
+	out := new(EffectiveAutoProvisionRules)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EffectiveFilterRules) DeepCopyInto(out *EffectiveFilterRules) {
+	*out = *in
+	if in.ExcludeExpressions != nil {
+		in, out := &in.ExcludeExpressions, &out.ExcludeExpressions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeWWNs != nil {
+		in, out := &in.IncludeWWNs, &out.IncludeWWNs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeSerials != nil {
+		in, out := &in.IncludeSerials, &out.IncludeSerials
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeModels != nil {
+		in, out := &in.IncludeModels, &out.IncludeModels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeBusPaths != nil {
+		in, out := &in.IncludeBusPaths, &out.IncludeBusPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeSizeRanges != nil {
+		in, out := &in.IncludeSizeRanges, &out.IncludeSizeRanges
+		*out = make([]DiskSizeRange, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EffectiveFilterRules.
+func (in *EffectiveFilterRules) DeepCopy() *EffectiveFilterRules {
+	if in == nil {
+		return nil
+	}
+	out := new(EffectiveFilterRules)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FilesystemInfo) DeepCopyInto(out *FilesystemInfo) {
 	*out = *in
+	if in.MountOptions != nil {
+		in, out := &in.MountOptions, &out.MountOptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -231,6 +464,23 @@ func (in *FilesystemInfo) DeepCopy() *FilesystemInfo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilesystemRepairStatus) DeepCopyInto(out *FilesystemRepairStatus) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilesystemRepairStatus.
+func (in *FilesystemRepairStatus) DeepCopy() *FilesystemRepairStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FilesystemRepairStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FilesystemStatus) DeepCopyInto(out *FilesystemStatus) {
 	*out = *in
@@ -238,6 +488,26 @@ func (in *FilesystemStatus) DeepCopyInto(out *FilesystemStatus) {
 		in, out := &in.LastFormattedAt, &out.LastFormattedAt
 		*out = (*in).DeepCopy()
 	}
+	if in.LastRepair != nil {
+		in, out := &in.LastRepair, &out.LastRepair
+		*out = new(FilesystemRepairStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MountOptions != nil {
+		in, out := &in.MountOptions, &out.MountOptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastTrim != nil {
+		in, out := &in.LastTrim, &out.LastTrim
+		*out = new(FilesystemTrimStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Usage != nil {
+		in, out := &in.Usage, &out.Usage
+		*out = new(FilesystemUsage)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -251,6 +521,95 @@ func (in *FilesystemStatus) DeepCopy() *FilesystemStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilesystemTrimStatus) DeepCopyInto(out *FilesystemTrimStatus) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilesystemTrimStatus.
+func (in *FilesystemTrimStatus) DeepCopy() *FilesystemTrimStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FilesystemTrimStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilesystemUsage) DeepCopyInto(out *FilesystemUsage) {
+	*out = *in
+	in.LastUpdated.DeepCopyInto(&out.LastUpdated)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilesystemUsage.
+func (in *FilesystemUsage) DeepCopy() *FilesystemUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(FilesystemUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilteredDisk) DeepCopyInto(out *FilteredDisk) {
+	*out = *in
+	if in.Partitions != nil {
+		in, out := &in.Partitions, &out.Partitions
+		*out = make([]FilteredPartition, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilteredDisk.
+func (in *FilteredDisk) DeepCopy() *FilteredDisk {
+	if in == nil {
+		return nil
+	}
+	out := new(FilteredDisk)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FilteredPartition) DeepCopyInto(out *FilteredPartition) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilteredPartition.
+func (in *FilteredPartition) DeepCopy() *FilteredPartition {
+	if in == nil {
+		return nil
+	}
+	out := new(FilteredPartition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IOStatsSummary) DeepCopyInto(out *IOStatsSummary) {
+	*out = *in
+	out.Window = in.Window
+	in.LastUpdated.DeepCopyInto(&out.LastUpdated)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IOStatsSummary.
+func (in *IOStatsSummary) DeepCopy() *IOStatsSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(IOStatsSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LVMProvisionerInfo) DeepCopyInto(out *LVMProvisionerInfo) {
 	*out = *in
@@ -354,27 +713,573 @@ func (in *LonghornProvisionerInfo) DeepCopy() *LonghornProvisionerInfo {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProvisionerInfo) DeepCopyInto(out *ProvisionerInfo) {
+func (in *MountRootRule) DeepCopyInto(out *MountRootRule) {
 	*out = *in
-	if in.LVM != nil {
-		in, out := &in.LVM, &out.LVM
-		*out = new(LVMProvisionerInfo)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Longhorn != nil {
-		in, out := &in.Longhorn, &out.Longhorn
-		*out = new(LonghornProvisionerInfo)
-		**out = **in
-	}
+	in.NodeTarget.DeepCopyInto(&out.NodeTarget)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionerInfo.
-func (in *ProvisionerInfo) DeepCopy() *ProvisionerInfo {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MountRootRule.
+func (in *MountRootRule) DeepCopy() *MountRootRule {
 	if in == nil {
 		return nil
 	}
-	out := new(ProvisionerInfo)
+	out := new(MountRootRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeConfigStatus) DeepCopyInto(out *NodeConfigStatus) {
+	*out = *in
+	in.Filters.DeepCopyInto(&out.Filters)
+	in.AutoProvision.DeepCopyInto(&out.AutoProvision)
+	if in.UdevRules != nil {
+		in, out := &in.UdevRules, &out.UdevRules
+		*out = make([]UdevMatcher, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Trim != nil {
+		in, out := &in.Trim, &out.Trim
+		*out = make([]TrimSchedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Tuning != nil {
+		in, out := &in.Tuning, &out.Tuning
+		*out = make([]TuningProfile, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Errors != nil {
+		in, out := &in.Errors, &out.Errors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastApplied != nil {
+		in, out := &in.LastApplied, &out.LastApplied
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeConfigStatus.
+func (in *NodeConfigStatus) DeepCopy() *NodeConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDiskInventory) DeepCopyInto(out *NodeDiskInventory) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDiskInventory.
+func (in *NodeDiskInventory) DeepCopy() *NodeDiskInventory {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDiskInventory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeDiskInventory) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDiskInventoryList) DeepCopyInto(out *NodeDiskInventoryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NodeDiskInventory, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDiskInventoryList.
+func (in *NodeDiskInventoryList) DeepCopy() *NodeDiskInventoryList {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDiskInventoryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeDiskInventoryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDiskInventorySpec) DeepCopyInto(out *NodeDiskInventorySpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDiskInventorySpec.
+func (in *NodeDiskInventorySpec) DeepCopy() *NodeDiskInventorySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDiskInventorySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDiskInventoryStatus) DeepCopyInto(out *NodeDiskInventoryStatus) {
+	*out = *in
+	out.Capacity = in.Capacity
+	if in.ByDriveType != nil {
+		in, out := &in.ByDriveType, &out.ByDriveType
+		*out = make(map[string]CapacitySummary, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ByProvisioner != nil {
+		in, out := &in.ByProvisioner, &out.ByProvisioner
+		*out = make(map[string]CapacitySummary, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ByTag != nil {
+		in, out := &in.ByTag, &out.ByTag
+		*out = make(map[string]CapacitySummary, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.FilterVerdicts != nil {
+		in, out := &in.FilterVerdicts, &out.FilterVerdicts
+		*out = make([]DiskFilterVerdict, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastScan != nil {
+		in, out := &in.LastScan, &out.LastScan
+		*out = new(ScanRecord)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastFullScan != nil {
+		in, out := &in.LastFullScan, &out.LastFullScan
+		*out = new(ScanRecord)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastUpdated != nil {
+		in, out := &in.LastUpdated, &out.LastUpdated
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDiskInventoryStatus.
+func (in *NodeDiskInventoryStatus) DeepCopy() *NodeDiskInventoryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDiskInventoryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDiskManagerConfig) DeepCopyInto(out *NodeDiskManagerConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDiskManagerConfig.
+func (in *NodeDiskManagerConfig) DeepCopy() *NodeDiskManagerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDiskManagerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeDiskManagerConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDiskManagerConfigList) DeepCopyInto(out *NodeDiskManagerConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NodeDiskManagerConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDiskManagerConfigList.
+func (in *NodeDiskManagerConfigList) DeepCopy() *NodeDiskManagerConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDiskManagerConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeDiskManagerConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDiskManagerConfigSpec) DeepCopyInto(out *NodeDiskManagerConfigSpec) {
+	*out = *in
+	if in.Filters != nil {
+		in, out := &in.Filters, &out.Filters
+		*out = make([]DiskFilterRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AutoProvision != nil {
+		in, out := &in.AutoProvision, &out.AutoProvision
+		*out = make([]AutoProvisionRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.UdevRules != nil {
+		in, out := &in.UdevRules, &out.UdevRules
+		*out = make([]UdevRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MountRoots != nil {
+		in, out := &in.MountRoots, &out.MountRoots
+		*out = make([]MountRootRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Trim != nil {
+		in, out := &in.Trim, &out.Trim
+		*out = make([]TrimRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Tuning != nil {
+		in, out := &in.Tuning, &out.Tuning
+		*out = make([]TuningRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDiskManagerConfigSpec.
+func (in *NodeDiskManagerConfigSpec) DeepCopy() *NodeDiskManagerConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDiskManagerConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDiskManagerConfigStatus) DeepCopyInto(out *NodeDiskManagerConfigStatus) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]NodeConfigStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDiskManagerConfigStatus.
+func (in *NodeDiskManagerConfigStatus) DeepCopy() *NodeDiskManagerConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDiskManagerConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeTarget) DeepCopyInto(out *NodeTarget) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeTarget.
+func (in *NodeTarget) DeepCopy() *NodeTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionerInfo) DeepCopyInto(out *ProvisionerInfo) {
+	*out = *in
+	if in.LVM != nil {
+		in, out := &in.LVM, &out.LVM
+		*out = new(LVMProvisionerInfo)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Longhorn != nil {
+		in, out := &in.Longhorn, &out.Longhorn
+		*out = new(LonghornProvisionerInfo)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionerInfo.
+func (in *ProvisionerInfo) DeepCopy() *ProvisionerInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionerInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScanRecord) DeepCopyInto(out *ScanRecord) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.Devices != nil {
+		in, out := &in.Devices, &out.Devices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScanRecord.
+func (in *ScanRecord) DeepCopy() *ScanRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(ScanRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UdevMatcher) DeepCopyInto(out *UdevMatcher) {
+	*out = *in
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UdevMatcher.
+func (in *UdevMatcher) DeepCopy() *UdevMatcher {
+	if in == nil {
+		return nil
+	}
+	out := new(UdevMatcher)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UdevRule) DeepCopyInto(out *UdevRule) {
+	*out = *in
+	in.NodeTarget.DeepCopyInto(&out.NodeTarget)
+	in.UdevMatcher.DeepCopyInto(&out.UdevMatcher)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UdevRule.
+func (in *UdevRule) DeepCopy() *UdevRule {
+	if in == nil {
+		return nil
+	}
+	out := new(UdevRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrimRule) DeepCopyInto(out *TrimRule) {
+	*out = *in
+	in.NodeTarget.DeepCopyInto(&out.NodeTarget)
+	in.TrimSchedule.DeepCopyInto(&out.TrimSchedule)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrimRule.
+func (in *TrimRule) DeepCopy() *TrimRule {
+	if in == nil {
+		return nil
+	}
+	out := new(TrimRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrimSchedule) DeepCopyInto(out *TrimSchedule) {
+	*out = *in
+	out.Interval = in.Interval
+	if in.BlockDevices != nil {
+		in, out := &in.BlockDevices, &out.BlockDevices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrimSchedule.
+func (in *TrimSchedule) DeepCopy() *TrimSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(TrimSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TuningProfile) DeepCopyInto(out *TuningProfile) {
+	*out = *in
+	if in.BlockDevices != nil {
+		in, out := &in.BlockDevices, &out.BlockDevices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReadAheadKB != nil {
+		in, out := &in.ReadAheadKB, &out.ReadAheadKB
+		*out = new(int64)
+		**out = **in
+	}
+	if in.NrRequests != nil {
+		in, out := &in.NrRequests, &out.NrRequests
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TuningProfile.
+func (in *TuningProfile) DeepCopy() *TuningProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(TuningProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TuningRule) DeepCopyInto(out *TuningRule) {
+	*out = *in
+	in.NodeTarget.DeepCopyInto(&out.NodeTarget)
+	in.TuningProfile.DeepCopyInto(&out.TuningProfile)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TuningRule.
+func (in *TuningRule) DeepCopy() *TuningRule {
+	if in == nil {
+		return nil
+	}
+	out := new(TuningRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TuningStatus) DeepCopyInto(out *TuningStatus) {
+	*out = *in
+	if in.LastApplied != nil {
+		in, out := &in.LastApplied, &out.LastApplied
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TuningStatus.
+func (in *TuningStatus) DeepCopy() *TuningStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TuningStatus)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -57,3 +57,37 @@ func NewLVMVolumeGroup(namespace, name string, obj LVMVolumeGroup) *LVMVolumeGro
 	obj.Namespace = namespace
 	return &obj
 }
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NodeDiskInventoryList is a list of NodeDiskInventory resources
+type NodeDiskInventoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []NodeDiskInventory `json:"items"`
+}
+
+func NewNodeDiskInventory(namespace, name string, obj NodeDiskInventory) *NodeDiskInventory {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("NodeDiskInventory").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NodeDiskManagerConfigList is a list of NodeDiskManagerConfig resources
+type NodeDiskManagerConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []NodeDiskManagerConfig `json:"items"`
+}
+
+func NewNodeDiskManagerConfig(namespace, name string, obj NodeDiskManagerConfig) *NodeDiskManagerConfig {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("NodeDiskManagerConfig").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
@@ -28,8 +28,10 @@ import (
 )
 
 var (
-	BlockDeviceResourceName    = "blockdevices"
-	LVMVolumeGroupResourceName = "lvmvolumegroups"
+	BlockDeviceResourceName           = "blockdevices"
+	LVMVolumeGroupResourceName        = "lvmvolumegroups"
+	NodeDiskInventoryResourceName     = "nodediskinventories"
+	NodeDiskManagerConfigResourceName = "nodediskmanagerconfigs"
 )
 
 // SchemeGroupVersion is group version used to register these objects
@@ -57,6 +59,10 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&BlockDeviceList{},
 		&LVMVolumeGroup{},
 		&LVMVolumeGroupList{},
+		&NodeDiskInventory{},
+		&NodeDiskInventoryList{},
+		&NodeDiskManagerConfig{},
+		&NodeDiskManagerConfigList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
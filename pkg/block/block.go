@@ -13,6 +13,7 @@ type Disk struct {
 	Label                  string                  `json:"label"`
 	SizeBytes              uint64                  `json:"size_bytes"`
 	PhysicalBlockSizeBytes uint64                  `json:"physical_block_size_bytes"`
+	LogicalBlockSizeBytes  uint64                  `json:"logical_block_size_bytes"`
 	DriveType              block.DriveType         `json:"drive_type"`
 	IsRemovable            bool                    `json:"removable"`
 	StorageController      block.StorageController `json:"storage_controller"`
@@ -25,6 +26,13 @@ type Disk struct {
 	Model                  string                  `json:"model"`
 	SerialNumber           string                  `json:"serial_number"`
 	WWN                    string                  `json:"wwn"`
+	FirmwareRevision       string                  `json:"firmware_revision"`
+	RotationRateRPM        uint32                  `json:"rotation_rate_rpm"` // 0 when unknown or not rotational
+	LinkSpeed              string                  `json:"link_speed"`        // e.g. "6.0 Gbps" for SATA or "8.0 GT/s PCIe x4" for NVMe
+	DiscardGranularity     uint64                  `json:"discard_granularity"`
+	DiscardMaxBytes        uint64                  `json:"discard_max_bytes"` // 0 when discard isn't supported
+	ZonedModel             string                  `json:"zoned_model"`       // none, host-aware or host-managed
+	WriteCacheEnabled      bool                    `json:"write_cache_enabled"`
 	Partitions             []*Partition            `json:"partitions"`
 }
 
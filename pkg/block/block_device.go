@@ -40,7 +40,9 @@ type Info interface {
 	GetDisks() []*Disk
 	GetPartitions() []*Partition
 	GetDiskByDevPath(name string) *Disk
+	GetDiskNameByDevPath(devPath string) (string, bool)
 	GetFileSystemInfoByDevPath(dname string) *FileSystemInfo
+	SupportsDiscard(devPath string) bool
 }
 
 type infoImpl struct {
@@ -85,6 +87,28 @@ func (i *infoImpl) GetDiskByDevPath(name string) *Disk {
 	return getDisk(i.ctx, paths, name)
 }
 
+// GetDiskNameByDevPath returns the name of the disk holding the device, i.e. the
+// device itself or the disk of a partition, and false if the device is gone
+func (i *infoImpl) GetDiskNameByDevPath(devPath string) (string, bool) {
+	if resolved, err := filepath.EvalSymlinks(devPath); err == nil {
+		devPath = resolved
+	}
+	name := strings.TrimPrefix(devPath, "/dev/")
+	paths := linuxpath.New(i.ctx)
+	if _, err := os.Stat(filepath.Join(paths.SysBlock, name)); err == nil {
+		return name, true
+	}
+	// partitions are only listed in /sys/class/block, which links to the directory below their disk
+	sysPath, err := filepath.EvalSymlinks(filepath.Join(filepath.Dir(paths.SysBlock), "class", "block", name))
+	if err != nil {
+		return name, false
+	}
+	if _, err := os.Stat(filepath.Join(sysPath, "partition")); err != nil {
+		return name, false
+	}
+	return filepath.Base(filepath.Dir(sysPath)), true
+}
+
 func (i *infoImpl) GetFileSystemInfoByDevPath(dname string) *FileSystemInfo {
 	paths := linuxpath.New(i.ctx)
 	mp, pt, ro := partitionInfo(i.ctx, paths, dname)
@@ -95,6 +119,16 @@ func (i *infoImpl) GetFileSystemInfoByDevPath(dname string) *FileSystemInfo {
 	}
 }
 
+// SupportsDiscard checks whether the disk holding the device accepts discard requests
+func (i *infoImpl) SupportsDiscard(devPath string) bool {
+	name, found := i.GetDiskNameByDevPath(devPath)
+	if !found {
+		return false
+	}
+	// /sys/block/$DEVICE/queue/discard_max_bytes is 0 for a disk without discard support
+	return diskQueueUint(linuxpath.New(i.ctx), name, "discard_max_bytes") > 0
+}
+
 func diskPhysicalBlockSizeBytes(paths *linuxpath.Paths, disk string) uint64 {
 	// We can find the sector size in Linux by looking at the
 	// /sys/block/$DEVICE/queue/physical_block_size file in sysfs
@@ -110,6 +144,27 @@ func diskPhysicalBlockSizeBytes(paths *linuxpath.Paths, disk string) uint64 {
 	return size
 }
 
+// diskQueueUint reads an unsigned integer attribute of /sys/block/$DEVICE/queue, or 0
+func diskQueueUint(paths *linuxpath.Paths, disk, attribute string) uint64 {
+	contents, err := os.ReadFile(filepath.Join(paths.SysBlock, disk, "queue", attribute))
+	if err != nil {
+		return 0
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(contents)), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+func diskQueueString(paths *linuxpath.Paths, disk, attribute string) string {
+	contents, err := os.ReadFile(filepath.Join(paths.SysBlock, disk, "queue", attribute))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(contents))
+}
+
 func diskSizeBytes(paths *linuxpath.Paths, disk string) uint64 {
 	// We can find the number of 512-byte sectors by examining the contents of
 	// /sys/block/$DEVICE/size and calculate the physical bytes accordingly.
@@ -244,6 +299,71 @@ func diskWWN(paths *linuxpath.Paths, disk string) string {
 	return util.UNKNOWN
 }
 
+func diskFirmwareRevision(paths *linuxpath.Paths, disk string) string {
+	if info, err := udevInfo(paths, disk); err == nil {
+		if revision, ok := info["ID_REVISION"]; ok {
+			return revision
+		}
+	}
+	// NVMe controllers report the firmware in device/firmware_rev, SCSI devices in device/rev
+	for _, name := range []string{"firmware_rev", "rev"} {
+		if contents, err := os.ReadFile(filepath.Join(paths.SysBlock, disk, "device", name)); err == nil {
+			return strings.TrimSpace(string(contents))
+		}
+	}
+	return ""
+}
+
+func diskRotationRateRPM(paths *linuxpath.Paths, disk string) uint32 {
+	info, err := udevInfo(paths, disk)
+	if err != nil {
+		return 0
+	}
+	// only set by udev for ATA disks, SSDs report 0
+	rpm, err := strconv.ParseUint(info["ID_ATA_ROTATION_RATE_RPM"], 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(rpm)
+}
+
+// diskLinkSpeed returns the negotiated speed of the link of a SATA or NVMe disk, or ""
+func diskLinkSpeed(paths *linuxpath.Paths, disk string) string {
+	sysRoot := filepath.Dir(paths.SysBlock)
+	if strings.HasPrefix(disk, "nvme") {
+		// /sys/block/nvme0n1/device is the NVMe controller, its device the PCI function
+		pciDevice := filepath.Join(paths.SysBlock, disk, "device", "device")
+		speed, err := os.ReadFile(filepath.Join(pciDevice, "current_link_speed"))
+		if err != nil {
+			return ""
+		}
+		width, err := os.ReadFile(filepath.Join(pciDevice, "current_link_width"))
+		if err != nil {
+			return strings.TrimSpace(string(speed))
+		}
+		return strings.TrimSpace(string(speed)) + " x" + strings.TrimSpace(string(width))
+	}
+
+	// a SATA disk sits under its ATA port, e.g. /sys/devices/.../ata1/host0/target0:0:0/0:0:0:0/block/sda
+	devicePath, err := filepath.EvalSymlinks(filepath.Join(paths.SysBlock, disk))
+	if err != nil {
+		return ""
+	}
+	for _, component := range strings.Split(devicePath, string(filepath.Separator)) {
+		if port, found := strings.CutPrefix(component, "ata"); found && port != "" {
+			if _, err := strconv.Atoi(port); err != nil {
+				continue
+			}
+			speed, err := os.ReadFile(filepath.Join(sysRoot, "class", "ata_link", "link"+port, "sata_spd"))
+			if err != nil {
+				return ""
+			}
+			return strings.TrimSpace(string(speed))
+		}
+	}
+	return ""
+}
+
 // diskPartitions takes the name of a disk (note: *not* the path of the disk,
 // but just the name. In other words, "sda", not "/dev/sda" and "nvme0n1" not
 // "/dev/nvme0n1") and returns a slice of pointers to Partition structs
@@ -315,6 +435,7 @@ func getDisk(ctx *context.Context, paths *linuxpath.Paths, dname string) *Disk {
 	}
 	size := diskSizeBytes(paths, dname)
 	pbs := diskPhysicalBlockSizeBytes(paths, dname)
+	lbs := diskQueueUint(paths, dname, "logical_block_size")
 	busPath := diskBusPath(paths, dname)
 	node := diskNUMANodeID(paths, dname)
 	vendor := diskVendor(paths, dname)
@@ -347,6 +468,7 @@ func getDisk(ctx *context.Context, paths *linuxpath.Paths, dname string) *Disk {
 		Label:                  label,
 		SizeBytes:              size,
 		PhysicalBlockSizeBytes: pbs,
+		LogicalBlockSizeBytes:  lbs,
 		DriveType:              driveType,
 		IsRemovable:            removable,
 		StorageController:      storageController,
@@ -358,6 +480,13 @@ func getDisk(ctx *context.Context, paths *linuxpath.Paths, dname string) *Disk {
 		Model:                  model,
 		SerialNumber:           serialNo,
 		WWN:                    wwn,
+		FirmwareRevision:       diskFirmwareRevision(paths, dname),
+		RotationRateRPM:        diskRotationRateRPM(paths, dname),
+		LinkSpeed:              diskLinkSpeed(paths, dname),
+		DiscardGranularity:     diskQueueUint(paths, dname, "discard_granularity"),
+		DiscardMaxBytes:        diskQueueUint(paths, dname, "discard_max_bytes"),
+		ZonedModel:             diskQueueString(paths, dname, "zoned"),
+		WriteCacheEnabled:      diskQueueString(paths, dname, "write_cache") == "write back",
 		FileSystemInfo:         fs,
 	}
 
@@ -0,0 +1,90 @@
+package block
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jaypipes/ghw/pkg/context"
+	"github.com/jaypipes/ghw/pkg/linuxpath"
+	"github.com/jaypipes/ghw/pkg/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeSysfs returns the paths of a fake root holding the given files
+func newFakeSysfs(t *testing.T, files map[string]string) *linuxpath.Paths {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return linuxpath.New(context.New(option.WithChroot(root)))
+}
+
+func TestDiskQueueAttributes(t *testing.T) {
+	paths := newFakeSysfs(t, map[string]string{
+		"sys/block/sda/queue/logical_block_size":  "4096\n",
+		"sys/block/sda/queue/discard_granularity": "4096\n",
+		"sys/block/sda/queue/discard_max_bytes":   "2147450880\n",
+		"sys/block/sda/queue/zoned":               "host-managed\n",
+		"sys/block/sda/queue/write_cache":         "write back\n",
+		"sys/block/sda/queue/nr_requests":         "none\n",
+	})
+	assert.Equal(t, uint64(4096), diskQueueUint(paths, "sda", "logical_block_size"))
+	assert.Equal(t, uint64(2147450880), diskQueueUint(paths, "sda", "discard_max_bytes"))
+	assert.Equal(t, "host-managed", diskQueueString(paths, "sda", "zoned"))
+	assert.Equal(t, "write back", diskQueueString(paths, "sda", "write_cache"))
+
+	// the missing or unparsable attributes are zero
+	assert.Zero(t, diskQueueUint(paths, "sda", "nr_requests"))
+	assert.Zero(t, diskQueueUint(paths, "sdb", "logical_block_size"))
+	assert.Empty(t, diskQueueString(paths, "sdb", "zoned"))
+}
+
+func TestDiskFirmwareRevisionAndRotationRate(t *testing.T) {
+	paths := newFakeSysfs(t, map[string]string{
+		"sys/block/sda/dev":                     "8:0\n",
+		"run/udev/data/b8:0":                    "E:ID_REVISION=SN03\nE:ID_ATA_ROTATION_RATE_RPM=7200\n",
+		"sys/block/sda/device/rev":              "XXXX\n",
+		"sys/block/sdb/dev":                     "8:16\n",
+		"run/udev/data/b8:16":                   "E:ID_ATA_ROTATION_RATE_RPM=0\n",
+		"sys/block/sdb/device/rev":              "1.0 \n",
+		"sys/block/nvme0n1/dev":                 "259:0\n",
+		"sys/block/nvme0n1/device/rev":          "\n",
+		"sys/block/nvme0n1/device/firmware_rev": "GDC5302Q\n",
+	})
+	// udev wins over sysfs
+	assert.Equal(t, "SN03", diskFirmwareRevision(paths, "sda"))
+	assert.Equal(t, uint32(7200), diskRotationRateRPM(paths, "sda"))
+
+	assert.Equal(t, "1.0", diskFirmwareRevision(paths, "sdb"))
+	assert.Zero(t, diskRotationRateRPM(paths, "sdb"))
+
+	// without a udev entry, e.g. NVMe controllers report the firmware in firmware_rev
+	assert.Equal(t, "GDC5302Q", diskFirmwareRevision(paths, "nvme0n1"))
+	assert.Zero(t, diskRotationRateRPM(paths, "nvme0n1"))
+
+	assert.Empty(t, diskFirmwareRevision(paths, "sdc"))
+}
+
+func TestDiskLinkSpeed(t *testing.T) {
+	paths := newFakeSysfs(t, map[string]string{
+		"sys/block/nvme0n1/device/device/current_link_speed":                               "8.0 GT/s PCIe\n",
+		"sys/block/nvme0n1/device/device/current_link_width":                               "4\n",
+		"sys/block/nvme1n1/device/device/current_link_speed":                               "16.0 GT/s PCIe\n",
+		"sys/devices/pci0000:00/0000:00:17.0/ata3/host2/target2:0:0/2:0:0:0/block/sda/dev": "8:0\n",
+		"sys/class/ata_link/link3/sata_spd":                                                "6.0 Gbps\n",
+		"sys/devices/pci0000:00/0000:00:1f.2/host0/target0:0:0/0:0:0:0/block/sdb/dev":      "8:16\n",
+	})
+	require.NoError(t, os.Symlink("../devices/pci0000:00/0000:00:17.0/ata3/host2/target2:0:0/2:0:0:0/block/sda", filepath.Join(paths.SysBlock, "sda")))
+	require.NoError(t, os.Symlink("../devices/pci0000:00/0000:00:1f.2/host0/target0:0:0/0:0:0:0/block/sdb", filepath.Join(paths.SysBlock, "sdb")))
+
+	assert.Equal(t, "8.0 GT/s PCIe x4", diskLinkSpeed(paths, "nvme0n1"))
+	assert.Equal(t, "16.0 GT/s PCIe", diskLinkSpeed(paths, "nvme1n1"))
+	assert.Equal(t, "6.0 Gbps", diskLinkSpeed(paths, "sda"))
+	// a disk which isn't behind an ATA port, e.g. SAS
+	assert.Empty(t, diskLinkSpeed(paths, "sdb"))
+	assert.Empty(t, diskLinkSpeed(paths, "sdc"))
+}
@@ -20,6 +20,8 @@ func main() {
 				Types: []interface{}{
 					diskv1.BlockDevice{},
 					diskv1.LVMVolumeGroup{},
+					diskv1.NodeDiskInventory{},
+					diskv1.NodeDiskManagerConfig{},
 				},
 				GenerateTypes:   true,
 				GenerateClients: true,
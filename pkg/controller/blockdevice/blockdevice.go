@@ -1,6 +1,8 @@
 package blockdevice
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -17,6 +19,11 @@ const (
 	ParentDeviceLabel = "ndm.harvesterhci.io/parent-device"
 	// DeviceTypeLabel indicates whether the device is a disk or a partition
 	DeviceTypeLabel = "ndm.harvesterhci.io/device-type"
+
+	// blockDeviceNameLength is the length of the generated names, i.e. 128 bits of the hash
+	blockDeviceNameLength = 32
+	// maxNameSuffix is the number of suffixed names tried when the generated name is taken
+	maxNameSuffix = 8
 )
 
 // GetDiskBlockDevice creates a BlockDevices from a given disk. Note that the _name_ of
@@ -49,20 +56,28 @@ func GetDiskBlockDevice(disk *block.Disk, nodeName, namespace string) *diskv1.Bl
 			Capacity: diskv1.DeviceCapcity{
 				SizeBytes:              disk.SizeBytes,
 				PhysicalBlockSizeBytes: disk.PhysicalBlockSizeBytes,
+				LogicalBlockSizeBytes:  disk.LogicalBlockSizeBytes,
 			},
 			Details: diskv1.DeviceDetails{
-				DeviceType:        diskv1.DeviceTypeDisk,
-				DriveType:         disk.DriveType.String(),
-				IsRemovable:       disk.IsRemovable,
-				StorageController: disk.StorageController.String(),
-				UUID:              disk.UUID,
-				PtUUID:            disk.PtUUID,
-				BusPath:           disk.BusPath,
-				Model:             disk.Model,
-				Vendor:            disk.Vendor,
-				SerialNumber:      disk.SerialNumber,
-				NUMANodeID:        disk.NUMANodeID,
-				WWN:               disk.WWN,
+				DeviceType:              diskv1.DeviceTypeDisk,
+				DriveType:               disk.DriveType.String(),
+				IsRemovable:             disk.IsRemovable,
+				StorageController:       disk.StorageController.String(),
+				UUID:                    disk.UUID,
+				PtUUID:                  disk.PtUUID,
+				BusPath:                 disk.BusPath,
+				Model:                   disk.Model,
+				Vendor:                  disk.Vendor,
+				SerialNumber:            disk.SerialNumber,
+				NUMANodeID:              disk.NUMANodeID,
+				WWN:                     disk.WWN,
+				FirmwareRevision:        disk.FirmwareRevision,
+				RotationRateRPM:         disk.RotationRateRPM,
+				LinkSpeed:               disk.LinkSpeed,
+				DiscardGranularityBytes: disk.DiscardGranularity,
+				DiscardMaxBytes:         disk.DiscardMaxBytes,
+				ZonedModel:              disk.ZonedModel,
+				WriteCacheEnabled:       disk.WriteCacheEnabled,
 			},
 			DevPath:    devPath,
 			FileSystem: fileSystemInfo,
@@ -87,3 +102,30 @@ func GetDiskBlockDevice(disk *block.Disk, nodeName, namespace string) *diskv1.Bl
 
 	return bd
 }
+
+// GenerateBlockDeviceName derives the name of a BlockDevice from the node name and the
+// hardware identity of the disk, i.e. the WWN, or Vendor+Model+Serial+BusPath if the disk
+// has no WWN. The same disk always gets the same name, even if its CR is deleted or the
+// node is reinstalled. It returns false if the disk has none of these identifiers.
+// An empty or "unknown" WWN, serial or bus path counts as missing.
+func GenerateBlockDeviceName(nodeName string, bd *diskv1.BlockDevice) (string, bool) {
+	details := bd.Status.DeviceStatus.Details
+	serial, hasSerial := getBlockDeviceSerial(bd)
+	if !hasSerial {
+		serial = ""
+	}
+	busPath, hasBusPath := getBlockDeviceBusPath(bd)
+	if !hasBusPath {
+		busPath = ""
+	}
+	var identity string
+	if wwn, ok := getBlockDeviceWWN(bd); ok {
+		identity = "wwn:" + wwn
+	} else if hasSerial || hasBusPath {
+		identity = strings.Join([]string{"id", details.Vendor, details.Model, serial, busPath}, ":")
+	} else {
+		return "", false
+	}
+	hash := sha256.Sum256([]byte(nodeName + "/" + identity))
+	return hex.EncodeToString(hash[:])[:blockDeviceNameLength], true
+}
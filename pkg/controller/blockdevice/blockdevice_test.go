@@ -0,0 +1,88 @@
+package blockdevice
+
+import (
+	"testing"
+
+	"github.com/jaypipes/ghw/pkg/util"
+	"github.com/stretchr/testify/assert"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/block"
+)
+
+func newIdentityBlockDevice(wwn, serial, busPath string) *diskv1.BlockDevice {
+	return &diskv1.BlockDevice{
+		Status: diskv1.BlockDeviceStatus{
+			DeviceStatus: diskv1.DeviceStatus{
+				Details: diskv1.DeviceDetails{
+					Vendor:       "ATA",
+					Model:        "QEMU HARDDISK",
+					WWN:          wwn,
+					SerialNumber: serial,
+					BusPath:      busPath,
+				},
+			},
+		},
+	}
+}
+
+func TestGenerateBlockDeviceName(t *testing.T) {
+	name := func(nodeName string, bd *diskv1.BlockDevice) string {
+		name, ok := GenerateBlockDeviceName(nodeName, bd)
+		assert.True(t, ok)
+		assert.Len(t, name, blockDeviceNameLength)
+		return name
+	}
+
+	byWWN := name("node1", newIdentityBlockDevice("0x5000c500a0b1c2d3", "S1", "pci-0000:00:1f.2-ata-1"))
+	assert.Equal(t, byWWN, name("node1", newIdentityBlockDevice("0x5000c500a0b1c2d3", "S2", "pci-0000:00:1f.2-ata-2")), "the WWN takes precedence over the serial and bus path")
+	assert.NotEqual(t, byWWN, name("node2", newIdentityBlockDevice("0x5000c500a0b1c2d3", "S1", "pci-0000:00:1f.2-ata-1")), "the name depends on the node")
+
+	bySerial := name("node1", newIdentityBlockDevice(util.UNKNOWN, "S1", "pci-0000:00:1f.2-ata-1"))
+	assert.NotEqual(t, byWWN, bySerial)
+	assert.Equal(t, bySerial, name("node1", newIdentityBlockDevice("", "S1", "pci-0000:00:1f.2-ata-1")))
+	assert.NotEqual(t, bySerial, name("node1", newIdentityBlockDevice(util.UNKNOWN, "S2", "pci-0000:00:1f.2-ata-1")))
+
+	// an unknown serial is the same as no serial, and doesn't make two disks look alike
+	byBusPath := name("node1", newIdentityBlockDevice(util.UNKNOWN, util.UNKNOWN, "pci-0000:00:1f.2-ata-1"))
+	assert.Equal(t, byBusPath, name("node1", newIdentityBlockDevice(util.UNKNOWN, "", "pci-0000:00:1f.2-ata-1")))
+	assert.NotEqual(t, byBusPath, name("node1", newIdentityBlockDevice(util.UNKNOWN, util.UNKNOWN, "pci-0000:00:1f.2-ata-2")))
+
+	for _, bd := range []*diskv1.BlockDevice{
+		newIdentityBlockDevice("", "", ""),
+		newIdentityBlockDevice(util.UNKNOWN, util.UNKNOWN, util.UNKNOWN),
+		newIdentityBlockDevice(util.UNKNOWN, "", util.UNKNOWN),
+	} {
+		_, ok := GenerateBlockDeviceName("node1", bd)
+		assert.False(t, ok, "no identity: %+v", bd.Status.DeviceStatus.Details)
+		// nor can the disk conflict with another one by its device ID
+		_, ok = getBlockDeviceIdentity(bd, diskv1.IdentityKeyDeviceID)
+		assert.False(t, ok, "no device ID: %+v", bd.Status.DeviceStatus.Details)
+	}
+}
+
+func TestGetDiskBlockDeviceHardwareMetadata(t *testing.T) {
+	disk := &block.Disk{
+		Name:                   "sdb",
+		SizeBytes:              4 << 40,
+		PhysicalBlockSizeBytes: 4096,
+		LogicalBlockSizeBytes:  512,
+		FirmwareRevision:       "SN03",
+		RotationRateRPM:        7200,
+		LinkSpeed:              "6.0 Gbps",
+		DiscardGranularity:     4096,
+		DiscardMaxBytes:        2147450880,
+		ZonedModel:             "host-aware",
+		WriteCacheEnabled:      true,
+	}
+	bd := GetDiskBlockDevice(disk, "node1", "longhorn-system")
+	assert.Equal(t, diskv1.DeviceCapcity{SizeBytes: 4 << 40, PhysicalBlockSizeBytes: 4096, LogicalBlockSizeBytes: 512}, bd.Status.DeviceStatus.Capacity)
+	details := bd.Status.DeviceStatus.Details
+	assert.Equal(t, "SN03", details.FirmwareRevision)
+	assert.Equal(t, uint32(7200), details.RotationRateRPM)
+	assert.Equal(t, "6.0 Gbps", details.LinkSpeed)
+	assert.Equal(t, uint64(4096), details.DiscardGranularityBytes)
+	assert.Equal(t, uint64(2147450880), details.DiscardMaxBytes)
+	assert.Equal(t, "host-aware", details.ZonedModel)
+	assert.True(t, details.WriteCacheEnabled)
+}
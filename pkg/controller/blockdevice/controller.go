@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
-	"strings"
+	"slices"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	gocommon "github.com/harvester/go-common/common"
@@ -18,12 +20,15 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 
 	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
 	"github.com/harvester/node-disk-manager/pkg/block"
 	"github.com/harvester/node-disk-manager/pkg/filter"
 	ctldiskv1 "github.com/harvester/node-disk-manager/pkg/generated/controllers/harvesterhci.io/v1beta1"
 	ctllonghornv1 "github.com/harvester/node-disk-manager/pkg/generated/controllers/longhorn.io/v1beta2"
+	"github.com/harvester/node-disk-manager/pkg/metrics"
 	"github.com/harvester/node-disk-manager/pkg/option"
 	"github.com/harvester/node-disk-manager/pkg/provisioner"
 	"github.com/harvester/node-disk-manager/pkg/utils"
@@ -32,6 +37,8 @@ import (
 const (
 	blockDeviceHandlerName = "harvester-block-device-handler"
 	configMapHandlerName   = "harvester-node-disk-manager-configmap-handler"
+	nodeLabelsHandlerName  = "harvester-node-disk-manager-node-labels-handler"
+	ndmConfigHandlerName   = "harvester-node-disk-manager-config-handler"
 	upgradeStateLabel      = "harvesterhci.io/upgradeState"
 	upgradeStateSucceeded  = "Succeeded"
 	upgradeStateFailed     = "Failed"
@@ -52,10 +59,44 @@ type Controller struct {
 
 	LVMVgClient     ctldiskv1.LVMVolumeGroupController
 	ConfigMaps      k8scorev1.ConfigMapController
+	NDMConfigs      ctldiskv1.NodeDiskManagerConfigController
+	NDMConfigCache  ctldiskv1.NodeDiskManagerConfigCache
 	provisionerLock *sync.Mutex // Lock for some specific provisioner operations, e.g. LVM
 
 	scanner   *Scanner
 	semaphore *provisioner.Semaphore
+
+	// nodeLabels are the last seen labels of the node, used by the node selectors of the filters
+	nodeLabels map[string]string
+
+	// dryRun reports the disk operations as events instead of applying them
+	dryRun   bool
+	recorder record.EventRecorder
+
+	// cordonReadOnlyDisks disables the Longhorn scheduling on the disks whose filesystem turned read-only
+	cordonReadOnlyDisks bool
+	// persistMounts persists the mounts of the Longhorn V1 disks in systemd mount units on the host
+	persistMounts bool
+	// fsPressureThreshold and inodePressureThreshold are the percentages of used space and
+	// inodes from which a provisioned disk is under FilesystemPressure, zero disables them
+	fsPressureThreshold    uint
+	inodePressureThreshold uint
+	// ioStats samples the I/O statistics of the disks, nil when disabled
+	ioStats *ioStatsSampler
+	// trimSchedules holds when the trim of the devices never trimmed was first scheduled, by device name
+	trimSchedules sync.Map
+	// usageReadAt holds when the filesystem usage of the devices was last read, by device name
+	usageReadAt sync.Map
+	// ndmConfigScan is the scan applying the rules of the NodeDiskManagerConfig not reported
+	// yet, only used by OnNDMConfigChange. ndmConfigScanPending tells the scan listener.
+	ndmConfigScan        *pendingConfigScan
+	ndmConfigScanPending atomic.Bool
+}
+
+// pendingConfigScan is a full scan requested to apply the rules effective on the node
+type pendingConfigScan struct {
+	status  diskv1.NodeConfigStatus
+	request uint64
 }
 
 type NeedMountUpdateOP int8
@@ -80,6 +121,8 @@ func Register(
 	bds ctldiskv1.BlockDeviceController,
 	lvmVGs ctldiskv1.LVMVolumeGroupController,
 	configMaps k8scorev1.ConfigMapController,
+	ndmConfigs ctldiskv1.NodeDiskManagerConfigController,
+	coreNodes k8scorev1.NodeController,
 	block block.Info,
 	opt *option.Option,
 	scanner *Scanner,
@@ -87,19 +130,30 @@ func Register(
 	CacheDiskTags = provisioner.NewLonghornDiskTags()
 	semaphoreObj := provisioner.NewSemaphore(opt.MaxConcurrentOps)
 	controller := &Controller{
-		Namespace:        opt.Namespace,
-		NodeName:         opt.NodeName,
-		NodeCache:        nodes.Cache(),
-		Nodes:            nodes,
-		UpgradeClient:    upgrades,
-		Blockdevices:     bds,
-		BlockdeviceCache: bds.Cache(),
-		LVMVgClient:      lvmVGs,
-		ConfigMaps:       configMaps,
-		BlockInfo:        block,
-		scanner:          scanner,
-		semaphore:        semaphoreObj,
-		provisionerLock:  &sync.Mutex{},
+		Namespace:              opt.Namespace,
+		NodeName:               opt.NodeName,
+		NodeCache:              nodes.Cache(),
+		Nodes:                  nodes,
+		UpgradeClient:          upgrades,
+		Blockdevices:           bds,
+		BlockdeviceCache:       bds.Cache(),
+		LVMVgClient:            lvmVGs,
+		ConfigMaps:             configMaps,
+		NDMConfigs:             ndmConfigs,
+		NDMConfigCache:         ndmConfigs.Cache(),
+		BlockInfo:              block,
+		scanner:                scanner,
+		semaphore:              semaphoreObj,
+		provisionerLock:        &sync.Mutex{},
+		dryRun:                 opt.DryRun,
+		cordonReadOnlyDisks:    opt.CordonReadOnlyDisks,
+		persistMounts:          opt.PersistMounts,
+		fsPressureThreshold:    opt.FilesystemPressureThreshold,
+		inodePressureThreshold: opt.InodePressureThreshold,
+		recorder:               scanner.Recorder,
+	}
+	if opt.IOStatsWindow > 0 {
+		controller.ioStats = newIOStatsSampler(opt.IOStatsWindow)
 	}
 
 	// This will run the scanner once (which includes the initial CacheDiskTags
@@ -113,18 +167,128 @@ func Register(
 		return err
 	}
 
-	utils.CallerWithCondLock(scanner.Cond, func() any {
-		logrus.Infof("Waking scanner once on startup in case any device paths have changed")
-		scanner.Cond.Signal()
-		return nil
+	logrus.Infof("Waking scanner once on startup in case any device paths have changed")
+	scanner.RequestScan("startup")
+	scanner.AddScanListener(func() {
+		// report the rules of the NodeDiskManagerConfig once they were applied
+		if controller.ndmConfigScanPending.Load() {
+			controller.NDMConfigs.Enqueue(filter.DefaultNDMConfigName)
+		}
 	})
 
 	bds.OnChange(ctx, blockDeviceHandlerName, controller.OnBlockDeviceChange)
 	bds.OnRemove(ctx, blockDeviceHandlerName, controller.OnBlockDeviceDelete)
 	configMaps.OnChange(ctx, configMapHandlerName, controller.OnConfigMapChange)
+	ndmConfigs.OnChange(ctx, ndmConfigHandlerName, controller.OnNDMConfigChange)
+	coreNodes.OnChange(ctx, nodeLabelsHandlerName, controller.OnNodeLabelsChange)
+	if controller.ioStats != nil {
+		go controller.sampleIOStats(ctx)
+	}
 	return nil
 }
 
+// OnNodeLabelsChange triggers disk rescan when the labels of this node change,
+// since the filter configurations may select the node by its labels.
+func (c *Controller) OnNodeLabelsChange(_ string, node *corev1.Node) (*corev1.Node, error) {
+	if node == nil || node.Name != c.NodeName {
+		return node, nil
+	}
+	if c.nodeLabels != nil && reflect.DeepEqual(c.nodeLabels, node.Labels) {
+		return node, nil
+	}
+
+	c.nodeLabels = node.Labels
+	logrus.Infof("Labels of node %s changed, triggering disk rescan", node.Name)
+	c.scanner.RequestScan("node labels changed")
+	// the rules effective on the node may change as well
+	c.NDMConfigs.Enqueue(filter.DefaultNDMConfigName)
+	return node, nil
+}
+
+// OnNDMConfigChange triggers disk rescan when the rules of the NodeDiskManagerConfig effective on
+// this node change, and reports them in the config status once a full scan applied them. Only the
+// entry of this node is written, the entries of the removed nodes are dropped by the node controller.
+func (c *Controller) OnNDMConfigChange(_ string, ndmConfig *diskv1.NodeDiskManagerConfig) (*diskv1.NodeDiskManagerConfig, error) {
+	if ndmConfig == nil || ndmConfig.DeletionTimestamp != nil || ndmConfig.Name != filter.DefaultNDMConfigName {
+		return ndmConfig, nil
+	}
+
+	nodeStatus, err := c.scanner.ConfigMapLoader.NodeConfigStatus(ndmConfig)
+	if err != nil {
+		return ndmConfig, err
+	}
+	index := slices.IndexFunc(ndmConfig.Status.Nodes, func(status diskv1.NodeConfigStatus) bool {
+		return status.NodeName == c.NodeName
+	})
+	if index >= 0 && nodeConfigStatusEqual(ndmConfig.Status.Nodes[index], nodeStatus) {
+		c.ndmConfigScan = nil
+		c.ndmConfigScanPending.Store(false)
+		return ndmConfig, nil
+	}
+
+	if pending := c.ndmConfigScan; pending == nil || !nodeConfigStatusEqual(pending.status, nodeStatus) {
+		logrus.Infof("NodeDiskManagerConfig %s changed, triggering disk rescan", ndmConfig.Name)
+		c.ndmConfigScanPending.Store(true)
+		c.ndmConfigScan = &pendingConfigScan{status: nodeStatus, request: c.scanner.RequestScan("NodeDiskManagerConfig changed")}
+		if index < 0 || !reflect.DeepEqual(ndmConfig.Status.Nodes[index].Trim, nodeStatus.Trim) ||
+			!reflect.DeepEqual(ndmConfig.Status.Nodes[index].Tuning, nodeStatus.Tuning) {
+			// reschedule the trims and re-apply the tuning of the disks
+			c.enqueueNodeBlockDevices()
+		}
+		return ndmConfig, nil
+	}
+	if !c.scanner.FullScanServed(c.ndmConfigScan.request) {
+		// the scan listener enqueues the config again
+		return ndmConfig, nil
+	}
+
+	ndmConfigCpy := ndmConfig.DeepCopy()
+	now := metav1.Now()
+	nodeStatus.LastApplied = &now
+	ndmConfigCpy.Status.Nodes = setNodeConfigStatus(ndmConfigCpy.Status.Nodes, nodeStatus)
+	updated, err := c.NDMConfigs.UpdateStatus(ndmConfigCpy)
+	if err != nil {
+		// a conflict with the agent of another node is retried with the latest config
+		return ndmConfig, err
+	}
+	c.ndmConfigScan = nil
+	c.ndmConfigScanPending.Store(false)
+	return updated, nil
+}
+
+// setNodeConfigStatus replaces the status of the node, or inserts it in the order of the node names
+func setNodeConfigStatus(statuses []diskv1.NodeConfigStatus, nodeStatus diskv1.NodeConfigStatus) []diskv1.NodeConfigStatus {
+	for i := range statuses {
+		if statuses[i].NodeName == nodeStatus.NodeName {
+			statuses[i] = nodeStatus
+			return statuses
+		}
+	}
+	statuses = append(statuses, nodeStatus)
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].NodeName < statuses[j].NodeName
+	})
+	return statuses
+}
+
+// enqueueNodeBlockDevices enqueues every block device of the node
+func (c *Controller) enqueueNodeBlockDevices() {
+	bds, err := c.BlockdeviceCache.List(c.Namespace, labels.SelectorFromSet(map[string]string{corev1.LabelHostname: c.NodeName}))
+	if err != nil {
+		logrus.Warnf("Failed to list the block devices of node %s: %v", c.NodeName, err)
+		return
+	}
+	for _, bd := range bds {
+		c.Blockdevices.Enqueue(c.Namespace, bd.Name)
+	}
+}
+
+// nodeConfigStatusEqual compares the node config status, ignoring the time it was applied
+func nodeConfigStatusEqual(a, b diskv1.NodeConfigStatus) bool {
+	a.LastApplied, b.LastApplied = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
 // OnConfigMapChange watches the ConfigMap changes and triggers disk rescan when filter configuration changes
 func (c *Controller) OnConfigMapChange(_ string, cm *corev1.ConfigMap) (*corev1.ConfigMap, error) {
 	if cm == nil {
@@ -132,14 +296,24 @@ func (c *Controller) OnConfigMapChange(_ string, cm *corev1.ConfigMap) (*corev1.
 	}
 
 	// Only trigger rescan for the specific ConfigMap
-	if cm.Name == filter.DefaultConfigMapName && cm.Namespace == filter.DefaultConfigMapNamespace {
-		logrus.Infof("ConfigMap %s/%s changed, triggering disk rescan", cm.Namespace, cm.Name)
-		utils.CallerWithCondLock(c.scanner.Cond, func() any {
-			c.scanner.Cond.Signal()
-			return nil
-		})
+	if cm.Name != filter.DefaultConfigMapName || cm.Namespace != filter.DefaultConfigMapNamespace {
+		return cm, nil
 	}
 
+	// The filters and auto-provision rules of the ConfigMap are only used
+	// when the NodeDiskManagerConfig doesn't exist
+	_, err := c.NDMConfigCache.Get(filter.DefaultNDMConfigName)
+	if err == nil {
+		logrus.Warnf("ConfigMap %s/%s changed, but its %s, %s and %s are ignored since NodeDiskManagerConfig %s takes precedence",
+			cm.Namespace, cm.Name, filter.FiltersConfigKey, filter.AutoProvisionConfigKey, filter.UdevRulesConfigKey, filter.DefaultNDMConfigName)
+		return cm, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return cm, err
+	}
+	logrus.Infof("ConfigMap %s/%s changed, triggering disk rescan", cm.Namespace, cm.Name)
+	c.scanner.RequestScan("ConfigMap changed")
+
 	return cm, nil
 }
 
@@ -150,14 +324,28 @@ func (c *Controller) OnBlockDeviceChange(_ string, device *diskv1.BlockDevice) (
 		return nil, nil
 	}
 
+	// The device of a block device in conflict may not be the disk it was provisioned with,
+	// so it is only unprovisioned or skipped while inactive until the conflict is resolved
+	conflicted := diskv1.IdentityConflict.IsTrue(device)
+	if conflicted && device.Spec.TrustedIdentity != "" {
+		// let the scanner check whether the trusted identity resolves the conflict
+		c.scanner.RequestDeviceScan("trusted identity set", device.Status.DeviceStatus.DevPath)
+	}
+
+	if c.dryRun {
+		return c.planBlockDeviceChange(device)
+	}
+
 	// give another chance to update provision for auto provision device
-	if len(c.scanner.AutoProvisionFilters) > 0 && !device.Spec.Provision && device.Status.DeviceStatus.FileSystem.LastFormattedAt == nil {
+	if !conflicted && !device.Spec.Provision && device.Status.DeviceStatus.FileSystem.LastFormattedAt == nil {
 		if devNew, needUpdated := c.updateAutoProvisionDevice(device); needUpdated {
 			return c.Blockdevices.Update(devNew)
 		}
 	}
 
 	deviceCpy := device.DeepCopy()
+	c.applyTuning(deviceCpy)
+	c.updateIOStats(deviceCpy)
 	provisionerInst, err := c.generateProvisioner(deviceCpy)
 	if err != nil {
 		logrus.Warnf("Failed to generate provisioner for device %s: %v", device.Name, err)
@@ -168,7 +356,11 @@ func (c *Controller) OnBlockDeviceChange(_ string, device *diskv1.BlockDevice) (
 		return c.Blockdevices.Update(deviceCpy)
 	}
 	if provisionerInst == nil {
-		logrus.Infof("Skip device %s as no provisioner found or not configured", device.Name)
+		if conflicted {
+			logrus.Warnf("Skip device %s until its identity conflict is resolved: %s", device.Name, diskv1.IdentityConflict.GetMessage(device))
+		} else {
+			logrus.Infof("Skip device %s as no provisioner found or not configured", device.Name)
+		}
 		return nil, nil
 	}
 
@@ -189,6 +381,11 @@ func (c *Controller) OnBlockDeviceChange(_ string, device *diskv1.BlockDevice) (
 		return nil, nil
 	}
 
+	if conflicted {
+		logrus.Warnf("Skip provisioning device %s until its identity conflict is resolved: %s", device.Name, diskv1.IdentityConflict.GetMessage(device))
+		return nil, nil
+	}
+
 	devPath, err := provisioner.ResolvePersistentDevPath(device)
 	if err != nil {
 		return nil, err
@@ -198,15 +395,23 @@ func (c *Controller) OnBlockDeviceChange(_ string, device *diskv1.BlockDevice) (
 		"device": devPath,
 	}).Debug("Checking to format device")
 	if formatted, requeue, err := provisionerInst.Format(devPath); !formatted {
+		if provisioner.NeedsRemount(device) {
+			c.recordRemount(deviceCpy, err)
+		}
+		c.recordRepair(device, deviceCpy)
 		if requeue {
 			c.Blockdevices.EnqueueAfter(c.Namespace, device.Name, jitterEnqueueDelay())
 		}
 		if !reflect.DeepEqual(device, deviceCpy) {
 			logrus.Debugf("Update block device %s for new formatting state", device.Name)
+			if provisioner.RepairInProgress(device) && !provisioner.RepairInProgress(deviceCpy) {
+				return c.saveRepairOutcome(deviceCpy)
+			}
 			return c.Blockdevices.Update(deviceCpy)
 		}
 		return device, err
 	}
+	c.recordMountPersisted(device, deviceCpy)
 
 	/*
 	 * Spec.Filesystem.Provisioned: What we desired to do
@@ -224,6 +429,17 @@ func (c *Controller) OnBlockDeviceChange(_ string, device *diskv1.BlockDevice) (
 		}).Info("Prepare to check the new device tags")
 		requeue, err := provisionerInst.Update()
 		c.handleCondDiskAddedToNodeAndRequeue(deviceCpy, err, requeue)
+
+		if diskv1.DeviceResizing.IsTrue(deviceCpy) {
+			if resizeWaitsForMount(deviceCpy) {
+				// the remount updates the mount point of the device, which reconciles it again
+				diskv1.DeviceResizing.Reason(deviceCpy, "WaitingForMount")
+				diskv1.DeviceResizing.Message(deviceCpy, fmt.Sprintf("Waiting for the disk to be mounted at %s", provisioner.ExtraDiskMountPoint(deviceCpy)))
+			} else {
+				requeue, err := provisionerInst.Resize(devPath)
+				c.handleCondResizingAndRequeue(deviceCpy, err, requeue)
+			}
+		}
 	}
 
 	if needProvisionerProvision(device, deviceCpy) {
@@ -235,6 +451,8 @@ func (c *Controller) OnBlockDeviceChange(_ string, device *diskv1.BlockDevice) (
 		c.handleCondDiskAddedToNodeAndRequeue(deviceCpy, err, requeue)
 	}
 
+	c.trimIfDue(deviceCpy, devPath)
+
 	return c.finalizeBlockDevice(device, deviceCpy, devPath)
 }
 
@@ -248,6 +466,97 @@ func (c *Controller) handleCondDiskAddedToNodeAndRequeue(device *diskv1.BlockDev
 	}
 }
 
+// handleCondResizingAndRequeue keeps the Resizing condition until the provisioned storage
+// was grown, so a failed attempt is retried
+func (c *Controller) handleCondResizingAndRequeue(device *diskv1.BlockDevice, err error, requeue bool) {
+	if err != nil {
+		logrus.Errorf("Failed to grow the provisioned storage of device %s: %v", device.Name, err)
+		diskv1.DeviceResizing.Reason(device, "Error")
+		diskv1.DeviceResizing.Message(device, err.Error())
+		c.recorder.Eventf(device, corev1.EventTypeWarning, utils.EventReasonResizeFailed, "Failed to grow the provisioned storage: %v", err)
+	} else if !requeue {
+		diskv1.DeviceResizing.SetStatusBool(device, false)
+		diskv1.DeviceResizing.Reason(device, "Resized")
+		diskv1.DeviceResizing.Message(device, fmt.Sprintf("Grew the provisioned storage to %d bytes", device.Status.DeviceStatus.Capacity.SizeBytes))
+		c.recorder.Eventf(device, corev1.EventTypeNormal, utils.EventReasonResized, "Grew the provisioned storage to %d bytes", device.Status.DeviceStatus.Capacity.SizeBytes)
+	}
+	if requeue {
+		c.Blockdevices.EnqueueAfter(c.Namespace, device.Name, jitterEnqueueDelay())
+	}
+}
+
+// resizeWaitsForMount checks whether the filesystem of a Longhorn v1 disk can't be grown
+// yet, resize2fs only grows an ext4 filesystem online while it is mounted
+func resizeWaitsForMount(device *diskv1.BlockDevice) bool {
+	if !isLonghornV1Device(device) {
+		return false
+	}
+	fs := device.Status.DeviceStatus.FileSystem
+	return fs == nil || fs.MountPoint != provisioner.ExtraDiskMountPoint(device)
+}
+
+// recordRemount records the outcome of mounting back a disk which left its mount point
+func (c *Controller) recordRemount(device *diskv1.BlockDevice, err error) {
+	if err != nil {
+		logrus.Errorf("Failed to remount device %s: %v", device.Name, err)
+		c.recorder.Eventf(device, corev1.EventTypeWarning, utils.EventReasonRemountFailed, "Failed to remount, Longhorn scheduling stays suspended: %v", err)
+	} else if diskv1.DeviceMounted.IsTrue(device) {
+		c.recorder.Eventf(device, corev1.EventTypeNormal, utils.EventReasonRemounted, "%s, resumed Longhorn scheduling", diskv1.DeviceMounted.GetMessage(device))
+	}
+}
+
+// recordRepair records the outcome of the filesystem repair which just ran
+func (c *Controller) recordRepair(oldBd, newBd *diskv1.BlockDevice) {
+	repair := newBd.Status.DeviceStatus.FileSystem.LastRepair
+	if repair == nil || repair.InProgress || reflect.DeepEqual(repair, oldBd.Status.DeviceStatus.FileSystem.LastRepair) {
+		return
+	}
+	if repair.Succeeded {
+		c.recorder.Eventf(newBd, corev1.EventTypeNormal, utils.EventReasonRepaired, "Repaired the filesystem with %q (exit code %d)", repair.Command, repair.ExitCode)
+	} else {
+		c.recorder.Eventf(newBd, corev1.EventTypeWarning, utils.EventReasonRepairFailed, "Failed to repair the filesystem with %q (exit code %d): %s", repair.Command, repair.ExitCode, repair.Output)
+	}
+}
+
+// saveRepairOutcome persists the outcome of the filesystem repair which just ran. The
+// block device may have been written while the tool ran, the outcome is then applied to
+// its latest version, so the repair isn't flagged as in progress anymore and doesn't run again.
+func (c *Controller) saveRepairOutcome(deviceCpy *diskv1.BlockDevice) (*diskv1.BlockDevice, error) {
+	updated, err := c.Blockdevices.Update(deviceCpy)
+	if !apierrors.IsConflict(err) {
+		return updated, err
+	}
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := c.Blockdevices.Get(deviceCpy.Namespace, deviceCpy.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		latest.Status.DeviceStatus.FileSystem.LastRepair = deviceCpy.Status.DeviceStatus.FileSystem.LastRepair
+		latest.Status.DeviceStatus.FileSystem.Corrupted = deviceCpy.Status.DeviceStatus.FileSystem.Corrupted
+		if latest.Spec.FileSystem != nil {
+			latest.Spec.FileSystem.Repaired = deviceCpy.Spec.FileSystem.Repaired
+		}
+		updated, err = c.Blockdevices.Update(latest)
+		return err
+	})
+	return updated, err
+}
+
+// recordMountPersisted records the drift of the persisted mount rewritten by the provisioner,
+// and the failures to persist the mount
+func (c *Controller) recordMountPersisted(oldBd, newBd *diskv1.BlockDevice) {
+	reason, message := diskv1.MountPersisted.GetReason(newBd), diskv1.MountPersisted.GetMessage(newBd)
+	if reason == diskv1.MountPersisted.GetReason(oldBd) && message == diskv1.MountPersisted.GetMessage(oldBd) {
+		return
+	}
+	switch reason {
+	case provisioner.MountPersistedReasonDrifted:
+		c.recorder.Event(newBd, corev1.EventTypeWarning, utils.EventReasonMountDrift, message)
+	case "Error":
+		c.recorder.Event(newBd, corev1.EventTypeWarning, utils.EventReasonPersistMountFailed, message)
+	}
+}
+
 func (c *Controller) finalizeBlockDevice(oldBd, newBd *diskv1.BlockDevice, devPath string) (*diskv1.BlockDevice, error) {
 	if !reflect.DeepEqual(oldBd, newBd) {
 		logrus.Debugf("Update block device %s for new provision state", oldBd.Name)
@@ -325,7 +634,17 @@ func (c *Controller) generateLHv1Provisioner(device *diskv1.BlockDevice) (provis
 	if err != nil {
 		return nil, err
 	}
-	return provisioner.NewLHV1Provisioner(device, c.BlockInfo, node, c.Nodes, c.NodeCache, CacheDiskTags, c.semaphore)
+	// the mount root is only picked when the disk is provisioned, so a config
+	// change never moves a disk Longhorn is using
+	fs := device.Status.DeviceStatus.FileSystem
+	if device.Spec.Provision && device.Status.ProvisionPhase == diskv1.ProvisionPhaseUnprovisioned && fs != nil && fs.MountRoot == "" {
+		mountRoot, err := c.scanner.ConfigMapLoader.LoadMountRoot()
+		if err != nil {
+			return nil, err
+		}
+		fs.MountRoot = mountRoot
+	}
+	return provisioner.NewLHV1Provisioner(device, c.BlockInfo, node, c.Nodes, c.NodeCache, CacheDiskTags, c.semaphore, c.cordonReadOnlyDisks, c.persistMounts)
 }
 
 func (c *Controller) generateLVMProvisioner(device *diskv1.BlockDevice) (provisioner.Provisioner, error) {
@@ -365,6 +684,12 @@ func (c *Controller) updateDeviceStatus(device *diskv1.BlockDevice, devPath stri
 	if lastFormatted != nil && newStatus.FileSystem.LastFormattedAt == nil {
 		newStatus.FileSystem.LastFormattedAt = lastFormatted
 	}
+	// the fields set by NDM itself can't be probed from the OS
+	newStatus.FileSystem.LastRepair = oldStatus.FileSystem.LastRepair
+	newStatus.FileSystem.MountRoot = oldStatus.FileSystem.MountRoot
+	newStatus.FileSystem.MountOptions = oldStatus.FileSystem.MountOptions
+	newStatus.FileSystem.LastTrim = oldStatus.FileSystem.LastTrim
+	c.updateFilesystemUsage(device, newStatus.FileSystem)
 
 	// Update device path
 	newStatus.DevPath = devPath
@@ -399,6 +724,8 @@ func (c *Controller) OnBlockDeviceDelete(_ string, device *diskv1.BlockDevice) (
 	if device == nil {
 		return nil, nil
 	}
+	c.removeMountUnit(device)
+	metrics.DeleteBlockDevice(device.Name)
 
 	bds, err := c.BlockdeviceCache.List(c.Namespace, labels.SelectorFromSet(map[string]string{
 		corev1.LabelHostname: c.NodeName,
@@ -412,6 +739,13 @@ func (c *Controller) OnBlockDeviceDelete(_ string, device *diskv1.BlockDevice) (
 		return nil, nil
 	}
 
+	if c.dryRun {
+		for _, bd := range bds {
+			c.recorder.Eventf(device, corev1.EventTypeNormal, utils.EventReasonDryRun, "would delete partition %s and remove it from longhorn node %s", bd.Name, c.NodeName)
+		}
+		return nil, nil
+	}
+
 	// Remove dangling blockdevice partitions
 	for _, bd := range bds {
 		if err := c.Blockdevices.Delete(c.Namespace, bd.Name, &metav1.DeleteOptions{}); err != nil {
@@ -452,11 +786,21 @@ func (c *Controller) OnBlockDeviceDelete(_ string, device *diskv1.BlockDevice) (
 }
 
 func (c *Controller) updateAutoProvisionDevice(device *diskv1.BlockDevice) (*diskv1.BlockDevice, bool) {
-	tmpDisk := &block.Disk{
-		// we only need the dev path for checking auto provision
-		Name: strings.TrimPrefix(device.Status.DeviceStatus.DevPath, "/dev/"),
-	}
-	if c.scanner.ApplyAutoProvisionFiltersForDisk(tmpDisk) && canAutoProvision(c.UpgradeClient) {
+	// the expressions of the filters may match any property of the disk, so rather than
+	// probing the disk on every reconcile, the verdict of the scan which probed it is used.
+	// Any change of the filters triggers a full scan, which enqueues the devices to auto-provision.
+	devPath := device.Status.DeviceStatus.DevPath
+	verdict, found := c.scanner.FilterVerdict(devPath)
+	if !found {
+		logrus.Debugf("Skip auto provision check of device %s, %s wasn't found by the last scan", device.Name, devPath)
+		return nil, false
+	}
+	if verdict.Excluded || verdict.Disk.ZonedModel == diskv1.ZonedModelHostManaged {
+		// e.g. a device found before the zoned filter excluded it, which no provisioner can handle
+		logrus.Debugf("Skip auto provision check of device %s, %s is excluded or a host-managed zoned device", device.Name, devPath)
+		return nil, false
+	}
+	if verdict.AutoProvisioned && canAutoProvision(c.UpgradeClient) {
 		logrus.Debugf("Update auto provision device %s", device.Name)
 		deviceCpy := device.DeepCopy()
 		deviceCpy.Spec.FileSystem.ForceFormatted = true
@@ -512,7 +856,36 @@ func jitterEnqueueDelay() time.Duration {
 
 func deviceIsNotActiveOrCorrupted(device *diskv1.BlockDevice) bool {
 	return device.Status.State == diskv1.BlockDeviceInactive ||
-		(device.Status.DeviceStatus.FileSystem.Corrupted && !device.Spec.FileSystem.ForceFormatted && !device.Spec.FileSystem.Repaired)
+		(device.Status.DeviceStatus.FileSystem.Corrupted && !device.Spec.FileSystem.ForceFormatted && !device.Spec.FileSystem.Repaired && !device.Spec.FileSystem.Repair)
+}
+
+// isLonghornV1Device returns true if the device is provisioned to Longhorn with the
+// v1 data engine, which is the default provisioner
+func isLonghornV1Device(device *diskv1.BlockDevice) bool {
+	info := device.Spec.Provisioner
+	if info == nil {
+		return true
+	}
+	if info.LVM != nil {
+		return false
+	}
+	return info.Longhorn == nil || info.Longhorn.EngineVersion == provisioner.TypeLonghornV1
+}
+
+// removeMountUnit removes the mount unit of a deleted Longhorn V1 disk,
+// so the host doesn't wait for the disk on boot
+func (c *Controller) removeMountUnit(device *diskv1.BlockDevice) {
+	if !c.persistMounts || device.Spec.NodeName != c.NodeName || device.Spec.FileSystem == nil || !device.Spec.FileSystem.Provisioned {
+		return
+	}
+	mountPoint := provisioner.ExtraDiskMountPoint(device)
+	if c.dryRun {
+		c.recorder.Eventf(device, corev1.EventTypeNormal, utils.EventReasonDryRun, "would remove the mount unit %s", utils.MountUnitName(mountPoint))
+		return
+	}
+	if err := utils.RemoveMountUnit(mountPoint); err != nil {
+		logrus.Warnf("Failed to remove the mount unit of deleted device %s: %v", device.Name, err)
+	}
 }
 
 func canSkipBlockDeviceChange(device *diskv1.BlockDevice, nodeName string) bool {
@@ -0,0 +1,337 @@
+package blockdevice
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	ghwblock "github.com/jaypipes/ghw/pkg/block"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/block"
+	"github.com/harvester/node-disk-manager/pkg/filter"
+	"github.com/harvester/node-disk-manager/pkg/provisioner"
+)
+
+// fakeBlockInfo serves the given disks, keyed by their name
+type fakeBlockInfo struct {
+	disks map[string]*block.Disk
+}
+
+func newFakeBlockInfo(disks ...*block.Disk) *fakeBlockInfo {
+	info := &fakeBlockInfo{disks: map[string]*block.Disk{}}
+	for _, disk := range disks {
+		info.disks[disk.Name] = disk
+	}
+	return info
+}
+
+func (i *fakeBlockInfo) GetDisks() []*block.Disk {
+	disks := []*block.Disk{}
+	for _, disk := range i.disks {
+		disks = append(disks, disk)
+	}
+	return disks
+}
+
+func (i *fakeBlockInfo) GetPartitions() []*block.Partition {
+	return nil
+}
+
+func (i *fakeBlockInfo) GetDiskByDevPath(name string) *block.Disk {
+	if disk, found := i.disks[strings.TrimPrefix(name, "/dev/")]; found {
+		return disk
+	}
+	// like the sysfs based implementation, a missing disk is returned with empty properties
+	return &block.Disk{Name: strings.TrimPrefix(name, "/dev/")}
+}
+
+func (i *fakeBlockInfo) GetDiskNameByDevPath(devPath string) (string, bool) {
+	name := strings.TrimPrefix(devPath, "/dev/")
+	_, found := i.disks[name]
+	return name, found
+}
+
+func (i *fakeBlockInfo) GetFileSystemInfoByDevPath(devPath string) *block.FileSystemInfo {
+	if disk, found := i.disks[strings.TrimPrefix(devPath, "/dev/")]; found {
+		return &disk.FileSystemInfo
+	}
+	return nil
+}
+
+func (i *fakeBlockInfo) SupportsDiscard(devPath string) bool {
+	if disk, found := i.disks[strings.TrimPrefix(devPath, "/dev/")]; found {
+		return disk.DiscardMaxBytes > 0
+	}
+	return false
+}
+
+func newDiskBlockDevice(name, devPath string) *diskv1.BlockDevice {
+	return &diskv1.BlockDevice{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "longhorn-system"},
+		Spec: diskv1.BlockDeviceSpec{
+			DevPath:    devPath,
+			FileSystem: &diskv1.FilesystemInfo{},
+		},
+		Status: diskv1.BlockDeviceStatus{
+			State: diskv1.BlockDeviceActive,
+			DeviceStatus: diskv1.DeviceStatus{
+				DevPath:    devPath,
+				Details:    diskv1.DeviceDetails{DeviceType: diskv1.DeviceTypeDisk},
+				FileSystem: &diskv1.FilesystemStatus{},
+			},
+		},
+	}
+}
+
+func TestUpdateAutoProvisionDevice(t *testing.T) {
+	expression, err := filter.RegisterCELFilter(`disk.sizeBytes > 500*GiB && disk.driveType == "SSD"`)
+	require.NoError(t, err)
+
+	info := newFakeBlockInfo(
+		&block.Disk{Name: "sdb", SizeBytes: 960 << 30, DriveType: ghwblock.DRIVE_TYPE_SSD},
+		&block.Disk{Name: "sdc", SizeBytes: 240 << 30, DriveType: ghwblock.DRIVE_TYPE_SSD},
+		&block.Disk{Name: "sde", SizeBytes: 960 << 30, DriveType: ghwblock.DRIVE_TYPE_SSD, ZonedModel: diskv1.ZonedModelHostManaged},
+	)
+	s := newTestScanner(newFakeBlockDevices(), info)
+	s.ExcludeFilters = filter.SetExcludeFilters("", "", "", "")
+	s.AutoProvisionFilters = []*filter.Filter{expression}
+	s.collectAllDevices()
+	// the disks are only probed by the scan
+	delete(info.disks, "sdb")
+	c := &Controller{BlockInfo: info, scanner: s}
+
+	tests := []struct {
+		name      string
+		devPath   string
+		provision bool
+	}{
+		{
+			name:      "large SSD matches the expression",
+			devPath:   "/dev/sdb",
+			provision: true,
+		},
+		{
+			name:    "small SSD doesn't match the expression",
+			devPath: "/dev/sdc",
+		},
+		{
+			name:    "a gone disk is never auto-provisioned",
+			devPath: "/dev/sdd",
+		},
+		{
+			name:    "a host-managed zoned disk is never auto-provisioned",
+			devPath: "/dev/sde",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device, updated := c.updateAutoProvisionDevice(newDiskBlockDevice("bd", tt.devPath))
+			assert.Equal(t, tt.provision, updated)
+			if tt.provision {
+				assert.True(t, device.Spec.Provision)
+				assert.True(t, device.Spec.FileSystem.ForceFormatted)
+				assert.NotNil(t, device.Spec.Provisioner.Longhorn)
+			}
+		})
+	}
+}
+
+func TestSetNodeConfigStatus(t *testing.T) {
+	nodes := []diskv1.NodeConfigStatus{{NodeName: "node1"}, {NodeName: "node2"}}
+	nodes = setNodeConfigStatus(nodes, diskv1.NodeConfigStatus{NodeName: "node0", ObservedGeneration: 2})
+	nodes = setNodeConfigStatus(nodes, diskv1.NodeConfigStatus{NodeName: "node2", ObservedGeneration: 3})
+	assert.Equal(t, []diskv1.NodeConfigStatus{{NodeName: "node0", ObservedGeneration: 2}, {NodeName: "node1"}, {NodeName: "node2", ObservedGeneration: 3}}, nodes)
+}
+
+func TestHandleCondResizingAndRequeue(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		requeue  bool
+		resizing bool
+		reason   string
+		event    string
+		requeued bool
+	}{
+		{
+			name:   "the storage was grown",
+			reason: "Resized",
+			event:  "Normal Resized Grew the provisioned storage to 214748364800 bytes",
+		},
+		{
+			name:     "a failed resize keeps the condition and is retried",
+			err:      fmt.Errorf("resize2fs: Device or resource busy"),
+			requeue:  true,
+			resizing: true,
+			reason:   "Error",
+			event:    "Warning ResizeFailed Failed to grow the provisioned storage: resize2fs: Device or resource busy",
+			requeued: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bds := newFakeBlockDevices()
+			recorder := record.NewFakeRecorder(10)
+			c := &Controller{Namespace: testNamespace, Blockdevices: bds, recorder: recorder}
+			device := newDiskBlockDevice("bd", "/dev/sdb")
+			device.Status.ProvisionPhase = diskv1.ProvisionPhaseProvisioned
+			device.Status.DeviceStatus.Capacity.SizeBytes = 200 << 30
+			diskv1.DeviceResizing.SetStatusBool(device, true)
+
+			c.handleCondResizingAndRequeue(device, tt.err, tt.requeue)
+			assert.Equal(t, tt.resizing, diskv1.DeviceResizing.IsTrue(device))
+			assert.Equal(t, tt.reason, diskv1.DeviceResizing.GetReason(device))
+			require.Len(t, recorder.Events, 1)
+			assert.Equal(t, tt.event, <-recorder.Events)
+			if tt.requeued {
+				assert.Equal(t, []string{"bd"}, bds.enqueuedNames())
+			} else {
+				assert.Empty(t, bds.enqueuedNames())
+			}
+		})
+	}
+}
+
+func TestResizeWaitsForMount(t *testing.T) {
+	device := newDiskBlockDevice("bd", "/dev/sdb")
+	device.Spec.Provision = true
+	assert.True(t, resizeWaitsForMount(device), "the disk is not mounted")
+
+	device.Status.DeviceStatus.FileSystem.MountPoint = "/mnt/bd"
+	assert.True(t, resizeWaitsForMount(device), "the disk is mounted elsewhere")
+
+	device.Status.DeviceStatus.FileSystem.MountPoint = "/var/lib/harvester/extra-disks/bd"
+	assert.False(t, resizeWaitsForMount(device))
+
+	device.Status.DeviceStatus.FileSystem.MountPoint = ""
+	device.Spec.Provisioner = &diskv1.ProvisionerInfo{LVM: &diskv1.LVMProvisionerInfo{VgName: "vg"}}
+	assert.False(t, resizeWaitsForMount(device), "the physical volume is grown unmounted")
+}
+func TestRecordRepair(t *testing.T) {
+	lastRepair := &diskv1.FilesystemRepairStatus{Command: "e2fsck -fy /dev/sdb", ExitCode: 1, Succeeded: true}
+	tests := []struct {
+		name       string
+		oldRepair  *diskv1.FilesystemRepairStatus
+		lastRepair *diskv1.FilesystemRepairStatus
+		event      string
+	}{
+		{
+			name: "no repair",
+		},
+		{
+			name:      "the last repair is not recorded again",
+			oldRepair: lastRepair,
+		},
+		{
+			name:       "the accepted repair isn't recorded until it ran",
+			lastRepair: &diskv1.FilesystemRepairStatus{Command: "e2fsck -fy /dev/sdb", ExitCode: -1, InProgress: true},
+		},
+		{
+			name:       "successful repair",
+			lastRepair: &diskv1.FilesystemRepairStatus{Command: "e2fsck -fy /dev/sdb", ExitCode: 1, Succeeded: true},
+			event:      `Normal Repaired Repaired the filesystem with "e2fsck -fy /dev/sdb" (exit code 1)`,
+		},
+		{
+			name:       "failed repair",
+			oldRepair:  lastRepair,
+			lastRepair: &diskv1.FilesystemRepairStatus{Command: "e2fsck -fy /dev/sdb", ExitCode: 4, Output: "UNEXPECTED INCONSISTENCY"},
+			event:      `Warning RepairFailed Failed to repair the filesystem with "e2fsck -fy /dev/sdb" (exit code 4): UNEXPECTED INCONSISTENCY`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			c := &Controller{recorder: recorder}
+			oldBd := newDiskBlockDevice("bd", "/dev/sdb")
+			oldBd.Status.DeviceStatus.FileSystem.LastRepair = tt.oldRepair
+			newBd := oldBd.DeepCopy()
+			if tt.lastRepair != nil {
+				newBd.Status.DeviceStatus.FileSystem.LastRepair = tt.lastRepair
+			}
+
+			c.recordRepair(oldBd, newBd)
+			if tt.event != "" {
+				require.Len(t, recorder.Events, 1)
+				assert.Equal(t, tt.event, <-recorder.Events)
+			} else {
+				assert.Empty(t, recorder.Events)
+			}
+		})
+	}
+}
+
+func TestRecordMountPersisted(t *testing.T) {
+	tests := []struct {
+		name    string
+		persist func(*diskv1.BlockDevice)
+		event   string
+	}{
+		{
+			name:    "unchanged condition",
+			persist: func(*diskv1.BlockDevice) {},
+		},
+		{
+			name: "persisted mount",
+			persist: func(bd *diskv1.BlockDevice) {
+				diskv1.MountPersisted.SetStatusBool(bd, true)
+				diskv1.MountPersisted.Reason(bd, provisioner.MountPersistedReasonPersisted)
+				diskv1.MountPersisted.Message(bd, "Persisted in data-bd.mount")
+			},
+		},
+		{
+			name: "drifted mount unit",
+			persist: func(bd *diskv1.BlockDevice) {
+				diskv1.MountPersisted.SetStatusBool(bd, true)
+				diskv1.MountPersisted.Reason(bd, provisioner.MountPersistedReasonDrifted)
+				diskv1.MountPersisted.Message(bd, "Rewrote the drifted unit data-bd.mount: the unit is not enabled")
+			},
+			event: "Warning MountDrift Rewrote the drifted unit data-bd.mount: the unit is not enabled",
+		},
+		{
+			name: "failure to persist the mount",
+			persist: func(bd *diskv1.BlockDevice) {
+				diskv1.MountPersisted.SetError(bd, "", fmt.Errorf("no filesystem UUID found on /dev/sdb"))
+				diskv1.MountPersisted.SetStatusBool(bd, false)
+			},
+			event: "Warning PersistMountFailed no filesystem UUID found on /dev/sdb",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			c := &Controller{recorder: recorder}
+			oldBd := newDiskBlockDevice("bd", "/dev/sdb")
+			newBd := oldBd.DeepCopy()
+			tt.persist(newBd)
+
+			c.recordMountPersisted(oldBd, newBd)
+			if tt.event != "" {
+				require.Len(t, recorder.Events, 1)
+				assert.Equal(t, tt.event, <-recorder.Events)
+			} else {
+				assert.Empty(t, recorder.Events)
+			}
+		})
+	}
+}
+
+func TestRemoveMountUnitDryRun(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	c := &Controller{NodeName: "node1", persistMounts: true, dryRun: true, recorder: recorder}
+	device := newDiskBlockDevice("bd", "/dev/sdb")
+	device.Spec.NodeName = "node1"
+
+	// an unprovisioned disk has no mount unit
+	c.removeMountUnit(device)
+	assert.Empty(t, recorder.Events)
+
+	device.Spec.FileSystem.Provisioned = true
+	c.removeMountUnit(device)
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t, `Normal DryRun would remove the mount unit var-lib-harvester-extra\x2ddisks-bd.mount`, <-recorder.Events)
+}
@@ -0,0 +1,108 @@
+package blockdevice
+
+import (
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/block"
+	"github.com/harvester/node-disk-manager/pkg/utils"
+)
+
+// scanDevicesOnNode is the targeted counterpart of scanBlockDevicesOnNode, it only
+// re-probes the disks of the given devices instead of every disk on the node. The
+// filters loaded by the last full scan are used, as any change of the configuration
+// triggers a full scan.
+//
+// The BDs are still matched against all the BDs of the node, so a disk which moved to
+// another device path keeps its BD. A BD is deactivated or deleted like in a full scan
+// if its disk is gone, or if one of the re-probed disks now sits at its device path.
+func (s *Scanner) scanDevicesOnNode(devPaths []string) error {
+	diskNames := make(map[string]struct{})
+	for _, devPath := range devPaths {
+		// the BDs of the devices which are gone are found by their device paths below
+		if name, ok := s.BlockInfo.GetDiskNameByDevPath(devPath); ok {
+			diskNames[name] = struct{}{}
+		}
+	}
+
+	disks := make([]*block.Disk, 0, len(diskNames))
+	for name := range diskNames {
+		disks = append(disks, s.BlockInfo.GetDiskByDevPath(utils.GetFullDevPath(name)))
+	}
+	devices, verdicts := s.collectDevices(disks)
+	s.updateFilterVerdicts(verdicts, devPaths, diskNames)
+
+	// unlike the periodic full scan, which lists the BDs from the API server to catch
+	// up with any missed change, the scans of the events are served by the cache
+	cachedBDs, err := s.Blockdevices.Cache().List(s.Namespace, labels.SelectorFromSet(map[string]string{
+		corev1.LabelHostname: s.NodeName,
+	}))
+	if err != nil {
+		return err
+	}
+	existingBDs := &diskv1.BlockDeviceList{Items: make([]diskv1.BlockDevice, 0, len(cachedBDs))}
+	for _, bd := range cachedBDs {
+		existingBDs.Items = append(existingBDs.Items, *bd.DeepCopy())
+	}
+
+	// The BDs of the disks which weren't re-probed stand in for their disks when
+	// looking for identity conflicts, the others may be about to change.
+	presentBDs := make(map[string]bool, len(existingBDs.Items))
+	identityClaims := devices
+	for i := range existingBDs.Items {
+		bd := &existingBDs.Items[i]
+		name, present := s.BlockInfo.GetDiskNameByDevPath(bd.Status.DeviceStatus.DevPath)
+		_, reprobed := diskNames[name]
+		presentBDs[bd.Name] = present && !reprobed
+		if presentBDs[bd.Name] && bd.Status.State == diskv1.BlockDeviceActive {
+			identityClaims = append(identityClaims, &deviceWithAutoProvision{bd: bd})
+		}
+	}
+	identityConflicts := findIdentityConflicts(identityClaims)
+
+	unmatchedBDs, err := s.syncBlockDevices(devices, existingBDs, identityConflicts)
+	if err != nil {
+		return err
+	}
+	for name := range unmatchedBDs {
+		// the disks of the other BDs are still there, they just weren't scanned
+		if presentBDs[name] {
+			delete(unmatchedBDs, name)
+		}
+	}
+	for i := range existingBDs.Items {
+		// a re-plugged disk lost its tuning, the controller re-applies it
+		bd := &existingBDs.Items[i]
+		if _, gone := unmatchedBDs[bd.Name]; !gone && !presentBDs[bd.Name] {
+			s.Blockdevices.Enqueue(s.Namespace, bd.Name)
+		}
+	}
+	for _, bd := range unmatchedBDs {
+		logrus.WithFields(logrus.Fields{
+			"name":   bd.Name,
+			"device": bd.Status.DeviceStatus.DevPath,
+		}).Info("block device is gone or replaced")
+	}
+	return s.deactivateOrDeleteBlockDevices(unmatchedBDs)
+}
+
+// updateFilterVerdicts replaces the verdicts of the re-probed disks and drops the
+// verdicts of the devices which are gone.
+func (s *Scanner) updateFilterVerdicts(verdicts []diskv1.DiskFilterVerdict, devPaths []string, diskNames map[string]struct{}) {
+	replaced := make(map[string]struct{}, len(devPaths)+len(diskNames))
+	for _, devPath := range devPaths {
+		replaced[devPath] = struct{}{}
+	}
+	for name := range diskNames {
+		replaced[utils.GetFullDevPath(name)] = struct{}{}
+	}
+
+	for _, verdict := range s.FilterVerdicts() {
+		if _, ok := replaced[verdict.DevPath]; !ok {
+			verdicts = append(verdicts, verdict)
+		}
+	}
+	s.setFilterVerdicts(verdicts)
+}
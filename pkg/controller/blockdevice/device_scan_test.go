@@ -0,0 +1,180 @@
+package blockdevice
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	ghwblock "github.com/jaypipes/ghw/pkg/block"
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/record"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/block"
+	diskfake "github.com/harvester/node-disk-manager/pkg/generated/clientset/versioned/fake"
+	ctldiskv1 "github.com/harvester/node-disk-manager/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/provisioner"
+	"github.com/harvester/node-disk-manager/pkg/utils/fake"
+)
+
+const testNamespace = "longhorn-system"
+
+// fakeBlockDevices serves the block devices of a fake clientset and records the
+// enqueued ones, with or without delay. The other methods are not implemented.
+type fakeBlockDevices struct {
+	ctldiskv1.BlockDeviceController
+	clientset *diskfake.Clientset
+
+	lock     sync.Mutex
+	enqueued []string
+}
+
+func newFakeBlockDevices(bds ...*diskv1.BlockDevice) *fakeBlockDevices {
+	clientset := diskfake.NewSimpleClientset()
+	for _, bd := range bds {
+		_ = clientset.Tracker().Add(bd)
+	}
+	return &fakeBlockDevices{clientset: clientset}
+}
+
+func (f *fakeBlockDevices) Get(namespace, name string, options metav1.GetOptions) (*diskv1.BlockDevice, error) {
+	return f.clientset.HarvesterhciV1beta1().BlockDevices(namespace).Get(context.TODO(), name, options)
+}
+
+func (f *fakeBlockDevices) List(namespace string, opts metav1.ListOptions) (*diskv1.BlockDeviceList, error) {
+	return f.clientset.HarvesterhciV1beta1().BlockDevices(namespace).List(context.TODO(), opts)
+}
+
+func (f *fakeBlockDevices) Create(bd *diskv1.BlockDevice) (*diskv1.BlockDevice, error) {
+	return f.clientset.HarvesterhciV1beta1().BlockDevices(bd.Namespace).Create(context.TODO(), bd, metav1.CreateOptions{})
+}
+
+func (f *fakeBlockDevices) Update(bd *diskv1.BlockDevice) (*diskv1.BlockDevice, error) {
+	return f.clientset.HarvesterhciV1beta1().BlockDevices(bd.Namespace).Update(context.TODO(), bd, metav1.UpdateOptions{})
+}
+
+func (f *fakeBlockDevices) Delete(namespace, name string, options *metav1.DeleteOptions) error {
+	return f.clientset.HarvesterhciV1beta1().BlockDevices(namespace).Delete(context.TODO(), name, *options)
+}
+
+func (f *fakeBlockDevices) Enqueue(_, name string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.enqueued = append(f.enqueued, name)
+}
+
+func (f *fakeBlockDevices) EnqueueAfter(namespace, name string, _ time.Duration) {
+	f.Enqueue(namespace, name)
+}
+
+func (f *fakeBlockDevices) Cache() generic.CacheInterface[*diskv1.BlockDevice] {
+	list, _ := f.List(testNamespace, metav1.ListOptions{})
+	bds := make([]*diskv1.BlockDevice, 0, len(list.Items))
+	for i := range list.Items {
+		bds = append(bds, &list.Items[i])
+	}
+	return fake.NewBlockDeviceCache(bds)
+}
+
+func (f *fakeBlockDevices) enqueuedNames() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return slices.Clone(f.enqueued)
+}
+
+// names returns the names of the block devices, sorted
+func (f *fakeBlockDevices) names(t *testing.T) []string {
+	list, err := f.List(testNamespace, metav1.ListOptions{LabelSelector: labels.Everything().String()})
+	require.NoError(t, err)
+	names := []string{}
+	for _, bd := range list.Items {
+		names = append(names, bd.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newSSD(name, serial string) *block.Disk {
+	return &block.Disk{
+		Name:         name,
+		SizeBytes:    100 << 30,
+		DriveType:    ghwblock.DRIVE_TYPE_SSD,
+		Vendor:       "ATA",
+		Model:        "QEMU HARDDISK",
+		SerialNumber: serial,
+		BusPath:      "pci-0000:00:1f.2-" + name,
+	}
+}
+
+// newScannedBlockDevice returns the block device the scanner creates for the disk, with the given name
+func newScannedBlockDevice(name string, disk *block.Disk) *diskv1.BlockDevice {
+	bd := GetDiskBlockDevice(disk, "node1", testNamespace)
+	bd.Name = name
+	return bd
+}
+
+func newTestScanner(bds *fakeBlockDevices, info block.Info) *Scanner {
+	CacheDiskTags = provisioner.NewLonghornDiskTags()
+	return &Scanner{
+		NodeName:     "node1",
+		Namespace:    testNamespace,
+		Blockdevices: bds,
+		BlockInfo:    info,
+		Cond:         sync.NewCond(&sync.Mutex{}),
+		Recorder:     record.NewFakeRecorder(10),
+	}
+}
+
+func TestScanDevicesOnNode(t *testing.T) {
+	sda, sdb := newSSD("sda", "S1"), newSSD("sdb", "S2")
+	// sda isn't re-probed, sdb was unplugged and is back, sdc is gone and sdd was plugged
+	bdA := newScannedBlockDevice("bd-a", sda)
+	bdB := newScannedBlockDevice("bd-b", sdb)
+	bdB.Status.State = diskv1.BlockDeviceInactive
+	bdB.Status.ProvisionPhase = diskv1.ProvisionPhaseProvisioned
+	bdC := newScannedBlockDevice("bd-c", newSSD("sdc", "S3"))
+	sdd := newSSD("sdd", "S4")
+	bds := newFakeBlockDevices(bdA, bdB, bdC)
+
+	s := newTestScanner(bds, newFakeBlockInfo(sda, sdb, sdd))
+	s.setFilterVerdicts([]diskv1.DiskFilterVerdict{{DevPath: "/dev/sda"}, {DevPath: "/dev/sdc"}})
+
+	require.NoError(t, s.scanDevicesOnNode([]string{"/dev/sdb", "/dev/sdc", "/dev/sdd"}))
+
+	names := bds.names(t)
+	require.Len(t, names, 3, "bd-c is deleted and a block device is created for sdd: %v", names)
+	assert.Contains(t, names, "bd-a")
+	assert.Contains(t, names, "bd-b")
+	for _, name := range names {
+		if name == "bd-a" || name == "bd-b" {
+			continue
+		}
+		created, err := bds.Get(testNamespace, name, metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "/dev/sdd", created.Status.DeviceStatus.DevPath)
+	}
+
+	reactivated, err := bds.Get(testNamespace, "bd-b", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, diskv1.BlockDeviceActive, reactivated.Status.State)
+	unchanged, err := bds.Get(testNamespace, "bd-a", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, bdA.Status, unchanged.Status)
+
+	// only the re-plugged disk is enqueued to re-apply its tuning
+	assert.Equal(t, []string{"bd-b"}, bds.enqueuedNames())
+
+	// the verdict of the disk which wasn't re-probed is kept, the one of the gone disk dropped
+	devPaths := []string{}
+	for _, verdict := range s.FilterVerdicts() {
+		devPaths = append(devPaths, verdict.DevPath)
+	}
+	assert.Equal(t, []string{"/dev/sda", "/dev/sdb", "/dev/sdd"}, devPaths)
+}
@@ -0,0 +1,94 @@
+package blockdevice
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/provisioner"
+	"github.com/harvester/node-disk-manager/pkg/utils"
+)
+
+// planBlockDeviceChange follows the same path as OnBlockDeviceChange, but
+// only reports the operations it would perform as events on the block device.
+// Neither the block device nor the disk is changed.
+func (c *Controller) planBlockDeviceChange(device *diskv1.BlockDevice) (*diskv1.BlockDevice, error) {
+	plan := []string{}
+
+	deviceCpy := device.DeepCopy()
+	plan = append(plan, c.planTuning(device)...)
+	conflicted := diskv1.IdentityConflict.IsTrue(device)
+	if !conflicted && !device.Spec.Provision && device.Status.DeviceStatus.FileSystem.LastFormattedAt == nil {
+		if devNew, needUpdated := c.updateAutoProvisionDevice(device); needUpdated {
+			plan = append(plan, fmt.Sprintf("auto-provision block device %s with %s", device.Name, provisioner.TypeLonghornV1))
+			deviceCpy = devNew
+		}
+	}
+
+	provisionerInst, err := c.generateProvisioner(deviceCpy)
+	if err != nil {
+		logrus.Warnf("Failed to generate provisioner for device %s: %v", device.Name, err)
+		return nil, err
+	}
+	if provisionerInst == nil {
+		c.reportPlan(device, plan)
+		return nil, nil
+	}
+	planner, ok := provisionerInst.(provisioner.Planner)
+	if !ok {
+		return nil, fmt.Errorf("provisioner of device %s does not support dry-run", device.Name)
+	}
+
+	if needProvisionerUnprovision(deviceCpy) {
+		steps, err := planner.PlanUnProvision()
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, steps...)
+	}
+
+	// the block devices in conflict are only unprovisioned
+	if deviceIsNotActiveOrCorrupted(deviceCpy) || conflicted {
+		c.reportPlan(device, plan)
+		return nil, nil
+	}
+
+	devPath, err := provisioner.ResolvePersistentDevPath(deviceCpy)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := planner.PlanFormat(devPath)
+	if err != nil {
+		return nil, err
+	}
+	plan = append(plan, steps...)
+
+	if needProvisionerUpdate(device, deviceCpy) {
+		steps, err := planner.PlanUpdate()
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, steps...)
+	}
+	if needProvisionerProvision(device, deviceCpy) {
+		steps, err := planner.PlanProvision()
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, steps...)
+	}
+
+	c.reportPlan(device, plan)
+	return nil, nil
+}
+
+func (c *Controller) reportPlan(device *diskv1.BlockDevice, plan []string) {
+	for _, step := range plan {
+		logrus.WithFields(logrus.Fields{
+			"device": device.Name,
+		}).Infof("[dry-run] would %s", step)
+		c.recorder.Eventf(device, corev1.EventTypeNormal, utils.EventReasonDryRun, "would %s", step)
+	}
+}
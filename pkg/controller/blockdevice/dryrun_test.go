@@ -0,0 +1,78 @@
+package blockdevice
+
+import (
+	"testing"
+
+	longhornv1 "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/provisioner"
+	"github.com/harvester/node-disk-manager/pkg/utils/fake"
+)
+
+func TestPlanBlockDeviceChange(t *testing.T) {
+	defer func(tags *provisioner.DiskTags) { CacheDiskTags = tags }(CacheDiskTags)
+	CacheDiskTags = provisioner.NewLonghornDiskTags()
+	CacheDiskTags.UpdateInitialized()
+
+	node := &longhornv1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1", Namespace: testNamespace},
+		Spec:       longhornv1.NodeSpec{Disks: map[string]longhornv1.DiskSpec{"bd": {AllowScheduling: true}}},
+	}
+	tests := []struct {
+		name   string
+		update func(*diskv1.BlockDevice)
+		events []string
+	}{
+		{
+			name:   "a device which is not provisioned is left alone",
+			update: func(*diskv1.BlockDevice) {},
+		},
+		{
+			name: "an unprovisioned disk is evicted first",
+			update: func(bd *diskv1.BlockDevice) {
+				bd.Spec.Provisioner = &diskv1.ProvisionerInfo{Longhorn: &diskv1.LonghornProvisionerInfo{EngineVersion: provisioner.TypeLonghornV1}}
+				bd.Status.ProvisionPhase = diskv1.ProvisionPhaseProvisioned
+				bd.Status.State = diskv1.BlockDeviceInactive
+			},
+			events: []string{"Normal DryRun would disable scheduling and request eviction of disk bd on longhorn node node1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			bds := newFakeBlockDevices()
+			c := &Controller{
+				Namespace:    testNamespace,
+				NodeName:     "node1",
+				NodeCache:    fake.NewLonghornNodeCache([]*longhornv1.Node{node}),
+				Blockdevices: bds,
+				BlockInfo:    newFakeBlockInfo(),
+				scanner:      &Scanner{ConfigMapLoader: newConfigMapLoader(t, "")},
+				dryRun:       true,
+				recorder:     recorder,
+			}
+			device := newDiskBlockDevice("bd", "/dev/sdb")
+			device.Spec.NodeName = "node1"
+			device.Status.ProvisionPhase = diskv1.ProvisionPhaseUnprovisioned
+			tt.update(device)
+			original := device.DeepCopy()
+
+			updated, err := c.OnBlockDeviceChange("", device)
+			require.NoError(t, err)
+			assert.Nil(t, updated)
+			assert.Equal(t, original, device, "the block device is not changed")
+			assert.Empty(t, bds.enqueuedNames())
+			close(recorder.Events)
+			var events []string
+			for event := range recorder.Events {
+				events = append(events, event)
+			}
+			assert.Equal(t, tt.events, events)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package blockdevice
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+const (
+	identityConflictReason = "DuplicateIdentity"
+	identityResolvedReason = "Resolved"
+)
+
+// identityConflict is an identity shared by a device with another device on the node
+type identityConflict struct {
+	key     diskv1.IdentityKey
+	value   string
+	devPath string
+}
+
+func (c identityConflict) String() string {
+	return fmt.Sprintf("%s %s is also reported by %s", c.key, c.value, c.devPath)
+}
+
+// findIdentityConflicts returns the identities claimed by more than one of the
+// devices found by the scanner, mapped by the device path of every claiming device.
+// This happens e.g. with RAID controllers exposing the same WWN for several disks,
+// or with a cloned disk bringing a duplicate filesystem UUID.
+func findIdentityConflicts(devices []*deviceWithAutoProvision) map[string][]identityConflict {
+	claims := map[diskv1.IdentityKey]map[string][]string{
+		diskv1.IdentityKeyUUID:     {},
+		diskv1.IdentityKeyWWN:      {},
+		diskv1.IdentityKeyDeviceID: {},
+	}
+	for _, device := range devices {
+		devPath := device.bd.Status.DeviceStatus.DevPath
+		for key, values := range claims {
+			if value, ok := getBlockDeviceIdentity(device.bd, key); ok {
+				values[value] = append(values[value], devPath)
+			}
+		}
+	}
+
+	conflicts := make(map[string][]identityConflict)
+	for key, values := range claims {
+		for value, devPaths := range values {
+			if len(devPaths) < 2 {
+				continue
+			}
+			for _, devPath := range devPaths {
+				for _, other := range devPaths {
+					if other != devPath {
+						conflicts[devPath] = append(conflicts[devPath], identityConflict{key: key, value: value, devPath: other})
+					}
+				}
+			}
+		}
+	}
+	for _, deviceConflicts := range conflicts {
+		sort.Slice(deviceConflicts, func(i, j int) bool {
+			return deviceConflicts[i].String() < deviceConflicts[j].String()
+		})
+	}
+	return conflicts
+}
+
+// getBlockDeviceIdentity returns the value of the identity key of the block device
+func getBlockDeviceIdentity(bd *diskv1.BlockDevice, key diskv1.IdentityKey) (string, bool) {
+	switch key {
+	case diskv1.IdentityKeyUUID:
+		return getBlockDeviceUUID(bd)
+	case diskv1.IdentityKeyWWN:
+		return getBlockDeviceWWN(bd)
+	case diskv1.IdentityKeyDeviceID:
+		details := bd.Status.DeviceStatus.Details
+		serial, hasSerial := getBlockDeviceSerial(bd)
+		busPath, hasBusPath := getBlockDeviceBusPath(bd)
+		if !hasSerial && !hasBusPath {
+			return "", false
+		}
+		return strings.Join([]string{details.Vendor, details.Model, serial, busPath}, "/"), true
+	}
+	return "", false
+}
+
+// hasIdentityConflict returns true if the identity key is shared with another device
+func hasIdentityConflict(conflicts []identityConflict, key diskv1.IdentityKey) bool {
+	for _, conflict := range conflicts {
+		if conflict.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// setIdentityConflict updates the IdentityConflict condition of the block device. The
+// conflict is resolved once the spec trusts an identity key of the device which is not in
+// conflict. The condition is only added when a conflict is found.
+func setIdentityConflict(bd *diskv1.BlockDevice, conflicts []identityConflict) {
+	if len(conflicts) == 0 {
+		if diskv1.IdentityConflict.GetStatus(bd) != "" {
+			diskv1.IdentityConflict.SetStatusBool(bd, false)
+			diskv1.IdentityConflict.Reason(bd, "")
+			diskv1.IdentityConflict.Message(bd, "")
+		}
+		return
+	}
+
+	details := make([]string, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		details = append(details, conflict.String())
+	}
+	message := strings.Join(details, "; ")
+
+	trusted := bd.Spec.TrustedIdentity
+	if _, ok := getBlockDeviceIdentity(bd, trusted); ok && !hasIdentityConflict(conflicts, trusted) {
+		diskv1.IdentityConflict.SetStatusBool(bd, false)
+		diskv1.IdentityConflict.Reason(bd, identityResolvedReason)
+		diskv1.IdentityConflict.Message(bd, fmt.Sprintf("%s, resolved by trusting %s", message, trusted))
+		return
+	}
+
+	diskv1.IdentityConflict.SetStatusBool(bd, true)
+	diskv1.IdentityConflict.Reason(bd, identityConflictReason)
+	diskv1.IdentityConflict.Message(bd, fmt.Sprintf("%s, set spec.trustedIdentity to an identity key which is not in conflict", message))
+}
@@ -0,0 +1,236 @@
+package blockdevice
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/metrics"
+	"github.com/harvester/node-disk-manager/pkg/provisioner"
+	"github.com/harvester/node-disk-manager/pkg/utils"
+)
+
+const (
+	// ioStatsStatusInterval is how often the I/O summary of a block device is refreshed in its
+	// status, the metrics are refreshed at every sampling window. Every refresh writes the
+	// whole block device, so the status only follows the load coarsely.
+	ioStatsStatusInterval = time.Hour
+	// ioStatsUtilizationStep is the change of utilization, in percent, refreshing
+	// the summary before the interval
+	ioStatsUtilizationStep = 25
+)
+
+var readDiskStats = utils.ReadDiskStats
+
+// ioStatsSampler samples /proc/diskstats for the disks of the active block devices
+type ioStatsSampler struct {
+	window time.Duration
+
+	lock sync.Mutex
+	// previous is the last sample, by disk name
+	previous     map[string]utils.DiskStats
+	previousTime time.Time
+	// summaries are the summaries of the last window, by block device name
+	summaries map[string]*diskv1.IOStatsSummary
+	// exported are the labels of the series exported for every block device
+	exported map[string]metrics.IOStatsLabels
+}
+
+func newIOStatsSampler(window time.Duration) *ioStatsSampler {
+	return &ioStatsSampler{
+		window:    window,
+		summaries: make(map[string]*diskv1.IOStatsSummary),
+		exported:  make(map[string]metrics.IOStatsLabels),
+	}
+}
+
+// summary returns the summary of the last window of the block device, or nil
+func (s *ioStatsSampler) summary(name string) *diskv1.IOStatsSummary {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.summaries[name].DeepCopy()
+}
+
+// sampleIOStats samples the I/O statistics at every window until the context is done
+func (c *Controller) sampleIOStats(ctx context.Context) {
+	ticker := time.NewTicker(c.ioStats.window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.sampleIOStatsOnce(time.Now()); err != nil {
+				logrus.Warnf("Failed to sample the I/O statistics: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sampleIOStatsOnce computes the I/O statistics of the active block devices since the
+// previous sample, exports them as metrics and enqueues the devices with an outdated summary
+func (c *Controller) sampleIOStatsOnce(now time.Time) error {
+	stats, err := readDiskStats()
+	if err != nil {
+		return err
+	}
+	bds, err := c.BlockdeviceCache.List(c.Namespace, labels.SelectorFromSet(map[string]string{
+		corev1.LabelHostname: c.NodeName,
+	}))
+	if err != nil {
+		return err
+	}
+
+	s := c.ioStats
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	previous, elapsed := s.previous, now.Sub(s.previousTime)
+	s.previous, s.previousTime = stats, now
+
+	sampled := make(map[string]struct{}, len(bds))
+	for _, bd := range bds {
+		if bd.Status.State != diskv1.BlockDeviceActive || bd.Status.DeviceStatus.Details.DeviceType != diskv1.DeviceTypeDisk {
+			continue
+		}
+		disk, found := c.BlockInfo.GetDiskNameByDevPath(bd.Status.DeviceStatus.DevPath)
+		if !found {
+			continue
+		}
+		current, ok := stats[disk]
+		before, hadBefore := previous[disk]
+		if !ok || !hadBefore || !countersIncreased(before, current) {
+			// the first sample of the disk, or its counters were reset by a re-plug
+			continue
+		}
+		sampled[bd.Name] = struct{}{}
+
+		rates := ioRates(before, current, elapsed)
+		labels := ioStatsLabels(bd)
+		if exported, found := s.exported[bd.Name]; found && exported != labels {
+			metrics.DeleteIOStats(bd.Name)
+		}
+		metrics.ObserveIOStats(labels, rates)
+		s.exported[bd.Name] = labels
+
+		summary := &diskv1.IOStatsSummary{
+			ReadIOPS:                 int64(rates.ReadsPerSecond),
+			WriteIOPS:                int64(rates.WritesPerSecond),
+			ReadBytesPerSecond:       int64(rates.ReadBytesPerSecond),
+			WriteBytesPerSecond:      int64(rates.WrittenBytesPerSecond),
+			ReadLatencyMicroseconds:  int64(rates.ReadLatencySeconds * 1e6),
+			WriteLatencyMicroseconds: int64(rates.WriteLatencySeconds * 1e6),
+			UtilizationPercent:       int64(rates.Utilization * 100),
+			Window:                   metav1.Duration{Duration: elapsed.Round(time.Second)},
+			LastUpdated:              metav1.NewTime(now),
+		}
+		s.summaries[bd.Name] = summary
+		if ioStatsOutdated(bd.Status.IOStats, summary) {
+			c.Blockdevices.Enqueue(c.Namespace, bd.Name)
+		}
+	}
+
+	// drop the statistics of the devices which are gone or inactive
+	for name := range s.summaries {
+		if _, found := sampled[name]; !found {
+			delete(s.summaries, name)
+		}
+	}
+	for name := range s.exported {
+		if _, found := sampled[name]; !found {
+			metrics.DeleteIOStats(name)
+			delete(s.exported, name)
+		}
+	}
+	return nil
+}
+
+// updateIOStats reports the summary of the last window in the status of the block
+// device when the reported summary is outdated
+func (c *Controller) updateIOStats(device *diskv1.BlockDevice) {
+	if c.ioStats == nil {
+		return
+	}
+	summary := c.ioStats.summary(device.Name)
+	if summary == nil {
+		if device.Status.State != diskv1.BlockDeviceActive {
+			device.Status.IOStats = nil
+		}
+		return
+	}
+	if ioStatsOutdated(device.Status.IOStats, summary) {
+		device.Status.IOStats = summary
+	}
+}
+
+// ioStatsOutdated checks whether the reported summary is missing, older than the status
+// interval, or differs from the summary of the last window by a significant utilization
+func ioStatsOutdated(reported, summary *diskv1.IOStatsSummary) bool {
+	if reported == nil {
+		return true
+	}
+	utilizationChange := summary.UtilizationPercent - reported.UtilizationPercent
+	return summary.LastUpdated.Sub(reported.LastUpdated.Time) >= ioStatsStatusInterval ||
+		utilizationChange >= ioStatsUtilizationStep || utilizationChange <= -ioStatsUtilizationStep
+}
+
+func countersIncreased(before, current utils.DiskStats) bool {
+	return current.ReadsCompleted >= before.ReadsCompleted && current.WritesCompleted >= before.WritesCompleted &&
+		current.SectorsRead >= before.SectorsRead && current.SectorsWritten >= before.SectorsWritten &&
+		current.ReadTimeMs >= before.ReadTimeMs && current.WriteTimeMs >= before.WriteTimeMs &&
+		current.IOTimeMs >= before.IOTimeMs
+}
+
+// ioRates computes the rates of the counters over the elapsed time, the latencies
+// are the time spent per completed request, like iostat's r_await and w_await
+func ioRates(before, current utils.DiskStats, elapsed time.Duration) metrics.IORates {
+	seconds := elapsed.Seconds()
+	if seconds <= 0 {
+		return metrics.IORates{}
+	}
+	reads := float64(current.ReadsCompleted - before.ReadsCompleted)
+	writes := float64(current.WritesCompleted - before.WritesCompleted)
+	rates := metrics.IORates{
+		ReadsPerSecond:        reads / seconds,
+		WritesPerSecond:       writes / seconds,
+		ReadBytesPerSecond:    float64((current.SectorsRead-before.SectorsRead)*utils.DiskStatsSectorSize) / seconds,
+		WrittenBytesPerSecond: float64((current.SectorsWritten-before.SectorsWritten)*utils.DiskStatsSectorSize) / seconds,
+		Utilization:           min(float64(current.IOTimeMs-before.IOTimeMs)/1000/seconds, 1),
+	}
+	if reads > 0 {
+		rates.ReadLatencySeconds = float64(current.ReadTimeMs-before.ReadTimeMs) / 1000 / reads
+	}
+	if writes > 0 {
+		rates.WriteLatencySeconds = float64(current.WriteTimeMs-before.WriteTimeMs) / 1000 / writes
+	}
+	return rates
+}
+
+// ioStatsLabels labels the statistics with the provisioner of a provisioned device
+func ioStatsLabels(bd *diskv1.BlockDevice) metrics.IOStatsLabels {
+	tags := slices.Clone(bd.Spec.Tags)
+	slices.Sort(tags)
+	labels := metrics.IOStatsLabels{
+		BlockDevice: bd.Name,
+		Node:        bd.Spec.NodeName,
+		Tags:        strings.Join(tags, ","),
+	}
+	if bd.Spec.Provision {
+		switch {
+		case bd.Spec.Provisioner != nil && bd.Spec.Provisioner.LVM != nil:
+			labels.Provisioner = provisioner.TypeLVM
+		case isLonghornV1Device(bd):
+			labels.Provisioner = provisioner.TypeLonghornV1
+		default:
+			labels.Provisioner = provisioner.TypeLonghornV2
+		}
+	}
+	return labels
+}
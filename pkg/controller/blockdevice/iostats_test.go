@@ -0,0 +1,177 @@
+package blockdevice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/block"
+	"github.com/harvester/node-disk-manager/pkg/metrics"
+	"github.com/harvester/node-disk-manager/pkg/provisioner"
+	"github.com/harvester/node-disk-manager/pkg/utils"
+)
+
+func TestIORates(t *testing.T) {
+	before := utils.DiskStats{ReadsCompleted: 1000, SectorsRead: 8000, ReadTimeMs: 2000, WritesCompleted: 500, SectorsWritten: 4000, WriteTimeMs: 3000, IOTimeMs: 10000}
+	current := utils.DiskStats{ReadsCompleted: 1100, SectorsRead: 10048, ReadTimeMs: 2500, WritesCompleted: 550, SectorsWritten: 6048, WriteTimeMs: 4000, IOTimeMs: 15000}
+	assert.Equal(t, metrics.IORates{
+		ReadsPerSecond:        10,
+		WritesPerSecond:       5,
+		ReadBytesPerSecond:    104857.6,
+		WrittenBytesPerSecond: 104857.6,
+		ReadLatencySeconds:    0.005,
+		WriteLatencySeconds:   0.02,
+		Utilization:           0.5,
+	}, ioRates(before, current, 10*time.Second))
+
+	// no request completed in the window, and the I/O time is rounded over the window
+	idle := before
+	idle.IOTimeMs += 10100
+	assert.Equal(t, metrics.IORates{Utilization: 1}, ioRates(before, idle, 10*time.Second))
+
+	assert.Equal(t, metrics.IORates{}, ioRates(before, current, 0))
+}
+
+func TestCountersIncreased(t *testing.T) {
+	before := utils.DiskStats{ReadsCompleted: 1000, SectorsRead: 8000, ReadTimeMs: 2000, WritesCompleted: 500, SectorsWritten: 4000, WriteTimeMs: 3000, IOTimeMs: 10000}
+	assert.True(t, countersIncreased(before, before))
+
+	current := before
+	current.ReadsCompleted++
+	current.IOTimeMs++
+	assert.True(t, countersIncreased(before, current))
+
+	// the counters start over when the disk is re-plugged
+	current.WriteTimeMs = 10
+	assert.False(t, countersIncreased(before, current))
+	assert.False(t, countersIncreased(before, utils.DiskStats{}))
+}
+
+func TestIOStatsLabels(t *testing.T) {
+	bd := newDiskBlockDevice("bd", "/dev/sdb")
+	bd.Spec.NodeName = "node1"
+	bd.Spec.Tags = []string{"ssd", "fast"}
+	assert.Equal(t, metrics.IOStatsLabels{BlockDevice: "bd", Node: "node1", Tags: "fast,ssd"}, ioStatsLabels(bd),
+		"the tags are sorted and a device which isn't provisioned has no provisioner")
+	assert.Equal(t, []string{"ssd", "fast"}, bd.Spec.Tags, "the tags of the device are not sorted")
+
+	bd.Spec.Provision = true
+	assert.Equal(t, provisioner.TypeLonghornV1, ioStatsLabels(bd).Provisioner)
+	bd.Spec.Provisioner = &diskv1.ProvisionerInfo{Longhorn: &diskv1.LonghornProvisionerInfo{EngineVersion: provisioner.TypeLonghornV2}}
+	assert.Equal(t, provisioner.TypeLonghornV2, ioStatsLabels(bd).Provisioner)
+	bd.Spec.Provisioner = &diskv1.ProvisionerInfo{LVM: &diskv1.LVMProvisionerInfo{VgName: "vg"}}
+	assert.Equal(t, provisioner.TypeLVM, ioStatsLabels(bd).Provisioner)
+}
+
+func newIOStatsBlockDevice(name, devPath string) *diskv1.BlockDevice {
+	bd := newDiskBlockDevice(name, devPath)
+	bd.Labels = map[string]string{corev1.LabelHostname: "node1"}
+	bd.Spec.NodeName = "node1"
+	return bd
+}
+
+func TestSampleIOStatsOnce(t *testing.T) {
+	defer func(read func() (map[string]utils.DiskStats, error)) { readDiskStats = read }(readDiskStats)
+	defer metrics.DeleteBlockDevice("bd")
+
+	inactive := newIOStatsBlockDevice("inactive", "/dev/sdc")
+	inactive.Status.State = diskv1.BlockDeviceInactive
+	bds := newFakeBlockDevices(newIOStatsBlockDevice("bd", "/dev/sdb"), inactive)
+	c := &Controller{
+		Namespace:        testNamespace,
+		NodeName:         "node1",
+		Blockdevices:     bds,
+		BlockdeviceCache: bds.Cache(),
+		BlockInfo:        newFakeBlockInfo(&block.Disk{Name: "sdb"}, &block.Disk{Name: "sdc"}),
+		ioStats:          newIOStatsSampler(10 * time.Second),
+	}
+	sample := func(now time.Time, stats map[string]utils.DiskStats) {
+		readDiskStats = func() (map[string]utils.DiskStats, error) {
+			return stats, nil
+		}
+		require.NoError(t, c.sampleIOStatsOnce(now))
+	}
+
+	now := time.Now()
+	sample(now, map[string]utils.DiskStats{
+		"sdb": {ReadsCompleted: 1000, ReadTimeMs: 2000, IOTimeMs: 10000},
+		"sdc": {ReadsCompleted: 1000},
+	})
+	assert.Nil(t, c.ioStats.summary("bd"), "the first sample has nothing to compare with")
+
+	now = now.Add(10 * time.Second)
+	sample(now, map[string]utils.DiskStats{
+		"sdb": {ReadsCompleted: 1100, SectorsRead: 2048, ReadTimeMs: 2500, IOTimeMs: 15000},
+		"sdc": {ReadsCompleted: 2000},
+	})
+	assert.Equal(t, &diskv1.IOStatsSummary{
+		ReadIOPS:                10,
+		ReadBytesPerSecond:      104857,
+		ReadLatencyMicroseconds: 5000,
+		UtilizationPercent:      50,
+		Window:                  metav1.Duration{Duration: 10 * time.Second},
+		LastUpdated:             metav1.NewTime(now),
+	}, c.ioStats.summary("bd"))
+	assert.Nil(t, c.ioStats.summary("inactive"))
+	assert.Equal(t, []string{"bd"}, bds.enqueuedNames(), "the device without a summary in its status is enqueued")
+
+	// the device is not enqueued again until its status summary is outdated
+	bd, err := bds.Get(testNamespace, "bd", metav1.GetOptions{})
+	require.NoError(t, err)
+	c.updateIOStats(bd)
+	assert.Equal(t, c.ioStats.summary("bd"), bd.Status.IOStats)
+	_, err = bds.Update(bd)
+	require.NoError(t, err)
+	c.BlockdeviceCache = bds.Cache()
+	now = now.Add(10 * time.Second)
+	sample(now, map[string]utils.DiskStats{"sdb": {ReadsCompleted: 1200, SectorsRead: 4096, ReadTimeMs: 3000, IOTimeMs: 20000}})
+	assert.Equal(t, []string{"bd"}, bds.enqueuedNames())
+
+	// a re-plugged disk starts over
+	now = now.Add(10 * time.Second)
+	sample(now, map[string]utils.DiskStats{"sdb": {ReadsCompleted: 10}})
+	assert.Nil(t, c.ioStats.summary("bd"))
+	assert.Empty(t, c.ioStats.exported)
+}
+
+func TestUpdateIOStats(t *testing.T) {
+	now := time.Now()
+	reported := &diskv1.IOStatsSummary{ReadIOPS: 10, LastUpdated: metav1.NewTime(now)}
+	bd := newDiskBlockDevice("bd", "/dev/sdb")
+	bd.Status.IOStats = reported
+
+	c := &Controller{}
+	c.updateIOStats(bd)
+	assert.Equal(t, reported, bd.Status.IOStats, "the statistics are not sampled")
+
+	c.ioStats = newIOStatsSampler(10 * time.Second)
+	c.updateIOStats(bd)
+	assert.Equal(t, reported, bd.Status.IOStats, "the last summary is kept while the device is active")
+
+	c.ioStats.summaries["bd"] = &diskv1.IOStatsSummary{ReadIOPS: 20, UtilizationPercent: 20, LastUpdated: metav1.NewTime(now.Add(time.Minute))}
+	c.updateIOStats(bd)
+	assert.Equal(t, reported, bd.Status.IOStats, "the reported summary is recent enough")
+
+	c.ioStats.summaries["bd"] = &diskv1.IOStatsSummary{ReadIOPS: 20, UtilizationPercent: 30, LastUpdated: metav1.NewTime(now.Add(time.Minute))}
+	c.updateIOStats(bd)
+	assert.Equal(t, c.ioStats.summaries["bd"], bd.Status.IOStats, "the utilization changed significantly")
+	reported = bd.Status.IOStats
+
+	c.ioStats.summaries["bd"] = &diskv1.IOStatsSummary{ReadIOPS: 40, UtilizationPercent: 30, LastUpdated: metav1.NewTime(now.Add(ioStatsStatusInterval))}
+	c.updateIOStats(bd)
+	assert.Equal(t, reported, bd.Status.IOStats, "the rates alone don't refresh the summary")
+
+	c.ioStats.summaries["bd"] = &diskv1.IOStatsSummary{ReadIOPS: 40, UtilizationPercent: 30, LastUpdated: metav1.NewTime(now.Add(time.Minute + ioStatsStatusInterval))}
+	c.updateIOStats(bd)
+	assert.Equal(t, c.ioStats.summaries["bd"], bd.Status.IOStats)
+
+	delete(c.ioStats.summaries, "bd")
+	bd.Status.State = diskv1.BlockDeviceInactive
+	c.updateIOStats(bd)
+	assert.Nil(t, bd.Status.IOStats)
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
@@ -15,6 +18,9 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 
 	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
 	"github.com/harvester/node-disk-manager/pkg/block"
@@ -24,6 +30,12 @@ import (
 	"github.com/harvester/node-disk-manager/pkg/utils"
 )
 
+const (
+	// scanDebounce is how long the scanner waits after being woken up, so a burst
+	// of udev or mount events is coalesced into a single scan
+	scanDebounce = 2 * time.Second
+)
+
 type Scanner struct {
 	NodeName             string
 	Namespace            string
@@ -36,6 +48,31 @@ type Scanner struct {
 	Cond                 *sync.Cond
 	Shutdown             bool
 	TerminatedChannels   *chan bool
+	// DryRun reports the changes as events instead of applying them
+	DryRun   bool
+	Recorder record.EventRecorder
+	// nodeRef is the reference of the Node of the scanner, the events are recorded on
+	nodeRef *corev1.ObjectReference
+	// RescanInterval is the period of the full scan and of the re-enqueue of the
+	// block devices of the node, which catch up with any missed event. Zero disables both.
+	RescanInterval time.Duration
+
+	// the results of the last scans, protected by resultsLock
+	resultsLock   sync.RWMutex
+	verdicts      []diskv1.DiskFilterVerdict
+	lastScan      *diskv1.ScanRecord
+	lastFullScan  *diskv1.ScanRecord
+	scanListeners []func()
+	// servedFullScans is the number of the last full scan request served by a successful scan
+	servedFullScans uint64
+
+	// the pending scan requests, protected by Cond.L
+	fullScanRequested bool
+	requestedDevices  map[string]struct{}
+	requestReasons    []string
+	// fullScanRequests numbers the full scan requests, takenFullScans is the last one taken by a scan
+	fullScanRequests uint64
+	takenFullScans   uint64
 }
 
 type deviceWithAutoProvision struct {
@@ -52,6 +89,9 @@ func NewScanner(
 	cond *sync.Cond,
 	shutdown bool,
 	ch *chan bool,
+	recorder record.EventRecorder,
+	dryRun bool,
+	rescanInterval time.Duration,
 ) *Scanner {
 	return &Scanner{
 		NodeName:           nodeName,
@@ -63,20 +103,36 @@ func NewScanner(
 		Cond:               cond,
 		Shutdown:           shutdown,
 		TerminatedChannels: ch,
+		Recorder:           recorder,
+		DryRun:             dryRun,
+		// the UID of the kubelet events, which `kubectl describe node` lists
+		nodeRef:        &corev1.ObjectReference{Kind: "Node", Name: nodeName, UID: types.UID(nodeName)},
+		RescanInterval: rescanInterval,
 	}
 }
 
 func (s *Scanner) Start(ctx context.Context) error {
 	// Always scan once on start
-	if err := s.scanBlockDevicesOnNode(ctx); err != nil {
+	err := s.scanBlockDevicesOnNode(ctx)
+	s.recordScan("initial scan", nil, err)
+	if err != nil {
 		return err
 	}
 
 	go func() {
 		for {
 			s.Cond.L.Lock()
-			logrus.Infof("Waiting new event to trigger...")
-			s.Cond.Wait()
+			for !s.Shutdown && !s.scanRequested() {
+				logrus.Infof("Waiting new event to trigger...")
+				s.Cond.Wait()
+			}
+
+			if !s.Shutdown {
+				// the requests made meanwhile are served by the same scan
+				s.Cond.L.Unlock()
+				time.Sleep(scanDebounce)
+				s.Cond.L.Lock()
+			}
 
 			if s.Shutdown {
 				logrus.Info("Prepare to stop scanner.")
@@ -86,29 +142,159 @@ func (s *Scanner) Start(ctx context.Context) error {
 				return
 			}
 
-			logrus.Infof("Scanner woke up, do scan...")
-			if err := s.scanBlockDevicesOnNode(ctx); err != nil {
-				logrus.Errorf("Failed to rescan block devices on node %s: %v", s.NodeName, err)
+			fullScan, devPaths, reason := s.takeScanRequests()
+			if fullScan {
+				logrus.Infof("Scanner woke up (%s), do scan...", reason)
+				err := s.scanBlockDevicesOnNode(ctx)
+				if err != nil {
+					logrus.Errorf("Failed to rescan block devices on node %s: %v", s.NodeName, err)
+				}
+				s.recordScan(reason, nil, err)
+			} else {
+				logrus.Infof("Scanner woke up (%s), scan devices %v...", reason, devPaths)
+				err := s.scanDevicesOnNode(devPaths)
+				if err != nil {
+					logrus.Errorf("Failed to rescan devices %v on node %s: %v", devPaths, s.NodeName, err)
+				}
+				s.recordScan(reason, devPaths, err)
 			}
 			s.Cond.L.Unlock()
 		}
 	}()
+
+	if s.RescanInterval > 0 {
+		go s.resyncPeriodically(ctx)
+	}
 	return nil
 }
 
+// resyncPeriodically requests a full scan and re-enqueues every block device of the
+// node at every RescanInterval, so the state is reconciled even if the udev or mount
+// events got lost, e.g. while the udev monitor was respawned.
+func (s *Scanner) resyncPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(s.RescanInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.RequestScan("periodic full scan")
+			s.enqueueBlockDevices()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// enqueueBlockDevices enqueues every block device of the node for the controller
+func (s *Scanner) enqueueBlockDevices() {
+	bds, err := s.Blockdevices.Cache().List(s.Namespace, labels.SelectorFromSet(map[string]string{
+		corev1.LabelHostname: s.NodeName,
+	}))
+	if err != nil {
+		logrus.Errorf("Failed to list block devices of node %s for the resync: %v", s.NodeName, err)
+		return
+	}
+	logrus.Debugf("Re-enqueue %d block devices of node %s", len(bds), s.NodeName)
+	for _, bd := range bds {
+		s.Blockdevices.Enqueue(bd.Namespace, bd.Name)
+	}
+}
+
+// RequestScan wakes the scanner up for a full scan of the node. It returns the number of
+// the request, see FullScanServed.
+func (s *Scanner) RequestScan(reason string) uint64 {
+	return utils.CallerWithCondLock(s.Cond, func() uint64 {
+		logrus.Debugf("Requesting scan of node %s: %s", s.NodeName, reason)
+		s.fullScanRequested = true
+		s.fullScanRequests++
+		s.addRequestReason(reason)
+		s.Cond.Signal()
+		return s.fullScanRequests
+	})
+}
+
+// FullScanServed checks whether a full scan taking the request with the given number, or
+// a later one, completed without error
+func (s *Scanner) FullScanServed(request uint64) bool {
+	s.resultsLock.RLock()
+	defer s.resultsLock.RUnlock()
+	return s.servedFullScans >= request
+}
+
+// RequestDeviceScan wakes the scanner up to re-probe the given devices only, e.g. the
+// device of an udev event. A partition is re-probed with its disk.
+func (s *Scanner) RequestDeviceScan(reason string, devPaths ...string) {
+	if len(devPaths) == 0 {
+		return
+	}
+	utils.CallerWithCondLock(s.Cond, func() any {
+		logrus.Debugf("Requesting scan of devices %v: %s", devPaths, reason)
+		if s.requestedDevices == nil {
+			s.requestedDevices = make(map[string]struct{})
+		}
+		for _, devPath := range devPaths {
+			s.requestedDevices[devPath] = struct{}{}
+		}
+		s.addRequestReason(reason)
+		s.Cond.Signal()
+		return nil
+	})
+}
+
+// addRequestReason must be called with Cond.L held
+func (s *Scanner) addRequestReason(reason string) {
+	if !slices.Contains(s.requestReasons, reason) {
+		s.requestReasons = append(s.requestReasons, reason)
+	}
+}
+
+// scanRequested must be called with Cond.L held
+func (s *Scanner) scanRequested() bool {
+	return s.fullScanRequested || len(s.requestedDevices) > 0
+}
+
+// takeScanRequests returns and clears the pending requests, a full scan covers
+// every requested device. It must be called with Cond.L held.
+func (s *Scanner) takeScanRequests() (bool, []string, string) {
+	fullScan := s.fullScanRequested
+	if fullScan {
+		s.takenFullScans = s.fullScanRequests
+	}
+	devPaths := make([]string, 0, len(s.requestedDevices))
+	for devPath := range s.requestedDevices {
+		devPaths = append(devPaths, devPath)
+	}
+	sort.Strings(devPaths)
+	reason := strings.Join(s.requestReasons, ", ")
+	s.fullScanRequested = false
+	s.requestedDevices = nil
+	s.requestReasons = nil
+	return fullScan, devPaths, reason
+}
+
 // collectAllDevices returns a slice containing every BlockDevice on the system.
 // The BlockDevices in the list will not have valid names, but the DeviceStatus
 // fields (UUID, WWN, Vendor, Model, SerialNumber, BusPath) will have been filled
 // in as completely as possible.
 func (s *Scanner) collectAllDevices() []*deviceWithAutoProvision {
-	allDevices := make([]*deviceWithAutoProvision, 0)
-	// list all the block devices
-	for _, disk := range s.BlockInfo.GetDisks() {
+	allDevices, verdicts := s.collectDevices(s.BlockInfo.GetDisks())
+	s.setFilterVerdicts(verdicts)
+	return allDevices
+}
+
+// collectDevices returns the BlockDevices of the disks which aren't excluded by
+// the filters, and the verdicts of the filters for every disk.
+func (s *Scanner) collectDevices(disks []*block.Disk) ([]*deviceWithAutoProvision, []diskv1.DiskFilterVerdict) {
+	devices := make([]*deviceWithAutoProvision, 0)
+	verdicts := make([]diskv1.DiskFilterVerdict, 0)
+	for _, disk := range disks {
 		logrus.WithFields(logrus.Fields{
 			"device": fmt.Sprintf("/dev/%s", disk.Name),
 		}).Info("Scanning device")
 		// ignore block device by filters
-		if s.ApplyExcludeFiltersForDisk(disk) {
+		verdict := s.excludeVerdictForDisk(disk)
+		if verdict.Excluded {
+			verdicts = append(verdicts, verdict)
 			continue
 		}
 		bd := GetDiskBlockDevice(disk, s.NodeName, s.Namespace)
@@ -121,10 +307,17 @@ func (s *Scanner) collectAllDevices() []*deviceWithAutoProvision {
 			"serial":  bd.Status.DeviceStatus.Details.SerialNumber, // Can be util.UNKNOWN
 			"buspath": bd.Status.DeviceStatus.Details.BusPath,      // Can be util.UNKNOWN
 		}).Info("Detected disk")
-		autoProv := s.ApplyAutoProvisionFiltersForDisk(disk)
-		allDevices = append(allDevices, &deviceWithAutoProvision{bd: bd, AutoProvisioned: autoProv})
+		autoProv := false
+		if matched := s.autoProvisionFilterForDisk(disk); matched != nil {
+			autoProv = true
+			verdict.AutoProvisioned = true
+			verdict.Filter = matched.Name
+			verdict.Rules = matched.DiskFilter.Details()
+		}
+		verdicts = append(verdicts, verdict)
+		devices = append(devices, &deviceWithAutoProvision{bd: bd, AutoProvisioned: autoProv})
 	}
-	return allDevices
+	return devices, verdicts
 }
 
 // handleExistingDev will update an existing BD CR based on the current state
@@ -143,7 +336,7 @@ func (s *Scanner) collectAllDevices() []*deviceWithAutoProvision {
 //     code path).
 //   - Changes of WWN might be unexepcted, except in that weird case where
 //     a kernel update changed the WWNs of existing disks.
-func (s *Scanner) handleExistingDev(oldBd *diskv1.BlockDevice, newBd *diskv1.BlockDevice, autoProvisioned bool) bool {
+func (s *Scanner) handleExistingDev(oldBd *diskv1.BlockDevice, newBd *diskv1.BlockDevice, autoProvisioned bool, conflicts []identityConflict) bool {
 	oldBdCp := oldBd.DeepCopy()
 
 	if oldBd.Status.State == diskv1.BlockDeviceActive {
@@ -170,6 +363,9 @@ func (s *Scanner) handleExistingDev(oldBd *diskv1.BlockDevice, newBd *diskv1.Blo
 			return false
 		}
 		// DevPath isn't changed, but other things might, e.g. UUID if someone manually formatted a disk
+		s.handleCapacityChange(oldBdCp, newBd.Status.DeviceStatus.Capacity.SizeBytes)
+		s.handleMountPointChange(oldBdCp, newBd.Status.DeviceStatus.FileSystem.MountPoint)
+		s.handleReadOnlyChange(oldBdCp, newBd.Status.DeviceStatus.FileSystem.MountPoint, newBd.Status.DeviceStatus.FileSystem.IsReadOnly)
 		oldBdCp.Status.DeviceStatus.Capacity = newBd.Status.DeviceStatus.Capacity
 		oldBdCp.Status.DeviceStatus.Details = newBd.Status.DeviceStatus.Details
 		oldBdCp.Status.DeviceStatus.Partitioned = newBd.Status.DeviceStatus.Partitioned
@@ -224,6 +420,7 @@ func (s *Scanner) handleExistingDev(oldBd *diskv1.BlockDevice, newBd *diskv1.Blo
 			oldBdCp.Status.DeviceStatus.FileSystem.IsReadOnly = newBd.Status.DeviceStatus.FileSystem.IsReadOnly
 		}
 	}
+	setIdentityConflict(oldBdCp, conflicts)
 
 	if !reflect.DeepEqual(oldBd, oldBdCp) {
 		logrus.WithFields(logrus.Fields{
@@ -231,12 +428,15 @@ func (s *Scanner) handleExistingDev(oldBd *diskv1.BlockDevice, newBd *diskv1.Blo
 			"status":    fmt.Sprintf("%+v", oldBd.Status.DeviceStatus),
 			"newStatus": fmt.Sprintf("%+v", oldBdCp.Status.DeviceStatus),
 		}).Info("updating device")
-		if _, err := s.Blockdevices.Update(oldBdCp); err != nil {
+		if s.DryRun {
+			s.Recorder.Eventf(oldBd, corev1.EventTypeNormal, utils.EventReasonDryRun, "would update block device: %s", describeDeviceChange(oldBd, oldBdCp))
+		} else if _, err := s.Blockdevices.Update(oldBdCp); err != nil {
 			logrus.WithFields(logrus.Fields{
 				"name": oldBd.Name,
 				"err":  err,
-			}).Error("error updating device, waking scanner")
-			s.Cond.Signal()
+			}).Error("error updating device, requesting another scan")
+			// Cond.L is held by the scan
+			s.fullScanRequested = true
 		}
 	} else if isDevAlreadyProvisioned(oldBd) {
 		logrus.WithFields(logrus.Fields{
@@ -255,6 +455,97 @@ func (s *Scanner) handleExistingDev(oldBd *diskv1.BlockDevice, newBd *diskv1.Blo
 	return true
 }
 
+// handleCapacityChange reports a change of the device capacity, e.g. after a virtual disk
+// or a SAN LUN was grown, and flags the provisioned devices which grew for resizing.
+// The controller then grows the provisioned storage, see DeviceResizing.
+func (s *Scanner) handleCapacityChange(bd *diskv1.BlockDevice, newSize uint64) {
+	oldSize := bd.Status.DeviceStatus.Capacity.SizeBytes
+	if oldSize == newSize || oldSize == 0 || s.DryRun {
+		// the dry-run mode already reports the change as an update of the block device
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"name":    bd.Name,
+		"device":  bd.Status.DeviceStatus.DevPath,
+		"oldSize": oldSize,
+		"newSize": newSize,
+	}).Info("capacity of the device changed")
+	eventType := corev1.EventTypeNormal
+	if newSize < oldSize && bd.Status.ProvisionPhase == diskv1.ProvisionPhaseProvisioned {
+		// the provisioned storage can't shrink, the data past the new end is lost
+		eventType = corev1.EventTypeWarning
+	}
+	s.Recorder.Eventf(bd, eventType, utils.EventReasonCapacityChanged, "Capacity of %s changed from %d to %d bytes", bd.Status.DeviceStatus.DevPath, oldSize, newSize)
+
+	if newSize > oldSize && bd.Status.ProvisionPhase == diskv1.ProvisionPhaseProvisioned {
+		diskv1.DeviceResizing.SetStatusBool(bd, true)
+		diskv1.DeviceResizing.Reason(bd, "")
+		diskv1.DeviceResizing.Message(bd, fmt.Sprintf("Capacity grew from %d to %d bytes", oldSize, newSize))
+	}
+}
+
+// handleMountPointChange flags a provisioned LonghornV1 disk which left its mount
+// point, e.g. unmounted by an admin or a crashed process. The controller then
+// suspends the Longhorn scheduling on the disk and mounts it back.
+func (s *Scanner) handleMountPointChange(bd *diskv1.BlockDevice, newMountPoint string) {
+	oldMountPoint := bd.Status.DeviceStatus.FileSystem.MountPoint
+	if oldMountPoint == newMountPoint || s.DryRun || !bd.Spec.Provision ||
+		bd.Status.ProvisionPhase != diskv1.ProvisionPhaseProvisioned || !isLonghornV1Device(bd) ||
+		oldMountPoint != provisioner.ExtraDiskMountPoint(bd) {
+		return
+	}
+
+	reason := provisioner.DeviceMountedReasonUnmounted
+	message := fmt.Sprintf("Unmounted from %s", oldMountPoint)
+	if newMountPoint != "" {
+		reason = provisioner.DeviceMountedReasonMoved
+		message = fmt.Sprintf("Moved from %s to %s", oldMountPoint, newMountPoint)
+	}
+	logrus.WithFields(logrus.Fields{
+		"name":          bd.Name,
+		"device":        bd.Status.DeviceStatus.DevPath,
+		"mountPoint":    oldMountPoint,
+		"newMountPoint": newMountPoint,
+	}).Warn("provisioned device left its mount point")
+	s.Recorder.Eventf(bd, corev1.EventTypeWarning, utils.EventReasonUnexpectedUnmount, "%s, suspending Longhorn scheduling until it is remounted", message)
+	diskv1.DeviceMounted.SetStatusBool(bd, false)
+	diskv1.DeviceMounted.Reason(bd, reason)
+	diskv1.DeviceMounted.Message(bd, message)
+}
+
+// handleReadOnlyChange flags a provisioned LonghornV1 disk whose filesystem turned
+// read-only at its mount point. NDM always mounts the disks read-write, and with
+// errors=remount-ro the kernel flips the filesystem read-only after an I/O error.
+func (s *Scanner) handleReadOnlyChange(bd *diskv1.BlockDevice, mountPoint string, readOnly bool) {
+	if s.DryRun || !bd.Spec.Provision || bd.Status.ProvisionPhase != diskv1.ProvisionPhaseProvisioned ||
+		!isLonghornV1Device(bd) || mountPoint != provisioner.ExtraDiskMountPoint(bd) {
+		return
+	}
+
+	unhealthy := diskv1.FilesystemHealthy.IsFalse(bd)
+	fields := logrus.Fields{
+		"name":       bd.Name,
+		"device":     bd.Status.DeviceStatus.DevPath,
+		"mountPoint": mountPoint,
+	}
+	if readOnly && !unhealthy {
+		message := fmt.Sprintf("Filesystem at %s turned read-only, likely after an I/O error. "+
+			"Check the disk, unmount the filesystem, then set spec.fileSystem.repair to repair and mount it back", mountPoint)
+		logrus.WithFields(fields).Warn("filesystem of provisioned device turned read-only")
+		s.Recorder.Event(bd, corev1.EventTypeWarning, utils.EventReasonFilesystemReadOnly, message)
+		diskv1.FilesystemHealthy.SetStatusBool(bd, false)
+		diskv1.FilesystemHealthy.Reason(bd, provisioner.FilesystemHealthyReasonReadOnly)
+		diskv1.FilesystemHealthy.Message(bd, message)
+	} else if !readOnly && unhealthy {
+		logrus.WithFields(fields).Info("filesystem of provisioned device is writable again")
+		s.Recorder.Eventf(bd, corev1.EventTypeNormal, utils.EventReasonFilesystemRecovered, "Filesystem at %s is writable again", mountPoint)
+		diskv1.FilesystemHealthy.SetStatusBool(bd, true)
+		diskv1.FilesystemHealthy.Reason(bd, provisioner.FilesystemHealthyReasonRecovered)
+		diskv1.FilesystemHealthy.Message(bd, fmt.Sprintf("Filesystem at %s is writable again", mountPoint))
+	}
+}
+
 func (s *Scanner) deactivateOrDeleteBlockDevices(oldBds map[string]*diskv1.BlockDevice) error {
 	for _, oldBd := range oldBds {
 		// It should be fine for devices that aren't actually provisioned to go away
@@ -264,6 +555,10 @@ func (s *Scanner) deactivateOrDeleteBlockDevices(oldBds map[string]*diskv1.Block
 		// "wrong" devices just go away).
 		if oldBd.Status.ProvisionPhase == diskv1.ProvisionPhaseUnprovisioned {
 			logrus.Debugf("Delete device %s", oldBd.Name)
+			if s.DryRun {
+				s.Recorder.Eventf(oldBd, corev1.EventTypeNormal, utils.EventReasonDryRun, "would delete block device %s as %s is gone", oldBd.Name, oldBd.Status.DeviceStatus.DevPath)
+				continue
+			}
 			if err := s.Blockdevices.Delete(oldBd.Namespace, oldBd.Name, &metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
 				return err
 			}
@@ -276,6 +571,10 @@ func (s *Scanner) deactivateOrDeleteBlockDevices(oldBds map[string]*diskv1.Block
 			continue
 		}
 		logrus.Debugf("Change the device %s to inactive.", oldBd.Name)
+		if s.DryRun {
+			s.Recorder.Eventf(oldBd, corev1.EventTypeNormal, utils.EventReasonDryRun, "would deactivate provisioned block device %s as %s is gone", oldBd.Name, oldBd.Status.DeviceStatus.DevPath)
+			continue
+		}
 		newBd := oldBd.DeepCopy()
 		newBd.Status.State = diskv1.BlockDeviceInactive
 		if !reflect.DeepEqual(oldBd, newBd) {
@@ -292,31 +591,60 @@ func (s *Scanner) deactivateOrDeleteBlockDevices(oldBds map[string]*diskv1.Block
 // reloadConfigMapFilters reloads filter and auto-provision configurations from ConfigMap
 // Falls back to environment variables if ConfigMap is not available or empty
 func (s *Scanner) loadConfigMapFilters(ctx context.Context) {
-	deviceFilter, vendorFilter, pathFilter, labelFilter, err := s.ConfigMapLoader.LoadFiltersFromConfigMap(ctx)
+	// the configurations are read and parsed once for all the filters
+	loader := s.ConfigMapLoader.Snapshot()
+	deviceFilter, vendorFilter, pathFilter, labelFilter, err := loader.LoadFiltersFromConfigMap(ctx)
 	if err != nil {
 		logrus.Warnf("Failed to reload filters from ConfigMap: %v, using environment variable fallback", err)
-		deviceFilter, vendorFilter, pathFilter, labelFilter = s.ConfigMapLoader.GetEnvFilters()
+		deviceFilter, vendorFilter, pathFilter, labelFilter = loader.GetEnvFilters()
 	} else if deviceFilter == "" && vendorFilter == "" && pathFilter == "" && labelFilter == "" {
 		// ConfigMap exists but is empty, use env var fallback
 		logrus.Info("ConfigMap filter data is empty, using environment variable fallback")
-		deviceFilter, vendorFilter, pathFilter, labelFilter = s.ConfigMapLoader.GetEnvFilters()
+		deviceFilter, vendorFilter, pathFilter, labelFilter = loader.GetEnvFilters()
 	}
 
-	// Update filters
-	s.ExcludeFilters = filter.SetExcludeFilters(deviceFilter, vendorFilter, pathFilter, labelFilter)
+	// Update filters. Dropping the expressions or the allow-list would expose the disks
+	// they exclude, so the previous filters are kept if either fails to load.
+	excludeFilters := filter.SetExcludeFilters(deviceFilter, vendorFilter, pathFilter, labelFilter)
 
-	autoProvisionFilter, err := s.ConfigMapLoader.LoadAutoProvisionFromConfigMap(ctx)
+	excludeExpressions, expressionsErr := loader.LoadExcludeExpressionsFromConfigMap(ctx)
+	if expressionsErr != nil {
+		logrus.Warnf("Failed to reload exclude expressions from ConfigMap: %v, keeping the previous filters", expressionsErr)
+	}
+
+	// The include filter goes last, so the exclude filters take precedence over it
+	includeFilter, includeErr := loader.LoadIncludeFilterFromConfigMap(ctx)
+	if includeErr != nil {
+		logrus.Warnf("Failed to reload include rules from ConfigMap: %v, keeping the previous filters", includeErr)
+	}
+
+	if expressionsErr == nil && includeErr == nil {
+		excludeFilters = append(excludeFilters, excludeExpressions...)
+		if includeFilter != nil {
+			excludeFilters = append(excludeFilters, includeFilter)
+		}
+		s.ExcludeFilters = excludeFilters
+	}
+
+	autoProvisionFilter, err := loader.LoadAutoProvisionFromConfigMap(ctx)
 	if err != nil {
 		logrus.Warnf("Failed to reload auto-provision from ConfigMap: %v, using environment variable fallback", err)
-		autoProvisionFilter = s.ConfigMapLoader.GetEnvAutoProvisionFilter()
+		autoProvisionFilter = loader.GetEnvAutoProvisionFilter()
 	} else if autoProvisionFilter == "" {
 		// ConfigMap exists but is empty, use env var fallback
 		logrus.Debug("ConfigMap auto-provision data is empty, using environment variable fallback")
-		autoProvisionFilter = s.ConfigMapLoader.GetEnvAutoProvisionFilter()
+		autoProvisionFilter = loader.GetEnvAutoProvisionFilter()
 	}
 
 	// Update auto-provision filters
 	s.AutoProvisionFilters = filter.SetAutoProvisionFilters(autoProvisionFilter)
+
+	autoProvisionExpressions, err := loader.LoadAutoProvisionExpressionsFromConfigMap(ctx)
+	if err != nil {
+		logrus.Warnf("Failed to reload auto-provision expressions from ConfigMap: %v", err)
+	} else {
+		s.AutoProvisionFilters = append(s.AutoProvisionFilters, autoProvisionExpressions...)
+	}
 }
 
 // scanBlockDevicesOnNode scans block devices on the node, and it will either create or update them.
@@ -339,10 +667,41 @@ func (s *Scanner) scanBlockDevicesOnNode(ctx context.Context) error {
 		return err
 	}
 
+	// the identities shared by several devices can't be used to find their BDs
+	identityConflicts := findIdentityConflicts(allDevices)
+	unmatchedBDs, err := s.syncBlockDevices(allDevices, existingBDs, identityConflicts)
+	if err != nil {
+		return err
+	}
+	if !CacheDiskTags.Initialized() {
+		CacheDiskTags.UpdateInitialized()
+		logrus.Debugf("CacheDiskTags initialized: %+v", CacheDiskTags)
+	}
+
+	return s.deactivateOrDeleteBlockDevices(unmatchedBDs)
+}
+
+// syncBlockDevices finds the existing BD of every device and updates it, or creates
+// a new BD for the device. It returns the existing BDs which no device was found for.
+func (s *Scanner) syncBlockDevices(devices []*deviceWithAutoProvision, existingBDs *diskv1.BlockDeviceList, identityConflicts map[string][]identityConflict) (map[string]*diskv1.BlockDevice, error) {
 	existingBDsByName, existingBDsByWWN, existingBDsByUUID := mapBlockDeviceIDs(existingBDs)
-	for _, device := range allDevices {
+	// names of the block devices on this node, existingBDsByName shrinks as the devices are found
+	takenNames := make(map[string]struct{}, len(existingBDs.Items))
+	for _, bd := range existingBDs.Items {
+		takenNames[bd.Name] = struct{}{}
+	}
+	for _, device := range devices {
 		newBd := device.bd
 		autoProvisioned := device.AutoProvisioned
+		conflicts := identityConflicts[newBd.Status.DeviceStatus.DevPath]
+		for _, conflict := range conflicts {
+			logrus.WithFields(logrus.Fields{
+				"device": newBd.Status.DeviceStatus.DevPath,
+				"key":    conflict.key,
+				"value":  conflict.value,
+				"other":  conflict.devPath,
+			}).Warn("identity shared with another device, not using it to find the BD")
+		}
 
 		var existingBd *diskv1.BlockDevice = nil
 
@@ -351,7 +710,8 @@ func (s *Scanner) scanBlockDevicesOnNode(ctx context.Context) error {
 		// 1. UUID (for provisioned disks)
 		// 2. WWN if there's no UUID
 		// 3. Vendor+Model+Serial+BusPath if there's no UUID or WWN
-		if uuid, uuidValid := getBlockDeviceUUID(newBd); uuidValid {
+		// An identity shared with another device is skipped.
+		if uuid, uuidValid := getBlockDeviceUUID(newBd); uuidValid && !hasIdentityConflict(conflicts, diskv1.IdentityKeyUUID) {
 			if foundBd, uuidExists := existingBDsByUUID[uuid]; uuidExists {
 				logrus.WithFields(logrus.Fields{
 					"device": newBd.Status.DeviceStatus.DevPath,
@@ -365,7 +725,7 @@ func (s *Scanner) scanBlockDevicesOnNode(ctx context.Context) error {
 			// picked up by WWN, or vendor+model+serial+buspath
 		}
 
-		if wwn, wwnValid := getBlockDeviceWWN(newBd); wwnValid && existingBd == nil {
+		if wwn, wwnValid := getBlockDeviceWWN(newBd); wwnValid && existingBd == nil && !hasIdentityConflict(conflicts, diskv1.IdentityKeyWWN) {
 			if foundBd, wwnExists := existingBDsByWWN[wwn]; wwnExists {
 				logrus.WithFields(logrus.Fields{
 					"device": newBd.Status.DeviceStatus.DevPath,
@@ -379,7 +739,7 @@ func (s *Scanner) scanBlockDevicesOnNode(ctx context.Context) error {
 			// gets picked up by vendor+model+serial+buspath
 		}
 
-		if existingBd == nil {
+		if existingBd == nil && !hasIdentityConflict(conflicts, diskv1.IdentityKeyDeviceID) {
 			// We have neither UUID nor WWN, so fall back to matching
 			// vendor+model+serial+buspath.  If these four match, it's
 			// the same device.  I don't think we can rely on any of
@@ -416,7 +776,7 @@ func (s *Scanner) scanBlockDevicesOnNode(ctx context.Context) error {
 			// Pick up the name of the existing block device we found (not strictly necessary,
 			// but just in case we try to use newBd.name in handleExistingDev...)
 			newBd.Name = existingBd.Name
-			if s.handleExistingDev(existingBd, newBd, autoProvisioned) {
+			if s.handleExistingDev(existingBd, newBd, autoProvisioned, conflicts) {
 				// only first time to update the cache
 				if !CacheDiskTags.Initialized() && existingBd.Spec.Tags != nil && len(existingBd.Spec.Tags) > 0 {
 					CacheDiskTags.UpdateDiskTags(existingBd.Name, existingBd.Spec.Tags)
@@ -426,7 +786,15 @@ func (s *Scanner) scanBlockDevicesOnNode(ctx context.Context) error {
 			}
 		} else {
 			// New block device, needs a name...
-			newBd.Name = uuid.NewString()
+			name, err := s.newBlockDeviceName(newBd, takenNames)
+			if err != nil {
+				return nil, err
+			}
+			newBd.Name = name
+			takenNames[newBd.Name] = struct{}{}
+			setIdentityConflict(newBd, conflicts)
+			// provisioning is blocked until the conflict is resolved
+			autoProvisioned = autoProvisioned && !diskv1.IdentityConflict.IsTrue(newBd)
 
 			logrus.WithFields(logrus.Fields{
 				"name":    newBd.Name,
@@ -438,8 +806,11 @@ func (s *Scanner) scanBlockDevicesOnNode(ctx context.Context) error {
 				"uuid":    newBd.Status.DeviceStatus.Details.UUID,
 				"wwn":     newBd.Status.DeviceStatus.Details.WWN,
 			}).Info("creating new BD")
-			if _, err := s.SaveBlockDevice(newBd, autoProvisioned); err != nil && !errors.IsAlreadyExists(err) {
-				return err
+			if s.DryRun {
+				s.recordNodeEvent(corev1.EventTypeNormal, utils.EventReasonDryRun,
+					"would create block device for %s (auto-provision: %t)", newBd.Status.DeviceStatus.DevPath, autoProvisioned && canAutoProvision(s.UpgradeClient))
+			} else if _, err := s.SaveBlockDevice(newBd, autoProvisioned); err != nil && !errors.IsAlreadyExists(err) {
+				return nil, err
 			}
 			// Add newly added disk to existingUUID and existingWWN maps in case there's
 			// any other disks to be added which somehow have duplicate UUIDs or WWNs.
@@ -451,15 +822,65 @@ func (s *Scanner) scanBlockDevicesOnNode(ctx context.Context) error {
 			}
 		}
 	}
-	if !CacheDiskTags.Initialized() {
-		CacheDiskTags.UpdateInitialized()
-		logrus.Debugf("CacheDiskTags initialized: %+v", CacheDiskTags)
+	return existingBDsByName, nil
+}
+
+// describeDeviceChange returns a short summary of the changes the scanner would apply to the block device
+func describeDeviceChange(oldBd, newBd *diskv1.BlockDevice) string {
+	changes := []string{}
+	if oldBd.Status.State != newBd.Status.State {
+		changes = append(changes, fmt.Sprintf("state %s -> %s", oldBd.Status.State, newBd.Status.State))
+	}
+	if isDevPathChanged(oldBd, newBd) {
+		changes = append(changes, fmt.Sprintf("devPath %s -> %s", oldBd.Status.DeviceStatus.DevPath, newBd.Status.DeviceStatus.DevPath))
+	}
+	if oldBd.Status.DeviceStatus.Capacity != newBd.Status.DeviceStatus.Capacity {
+		changes = append(changes, fmt.Sprintf("size %d -> %d", oldBd.Status.DeviceStatus.Capacity.SizeBytes, newBd.Status.DeviceStatus.Capacity.SizeBytes))
+	}
+	if !reflect.DeepEqual(oldBd.Status.DeviceStatus.Details, newBd.Status.DeviceStatus.Details) {
+		changes = append(changes, "device details")
 	}
+	if !reflect.DeepEqual(oldBd.Status.DeviceStatus.FileSystem, newBd.Status.DeviceStatus.FileSystem) {
+		changes = append(changes, "filesystem status")
+	}
+	if len(changes) == 0 {
+		return "device status"
+	}
+	return strings.Join(changes, ", ")
+}
 
-	if err := s.deactivateOrDeleteBlockDevices(existingBDsByName); err != nil {
-		return err
+// newBlockDeviceName returns the name for a new block device, derived from its hardware
+// identity. Block devices created with random names before keep their names, as they are
+// found by UUID, WWN or Vendor+Model+Serial+BusPath rather than by name.
+// If the name is already taken, e.g. by another disk reporting the same WWN, a numbered
+// suffix is appended. A random name is used when the disk has no hardware identity or
+// no suffix is available. An error is returned if a name can't be checked.
+func (s *Scanner) newBlockDeviceName(bd *diskv1.BlockDevice, takenNames map[string]struct{}) (string, error) {
+	name, ok := GenerateBlockDeviceName(s.NodeName, bd)
+	if !ok {
+		return uuid.NewString(), nil
 	}
-	return nil
+
+	for i := 0; i <= maxNameSuffix; i++ {
+		candidate := name
+		if i > 0 {
+			candidate = fmt.Sprintf("%s-%d", name, i)
+		}
+		if _, taken := takenNames[candidate]; !taken {
+			// the block devices of the other nodes aren't listed
+			_, err := s.Blockdevices.Get(s.Namespace, candidate, metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				return candidate, nil
+			} else if err != nil {
+				return "", fmt.Errorf("failed to check whether block device name %s is taken: %w", candidate, err)
+			}
+		}
+		logrus.WithFields(logrus.Fields{
+			"name":   candidate,
+			"device": bd.Status.DeviceStatus.DevPath,
+		}).Warn("block device name already taken")
+	}
+	return uuid.NewString(), nil
 }
 
 func getBlockDeviceWWN(bd *diskv1.BlockDevice) (string, bool) {
@@ -467,6 +888,15 @@ func getBlockDeviceWWN(bd *diskv1.BlockDevice) (string, bool) {
 	return bd.Status.DeviceStatus.Details.WWN, bd.Status.DeviceStatus.Details.WWN != "" && bd.Status.DeviceStatus.Details.WWN != util.UNKNOWN
 }
 
+func getBlockDeviceSerial(bd *diskv1.BlockDevice) (string, bool) {
+	// ghw reports "unknown" when the disk has no serial number, e.g. on some virtual disks
+	return bd.Status.DeviceStatus.Details.SerialNumber, bd.Status.DeviceStatus.Details.SerialNumber != "" && bd.Status.DeviceStatus.Details.SerialNumber != util.UNKNOWN
+}
+
+func getBlockDeviceBusPath(bd *diskv1.BlockDevice) (string, bool) {
+	return bd.Status.DeviceStatus.Details.BusPath, bd.Status.DeviceStatus.Details.BusPath != "" && bd.Status.DeviceStatus.Details.BusPath != util.UNKNOWN
+}
+
 func getBlockDeviceUUID(bd *diskv1.BlockDevice) (string, bool) {
 	// UUID should always be either valid or an empty string, but doesn't hurt to also check for "unknown"
 	return bd.Status.DeviceStatus.Details.UUID, bd.Status.DeviceStatus.Details.UUID != "" && bd.Status.DeviceStatus.Details.UUID != util.UNKNOWN
@@ -498,42 +928,183 @@ func mapBlockDeviceIDs(bdList *diskv1.BlockDeviceList) (names map[string]*diskv1
 // registered exclude filters. If the disk meets one of the criteria, it
 // returns true.
 func (s *Scanner) ApplyExcludeFiltersForDisk(disk *block.Disk) bool {
+	return s.excludeVerdictForDisk(disk).Excluded
+}
+
+// excludeVerdictForDisk returns the verdict of the exclude filters for the disk
+func (s *Scanner) excludeVerdictForDisk(disk *block.Disk) diskv1.DiskFilterVerdict {
+	verdict := diskv1.DiskFilterVerdict{
+		DevPath: utils.GetFullDevPath(disk.Name),
+		Disk:    filter.NewFilteredDisk(disk),
+	}
+
 	if strings.HasPrefix(disk.Name, "dm-") {
 		if _, err := utils.IsMultipathDevice(disk.Name); err == nil {
 			logrus.Infof("accept block device /dev/%s because it's a multipath device", disk.Name)
-			return false
+			return verdict
 		}
 
 		logrus.Infof("block device /dev/%s ignored because it's a dm device (likely LHv2 volume)", disk.Name)
-		return true
+		verdict.Excluded = true
+		verdict.Reason = "device mapper device which is not a multipath device"
+		return verdict
 	}
 
-	for _, filter := range s.ExcludeFilters {
-		if filter.ApplyDiskFilter(disk) {
-			logrus.Infof("block device /dev/%s ignored by %s and rules: %s", disk.Name, filter.Name, filter.DiskFilter.Details())
-			return true
-		}
+	if matched := filter.FirstMatch(s.ExcludeFilters, disk); matched != nil {
+		logrus.Infof("block device /dev/%s ignored by %s and rules: %s", disk.Name, matched.Name, matched.DiskFilter.Details())
+		verdict.Excluded = true
+		verdict.Filter = matched.Name
+		verdict.Rules = matched.DiskFilter.Details()
+		return verdict
 	}
 
 	if _, err := utils.IsManagedByMultipath(disk.Name); err == nil {
 		logrus.Infof("block device /dev/%s is managed by multipath device, ignored", disk.Name)
-		return true
+		verdict.Excluded = true
+		verdict.Reason = "managed by a multipath device"
+		return verdict
 	}
 
-	return false
+	return verdict
 }
 
 // ApplyAutoProvisionFiltersForDisk check the status of disk for every
 // registered auto-provision filters. If the disk meets one of the criteria, it
 // returns true.
 func (s *Scanner) ApplyAutoProvisionFiltersForDisk(disk *block.Disk) bool {
-	for _, filter := range s.AutoProvisionFilters {
-		if filter.ApplyDiskFilter(disk) {
-			logrus.Debugf("block device /dev/%s is promoted to auto-provision by %s", disk.Name, filter.Name)
-			return true
+	return s.autoProvisionFilterForDisk(disk) != nil
+}
+
+// autoProvisionFilterForDisk returns the auto-provision filter matching the disk, or nil
+func (s *Scanner) autoProvisionFilterForDisk(disk *block.Disk) *filter.Filter {
+	matched := filter.FirstMatch(s.AutoProvisionFilters, disk)
+	if matched != nil {
+		logrus.Debugf("block device /dev/%s is promoted to auto-provision by %s", disk.Name, matched.Name)
+	}
+	return matched
+}
+
+// FilterVerdicts returns the verdicts of the filters for the disks found by the last scan
+func (s *Scanner) FilterVerdicts() []diskv1.DiskFilterVerdict {
+	s.resultsLock.RLock()
+	defer s.resultsLock.RUnlock()
+	return slices.Clone(s.verdicts)
+}
+
+// FilterVerdict returns the verdict of the filters for the disk at the device path,
+// or false if the last scan didn't find the disk
+func (s *Scanner) FilterVerdict(devPath string) (diskv1.DiskFilterVerdict, bool) {
+	s.resultsLock.RLock()
+	defer s.resultsLock.RUnlock()
+	// the verdicts are kept sorted by their device paths
+	i, found := slices.BinarySearchFunc(s.verdicts, devPath, func(verdict diskv1.DiskFilterVerdict, devPath string) int {
+		return strings.Compare(verdict.DevPath, devPath)
+	})
+	if !found {
+		return diskv1.DiskFilterVerdict{}, false
+	}
+	return s.verdicts[i], true
+}
+
+// ScanRecords returns the records of the last scan and of the last full scan,
+// either is nil until such a scan ran
+func (s *Scanner) ScanRecords() (*diskv1.ScanRecord, *diskv1.ScanRecord) {
+	s.resultsLock.RLock()
+	defer s.resultsLock.RUnlock()
+	return s.lastScan.DeepCopy(), s.lastFullScan.DeepCopy()
+}
+
+// AddScanListener registers a function called after every scan
+func (s *Scanner) AddScanListener(listener func()) {
+	s.scanListeners = append(s.scanListeners, listener)
+}
+
+func (s *Scanner) setFilterVerdicts(verdicts []diskv1.DiskFilterVerdict) {
+	sort.Slice(verdicts, func(i, j int) bool {
+		return verdicts[i].DevPath < verdicts[j].DevPath
+	})
+	s.resultsLock.Lock()
+	changed := changedExclusions(s.verdicts, verdicts)
+	s.verdicts = verdicts
+	s.resultsLock.Unlock()
+
+	// the disks are scanned over and over, only report the disks whose exclusion changed
+	for _, verdict := range changed {
+		if !verdict.Excluded {
+			logrus.Infof("block device %s is no longer excluded", verdict.DevPath)
+			s.recordNodeEvent(corev1.EventTypeNormal, utils.EventReasonDiskIncluded, "Disk %s is no longer excluded by the filters", verdict.DevPath)
+			continue
+		}
+		logrus.Infof("block device %s is excluded: %s", verdict.DevPath, describeExclusion(verdict))
+		s.recordNodeEvent(corev1.EventTypeNormal, utils.EventReasonDiskExcluded, "Disk %s is excluded: %s", verdict.DevPath, describeExclusion(verdict))
+	}
+}
+
+// changedExclusions returns the new verdicts of the disks which got excluded, got excluded by
+// another filter, or are no longer excluded since the previous verdicts
+func changedExclusions(previous, verdicts []diskv1.DiskFilterVerdict) []diskv1.DiskFilterVerdict {
+	previousByPath := make(map[string]diskv1.DiskFilterVerdict, len(previous))
+	for _, verdict := range previous {
+		previousByPath[verdict.DevPath] = verdict
+	}
+
+	changed := []diskv1.DiskFilterVerdict{}
+	for _, verdict := range verdicts {
+		old, found := previousByPath[verdict.DevPath]
+		if !found {
+			if verdict.Excluded {
+				changed = append(changed, verdict)
+			}
+			continue
+		}
+		if old.Excluded != verdict.Excluded ||
+			(verdict.Excluded && (old.Filter != verdict.Filter || old.Reason != verdict.Reason)) {
+			changed = append(changed, verdict)
 		}
 	}
-	return false
+	return changed
+}
+
+func describeExclusion(verdict diskv1.DiskFilterVerdict) string {
+	if verdict.Filter != "" {
+		return fmt.Sprintf("%s (%s)", verdict.Filter, verdict.Rules)
+	}
+	return verdict.Reason
+}
+
+// recordNodeEvent records an event on the Node of the scanner, for the events not related to
+// an existing block device
+func (s *Scanner) recordNodeEvent(eventType, reason, messageFmt string, args ...interface{}) {
+	s.Recorder.Eventf(s.nodeRef, eventType, reason, messageFmt, args...)
+}
+
+// recordScan records the scan of the given devices, or a full scan when there is no
+// device, and notifies the listeners
+func (s *Scanner) recordScan(reason string, devPaths []string, err error) {
+	record := &diskv1.ScanRecord{
+		Time:    metav1.Now(),
+		Reason:  reason,
+		Full:    len(devPaths) == 0,
+		Devices: devPaths,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	s.resultsLock.Lock()
+	s.lastScan = record
+	if record.Full {
+		s.lastFullScan = record
+		if err == nil {
+			// takenFullScans is only written by the scans, which make this call
+			s.servedFullScans = s.takenFullScans
+		}
+	}
+	s.resultsLock.Unlock()
+
+	for _, listener := range s.scanListeners {
+		listener()
+	}
 }
 
 // SaveBlockDevice persists the blockedevice information.
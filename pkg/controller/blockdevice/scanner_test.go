@@ -0,0 +1,458 @@
+package blockdevice
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/filter"
+	"github.com/harvester/node-disk-manager/pkg/provisioner"
+	"github.com/harvester/node-disk-manager/pkg/utils"
+	"github.com/harvester/node-disk-manager/pkg/utils/fake"
+)
+
+func TestChangedExclusions(t *testing.T) {
+	previous := []diskv1.DiskFilterVerdict{
+		{DevPath: "/dev/sda", Excluded: true, Filter: "vendor filter"},
+		{DevPath: "/dev/sdb"},
+		{DevPath: "/dev/sdc", Excluded: true, Filter: "path filter"},
+		{DevPath: "/dev/sdd", Excluded: true, Reason: "managed by a multipath device"},
+	}
+	verdicts := []diskv1.DiskFilterVerdict{
+		// unchanged
+		{DevPath: "/dev/sda", Excluded: true, Filter: "vendor filter"},
+		// got excluded
+		{DevPath: "/dev/sdb", Excluded: true, Filter: "label filter"},
+		// excluded by another filter
+		{DevPath: "/dev/sdc", Excluded: true, Filter: "label filter"},
+		// no longer excluded
+		{DevPath: "/dev/sdd"},
+		// new disks, only the excluded one is reported
+		{DevPath: "/dev/sde", Excluded: true, Reason: "device mapper device which is not a multipath device"},
+		{DevPath: "/dev/sdf"},
+	}
+
+	changed := changedExclusions(previous, verdicts)
+	devPaths := []string{}
+	for _, verdict := range changed {
+		devPaths = append(devPaths, verdict.DevPath)
+	}
+	assert.Equal(t, []string{"/dev/sdb", "/dev/sdc", "/dev/sdd", "/dev/sde"}, devPaths)
+
+	assert.Empty(t, changedExclusions(verdicts, verdicts), "nothing is reported when the verdicts don't change")
+}
+
+func newConfigMapLoader(t *testing.T, filtersYAML string) *filter.ConfigMapLoader {
+	clientset := corefake.NewSimpleClientset()
+	require.NoError(t, clientset.Tracker().Add(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: filter.DefaultConfigMapName, Namespace: filter.DefaultConfigMapNamespace},
+		Data:       map[string]string{filter.FiltersConfigKey: filtersYAML},
+	}))
+	return filter.NewConfigMapLoader(fake.FakeConfigMapCache(clientset.CoreV1().ConfigMaps), "node1", "", "", "", "")
+}
+
+func filterNames(filters []*filter.Filter) []string {
+	names := []string{}
+	for _, f := range filters {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func TestLoadConfigMapFilters(t *testing.T) {
+	s := &Scanner{ConfigMapLoader: newConfigMapLoader(t, `- hostname: "*"
+  excludeVendors: ["longhorn"]
+  excludeExpressions: ['disk.sizeBytes < 1*GiB']
+  includeModels: ["MZ7LH*"]`)}
+	s.loadConfigMapFilters(context.TODO())
+	names := filterNames(s.ExcludeFilters)
+	assert.Contains(t, names, "vendor filter")
+	assert.Contains(t, names, "expression filter")
+	assert.Equal(t, "include filter", names[len(names)-1], "the allow-list goes last")
+
+	previous := s.ExcludeFilters
+	tests := []struct {
+		name        string
+		filtersYAML string
+	}{
+		{
+			name: "invalid include size range",
+			filtersYAML: `- hostname: "*"
+  includeSizeRange:
+    min: lots`,
+		},
+		{
+			name: "invalid exclude expression",
+			filtersYAML: `- hostname: "*"
+  excludeExpressions: ['disk.vendor ==']`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.ConfigMapLoader = newConfigMapLoader(t, tt.filtersYAML)
+			s.loadConfigMapFilters(context.TODO())
+			assert.Equal(t, previous, s.ExcludeFilters, "the previous filters are kept")
+		})
+	}
+}
+
+func TestTakeScanRequests(t *testing.T) {
+	s := newTestScanner(newFakeBlockDevices(), newFakeBlockInfo())
+
+	// a burst of device requests is served by a single scan of every requested device
+	s.RequestDeviceScan("udev add", "/dev/sdb")
+	s.RequestDeviceScan("udev change", "/dev/sda", "/dev/sdb")
+	s.RequestDeviceScan("udev add", "/dev/sdc")
+	s.RequestDeviceScan("no device")
+	assert.True(t, s.scanRequested())
+	fullScan, devPaths, reason := s.takeScanRequests()
+	assert.False(t, fullScan)
+	assert.Equal(t, []string{"/dev/sda", "/dev/sdb", "/dev/sdc"}, devPaths)
+	assert.Equal(t, "udev add, udev change", reason)
+	assert.False(t, s.scanRequested(), "the requests are cleared once taken")
+
+	// a full scan covers the requested devices
+	s.RequestDeviceScan("udev add", "/dev/sdd")
+	s.RequestScan("ConfigMap changed")
+	fullScan, devPaths, reason = s.takeScanRequests()
+	assert.True(t, fullScan)
+	assert.Equal(t, []string{"/dev/sdd"}, devPaths)
+	assert.Equal(t, "udev add, ConfigMap changed", reason)
+	assert.False(t, s.scanRequested())
+}
+
+func TestScanDebounce(t *testing.T) {
+	sda, sdb := newSSD("sda", "S1"), newSSD("sdb", "S2")
+	s := newTestScanner(newFakeBlockDevices(), newFakeBlockInfo(sda, sdb))
+	s.ConfigMapLoader = newConfigMapLoader(t, "")
+	terminated := make(chan bool, 1)
+	s.TerminatedChannels = &terminated
+	scanned := make(chan struct{}, 10)
+	s.AddScanListener(func() { scanned <- struct{}{} })
+
+	require.NoError(t, s.Start(context.Background()))
+	<-scanned
+	_, fullScan := s.ScanRecords()
+	require.NotNil(t, fullScan)
+	assert.Equal(t, "initial scan", fullScan.Reason)
+
+	// the events of a burst are served by a single scan
+	s.RequestDeviceScan("udev add", "/dev/sda")
+	s.RequestDeviceScan("udev change", "/dev/sdb")
+	s.RequestDeviceScan("udev change", "/dev/sda")
+	select {
+	case <-scanned:
+	case <-time.After(scanDebounce + 5*time.Second):
+		t.Fatal("the requested scan didn't run")
+	}
+	lastScan, _ := s.ScanRecords()
+	assert.False(t, lastScan.Full)
+	assert.Equal(t, []string{"/dev/sda", "/dev/sdb"}, lastScan.Devices)
+	assert.Equal(t, "udev add, udev change", lastScan.Reason)
+	assert.Empty(t, lastScan.Error)
+
+	utils.CallerWithCondLock(s.Cond, func() any {
+		s.Shutdown = true
+		s.Cond.Signal()
+		return nil
+	})
+	<-terminated
+	assert.Empty(t, scanned, "no other scan ran")
+}
+
+func TestResyncPeriodically(t *testing.T) {
+	bdA := newScannedBlockDevice("bd-a", newSSD("sda", "S1"))
+	bdB := newScannedBlockDevice("bd-b", newSSD("sdb", "S2"))
+	other := newScannedBlockDevice("bd-other", newSSD("sda", "S3"))
+	other.Labels[corev1.LabelHostname] = "node2"
+	bds := newFakeBlockDevices(bdA, bdB, other)
+	s := newTestScanner(bds, newFakeBlockInfo())
+	s.RescanInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.resyncPeriodically(ctx)
+		close(done)
+	}()
+
+	// every tick requests a full scan and re-enqueues the block devices of the node only
+	assert.Eventually(t, func() bool {
+		return len(bds.enqueuedNames()) >= 4
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	assert.NotContains(t, bds.enqueuedNames(), "bd-other")
+	assert.Subset(t, bds.enqueuedNames(), []string{"bd-a", "bd-b"})
+	fullScan, devPaths, reason := s.takeScanRequests()
+	assert.True(t, fullScan)
+	assert.Empty(t, devPaths)
+	assert.Equal(t, "periodic full scan", reason, "the requests of the ticks are merged")
+}
+
+func TestRecordScan(t *testing.T) {
+	s := newTestScanner(newFakeBlockDevices(), newFakeBlockInfo())
+	notified := 0
+	s.AddScanListener(func() { notified++ })
+
+	lastScan, lastFullScan := s.ScanRecords()
+	assert.Nil(t, lastScan)
+	assert.Nil(t, lastFullScan)
+
+	s.recordScan("initial scan", nil, nil)
+	lastScan, lastFullScan = s.ScanRecords()
+	require.NotNil(t, lastScan)
+	assert.True(t, lastScan.Full)
+	assert.Equal(t, "initial scan", lastScan.Reason)
+	assert.Empty(t, lastScan.Error)
+	assert.Equal(t, lastScan, lastFullScan)
+
+	// a device scan doesn't replace the record of the last full scan
+	s.recordScan("udev add", []string{"/dev/sdb"}, fmt.Errorf("connection refused"))
+	lastScan, lastFullScan = s.ScanRecords()
+	assert.False(t, lastScan.Full)
+	assert.Equal(t, []string{"/dev/sdb"}, lastScan.Devices)
+	assert.Equal(t, "udev add", lastScan.Reason)
+	assert.Equal(t, "connection refused", lastScan.Error)
+	assert.Equal(t, "initial scan", lastFullScan.Reason)
+	assert.Equal(t, 2, notified)
+
+	// the records are returned as copies
+	lastScan.Devices[0] = "/dev/sdc"
+	lastScan, _ = s.ScanRecords()
+	assert.Equal(t, []string{"/dev/sdb"}, lastScan.Devices)
+}
+
+func TestNewBlockDeviceName(t *testing.T) {
+	bd := newScannedBlockDevice("", newSSD("sdb", "S2"))
+	name, ok := GenerateBlockDeviceName("node1", bd)
+	require.True(t, ok)
+	bds := newFakeBlockDevices(newScannedBlockDevice(name+"-1", newSSD("sdc", "S2")))
+	s := newTestScanner(bds, newFakeBlockInfo())
+
+	got, err := s.newBlockDeviceName(bd, map[string]struct{}{})
+	require.NoError(t, err)
+	assert.Equal(t, name, got)
+
+	// the names taken by this scan or by existing block devices are skipped
+	got, err = s.newBlockDeviceName(bd, map[string]struct{}{name: {}})
+	require.NoError(t, err)
+	assert.Equal(t, name+"-2", got)
+
+	// a name which can't be checked isn't assumed to be free
+	bds.clientset.PrependReactor("get", "blockdevices", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("connection refused")
+	})
+	_, err = s.newBlockDeviceName(bd, map[string]struct{}{})
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+func TestFullScanServed(t *testing.T) {
+	s := newTestScanner(newFakeBlockDevices(), newFakeBlockInfo())
+
+	first := s.RequestScan("NodeDiskManagerConfig changed")
+	assert.False(t, s.FullScanServed(first))
+	fullScan, _, reason := s.takeScanRequests()
+	require.True(t, fullScan)
+
+	// a request made while the scan runs is served by the next one
+	second := s.RequestScan("ConfigMap changed")
+	s.recordScan(reason, nil, nil)
+	assert.True(t, s.FullScanServed(first))
+	assert.False(t, s.FullScanServed(second))
+
+	// a failed scan or a device scan serves no request
+	fullScan, _, reason = s.takeScanRequests()
+	require.True(t, fullScan)
+	s.recordScan(reason, nil, fmt.Errorf("connection refused"))
+	assert.False(t, s.FullScanServed(second))
+	s.RequestDeviceScan("udev add", "/dev/sdb")
+	_, devPaths, reason := s.takeScanRequests()
+	s.recordScan(reason, devPaths, nil)
+	assert.False(t, s.FullScanServed(second))
+}
+
+func TestHandleCapacityChange(t *testing.T) {
+	tests := []struct {
+		name        string
+		oldSize     uint64
+		newSize     uint64
+		provisioned bool
+		dryRun      bool
+		event       bool
+		warning     bool
+		resizing    bool
+	}{
+		{
+			name:        "unchanged capacity",
+			oldSize:     100 << 30,
+			newSize:     100 << 30,
+			provisioned: true,
+		},
+		{
+			name:        "the capacity of a new device is not a change",
+			newSize:     100 << 30,
+			provisioned: true,
+		},
+		{
+			name:        "a provisioned device which grew is resized",
+			oldSize:     100 << 30,
+			newSize:     200 << 30,
+			provisioned: true,
+			event:       true,
+			resizing:    true,
+		},
+		{
+			name:        "a provisioned device which shrank is reported as a warning",
+			oldSize:     200 << 30,
+			newSize:     100 << 30,
+			provisioned: true,
+			event:       true,
+			warning:     true,
+		},
+		{
+			name:    "an unprovisioned device which grew is only reported",
+			oldSize: 100 << 30,
+			newSize: 200 << 30,
+			event:   true,
+		},
+		{
+			name:        "the dry-run mode reports the change with the update",
+			oldSize:     100 << 30,
+			newSize:     200 << 30,
+			provisioned: true,
+			dryRun:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			s := &Scanner{Recorder: recorder, DryRun: tt.dryRun}
+			bd := newDiskBlockDevice("bd", "/dev/sdb")
+			bd.Status.DeviceStatus.Capacity.SizeBytes = tt.oldSize
+			if tt.provisioned {
+				bd.Status.ProvisionPhase = diskv1.ProvisionPhaseProvisioned
+			}
+
+			s.handleCapacityChange(bd, tt.newSize)
+			assert.Equal(t, tt.resizing, diskv1.DeviceResizing.IsTrue(bd))
+			if tt.event {
+				require.Len(t, recorder.Events, 1)
+				eventType := corev1.EventTypeNormal
+				if tt.warning {
+					eventType = corev1.EventTypeWarning
+				}
+				assert.Equal(t, fmt.Sprintf("%s %s Capacity of /dev/sdb changed from %d to %d bytes", eventType, utils.EventReasonCapacityChanged, tt.oldSize, tt.newSize), <-recorder.Events)
+			} else {
+				assert.Empty(t, recorder.Events)
+			}
+		})
+	}
+}
+
+func TestHandleReadOnlyChange(t *testing.T) {
+	mountPoint := "/var/lib/harvester/extra-disks/bd"
+	tests := []struct {
+		name        string
+		unhealthy   bool
+		mountPoint  string
+		readOnly    bool
+		provisioned bool
+		dryRun      bool
+		healthy     *bool
+		reason      string
+		event       string
+	}{
+		{
+			name:        "writable filesystem",
+			mountPoint:  mountPoint,
+			provisioned: true,
+		},
+		{
+			name:        "the filesystem turned read-only",
+			mountPoint:  mountPoint,
+			readOnly:    true,
+			provisioned: true,
+			healthy:     &[]bool{false}[0],
+			reason:      provisioner.FilesystemHealthyReasonReadOnly,
+			event:       "Warning " + utils.EventReasonFilesystemReadOnly,
+		},
+		{
+			name:        "the read-only filesystem is only reported once",
+			unhealthy:   true,
+			mountPoint:  mountPoint,
+			readOnly:    true,
+			provisioned: true,
+			healthy:     &[]bool{false}[0],
+			reason:      provisioner.FilesystemHealthyReasonReadOnly,
+		},
+		{
+			name:        "the filesystem is writable again",
+			unhealthy:   true,
+			mountPoint:  mountPoint,
+			provisioned: true,
+			healthy:     &[]bool{true}[0],
+			reason:      provisioner.FilesystemHealthyReasonRecovered,
+			event:       "Normal " + utils.EventReasonFilesystemRecovered,
+		},
+		{
+			name:        "a read-only filesystem at another mount point is ignored",
+			mountPoint:  "/mnt/bd",
+			readOnly:    true,
+			provisioned: true,
+		},
+		{
+			name:       "a read-only filesystem of an unprovisioned device is ignored",
+			mountPoint: mountPoint,
+			readOnly:   true,
+		},
+		{
+			name:        "the dry-run mode doesn't flag the filesystem",
+			mountPoint:  mountPoint,
+			readOnly:    true,
+			provisioned: true,
+			dryRun:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			s := &Scanner{Recorder: recorder, DryRun: tt.dryRun}
+			bd := newDiskBlockDevice("bd", "/dev/sdb")
+			if tt.provisioned {
+				bd.Spec.Provision = true
+				bd.Status.ProvisionPhase = diskv1.ProvisionPhaseProvisioned
+			}
+			if tt.unhealthy {
+				diskv1.FilesystemHealthy.SetStatusBool(bd, false)
+				diskv1.FilesystemHealthy.Reason(bd, provisioner.FilesystemHealthyReasonReadOnly)
+			}
+
+			s.handleReadOnlyChange(bd, tt.mountPoint, tt.readOnly)
+			if tt.healthy == nil {
+				assert.Empty(t, diskv1.FilesystemHealthy.GetStatus(bd))
+			} else {
+				assert.Equal(t, *tt.healthy, diskv1.FilesystemHealthy.IsTrue(bd))
+				assert.Equal(t, tt.reason, diskv1.FilesystemHealthy.GetReason(bd))
+			}
+			if tt.event != "" {
+				require.Len(t, recorder.Events, 1)
+				assert.Contains(t, <-recorder.Events, tt.event)
+			} else {
+				assert.Empty(t, recorder.Events)
+			}
+		})
+	}
+}
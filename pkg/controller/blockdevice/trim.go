@@ -0,0 +1,120 @@
+package blockdevice
+
+import (
+	"hash/fnv"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/metrics"
+	"github.com/harvester/node-disk-manager/pkg/provisioner"
+	"github.com/harvester/node-disk-manager/pkg/utils"
+)
+
+// trimJitterRatio spreads the trims of the disks over a tenth of their interval,
+// so the nodes sharing a schedule don't trim at the same time
+const trimJitterRatio = 10
+
+// trimIfDue runs fstrim on a mounted LonghornV1 SSD disk whose trim schedule is due,
+// and requeues the device for its next trim otherwise
+func (c *Controller) trimIfDue(device *diskv1.BlockDevice, devPath string) {
+	if !isTrimCandidate(device) {
+		return
+	}
+	schedules, err := c.scanner.ConfigMapLoader.LoadTrimSchedules()
+	if err != nil {
+		logrus.Warnf("Failed to load the trim schedules: %v", err)
+		return
+	}
+	interval := trimInterval(schedules, device)
+	if interval <= 0 || !c.BlockInfo.SupportsDiscard(devPath) {
+		return
+	}
+	if wait := time.Until(nextTrimTime(device, interval, c.trimScheduledAt(device))); wait > 0 {
+		c.Blockdevices.EnqueueAfter(c.Namespace, device.Name, wait)
+		return
+	}
+	if !c.semaphore.Acquire() {
+		logrus.Infof("Hit maximum concurrent count. Requeue device %s", device.Name)
+		c.Blockdevices.EnqueueAfter(c.Namespace, device.Name, jitterEnqueueDelay())
+		return
+	}
+	defer c.semaphore.Release()
+
+	mountPoint := device.Status.DeviceStatus.FileSystem.MountPoint
+	logrus.Infof("Trim the filesystem of device %s mounted at %s", device.Name, mountPoint)
+	trimmed, err := utils.Fstrim(mountPoint)
+	metrics.ObserveTrim(device.Name, trimmed, err)
+	status := &diskv1.FilesystemTrimStatus{
+		Time:         metav1.Now(),
+		TrimmedBytes: trimmed,
+		Succeeded:    err == nil,
+	}
+	if err != nil {
+		status.Message = err.Error()
+		c.recorder.Eventf(device, corev1.EventTypeWarning, utils.EventReasonTrimFailed, "Failed to trim the filesystem: %v", err)
+	} else {
+		c.recorder.Eventf(device, corev1.EventTypeNormal, utils.EventReasonTrimmed, "Trimmed %d bytes of the filesystem", trimmed)
+	}
+	device.Status.DeviceStatus.FileSystem.LastTrim = status
+	c.trimSchedules.Delete(device.Name)
+	c.Blockdevices.EnqueueAfter(c.Namespace, device.Name, time.Until(nextTrimTime(device, interval, time.Time{})))
+}
+
+// trimScheduledAt returns when the controller first scheduled the trim of a device that was never
+// trimmed, so the existing disks aren't all trimmed at once after an upgrade or a restart
+func (c *Controller) trimScheduledAt(device *diskv1.BlockDevice) time.Time {
+	scheduledAt, _ := c.trimSchedules.LoadOrStore(device.Name, time.Now())
+	return scheduledAt.(time.Time)
+}
+
+// isTrimCandidate checks whether the device is a provisioned LonghornV1 SSD disk with a
+// healthy filesystem mounted at its mount point
+func isTrimCandidate(device *diskv1.BlockDevice) bool {
+	if !device.Spec.Provision || device.Status.ProvisionPhase != diskv1.ProvisionPhaseProvisioned || !isLonghornV1Device(device) {
+		return false
+	}
+	if device.Status.DeviceStatus.Details.DriveType != string(diskv1.DriveTypeSSD) {
+		return false
+	}
+	fs := device.Status.DeviceStatus.FileSystem
+	return fs != nil && fs.MountPoint == provisioner.ExtraDiskMountPoint(device) &&
+		!fs.IsReadOnly && !diskv1.FilesystemHealthy.IsFalse(device)
+}
+
+// trimInterval returns the interval of the last schedule matching the device, or zero if none matches
+func trimInterval(schedules []diskv1.TrimSchedule, device *diskv1.BlockDevice) time.Duration {
+	var interval time.Duration
+	for _, schedule := range schedules {
+		if selectsBlockDevice(schedule.BlockDevices, schedule.Tags, device) {
+			interval = schedule.Interval.Duration
+		}
+	}
+	return interval
+}
+
+// selectsBlockDevice checks whether the device is named or has any of the tags,
+// every device is selected when both the names and the tags are empty
+func selectsBlockDevice(names, tags []string, device *diskv1.BlockDevice) bool {
+	matchesAll := len(names) == 0 && len(tags) == 0
+	matchesTag := slices.ContainsFunc(tags, func(tag string) bool {
+		return slices.Contains(device.Spec.Tags, tag)
+	})
+	return matchesAll || matchesTag || slices.Contains(names, device.Name)
+}
+
+// nextTrimTime returns the time of the next trim, one interval after the last trim or, for a device
+// never trimmed, when its trim was scheduled, delayed by a jitter derived from the device name
+func nextTrimTime(device *diskv1.BlockDevice, interval time.Duration, scheduledAt time.Time) time.Time {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(device.Name))
+	jitter := time.Duration(hash.Sum32()) * time.Second % (interval / trimJitterRatio)
+	if lastTrim := device.Status.DeviceStatus.FileSystem.LastTrim; lastTrim != nil {
+		return lastTrim.Time.Add(interval + jitter)
+	}
+	return scheduledAt.Add(jitter)
+}
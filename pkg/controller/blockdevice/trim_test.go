@@ -0,0 +1,133 @@
+package blockdevice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+func newTrimBlockDevice() *diskv1.BlockDevice {
+	bd := newDiskBlockDevice("bd", "/dev/sdb")
+	bd.Spec.Provision = true
+	bd.Spec.Tags = []string{"fast"}
+	bd.Status.ProvisionPhase = diskv1.ProvisionPhaseProvisioned
+	bd.Status.DeviceStatus.Details.DriveType = string(diskv1.DriveTypeSSD)
+	bd.Status.DeviceStatus.FileSystem.MountPoint = "/var/lib/harvester/extra-disks/bd"
+	return bd
+}
+
+func TestIsTrimCandidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		update    func(*diskv1.BlockDevice)
+		candidate bool
+	}{
+		{
+			name:      "mounted SSD disk",
+			update:    func(*diskv1.BlockDevice) {},
+			candidate: true,
+		},
+		{
+			name: "HDD disk",
+			update: func(bd *diskv1.BlockDevice) {
+				bd.Status.DeviceStatus.Details.DriveType = string(diskv1.DriveTypeHDD)
+			},
+		},
+		{
+			name: "unprovisioned disk",
+			update: func(bd *diskv1.BlockDevice) {
+				bd.Status.ProvisionPhase = diskv1.ProvisionPhaseUnprovisioned
+			},
+		},
+		{
+			name: "LVM disk",
+			update: func(bd *diskv1.BlockDevice) {
+				bd.Spec.Provisioner = &diskv1.ProvisionerInfo{LVM: &diskv1.LVMProvisionerInfo{VgName: "vg"}}
+			},
+		},
+		{
+			name: "disk mounted at another mount point",
+			update: func(bd *diskv1.BlockDevice) {
+				bd.Status.DeviceStatus.FileSystem.MountPoint = "/mnt/bd"
+			},
+		},
+		{
+			name: "read-only filesystem",
+			update: func(bd *diskv1.BlockDevice) {
+				bd.Status.DeviceStatus.FileSystem.IsReadOnly = true
+			},
+		},
+		{
+			name: "unhealthy filesystem",
+			update: func(bd *diskv1.BlockDevice) {
+				diskv1.FilesystemHealthy.SetStatusBool(bd, false)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bd := newTrimBlockDevice()
+			tt.update(bd)
+			assert.Equal(t, tt.candidate, isTrimCandidate(bd))
+		})
+	}
+}
+
+func TestTrimInterval(t *testing.T) {
+	week := metav1.Duration{Duration: 168 * time.Hour}
+	day := metav1.Duration{Duration: 24 * time.Hour}
+	tests := []struct {
+		name      string
+		schedules []diskv1.TrimSchedule
+		interval  time.Duration
+	}{
+		{
+			name: "no schedule",
+		},
+		{
+			name:      "schedule of every disk",
+			schedules: []diskv1.TrimSchedule{{Interval: week}},
+			interval:  week.Duration,
+		},
+		{
+			name:      "schedule of other disks",
+			schedules: []diskv1.TrimSchedule{{Interval: week, BlockDevices: []string{"other"}, Tags: []string{"slow"}}},
+		},
+		{
+			name:      "the last matching schedule wins",
+			schedules: []diskv1.TrimSchedule{{Interval: week}, {Interval: day, Tags: []string{"fast"}}, {Interval: week, Tags: []string{"slow"}}},
+			interval:  day.Duration,
+		},
+		{
+			name:      "a schedule matching by name disables the trim",
+			schedules: []diskv1.TrimSchedule{{Interval: week}, {BlockDevices: []string{"bd"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.interval, trimInterval(tt.schedules, newTrimBlockDevice()))
+		})
+	}
+}
+
+func TestNextTrimTime(t *testing.T) {
+	interval := 168 * time.Hour
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	scheduled := created.Add(365 * 24 * time.Hour)
+	bd := newTrimBlockDevice()
+	bd.CreationTimestamp = metav1.NewTime(created)
+
+	next := nextTrimTime(bd, interval, scheduled)
+	jitter := next.Sub(scheduled)
+	assert.GreaterOrEqual(t, jitter, time.Duration(0), "the first trim doesn't depend on the creation of the device")
+	assert.Less(t, jitter, interval/trimJitterRatio)
+	assert.Equal(t, next, nextTrimTime(bd, interval, scheduled), "the jitter of a device is stable")
+
+	lastTrim := created.Add(30 * 24 * time.Hour)
+	bd.Status.DeviceStatus.FileSystem.LastTrim = &diskv1.FilesystemTrimStatus{Time: metav1.NewTime(lastTrim)}
+	assert.Equal(t, lastTrim.Add(interval+jitter), nextTrimTime(bd, interval, scheduled))
+}
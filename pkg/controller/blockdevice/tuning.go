@@ -0,0 +1,182 @@
+package blockdevice
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/utils"
+)
+
+// sysfsWriteCacheModes are the values of /sys/block/<disk>/queue/write_cache
+var sysfsWriteCacheModes = map[diskv1.WriteCacheMode]string{
+	diskv1.WriteCacheBack:    "write back",
+	diskv1.WriteCacheThrough: "write through",
+}
+
+// queueAttribute is a queue attribute of a disk to write
+type queueAttribute struct {
+	name  string
+	value string
+}
+
+func (a queueAttribute) String() string {
+	return fmt.Sprintf("%s=%q", a.name, a.value)
+}
+
+// applyTuning writes the tuning profile matching an active disk to its queue attributes
+// when they differ, and reports the effective values in the status. The attributes
+// of a disk no longer matching any profile are left as they are.
+func (c *Controller) applyTuning(device *diskv1.BlockDevice) {
+	if device.Status.State != diskv1.BlockDeviceActive || device.Status.DeviceStatus.Details.DeviceType != diskv1.DeviceTypeDisk {
+		return
+	}
+	profiles, err := c.scanner.ConfigMapLoader.LoadTuningProfiles()
+	if err != nil {
+		logrus.Warnf("Failed to load the tuning profiles: %v", err)
+		return
+	}
+	profile := tuningProfile(profiles, device)
+	if profile == nil {
+		device.Status.Tuning = nil
+		return
+	}
+	disk, found := c.BlockInfo.GetDiskNameByDevPath(device.Status.DeviceStatus.DevPath)
+	if !found {
+		return
+	}
+
+	current, err := utils.ReadQueueSettings(disk)
+	if err != nil {
+		logrus.Warnf("Failed to read the queue attributes of device %s: %v", device.Name, err)
+		return
+	}
+	changes := tuningChanges(profile, current)
+	if len(changes) == 0 {
+		device.Status.Tuning = tuningStatus(current, device.Status.Tuning)
+		return
+	}
+
+	// the attributes changed behind NDM's back since they were reported, e.g. by
+	// hand or by re-plugging the disk, unlike the attributes of a changed profile
+	drifted := device.Status.Tuning != nil && !equalTuningStatus(tuningStatus(current, nil), device.Status.Tuning)
+	logrus.Infof("Tune the queue attributes %v of device %s", changes, device.Name)
+	var writeErr error
+	for _, change := range changes {
+		if writeErr = utils.WriteQueueAttribute(disk, change.name, change.value); writeErr != nil {
+			c.recorder.Eventf(device, corev1.EventTypeWarning, utils.EventReasonTuningFailed, "Failed to tune the disk: %v", writeErr)
+			break
+		}
+	}
+	previous := device.Status.Tuning
+	if writeErr == nil {
+		now := metav1.Now()
+		previous = &diskv1.TuningStatus{LastApplied: &now}
+	}
+	if current, err = utils.ReadQueueSettings(disk); err != nil {
+		logrus.Warnf("Failed to read the queue attributes of device %s: %v", device.Name, err)
+		return
+	}
+	device.Status.Tuning = tuningStatus(current, previous)
+	if drifted && writeErr == nil {
+		c.recorder.Eventf(device, corev1.EventTypeWarning, utils.EventReasonTuningDrift, "Re-applied the drifted queue attributes %v", changes)
+	}
+}
+
+// tuningProfile merges the profiles matching the device, the fields set by the last
+// profile win. It returns nil if no profile matches.
+func tuningProfile(profiles []diskv1.TuningProfile, device *diskv1.BlockDevice) *diskv1.TuningProfile {
+	var merged *diskv1.TuningProfile
+	for _, profile := range profiles {
+		if !selectsBlockDevice(profile.BlockDevices, profile.Tags, device) {
+			continue
+		}
+		if merged == nil {
+			merged = &diskv1.TuningProfile{}
+		}
+		if profile.Scheduler != "" {
+			merged.Scheduler = profile.Scheduler
+		}
+		if profile.ReadAheadKB != nil {
+			merged.ReadAheadKB = profile.ReadAheadKB
+		}
+		if profile.NrRequests != nil {
+			merged.NrRequests = profile.NrRequests
+		}
+		if profile.WriteCache != "" {
+			merged.WriteCache = profile.WriteCache
+		}
+	}
+	return merged
+}
+
+// tuningChanges lists the attributes of the profile which differ from the current ones. The
+// scheduler comes first as switching it resets nr_requests to the default of the scheduler.
+func tuningChanges(profile *diskv1.TuningProfile, current *utils.QueueSettings) []queueAttribute {
+	var changes []queueAttribute
+	if profile.Scheduler != "" && profile.Scheduler != current.Scheduler {
+		changes = append(changes, queueAttribute{utils.QueueAttrScheduler, profile.Scheduler})
+	}
+	if profile.ReadAheadKB != nil && *profile.ReadAheadKB != current.ReadAheadKB {
+		changes = append(changes, queueAttribute{utils.QueueAttrReadAheadKB, strconv.FormatInt(*profile.ReadAheadKB, 10)})
+	}
+	if profile.NrRequests != nil && (*profile.NrRequests != current.NrRequests || len(changes) > 0 && changes[0].name == utils.QueueAttrScheduler) {
+		changes = append(changes, queueAttribute{utils.QueueAttrNrRequests, strconv.FormatInt(*profile.NrRequests, 10)})
+	}
+	if mode := sysfsWriteCacheModes[profile.WriteCache]; mode != "" && mode != current.WriteCache {
+		changes = append(changes, queueAttribute{utils.QueueAttrWriteCache, mode})
+	}
+	return changes
+}
+
+// tuningStatus reports the current attributes, keeping the time the profile was last applied
+func tuningStatus(current *utils.QueueSettings, previous *diskv1.TuningStatus) *diskv1.TuningStatus {
+	status := &diskv1.TuningStatus{
+		Scheduler:   current.Scheduler,
+		ReadAheadKB: current.ReadAheadKB,
+		NrRequests:  current.NrRequests,
+	}
+	for mode, sysfsMode := range sysfsWriteCacheModes {
+		if sysfsMode == current.WriteCache {
+			status.WriteCache = mode
+		}
+	}
+	if previous != nil {
+		status.LastApplied = previous.LastApplied
+	}
+	return status
+}
+
+func equalTuningStatus(a, b *diskv1.TuningStatus) bool {
+	return a.Scheduler == b.Scheduler && a.ReadAheadKB == b.ReadAheadKB &&
+		a.NrRequests == b.NrRequests && a.WriteCache == b.WriteCache
+}
+
+// planTuning describes the queue attributes the tuning profile of the device would change
+func (c *Controller) planTuning(device *diskv1.BlockDevice) []string {
+	if device.Status.State != diskv1.BlockDeviceActive || device.Status.DeviceStatus.Details.DeviceType != diskv1.DeviceTypeDisk {
+		return nil
+	}
+	profiles, err := c.scanner.ConfigMapLoader.LoadTuningProfiles()
+	if err != nil {
+		return nil
+	}
+	profile := tuningProfile(profiles, device)
+	disk, found := c.BlockInfo.GetDiskNameByDevPath(device.Status.DeviceStatus.DevPath)
+	if profile == nil || !found {
+		return nil
+	}
+	current, err := utils.ReadQueueSettings(disk)
+	if err != nil {
+		return nil
+	}
+	var plan []string
+	for _, change := range tuningChanges(profile, current) {
+		plan = append(plan, fmt.Sprintf("set queue/%s of disk %s to %q", change.name, disk, change.value))
+	}
+	return plan
+}
@@ -0,0 +1,90 @@
+package blockdevice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/utils"
+)
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func TestTuningProfile(t *testing.T) {
+	device := newDiskBlockDevice("bd", "/dev/sdb")
+	device.Spec.Tags = []string{"fast"}
+
+	assert.Nil(t, tuningProfile(nil, device))
+	assert.Nil(t, tuningProfile([]diskv1.TuningProfile{{Tags: []string{"slow"}, Scheduler: "bfq"}}, device))
+
+	profile := tuningProfile([]diskv1.TuningProfile{
+		{Scheduler: "mq-deadline", ReadAheadKB: int64Ptr(128), WriteCache: diskv1.WriteCacheBack},
+		{Tags: []string{"fast"}, Scheduler: "none", NrRequests: int64Ptr(1023)},
+		{BlockDevices: []string{"other"}, ReadAheadKB: int64Ptr(4096)},
+		{BlockDevices: []string{"bd"}, ReadAheadKB: int64Ptr(256)},
+	}, device)
+	assert.Equal(t, &diskv1.TuningProfile{
+		Scheduler:   "none",
+		ReadAheadKB: int64Ptr(256),
+		NrRequests:  int64Ptr(1023),
+		WriteCache:  diskv1.WriteCacheBack,
+	}, profile)
+}
+
+func TestTuningChanges(t *testing.T) {
+	current := &utils.QueueSettings{Scheduler: "mq-deadline", ReadAheadKB: 128, NrRequests: 64, WriteCache: "write back"}
+	tests := []struct {
+		name    string
+		profile diskv1.TuningProfile
+		changes []queueAttribute
+	}{
+		{
+			name: "empty profile",
+		},
+		{
+			name:    "the attributes already match",
+			profile: diskv1.TuningProfile{Scheduler: "mq-deadline", ReadAheadKB: int64Ptr(128), NrRequests: int64Ptr(64), WriteCache: diskv1.WriteCacheBack},
+		},
+		{
+			name:    "changed attributes",
+			profile: diskv1.TuningProfile{ReadAheadKB: int64Ptr(4096), WriteCache: diskv1.WriteCacheThrough},
+			changes: []queueAttribute{
+				{utils.QueueAttrReadAheadKB, "4096"},
+				{utils.QueueAttrWriteCache, "write through"},
+			},
+		},
+		{
+			name:    "the queue depth is written again after switching the scheduler",
+			profile: diskv1.TuningProfile{Scheduler: "bfq", NrRequests: int64Ptr(64)},
+			changes: []queueAttribute{
+				{utils.QueueAttrScheduler, "bfq"},
+				{utils.QueueAttrNrRequests, "64"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.changes, tuningChanges(&tt.profile, current))
+		})
+	}
+}
+
+func TestTuningStatus(t *testing.T) {
+	current := &utils.QueueSettings{Scheduler: "none", ReadAheadKB: 128, NrRequests: 1023, WriteCache: "write through"}
+	status := tuningStatus(current, nil)
+	assert.Equal(t, &diskv1.TuningStatus{Scheduler: "none", ReadAheadKB: 128, NrRequests: 1023, WriteCache: diskv1.WriteCacheThrough}, status)
+
+	lastApplied := metav1.Now()
+	status = tuningStatus(current, &diskv1.TuningStatus{Scheduler: "bfq", LastApplied: &lastApplied})
+	assert.Equal(t, "none", status.Scheduler)
+	assert.Equal(t, &lastApplied, status.LastApplied)
+
+	// the time the profile was applied is not compared
+	assert.True(t, equalTuningStatus(status, tuningStatus(current, nil)))
+	current.ReadAheadKB = 4096
+	assert.False(t, equalTuningStatus(status, tuningStatus(current, nil)))
+}
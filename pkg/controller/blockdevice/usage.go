@@ -0,0 +1,118 @@
+package blockdevice
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/provisioner"
+	"github.com/harvester/node-disk-manager/pkg/utils"
+)
+
+const (
+	// usageRefreshInterval is how often the usage of a mounted provisioned disk is read
+	usageRefreshInterval = 5 * time.Minute
+	// usageBucketPercent is the granularity of the reported usage, a usage read is only written
+	// to the block device when its used space or inodes moved to another bucket, or the size or
+	// the pressure changed, so a disk being written to isn't updated at every refresh
+	usageBucketPercent = 5
+
+	filesystemPressureReasonSpace   = "LowSpace"
+	filesystemPressureReasonInodes  = "LowInodes"
+	filesystemPressureReasonNone    = "Normal"
+	filesystemPressureReasonUnknown = "NotMounted"
+)
+
+var getFilesystemUsage = utils.GetFilesystemUsage
+
+// updateFilesystemUsage reports the usage of the filesystem mounted at the mount point of a
+// provisioned disk in the new status, and sets the FilesystemPressure condition of the device
+func (c *Controller) updateFilesystemUsage(device *diskv1.BlockDevice, newFS *diskv1.FilesystemStatus) {
+	oldUsage := device.Status.DeviceStatus.FileSystem.Usage
+	if !device.Spec.Provision || !isLonghornV1Device(device) || newFS.MountPoint == "" ||
+		newFS.MountPoint != provisioner.ExtraDiskMountPoint(device) {
+		newFS.Usage = nil
+		c.usageReadAt.Delete(device.Name)
+		if diskv1.FilesystemPressure.GetStatus(device) != "" {
+			diskv1.FilesystemPressure.SetStatus(device, string(corev1.ConditionUnknown))
+			diskv1.FilesystemPressure.Reason(device, filesystemPressureReasonUnknown)
+			diskv1.FilesystemPressure.Message(device, "")
+		}
+		return
+	}
+	if readAt, found := c.usageReadAt.Load(device.Name); found && oldUsage != nil {
+		if wait := usageRefreshInterval - time.Since(readAt.(time.Time)); wait > 0 {
+			newFS.Usage = oldUsage
+			c.Blockdevices.EnqueueAfter(c.Namespace, device.Name, wait)
+			return
+		}
+	}
+
+	usage, err := getFilesystemUsage(newFS.MountPoint)
+	if err != nil {
+		logrus.Warnf("Failed to get the filesystem usage of device %s: %v", device.Name, err)
+		newFS.Usage = oldUsage
+		return
+	}
+	c.usageReadAt.Store(device.Name, time.Now())
+	newFS.Usage = &diskv1.FilesystemUsage{
+		TotalBytes:     usage.TotalBytes,
+		UsedBytes:      usage.UsedBytes,
+		AvailableBytes: usage.AvailableBytes,
+		TotalInodes:    usage.TotalInodes,
+		UsedInodes:     usage.UsedInodes,
+		LastUpdated:    metav1.Now(),
+	}
+	pressure := diskv1.FilesystemPressure.GetStatus(device)
+	c.setFilesystemPressure(device, newFS.Usage)
+	if oldUsage != nil && sameUsageBucket(oldUsage, newFS.Usage) && diskv1.FilesystemPressure.GetStatus(device) == pressure {
+		newFS.Usage = oldUsage
+	}
+	c.Blockdevices.EnqueueAfter(c.Namespace, device.Name, usageRefreshInterval)
+}
+
+// sameUsageBucket checks whether two usages of a filesystem have the same size, and
+// their used space and inodes fall in the same bucket
+func sameUsageBucket(a, b *diskv1.FilesystemUsage) bool {
+	return a.TotalBytes == b.TotalBytes && a.TotalInodes == b.TotalInodes &&
+		usagePercent(a.UsedBytes, a.UsedBytes+a.AvailableBytes)/usageBucketPercent ==
+			usagePercent(b.UsedBytes, b.UsedBytes+b.AvailableBytes)/usageBucketPercent &&
+		usagePercent(a.UsedInodes, a.TotalInodes)/usageBucketPercent == usagePercent(b.UsedInodes, b.TotalInodes)/usageBucketPercent
+}
+
+// setFilesystemPressure sets the FilesystemPressure condition when the used space or
+// inodes cross their threshold, and records an event when the pressure starts
+func (c *Controller) setFilesystemPressure(device *diskv1.BlockDevice, usage *diskv1.FilesystemUsage) {
+	// the used space is relative to the space usable by unprivileged users, like df
+	spacePercent := usagePercent(usage.UsedBytes, usage.UsedBytes+usage.AvailableBytes)
+	inodesPercent := usagePercent(usage.UsedInodes, usage.TotalInodes)
+
+	reason, message := filesystemPressureReasonNone, ""
+	switch {
+	case c.fsPressureThreshold > 0 && spacePercent >= c.fsPressureThreshold:
+		reason = filesystemPressureReasonSpace
+		message = fmt.Sprintf("%d%% of the space is used, the threshold is %d%%", spacePercent, c.fsPressureThreshold)
+	case c.inodePressureThreshold > 0 && inodesPercent >= c.inodePressureThreshold:
+		reason = filesystemPressureReasonInodes
+		message = fmt.Sprintf("%d%% of the inodes are used, the threshold is %d%%", inodesPercent, c.inodePressureThreshold)
+	}
+
+	underPressure := reason != filesystemPressureReasonNone
+	if underPressure && !diskv1.FilesystemPressure.IsTrue(device) {
+		c.recorder.Event(device, corev1.EventTypeWarning, utils.EventReasonFilesystemPressure, message)
+	}
+	diskv1.FilesystemPressure.SetStatusBool(device, underPressure)
+	diskv1.FilesystemPressure.Reason(device, reason)
+	diskv1.FilesystemPressure.Message(device, message)
+}
+
+func usagePercent(used, total int64) uint {
+	if total <= 0 {
+		return 0
+	}
+	return uint(used * 100 / total)
+}
@@ -0,0 +1,185 @@
+package blockdevice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/utils"
+)
+
+func TestUsagePercent(t *testing.T) {
+	assert.Equal(t, uint(0), usagePercent(0, 0))
+	assert.Equal(t, uint(0), usagePercent(10, 0))
+	assert.Equal(t, uint(50), usagePercent(50, 100))
+	assert.Equal(t, uint(89), usagePercent(899, 1000))
+	assert.Equal(t, uint(100), usagePercent(100, 100))
+}
+
+func TestSetFilesystemPressure(t *testing.T) {
+	tests := []struct {
+		name           string
+		usage          diskv1.FilesystemUsage
+		fsThreshold    uint
+		inodeThreshold uint
+		underPressure  bool
+		pressure       bool
+		reason         string
+		message        string
+		event          bool
+	}{
+		{
+			name:           "enough space and inodes",
+			usage:          diskv1.FilesystemUsage{UsedBytes: 50, AvailableBytes: 50, UsedInodes: 10, TotalInodes: 100},
+			fsThreshold:    90,
+			inodeThreshold: 90,
+			reason:         filesystemPressureReasonNone,
+		},
+		{
+			name:           "low space",
+			usage:          diskv1.FilesystemUsage{UsedBytes: 90, AvailableBytes: 10, UsedInodes: 10, TotalInodes: 100},
+			fsThreshold:    90,
+			inodeThreshold: 90,
+			pressure:       true,
+			reason:         filesystemPressureReasonSpace,
+			message:        "90% of the space is used, the threshold is 90%",
+			event:          true,
+		},
+		{
+			name:           "low inodes",
+			usage:          diskv1.FilesystemUsage{UsedBytes: 50, AvailableBytes: 50, UsedInodes: 95, TotalInodes: 100},
+			fsThreshold:    90,
+			inodeThreshold: 90,
+			pressure:       true,
+			reason:         filesystemPressureReasonInodes,
+			message:        "95% of the inodes are used, the threshold is 90%",
+			event:          true,
+		},
+		{
+			name:           "the pressure start is only recorded once",
+			usage:          diskv1.FilesystemUsage{UsedBytes: 95, AvailableBytes: 5},
+			fsThreshold:    90,
+			inodeThreshold: 90,
+			underPressure:  true,
+			pressure:       true,
+			reason:         filesystemPressureReasonSpace,
+			message:        "95% of the space is used, the threshold is 90%",
+		},
+		{
+			name:          "the pressure is over",
+			usage:         diskv1.FilesystemUsage{UsedBytes: 50, AvailableBytes: 50},
+			fsThreshold:   90,
+			underPressure: true,
+			reason:        filesystemPressureReasonNone,
+		},
+		{
+			name:   "disabled thresholds",
+			usage:  diskv1.FilesystemUsage{UsedBytes: 100, UsedInodes: 100, TotalInodes: 100},
+			reason: filesystemPressureReasonNone,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			c := &Controller{recorder: recorder, fsPressureThreshold: tt.fsThreshold, inodePressureThreshold: tt.inodeThreshold}
+			device := newDiskBlockDevice("bd", "/dev/sdb")
+			if tt.underPressure {
+				diskv1.FilesystemPressure.SetStatusBool(device, true)
+			}
+
+			c.setFilesystemPressure(device, &tt.usage)
+			assert.Equal(t, tt.pressure, diskv1.FilesystemPressure.IsTrue(device))
+			assert.Equal(t, tt.reason, diskv1.FilesystemPressure.GetReason(device))
+			assert.Equal(t, tt.message, diskv1.FilesystemPressure.GetMessage(device))
+			if tt.event {
+				require.Len(t, recorder.Events, 1)
+				assert.Equal(t, "Warning FilesystemPressure "+tt.message, <-recorder.Events)
+			} else {
+				assert.Empty(t, recorder.Events)
+			}
+		})
+	}
+}
+
+func TestUpdateFilesystemUsage(t *testing.T) {
+	mountPoint := "/var/lib/harvester/extra-disks/bd"
+	newDevice := func() *diskv1.BlockDevice {
+		device := newDiskBlockDevice("bd", "/dev/sdb")
+		device.Spec.Provision = true
+		device.Status.DeviceStatus.FileSystem.MountPoint = mountPoint
+		return device
+	}
+
+	t.Run("the usage of a disk which left its mount point is dropped", func(t *testing.T) {
+		bds := newFakeBlockDevices()
+		c := &Controller{Namespace: testNamespace, Blockdevices: bds, recorder: record.NewFakeRecorder(10)}
+		device := newDevice()
+		device.Status.DeviceStatus.FileSystem.Usage = &diskv1.FilesystemUsage{UsedBytes: 95, AvailableBytes: 5, LastUpdated: metav1.Now()}
+		diskv1.FilesystemPressure.SetStatusBool(device, true)
+
+		newFS := &diskv1.FilesystemStatus{}
+		c.updateFilesystemUsage(device, newFS)
+		assert.Nil(t, newFS.Usage)
+		assert.Equal(t, "Unknown", diskv1.FilesystemPressure.GetStatus(device))
+		assert.Equal(t, filesystemPressureReasonUnknown, diskv1.FilesystemPressure.GetReason(device))
+		assert.Empty(t, bds.enqueuedNames())
+	})
+
+	t.Run("no condition is added to a disk never mounted", func(t *testing.T) {
+		bds := newFakeBlockDevices()
+		c := &Controller{Namespace: testNamespace, Blockdevices: bds, recorder: record.NewFakeRecorder(10)}
+		device := newDevice()
+
+		c.updateFilesystemUsage(device, &diskv1.FilesystemStatus{})
+		assert.Empty(t, diskv1.FilesystemPressure.GetStatus(device))
+	})
+
+	t.Run("a recent usage is kept until the next refresh", func(t *testing.T) {
+		bds := newFakeBlockDevices()
+		c := &Controller{Namespace: testNamespace, Blockdevices: bds, recorder: record.NewFakeRecorder(10)}
+		device := newDevice()
+		usage := &diskv1.FilesystemUsage{UsedBytes: 50, AvailableBytes: 50, LastUpdated: metav1.NewTime(time.Now().Add(-time.Hour))}
+		device.Status.DeviceStatus.FileSystem.Usage = usage
+		c.usageReadAt.Store("bd", time.Now().Add(-time.Minute))
+
+		newFS := &diskv1.FilesystemStatus{MountPoint: mountPoint}
+		c.updateFilesystemUsage(device, newFS)
+		assert.Same(t, usage, newFS.Usage)
+		assert.Equal(t, []string{"bd"}, bds.enqueuedNames())
+	})
+
+	t.Run("the usage is only reported when it changes significantly", func(t *testing.T) {
+		defer func(get func(string) (*utils.FilesystemUsage, error)) { getFilesystemUsage = get }(getFilesystemUsage)
+		read := &utils.FilesystemUsage{TotalBytes: 100, UsedBytes: 52, AvailableBytes: 48, TotalInodes: 100, UsedInodes: 10}
+		getFilesystemUsage = func(string) (*utils.FilesystemUsage, error) {
+			copied := *read
+			return &copied, nil
+		}
+		bds := newFakeBlockDevices()
+		c := &Controller{Namespace: testNamespace, Blockdevices: bds, recorder: record.NewFakeRecorder(10), fsPressureThreshold: 90}
+		device := newDevice()
+		usage := &diskv1.FilesystemUsage{TotalBytes: 100, UsedBytes: 50, AvailableBytes: 50, TotalInodes: 100, UsedInodes: 10, LastUpdated: metav1.NewTime(time.Now().Add(-time.Hour))}
+		device.Status.DeviceStatus.FileSystem.Usage = usage
+		diskv1.FilesystemPressure.SetStatusBool(device, false)
+
+		newFS := &diskv1.FilesystemStatus{MountPoint: mountPoint}
+		c.updateFilesystemUsage(device, newFS)
+		assert.Same(t, usage, newFS.Usage, "the used space stays in the same bucket")
+
+		c.usageReadAt.Delete("bd")
+		read.UsedBytes, read.AvailableBytes = 56, 44
+		c.updateFilesystemUsage(device, newFS)
+		assert.Equal(t, int64(56), newFS.Usage.UsedBytes, "the used space moved to another bucket")
+
+		device.Status.DeviceStatus.FileSystem.Usage = newFS.Usage
+		c.usageReadAt.Delete("bd")
+		read.TotalBytes, read.UsedBytes, read.AvailableBytes = 90, 50, 40
+		c.updateFilesystemUsage(device, newFS)
+		assert.Equal(t, int64(90), newFS.Usage.TotalBytes, "the filesystem shrank")
+	})
+}
@@ -0,0 +1,221 @@
+package inventory
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	ctldiskv1 "github.com/harvester/node-disk-manager/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/option"
+	"github.com/harvester/node-disk-manager/pkg/provisioner"
+)
+
+const (
+	inventoryHandlerName            = "harvester-ndm-inventory-handler"
+	inventoryBlockDeviceHandlerName = "harvester-ndm-inventory-blockdevice-handler"
+)
+
+type Controller struct {
+	namespace string
+	nodeName  string
+	// dryRun flags the inventory of an agent running in dry-run mode
+	dryRun bool
+
+	Inventories      ctldiskv1.NodeDiskInventoryController
+	InventoryCache   ctldiskv1.NodeDiskInventoryCache
+	BlockDeviceCache ctldiskv1.BlockDeviceCache
+	Scans            ScanResultSource
+}
+
+// ScanResultSource provides the filter verdicts of the disks found on this node and
+// the records of the last scans, i.e. the block device scanner
+type ScanResultSource interface {
+	FilterVerdicts() []diskv1.DiskFilterVerdict
+	ScanRecords() (*diskv1.ScanRecord, *diskv1.ScanRecord)
+	AddScanListener(listener func())
+}
+
+// Register register the node disk inventory controller
+func Register(ctx context.Context, inventories ctldiskv1.NodeDiskInventoryController, bds ctldiskv1.BlockDeviceController, scans ScanResultSource, opt *option.Option) error {
+	c := &Controller{
+		namespace:        opt.Namespace,
+		nodeName:         opt.NodeName,
+		dryRun:           opt.DryRun,
+		Inventories:      inventories,
+		InventoryCache:   inventories.Cache(),
+		BlockDeviceCache: bds.Cache(),
+		Scans:            scans,
+	}
+
+	inventories.OnChange(ctx, inventoryHandlerName, c.OnInventoryChange)
+	bds.OnChange(ctx, inventoryBlockDeviceHandlerName, c.OnBlockDeviceChange)
+	// the verdicts and scan records are only kept in memory, publish them after every scan
+	scans.AddScanListener(func() {
+		inventories.Enqueue(c.nodeName)
+	})
+
+	// make sure the inventory is created even if there is no block device on the node
+	inventories.Enqueue(c.nodeName)
+	return nil
+}
+
+// OnBlockDeviceChange enqueues the inventory of this node whenever one of its block devices changes.
+// A deleted device is passed in as nil, so we always recompute in that case.
+func (c *Controller) OnBlockDeviceChange(_ string, device *diskv1.BlockDevice) (*diskv1.BlockDevice, error) {
+	if device != nil && device.Spec.NodeName != c.nodeName {
+		return nil, nil
+	}
+	c.Inventories.Enqueue(c.nodeName)
+	return nil, nil
+}
+
+// OnInventoryChange recomputes the inventory of this node from the block devices in the cache
+func (c *Controller) OnInventoryChange(key string, inventory *diskv1.NodeDiskInventory) (*diskv1.NodeDiskInventory, error) {
+	if key != c.nodeName {
+		return nil, nil
+	}
+	if inventory != nil && inventory.DeletionTimestamp != nil {
+		return nil, nil
+	}
+
+	if inventory == nil {
+		var err error
+		inventory, err = c.InventoryCache.Get(c.nodeName)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		if errors.IsNotFound(err) {
+			logrus.Infof("Create node disk inventory for node %s", c.nodeName)
+			inventory, err = c.Inventories.Create(&diskv1.NodeDiskInventory{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: c.nodeName,
+				},
+				Spec: diskv1.NodeDiskInventorySpec{
+					NodeName: c.nodeName,
+				},
+			})
+			if err != nil && !errors.IsAlreadyExists(err) {
+				return nil, err
+			}
+			if errors.IsAlreadyExists(err) {
+				// the cache is not synced yet, retry later
+				c.Inventories.Enqueue(c.nodeName)
+				return nil, nil
+			}
+		}
+	}
+
+	bds, err := c.BlockDeviceCache.List(c.namespace, labels.SelectorFromSet(map[string]string{
+		v1.LabelHostname: c.nodeName,
+	}))
+	if err != nil {
+		return inventory, err
+	}
+
+	status := Summarize(bds)
+	status.DryRun = c.dryRun
+	status.FilterVerdicts = c.Scans.FilterVerdicts()
+	status.LastScan, status.LastFullScan = c.Scans.ScanRecords()
+	if summaryEqual(inventory.Status, status) {
+		return inventory, nil
+	}
+
+	inventoryCpy := inventory.DeepCopy()
+	status.LastUpdated = &metav1.Time{Time: metav1.Now().Time}
+	inventoryCpy.Status = status
+	logrus.Debugf("Update node disk inventory %s: %d devices, %d provisioned", inventory.Name, status.Devices, status.ProvisionedDevices)
+	return c.Inventories.UpdateStatus(inventoryCpy)
+}
+
+// Summarize builds the inventory status from the given block devices.
+// Inactive devices are counted but do not contribute to the capacity.
+func Summarize(bds []*diskv1.BlockDevice) diskv1.NodeDiskInventoryStatus {
+	status := diskv1.NodeDiskInventoryStatus{}
+	for _, bd := range bds {
+		status.Devices++
+		if bd.Status.DeviceStatus.FileSystem != nil && bd.Status.DeviceStatus.FileSystem.Corrupted {
+			status.CorruptedDevices++
+		}
+		if bd.Status.State != diskv1.BlockDeviceActive {
+			status.InactiveDevices++
+			continue
+		}
+		status.ActiveDevices++
+
+		provisioned := bd.Status.ProvisionPhase == diskv1.ProvisionPhaseProvisioned
+		if provisioned {
+			status.ProvisionedDevices++
+		}
+		size := bd.Status.DeviceStatus.Capacity.SizeBytes
+		addCapacity(&status.Capacity, size, provisioned)
+
+		if driveType := bd.Status.DeviceStatus.Details.DriveType; driveType != "" {
+			status.ByDriveType = addToGroup(status.ByDriveType, driveType, size, provisioned)
+		}
+		if provisionerType := provisionerTypeOf(bd); provisionerType != "" {
+			status.ByProvisioner = addToGroup(status.ByProvisioner, provisionerType, size, provisioned)
+		}
+		for _, tag := range tagsOf(bd) {
+			status.ByTag = addToGroup(status.ByTag, tag, size, provisioned)
+		}
+	}
+	return status
+}
+
+func addCapacity(summary *diskv1.CapacitySummary, size uint64, provisioned bool) {
+	summary.Devices++
+	summary.TotalBytes += size
+	if provisioned {
+		summary.ProvisionedBytes += size
+	} else {
+		summary.FreeBytes += size
+	}
+}
+
+func addToGroup(groups map[string]diskv1.CapacitySummary, key string, size uint64, provisioned bool) map[string]diskv1.CapacitySummary {
+	if groups == nil {
+		groups = map[string]diskv1.CapacitySummary{}
+	}
+	summary := groups[key]
+	addCapacity(&summary, size, provisioned)
+	groups[key] = summary
+	return groups
+}
+
+func provisionerTypeOf(bd *diskv1.BlockDevice) string {
+	if bd.Spec.Provisioner == nil {
+		return ""
+	}
+	if bd.Spec.Provisioner.LVM != nil {
+		return provisioner.TypeLVM
+	}
+	if bd.Spec.Provisioner.Longhorn != nil {
+		if bd.Spec.Provisioner.Longhorn.EngineVersion == "" {
+			return provisioner.TypeLonghornV1
+		}
+		return bd.Spec.Provisioner.Longhorn.EngineVersion
+	}
+	return ""
+}
+
+// tagsOf prefers the tags synced back from the Longhorn node and falls back
+// to the desired tags when the device is not yet added to the node.
+func tagsOf(bd *diskv1.BlockDevice) []string {
+	if len(bd.Status.Tags) > 0 {
+		return bd.Status.Tags
+	}
+	return bd.Spec.Tags
+}
+
+// summaryEqual compares two inventory statuses ignoring the update timestamp
+func summaryEqual(a, b diskv1.NodeDiskInventoryStatus) bool {
+	a.LastUpdated = nil
+	b.LastUpdated = nil
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,115 @@
+package inventory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/provisioner"
+)
+
+func newBlockDevice(name string, state diskv1.BlockDeviceState, phase diskv1.BlockDeviceProvisionPhase, size uint64, driveType string) *diskv1.BlockDevice {
+	return &diskv1.BlockDevice{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: diskv1.BlockDeviceStatus{
+			State:          state,
+			ProvisionPhase: phase,
+			DeviceStatus: diskv1.DeviceStatus{
+				Capacity:   diskv1.DeviceCapcity{SizeBytes: size},
+				Details:    diskv1.DeviceDetails{DriveType: driveType},
+				FileSystem: &diskv1.FilesystemStatus{},
+			},
+		},
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	provisioned := newBlockDevice("provisioned", diskv1.BlockDeviceActive, diskv1.ProvisionPhaseProvisioned, 100, "SSD")
+	provisioned.Spec.Provisioner = &diskv1.ProvisionerInfo{Longhorn: &diskv1.LonghornProvisionerInfo{}}
+	provisioned.Spec.Tags = []string{"fast"}
+	provisioned.Status.Tags = []string{"fast", "synced"}
+
+	lvm := newBlockDevice("lvm", diskv1.BlockDeviceActive, diskv1.ProvisionPhaseProvisioned, 300, "HDD")
+	lvm.Spec.Provisioner = &diskv1.ProvisionerInfo{LVM: &diskv1.LVMProvisionerInfo{VgName: "vg01"}}
+	lvm.Spec.Tags = []string{"slow"}
+
+	free := newBlockDevice("free", diskv1.BlockDeviceActive, diskv1.ProvisionPhaseUnprovisioned, 50, "SSD")
+
+	inactive := newBlockDevice("inactive", diskv1.BlockDeviceInactive, diskv1.ProvisionPhaseProvisioned, 1000, "HDD")
+	inactive.Status.DeviceStatus.FileSystem.Corrupted = true
+
+	status := Summarize([]*diskv1.BlockDevice{provisioned, lvm, free, inactive})
+
+	assert.Equal(t, 4, status.Devices)
+	assert.Equal(t, 3, status.ActiveDevices)
+	assert.Equal(t, 1, status.InactiveDevices)
+	assert.Equal(t, 1, status.CorruptedDevices)
+	assert.Equal(t, 2, status.ProvisionedDevices)
+	// inactive devices don't contribute to the capacity
+	assert.Equal(t, diskv1.CapacitySummary{Devices: 3, TotalBytes: 450, ProvisionedBytes: 400, FreeBytes: 50}, status.Capacity)
+	assert.Equal(t, map[string]diskv1.CapacitySummary{
+		"SSD": {Devices: 2, TotalBytes: 150, ProvisionedBytes: 100, FreeBytes: 50},
+		"HDD": {Devices: 1, TotalBytes: 300, ProvisionedBytes: 300},
+	}, status.ByDriveType)
+	assert.Equal(t, map[string]diskv1.CapacitySummary{
+		provisioner.TypeLonghornV1: {Devices: 1, TotalBytes: 100, ProvisionedBytes: 100},
+		provisioner.TypeLVM:        {Devices: 1, TotalBytes: 300, ProvisionedBytes: 300},
+	}, status.ByProvisioner)
+	// the tags synced from the Longhorn node take precedence over the desired tags
+	assert.Equal(t, map[string]diskv1.CapacitySummary{
+		"fast":   {Devices: 1, TotalBytes: 100, ProvisionedBytes: 100},
+		"synced": {Devices: 1, TotalBytes: 100, ProvisionedBytes: 100},
+		"slow":   {Devices: 1, TotalBytes: 300, ProvisionedBytes: 300},
+	}, status.ByTag)
+}
+
+func TestSummarizeEmpty(t *testing.T) {
+	status := Summarize(nil)
+	assert.Equal(t, diskv1.NodeDiskInventoryStatus{}, status)
+}
+
+func TestProvisionerTypeOf(t *testing.T) {
+	tests := []struct {
+		name        string
+		provisioner *diskv1.ProvisionerInfo
+		expected    string
+	}{
+		{
+			name:     "not provisioned",
+			expected: "",
+		},
+		{
+			name:        "longhorn without engine version",
+			provisioner: &diskv1.ProvisionerInfo{Longhorn: &diskv1.LonghornProvisionerInfo{}},
+			expected:    provisioner.TypeLonghornV1,
+		},
+		{
+			name:        "longhorn v2",
+			provisioner: &diskv1.ProvisionerInfo{Longhorn: &diskv1.LonghornProvisionerInfo{EngineVersion: provisioner.TypeLonghornV2}},
+			expected:    provisioner.TypeLonghornV2,
+		},
+		{
+			name:        "lvm",
+			provisioner: &diskv1.ProvisionerInfo{LVM: &diskv1.LVMProvisionerInfo{VgName: "vg01"}},
+			expected:    provisioner.TypeLVM,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bd := &diskv1.BlockDevice{Spec: diskv1.BlockDeviceSpec{Provisioner: tt.provisioner}}
+			assert.Equal(t, tt.expected, provisionerTypeOf(bd))
+		})
+	}
+}
+
+func TestSummaryEqual(t *testing.T) {
+	a := diskv1.NodeDiskInventoryStatus{Devices: 1, LastUpdated: &metav1.Time{}}
+	b := diskv1.NodeDiskInventoryStatus{Devices: 1}
+	assert.True(t, summaryEqual(a, b), "the update time is ignored")
+
+	b.Devices = 2
+	assert.False(t, summaryEqual(a, b))
+	assert.NotNil(t, a.LastUpdated, "the compared statuses are left untouched")
+}
@@ -3,25 +3,37 @@ package node
 import (
 	"context"
 	"reflect"
+	"slices"
 	"strings"
 
 	longhornv1 "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/filter"
 	ctldiskv1 "github.com/harvester/node-disk-manager/pkg/generated/controllers/harvesterhci.io/v1beta1"
 	ctllonghornv1 "github.com/harvester/node-disk-manager/pkg/generated/controllers/longhorn.io/v1beta2"
 	"github.com/harvester/node-disk-manager/pkg/option"
+	"github.com/harvester/node-disk-manager/pkg/utils"
 )
 
 type Controller struct {
 	namespace string
 	nodeName  string
+	// dryRun reports the changes as events instead of applying them
+	dryRun   bool
+	recorder record.EventRecorder
 
 	BlockDevices     ctldiskv1.BlockDeviceController
 	BlockDeviceCache ctldiskv1.BlockDeviceCache
+	Inventories      ctldiskv1.NodeDiskInventoryController
+	NDMConfigs       ctldiskv1.NodeDiskManagerConfigController
 	Nodes            ctllonghornv1.NodeController
 }
 
@@ -30,14 +42,18 @@ const (
 )
 
 // Register register the longhorn node CRD controller
-func Register(ctx context.Context, nodes ctllonghornv1.NodeController, bds ctldiskv1.BlockDeviceController, opt *option.Option) error {
+func Register(ctx context.Context, nodes ctllonghornv1.NodeController, bds ctldiskv1.BlockDeviceController, inventories ctldiskv1.NodeDiskInventoryController, ndmConfigs ctldiskv1.NodeDiskManagerConfigController, recorder record.EventRecorder, opt *option.Option) error {
 
 	c := &Controller{
 		namespace:        opt.Namespace,
 		nodeName:         opt.NodeName,
+		dryRun:           opt.DryRun,
+		recorder:         recorder,
 		Nodes:            nodes,
 		BlockDevices:     bds,
 		BlockDeviceCache: bds.Cache(),
+		Inventories:      inventories,
+		NDMConfigs:       ndmConfigs,
 	}
 
 	nodes.OnChange(ctx, blockDeviceNodeHandlerName, c.OnNodeChange)
@@ -83,6 +99,10 @@ func (c *Controller) OnNodeChange(_ string, node *longhornv1.Node) (*longhornv1.
 		bdCpy := bd.DeepCopy()
 		bdCpy.Status.Tags = disk.Tags
 		if !reflect.DeepEqual(bd, bdCpy) {
+			if c.dryRun {
+				c.recorder.Eventf(bd, v1.EventTypeNormal, utils.EventReasonDryRun, "would update tags from %v to %v", bd.Status.Tags, disk.Tags)
+				continue
+			}
 			logrus.Debugf("Update block device %s tags (Status) from %v to %v", bd.Name, bd.Status.Tags, disk.Tags)
 			if _, err := c.BlockDevices.Update(bdCpy); err != nil {
 				logrus.Warnf("Update block device %s failed: %v", bd.Name, err)
@@ -93,7 +113,8 @@ func (c *Controller) OnNodeChange(_ string, node *longhornv1.Node) (*longhornv1.
 	return nil, nil
 }
 
-// OnNodeDelete watch the node CR on remove and delete node related block devices
+// OnNodeDelete watch the node CR on remove and delete node related block devices, disk inventory
+// and the status of the node in the NodeDiskManagerConfig
 func (c *Controller) OnNodeDelete(_ string, node *longhornv1.Node) (*longhornv1.Node, error) {
 	if node == nil || node.DeletionTimestamp == nil {
 		return nil, nil
@@ -106,10 +127,49 @@ func (c *Controller) OnNodeDelete(_ string, node *longhornv1.Node) (*longhornv1.
 		return node, err
 	}
 
+	if c.dryRun {
+		for _, bd := range bds {
+			c.recorder.Eventf(bd, v1.EventTypeNormal, utils.EventReasonDryRun, "would delete block device %s as node %s is removed", bd.Name, node.Name)
+		}
+		return nil, nil
+	}
+
 	for _, bd := range bds {
 		if err := c.BlockDevices.Delete(c.namespace, bd.Name, &metav1.DeleteOptions{}); err != nil {
 			return node, err
 		}
 	}
+
+	if err := c.Inventories.Delete(node.Name, &metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return node, err
+	}
+
+	if err := c.pruneNDMConfigStatus(node.Name); err != nil {
+		return node, err
+	}
 	return nil, nil
 }
+
+// pruneNDMConfigStatus drops the status of the removed node from the NodeDiskManagerConfig
+func (c *Controller) pruneNDMConfigStatus(nodeName string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		ndmConfig, err := c.NDMConfigs.Get(filter.DefaultNDMConfigName, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		index := slices.IndexFunc(ndmConfig.Status.Nodes, func(status diskv1.NodeConfigStatus) bool {
+			return status.NodeName == nodeName
+		})
+		if index < 0 {
+			return nil
+		}
+
+		logrus.Infof("Node %s is removed, dropping it from the status of NodeDiskManagerConfig", nodeName)
+		ndmConfigCpy := ndmConfig.DeepCopy()
+		ndmConfigCpy.Status.Nodes = slices.Delete(ndmConfigCpy.Status.Nodes, index, index+1)
+		_, err = c.NDMConfigs.UpdateStatus(ndmConfigCpy)
+		return err
+	})
+}
@@ -11,16 +11,21 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 
 	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
 	ctldiskv1 "github.com/harvester/node-disk-manager/pkg/generated/controllers/harvesterhci.io/v1beta1"
 	"github.com/harvester/node-disk-manager/pkg/lvm"
 	"github.com/harvester/node-disk-manager/pkg/option"
+	"github.com/harvester/node-disk-manager/pkg/utils"
 )
 
 type Controller struct {
 	namespace string
 	nodeName  string
+	// dryRun reports the LVM changes as events instead of applying them
+	dryRun   bool
+	recorder record.EventRecorder
 
 	LVMVolumeGroupCache ctldiskv1.LVMVolumeGroupCache
 	LVMVolumeGroups     ctldiskv1.LVMVolumeGroupController
@@ -30,11 +35,13 @@ const (
 	lvmVGHandlerName = "harvester-lvm-volumegroup-handler"
 )
 
-func Register(ctx context.Context, lvmVGs ctldiskv1.LVMVolumeGroupController, opt *option.Option) error {
+func Register(ctx context.Context, lvmVGs ctldiskv1.LVMVolumeGroupController, recorder record.EventRecorder, opt *option.Option) error {
 
 	c := &Controller{
 		namespace:           opt.Namespace,
 		nodeName:            opt.NodeName,
+		dryRun:              opt.DryRun,
+		recorder:            recorder,
 		LVMVolumeGroups:     lvmVGs,
 		LVMVolumeGroupCache: lvmVGs.Cache(),
 	}
@@ -57,6 +64,11 @@ func (c *Controller) OnLVMVGChange(_ string, lvmVG *diskv1.LVMVolumeGroup) (*dis
 
 	logrus.Infof("Prepare to handle LVMVolumeGroup %s changed: %v", lvmVG.Name, lvmVG)
 
+	if c.dryRun {
+		c.reportPlan(lvmVG)
+		return nil, nil
+	}
+
 	switch lvmVG.Spec.DesiredState {
 	case diskv1.VGStateEnabled:
 		logrus.Infof("Prepare to enable LVMVolumeGroup %s", lvmVG.Name)
@@ -73,6 +85,10 @@ func (c *Controller) OnLVMVGRemove(_ string, lvmVG *diskv1.LVMVolumeGroup) (*dis
 	if lvmVG == nil || lvmVG.DeletionTimestamp != nil {
 		// make sure the volume group is already deleted
 		logrus.Infof("Ensure the lvm volume group is already deleted if the lvmVG CR is nil")
+		if c.dryRun {
+			logrus.Infof("Dry-run: skip removing the volume group of the deleted LVMVolumeGroup")
+			return nil, nil
+		}
 		return c.removeLVMVolumeGroup(lvmVG)
 	}
 
@@ -155,6 +171,25 @@ func (c *Controller) removeLVMVolumeGroup(lvmVG *diskv1.LVMVolumeGroup) (*diskv1
 	return nil, nil
 }
 
+// reportPlan records the LVM changes the controller would apply to the volume group as events
+func (c *Controller) reportPlan(lvmVG *diskv1.LVMVolumeGroup) {
+	switch lvmVG.Spec.DesiredState {
+	case diskv1.VGStateEnabled:
+		currentDevs := map[string]string{}
+		if lvmVG.Status != nil && lvmVG.Status.Devices != nil {
+			currentDevs = lvmVG.Status.Devices
+		}
+		for bdName, dev := range getToAddDevs(lvmVG.Spec.Devices, currentDevs) {
+			c.recorder.Eventf(lvmVG, corev1.EventTypeNormal, utils.EventReasonDryRun, "would add %s (%s) to volume group %s", dev, bdName, lvmVG.Spec.VgName)
+		}
+		for bdName, dev := range getToRemoveDevs(lvmVG.Spec.Devices, currentDevs) {
+			c.recorder.Eventf(lvmVG, corev1.EventTypeNormal, utils.EventReasonDryRun, "would remove %s (%s) from volume group %s", dev, bdName, lvmVG.Spec.VgName)
+		}
+	case diskv1.VGStateDisabled:
+		c.recorder.Eventf(lvmVG, corev1.EventTypeNormal, utils.EventReasonDryRun, "would deactivate volume group %s", lvmVG.Spec.VgName)
+	}
+}
+
 func checkPVAndVG(pvsResult map[string]string, targetPV, targetVG string) (pvFound, vgFound bool, pvCount int) {
 	pvCount = 0
 	for pv, vg := range pvsResult {
@@ -3,6 +3,8 @@ package data
 import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+
+	"github.com/harvester/node-disk-manager/pkg/generated/clientset/versioned"
 )
 
 // Init adds built-in resources
@@ -12,7 +14,12 @@ func Init(restConfig *rest.Config) error {
 		return err
 	}
 
-	if err := addNDMConfigMap(clientset); err != nil {
+	diskClientset, err := versioned.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+
+	if err := addNDMConfig(clientset, diskClientset); err != nil {
 		return err
 	}
 
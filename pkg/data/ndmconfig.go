@@ -0,0 +1,103 @@
+package data
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/filter"
+	"github.com/harvester/node-disk-manager/pkg/generated/clientset/versioned"
+)
+
+const (
+	ConfigMapName      = "harvester-node-disk-manager"
+	ConfigMapNamespace = "harvester-system"
+)
+
+// addNDMConfig creates the default NodeDiskManagerConfig. The rules are migrated
+// from the harvester-node-disk-manager ConfigMap if it exists, otherwise the
+// default rules are used.
+func addNDMConfig(clientset *kubernetes.Clientset, diskClientset *versioned.Clientset) error {
+	ndmConfigs := diskClientset.HarvesterhciV1beta1().NodeDiskManagerConfigs()
+	_, err := ndmConfigs.Get(context.TODO(), filter.DefaultNDMConfigName, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	ndmConfig := &diskv1.NodeDiskManagerConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: filter.DefaultNDMConfigName,
+		},
+		Spec: defaultNDMConfigSpec(),
+	}
+
+	configMap, err := clientset.CoreV1().ConfigMaps(ConfigMapNamespace).Get(context.TODO(), ConfigMapName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		logrus.Infof("Migrating ConfigMap %s/%s to NodeDiskManagerConfig %s", ConfigMapNamespace, ConfigMapName, filter.DefaultNDMConfigName)
+		ndmConfig.Spec = migrateConfigMap(configMap.Data)
+	}
+
+	_, err = ndmConfigs.Create(context.TODO(), ndmConfig, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	return nil
+}
+
+func defaultNDMConfigSpec() diskv1.NodeDiskManagerConfigSpec {
+	return diskv1.NodeDiskManagerConfigSpec{
+		Filters: []diskv1.DiskFilterRule{
+			{
+				NodeTarget:    diskv1.NodeTarget{Hostname: "*"},
+				ExcludeLabels: []string{"COS_*", "HARV_*"},
+			},
+		},
+	}
+}
+
+// migrateConfigMap converts the filters.yaml, autoprovision.yaml and udevrules.json of the ConfigMap.
+// A key which can't be parsed is skipped, just like the agent ignored it before.
+func migrateConfigMap(data map[string]string) diskv1.NodeDiskManagerConfigSpec {
+	spec := diskv1.NodeDiskManagerConfigSpec{}
+	loader := filter.NewConfigMapLoader(nil, "", "", "", "", "")
+
+	if filtersYAML := data[filter.FiltersConfigKey]; filtersYAML != "" {
+		configs, err := loader.ParseFilterConfigs(filtersYAML)
+		if err != nil {
+			logrus.Warnf("Skip migrating %s: %v", filter.FiltersConfigKey, err)
+		} else {
+			spec.Filters = filter.FilterRulesFromConfigs(configs)
+		}
+	}
+
+	if autoProvYAML := data[filter.AutoProvisionConfigKey]; autoProvYAML != "" {
+		configs, err := loader.ParseAutoProvisionConfigs(autoProvYAML)
+		if err != nil {
+			logrus.Warnf("Skip migrating %s: %v", filter.AutoProvisionConfigKey, err)
+		} else {
+			spec.AutoProvision = filter.AutoProvisionRulesFromConfigs(configs)
+		}
+	}
+
+	if udevRulesJSON := data[filter.UdevRulesConfigKey]; udevRulesJSON != "" {
+		rules, err := filter.ParseUdevRules(udevRulesJSON)
+		if err != nil {
+			logrus.Warnf("Skip migrating %s: %v", filter.UdevRulesConfigKey, err)
+		} else {
+			spec.UdevRules = filter.UdevRulesFromDefinitions(rules)
+		}
+	}
+
+	return spec
+}
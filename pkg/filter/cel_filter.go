@@ -0,0 +1,225 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/ext"
+	"github.com/sirupsen/logrus"
+
+	"github.com/harvester/node-disk-manager/pkg/block"
+	"github.com/harvester/node-disk-manager/pkg/utils"
+)
+
+const (
+	celFilterName = "expression filter"
+	celDiskVar    = "disk"
+	// celDiskType is the CEL type name of celDisk
+	celDiskType = "filter.celDisk"
+)
+
+var (
+	errNotBool = errors.New("expression does not evaluate to bool")
+
+	celEnvOnce sync.Once
+	celEnv     *cel.Env
+	celEnvErr  error
+)
+
+// celDiskFilter matches the disks for which the CEL expression evaluates to true.
+// The expression sees the disk as the variable `disk`, see celDisk for the fields.
+type celDiskFilter struct {
+	expression string
+	program    cel.Program
+}
+
+// RegisterCELFilter compiles the CEL expression into a filter, e.g.
+//
+//	disk.driveType == "SSD" && disk.sizeBytes > 500*GiB && !disk.isRemovable
+//
+// The expression must evaluate to a boolean, and may only refer to the fields of celDisk.
+// Both are checked when compiling, since the disk variable is typed.
+func RegisterCELFilter(expression string) (*Filter, error) {
+	env, err := getCELEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	ast, iss := env.Compile(expression)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf("failed to compile expression %q: %w", expression, iss.Err())
+	}
+	if outputType := ast.OutputType(); !outputType.IsExactType(cel.BoolType) {
+		return nil, fmt.Errorf("%w: expression %q evaluates to %s", errNotBool, expression, outputType)
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build expression %q: %w", expression, err)
+	}
+
+	return &Filter{
+		Name:       celFilterName,
+		DiskFilter: &celDiskFilter{expression: expression, program: program},
+	}, nil
+}
+
+// RegisterCELFilters compiles every non-empty expression into a filter
+func RegisterCELFilters(expressions ...string) ([]*Filter, error) {
+	filters := []*Filter{}
+	for _, expression := range expressions {
+		if strings.TrimSpace(expression) == "" {
+			continue
+		}
+		f, err := RegisterCELFilter(expression)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+func getCELEnv() (*cel.Env, error) {
+	celEnvOnce.Do(func() {
+		celEnv, celEnvErr = cel.NewEnv(
+			ext.NativeTypes(reflect.TypeOf(&celDisk{}), ext.ParseStructTag("cel")),
+			cel.Variable(celDiskVar, cel.ObjectType(celDiskType)),
+			cel.Constant("KiB", cel.IntType, types.Int(1<<10)),
+			cel.Constant("MiB", cel.IntType, types.Int(1<<20)),
+			cel.Constant("GiB", cel.IntType, types.Int(1<<30)),
+			cel.Constant("TiB", cel.IntType, types.Int(1<<40)),
+			cel.CrossTypeNumericComparisons(true),
+		)
+	})
+	return celEnv, celEnvErr
+}
+
+// Match returns true if the expression evaluates to true for the disk.
+// An evaluation error is logged and treated as no match.
+func (f *celDiskFilter) Match(disk *block.Disk) bool {
+	matched, err := f.eval(disk)
+	if err != nil {
+		logrus.Errorf("failed to evaluate expression %q on disk %s: %v", f.expression, disk.Name, err)
+		return false
+	}
+	return matched
+}
+
+func (f *celDiskFilter) eval(disk *block.Disk) (bool, error) {
+	out, _, err := f.program.Eval(map[string]any{celDiskVar: celDiskView(disk)})
+	if err != nil {
+		return false, err
+	}
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("%w: %v", errNotBool, out.Value())
+	}
+	return matched, nil
+}
+
+// Details returns the expression
+func (f *celDiskFilter) Details() string {
+	return "expression: " + f.expression
+}
+
+// celDisk is the disk as seen by the CEL expressions. The sizes are signed
+// integers, so they can be compared with the size constants.
+type celDisk struct {
+	Name                   string          `cel:"name"`
+	DevPath                string          `cel:"devPath"`
+	Label                  string          `cel:"label"`
+	SizeBytes              int64           `cel:"sizeBytes"`
+	PhysicalBlockSizeBytes int64           `cel:"physicalBlockSizeBytes"`
+	LogicalBlockSizeBytes  int64           `cel:"logicalBlockSizeBytes"`
+	DriveType              string          `cel:"driveType"`
+	IsRemovable            bool            `cel:"isRemovable"`
+	StorageController      string          `cel:"storageController"`
+	UUID                   string          `cel:"uuid"`
+	PtUUID                 string          `cel:"ptUUID"`
+	BusPath                string          `cel:"busPath"`
+	Vendor                 string          `cel:"vendor"`
+	Model                  string          `cel:"model"`
+	SerialNumber           string          `cel:"serialNumber"`
+	WWN                    string          `cel:"wwn"`
+	FirmwareRevision       string          `cel:"firmwareRevision"`
+	RotationRateRPM        int64           `cel:"rotationRateRPM"`
+	LinkSpeed              string          `cel:"linkSpeed"`
+	DiscardGranularity     int64           `cel:"discardGranularity"`
+	DiscardMaxBytes        int64           `cel:"discardMaxBytes"`
+	ZonedModel             string          `cel:"zonedModel"`
+	WriteCacheEnabled      bool            `cel:"writeCacheEnabled"`
+	FileSystem             celFileSystem   `cel:"fileSystem"`
+	Partitions             []*celPartition `cel:"partitions"`
+}
+
+type celPartition struct {
+	Name       string        `cel:"name"`
+	Label      string        `cel:"label"`
+	SizeBytes  int64         `cel:"sizeBytes"`
+	UUID       string        `cel:"uuid"`
+	FsUUID     string        `cel:"fsUUID"`
+	PartType   string        `cel:"partType"`
+	FileSystem celFileSystem `cel:"fileSystem"`
+}
+
+type celFileSystem struct {
+	Type       string `cel:"type"`
+	MountPoint string `cel:"mountPoint"`
+	IsReadOnly bool   `cel:"isReadOnly"`
+}
+
+// celDiskView exposes the disk to the CEL expressions
+func celDiskView(disk *block.Disk) *celDisk {
+	partitions := make([]*celPartition, 0, len(disk.Partitions))
+	for _, part := range disk.Partitions {
+		partitions = append(partitions, &celPartition{
+			Name:       part.Name,
+			Label:      part.Label,
+			SizeBytes:  int64(part.SizeBytes),
+			UUID:       part.UUID,
+			FsUUID:     part.FsUUID,
+			PartType:   part.PartType,
+			FileSystem: celFileSystemView(part.FileSystemInfo),
+		})
+	}
+	return &celDisk{
+		Name:                   disk.Name,
+		DevPath:                utils.GetFullDevPath(disk.Name),
+		Label:                  disk.Label,
+		SizeBytes:              int64(disk.SizeBytes),
+		PhysicalBlockSizeBytes: int64(disk.PhysicalBlockSizeBytes),
+		LogicalBlockSizeBytes:  int64(disk.LogicalBlockSizeBytes),
+		DriveType:              disk.DriveType.String(),
+		IsRemovable:            disk.IsRemovable,
+		StorageController:      disk.StorageController.String(),
+		UUID:                   disk.UUID,
+		PtUUID:                 disk.PtUUID,
+		BusPath:                disk.BusPath,
+		Vendor:                 disk.Vendor,
+		Model:                  disk.Model,
+		SerialNumber:           disk.SerialNumber,
+		WWN:                    disk.WWN,
+		FirmwareRevision:       disk.FirmwareRevision,
+		RotationRateRPM:        int64(disk.RotationRateRPM),
+		LinkSpeed:              disk.LinkSpeed,
+		DiscardGranularity:     int64(disk.DiscardGranularity),
+		DiscardMaxBytes:        int64(disk.DiscardMaxBytes),
+		ZonedModel:             disk.ZonedModel,
+		WriteCacheEnabled:      disk.WriteCacheEnabled,
+		FileSystem:             celFileSystemView(disk.FileSystemInfo),
+		Partitions:             partitions,
+	}
+}
+
+func celFileSystemView(fs block.FileSystemInfo) celFileSystem {
+	return celFileSystem{
+		Type:       fs.Type,
+		MountPoint: fs.MountPoint,
+		IsReadOnly: fs.IsReadOnly,
+	}
+}
@@ -2,16 +2,22 @@ package filter
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/pilebones/go-udev/netlink"
 	k8scorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	ctldiskv1 "github.com/harvester/node-disk-manager/pkg/generated/controllers/harvesterhci.io/v1beta1"
 	"github.com/harvester/node-disk-manager/pkg/provisioner"
 )
 
@@ -20,21 +26,49 @@ const (
 	DefaultConfigMapNamespace = "harvester-system"
 	FiltersConfigKey          = "filters.yaml"
 	AutoProvisionConfigKey    = "autoprovision.yaml"
+	// UdevRulesConfigKey holds the rules matching the udev events handled by NDM
+	UdevRulesConfigKey = "udevrules.json"
+	// DefaultNDMConfigName is the name of the NodeDiskManagerConfig read by NDM
+	DefaultNDMConfigName = "default"
+	// MinTrimInterval is the shortest interval between two trims of a disk
+	MinTrimInterval = time.Hour
 )
 
 // FilterConfig represents a single filter configuration block
 type FilterConfig struct {
-	Hostname       string   `yaml:"hostname"`
+	Hostname string `yaml:"hostname"`
+	// NodeSelector restricts the block to the nodes with matching labels.
+	// The hostname can be omitted if the node selector is set.
+	NodeSelector *NodeSelector `yaml:"nodeSelector,omitempty"`
+
 	ExcludeDevices []string `yaml:"excludeDevices,omitempty"`
 	ExcludeLabels  []string `yaml:"excludeLabels,omitempty"`
 	ExcludeVendors []string `yaml:"excludeVendors,omitempty"`
 	ExcludePaths   []string `yaml:"excludePaths,omitempty"`
+
+	// The include fields form an allow-list. When any is set, only the disks
+	// matching one of them are managed. The exclude fields and the built-in
+	// defaults still take precedence over the allow-list.
+	IncludeWWNs      []string   `yaml:"includeWWNs,omitempty"`
+	IncludeSerials   []string   `yaml:"includeSerials,omitempty"`
+	IncludeModels    []string   `yaml:"includeModels,omitempty"`
+	IncludeBusPaths  []string   `yaml:"includeBusPaths,omitempty"`
+	IncludeSizeRange *SizeRange `yaml:"includeSizeRange,omitempty"`
+
+	// CEL expressions evaluated against the disk, a disk is excluded if any evaluates to true
+	ExcludeExpressions []string `yaml:"excludeExpressions,omitempty"`
 }
 
 // AutoProvisionConfig represents a single auto-provision configuration block
 type AutoProvisionConfig struct {
-	Hostname string   `yaml:"hostname"`
-	Devices  []string `yaml:"devices,omitempty"`
+	Hostname string `yaml:"hostname"`
+	// NodeSelector restricts the block to the nodes with matching labels.
+	// The hostname can be omitted if the node selector is set.
+	NodeSelector *NodeSelector `yaml:"nodeSelector,omitempty"`
+
+	Devices []string `yaml:"devices,omitempty"`
+	// CEL expressions evaluated against the disk, a disk is auto-provisioned if any evaluates to true
+	Expressions []string `yaml:"expressions,omitempty"`
 
 	// We haven't support auto provision for longhorn v2 and LVM yet.
 	// But, we keep the provisioner and params fields for future extension.
@@ -44,10 +78,18 @@ type AutoProvisionConfig struct {
 
 // ConfigMapLoader loads filter configurations from ConfigMap
 type ConfigMapLoader struct {
-	configMapClient k8scorev1.ConfigMapClient
-	namespace       string
-	configMapName   string
-	nodeName        string
+	configMapCache k8scorev1.ConfigMapCache
+	namespace      string
+	configMapName  string
+	nodeName       string
+	// nodeCache reads the labels of the node for the node selectors,
+	// nodeLabels is used instead when the cache is not set
+	nodeCache  k8scorev1.NodeCache
+	nodeLabels map[string]string
+	// ndmConfigCache reads the NodeDiskManagerConfig, which takes precedence over the ConfigMap
+	ndmConfigCache ctldiskv1.NodeDiskManagerConfigCache
+	// snapshot holds the configurations read once for all the loads of a reload, see Snapshot
+	snapshot *configSnapshot
 	// Fallback values from environment variables (used when ConfigMap is not available or empty)
 	envVendorFilter        string
 	envPathFilter          string
@@ -56,9 +98,9 @@ type ConfigMapLoader struct {
 }
 
 // NewConfigMapLoader creates a new ConfigMapLoader
-func NewConfigMapLoader(configMapClient k8scorev1.ConfigMapClient, nodeName string, envVendorFilter, envPathFilter, envLabelFilter, envAutoProvisionFilter string) *ConfigMapLoader {
+func NewConfigMapLoader(configMapCache k8scorev1.ConfigMapCache, nodeName string, envVendorFilter, envPathFilter, envLabelFilter, envAutoProvisionFilter string) *ConfigMapLoader {
 	return &ConfigMapLoader{
-		configMapClient:        configMapClient,
+		configMapCache:         configMapCache,
 		namespace:              DefaultConfigMapNamespace,
 		configMapName:          DefaultConfigMapName,
 		nodeName:               nodeName,
@@ -69,6 +111,45 @@ func NewConfigMapLoader(configMapClient k8scorev1.ConfigMapClient, nodeName stri
 	}
 }
 
+// SetNodeCache sets the cache used to read the labels of the current node,
+// which are needed to evaluate the node selectors
+func (c *ConfigMapLoader) SetNodeCache(nodeCache k8scorev1.NodeCache) {
+	c.nodeCache = nodeCache
+}
+
+// SetNDMConfigCache sets the cache used to read the NodeDiskManagerConfig.
+// The ConfigMap is only used when the NodeDiskManagerConfig doesn't exist.
+func (c *ConfigMapLoader) SetNDMConfigCache(ndmConfigCache ctldiskv1.NodeDiskManagerConfigCache) {
+	c.ndmConfigCache = ndmConfigCache
+}
+
+// configSnapshot holds the node labels and the parsed configurations of a reload
+type configSnapshot struct {
+	nodeErr         error
+	filterConfigs   []FilterConfig
+	filterErr       error
+	autoProvConfigs []AutoProvisionConfig
+	autoProvErr     error
+}
+
+// Snapshot returns a copy of the loader which reads the labels of the node and parses
+// the filter and auto-provision configurations once, for all its Load* calls. A reload
+// loads them several times, and the configurations must not change in between.
+func (c *ConfigMapLoader) Snapshot() *ConfigMapLoader {
+	loader := *c
+	snapshot := &configSnapshot{}
+	if nodeLoader, err := c.forNode(); err != nil {
+		snapshot.nodeErr = err
+	} else {
+		loader.nodeCache = nil
+		loader.nodeLabels = nodeLoader.nodeLabels
+	}
+	snapshot.filterConfigs, snapshot.filterErr = c.getFilterConfigs()
+	snapshot.autoProvConfigs, snapshot.autoProvErr = c.getAutoProvisionConfigs()
+	loader.snapshot = snapshot
+	return &loader
+}
+
 // GetEnvFilters returns the fallback environment variable values for filters
 // deviceFilter is always empty as it's a new feature only available via ConfigMap
 func (c *ConfigMapLoader) GetEnvFilters() (deviceFilter, vendorFilter, pathFilter, labelFilter string) {
@@ -80,37 +161,31 @@ func (c *ConfigMapLoader) GetEnvAutoProvisionFilter() string {
 	return c.envAutoProvisionFilter
 }
 
-// LoadFiltersFromConfigMap loads filter configurations from ConfigMap
-// Returns the merged filter strings for the current node, or empty strings if ConfigMap doesn't exist
+// LoadFiltersFromConfigMap loads filter configurations from the NodeDiskManagerConfig, or the ConfigMap
+// Returns the merged filter strings for the current node, or empty strings if neither exists
 func (c *ConfigMapLoader) LoadFiltersFromConfigMap(ctx context.Context) (deviceFilter, vendorFilter, pathFilter, labelFilter string, err error) {
-	logrus.Debug("Attempting to load filter configuration from ConfigMap")
+	logrus.Debug("Attempting to load filter configuration")
 
-	configMap, err := c.configMapClient.Get(c.namespace, c.configMapName, metav1.GetOptions{})
+	loader, err := c.forNode()
+	if err != nil {
+		return "", "", "", "", err
+	}
+	filterConfigs, err := c.getFilterConfigs()
 	if err != nil {
 		if errors.IsNotFound(err) {
-			logrus.Infof("ConfigMap %s/%s not found, will fallback to environment variables", c.namespace, c.configMapName)
 			return "", "", "", "", nil
 		}
-		return "", "", "", "", fmt.Errorf("failed to get ConfigMap: %w", err)
-	}
-
-	// Parse filters.yaml
-	filtersYAML, exists := configMap.Data[FiltersConfigKey]
-	if !exists {
-		logrus.Warnf("ConfigMap %s/%s exists but missing %s key, will fallback to environment variables", c.namespace, c.configMapName, FiltersConfigKey)
-		return "", "", "", "", nil
-	}
-
-	filterConfigs, err := c.ParseFilterConfigs(filtersYAML)
-	if err != nil {
-		logrus.Errorf("Failed to parse %s from ConfigMap: %v, will fallback to environment variables", FiltersConfigKey, err)
-		return "", "", "", "", nil
+		if errParse := (*parseError)(nil); stderrors.As(err, &errParse) {
+			logrus.Errorf("Failed to parse %s from ConfigMap: %v, will fallback to environment variables", FiltersConfigKey, err)
+			return "", "", "", "", nil
+		}
+		return "", "", "", "", err
 	}
 
 	// Merge configurations: global ("*") + node-specific
-	deviceFilter, vendorFilter, pathFilter, labelFilter = c.mergeFilterConfigs(filterConfigs)
+	deviceFilter, vendorFilter, pathFilter, labelFilter = loader.mergeFilterConfigs(filterConfigs)
 
-	logrus.Infof("Successfully loaded filter configuration from ConfigMap for node %s", c.nodeName)
+	logrus.Infof("Successfully loaded filter configuration for node %s", c.nodeName)
 	logrus.Infof("  - ExcludeDevices: %s", deviceFilter)
 	logrus.Infof("  - ExcludeVendors: %s", vendorFilter)
 	logrus.Infof("  - ExcludePaths: %s", pathFilter)
@@ -119,42 +194,283 @@ func (c *ConfigMapLoader) LoadFiltersFromConfigMap(ctx context.Context) (deviceF
 	return deviceFilter, vendorFilter, pathFilter, labelFilter, nil
 }
 
-// LoadAutoProvisionFromConfigMap loads auto-provision configurations from ConfigMap
-// Returns the merged device paths string for the current node, or empty string if ConfigMap doesn't exist
-func (c *ConfigMapLoader) LoadAutoProvisionFromConfigMap(ctx context.Context) (devPaths string, err error) {
-	logrus.Info("Attempting to load auto-provision configuration from ConfigMap")
+// LoadIncludeFilterFromConfigMap loads the include rules from the NodeDiskManagerConfig, or the ConfigMap
+// Returns the include filter for the current node, or nil if no include rule is configured
+func (c *ConfigMapLoader) LoadIncludeFilterFromConfigMap(ctx context.Context) (*Filter, error) {
+	loader, err := c.forNode()
+	if err != nil {
+		return nil, err
+	}
+	filterConfigs, err := c.getFilterConfigs()
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	rules := loader.mergeIncludeConfigs(filterConfigs)
+	if !rules.IsEmpty() {
+		logrus.Infof("Successfully loaded include rules for node %s", c.nodeName)
+		logrus.Infof("  - IncludeWWNs: %s", strings.Join(rules.WWNs, ","))
+		logrus.Infof("  - IncludeSerials: %s", strings.Join(rules.Serials, ","))
+		logrus.Infof("  - IncludeModels: %s", strings.Join(rules.Models, ","))
+		logrus.Infof("  - IncludeBusPaths: %s", strings.Join(rules.BusPaths, ","))
+		logrus.Infof("  - IncludeSizeRanges: %v", rules.SizeRanges)
+	}
+	return RegisterIncludeFilter(rules)
+}
 
-	configMap, err := c.configMapClient.Get(c.namespace, DefaultConfigMapName, metav1.GetOptions{})
+// LoadExcludeExpressionsFromConfigMap loads the exclude expressions from the NodeDiskManagerConfig, or the ConfigMap
+// Returns the expression filters for the current node, or nil if none is configured
+func (c *ConfigMapLoader) LoadExcludeExpressionsFromConfigMap(ctx context.Context) ([]*Filter, error) {
+	loader, err := c.forNode()
+	if err != nil {
+		return nil, err
+	}
+	filterConfigs, err := c.getFilterConfigs()
 	if err != nil {
 		if errors.IsNotFound(err) {
-			logrus.Infof("ConfigMap %s/%s not found, will fallback to environment variables", c.namespace, DefaultConfigMapName)
-			return "", nil
+			return nil, nil
 		}
-		return "", fmt.Errorf("failed to get ConfigMap: %w", err)
+		return nil, err
 	}
 
-	// Parse autoprovision.yaml
-	autoProvYAML, exists := configMap.Data[AutoProvisionConfigKey]
-	if !exists {
-		logrus.Infof("ConfigMap %s/%s exists but missing %s key, auto-provision not configured", c.namespace, DefaultConfigMapName, AutoProvisionConfigKey)
-		return "", nil
+	expressions := loader.mergeExcludeExpressions(filterConfigs)
+	if len(expressions) > 0 {
+		logrus.Infof("Successfully loaded exclude expressions for node %s", c.nodeName)
+		logrus.Infof("  - ExcludeExpressions: %s", strings.Join(expressions, "; "))
 	}
+	return RegisterCELFilters(expressions...)
+}
 
-	autoProvConfigs, err := c.ParseAutoProvisionConfigs(autoProvYAML)
+// LoadAutoProvisionExpressionsFromConfigMap loads the auto-provision expressions from the NodeDiskManagerConfig, or the ConfigMap
+// Returns the expression filters for the current node, or nil if none is configured
+func (c *ConfigMapLoader) LoadAutoProvisionExpressionsFromConfigMap(ctx context.Context) ([]*Filter, error) {
+	loader, err := c.forNode()
 	if err != nil {
-		logrus.Errorf("Failed to parse %s from ConfigMap: %v, will fallback to environment variables", AutoProvisionConfigKey, err)
-		return "", nil
+		return nil, err
+	}
+	autoProvConfigs, err := c.getAutoProvisionConfigs()
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	expressions := loader.mergeAutoProvisionExpressions(autoProvConfigs)
+	if len(expressions) > 0 {
+		logrus.Infof("Successfully loaded auto-provision expressions for node %s", c.nodeName)
+		logrus.Infof("  - Expressions: %s", strings.Join(expressions, "; "))
+	}
+	return RegisterCELFilters(expressions...)
+}
+
+// LoadAutoProvisionFromConfigMap loads auto-provision configurations from the NodeDiskManagerConfig, or the ConfigMap
+// Returns the merged device paths string for the current node, or empty string if neither exists
+func (c *ConfigMapLoader) LoadAutoProvisionFromConfigMap(ctx context.Context) (devPaths string, err error) {
+	logrus.Info("Attempting to load auto-provision configuration")
+
+	loader, err := c.forNode()
+	if err != nil {
+		return "", err
+	}
+	autoProvConfigs, err := c.getAutoProvisionConfigs()
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		if errParse := (*parseError)(nil); stderrors.As(err, &errParse) {
+			logrus.Errorf("Failed to parse %s from ConfigMap: %v, will fallback to environment variables", AutoProvisionConfigKey, err)
+			return "", nil
+		}
+		return "", err
 	}
 
 	// Merge configurations: global ("*") + node-specific
-	devPaths = c.mergeAutoProvisionConfigs(autoProvConfigs)
+	devPaths = loader.mergeAutoProvisionConfigs(autoProvConfigs)
 
-	logrus.Infof("Successfully loaded auto-provision configuration from ConfigMap for node %s", c.nodeName)
+	logrus.Infof("Successfully loaded auto-provision configuration for node %s", c.nodeName)
 	logrus.Infof("  - Devices: %s", devPaths)
 
 	return devPaths, nil
 }
 
+// LoadUdevRules returns the rules matching the udev events handled on the node, from the
+// NodeDiskManagerConfig, or the ConfigMap. Nil is returned if neither sets rules for the node.
+func (c *ConfigMapLoader) LoadUdevRules() (*netlink.RuleDefinitions, error) {
+	ndmConfig, err := c.getNDMConfig()
+	if err != nil {
+		return nil, err
+	}
+	if ndmConfig != nil {
+		loader, err := c.forNode()
+		if err != nil {
+			return nil, err
+		}
+		return UdevRulesFromMatchers(loader.mergeUdevConfigs(UdevConfigsFromRules(ndmConfig.Spec.UdevRules)))
+	}
+
+	udevRulesJSON, err := c.getConfigMapValue(UdevRulesConfigKey)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	rules, err := ParseUdevRules(udevRulesJSON)
+	if err != nil {
+		return nil, &parseError{err: err}
+	}
+	return rules, nil
+}
+
+// LoadMountRoot returns the directory the LonghornV1 disks of the node are mounted under,
+// or "" for the default one. The mount root is only configured in the NodeDiskManagerConfig.
+func (c *ConfigMapLoader) LoadMountRoot() (string, error) {
+	ndmConfig, err := c.getNDMConfig()
+	if err != nil || ndmConfig == nil {
+		return "", err
+	}
+	loader, err := c.forNode()
+	if err != nil {
+		return "", err
+	}
+	return loader.mergeMountRootConfigs(MountRootConfigsFromRules(ndmConfig.Spec.MountRoots)), nil
+}
+
+// LoadTrimSchedules returns the trim schedules of the node, in order. The
+// trim schedules are only configured in the NodeDiskManagerConfig.
+func (c *ConfigMapLoader) LoadTrimSchedules() ([]diskv1.TrimSchedule, error) {
+	ndmConfig, err := c.getNDMConfig()
+	if err != nil || ndmConfig == nil {
+		return nil, err
+	}
+	loader, err := c.forNode()
+	if err != nil {
+		return nil, err
+	}
+	return loader.mergeTrimConfigs(TrimConfigsFromRules(ndmConfig.Spec.Trim)), nil
+}
+
+// LoadTuningProfiles returns the tuning profiles of the node, in order. The
+// tuning profiles are only configured in the NodeDiskManagerConfig.
+func (c *ConfigMapLoader) LoadTuningProfiles() ([]diskv1.TuningProfile, error) {
+	ndmConfig, err := c.getNDMConfig()
+	if err != nil || ndmConfig == nil {
+		return nil, err
+	}
+	loader, err := c.forNode()
+	if err != nil {
+		return nil, err
+	}
+	return loader.mergeTuningConfigs(TuningConfigsFromRules(ndmConfig.Spec.Tuning)), nil
+}
+
+// getFilterConfigs returns the filter configurations of the NodeDiskManagerConfig.
+// The ConfigMap is used if the NodeDiskManagerConfig doesn't exist, and a NotFound
+// error is returned if neither the NodeDiskManagerConfig nor the ConfigMap key exists.
+func (c *ConfigMapLoader) getFilterConfigs() ([]FilterConfig, error) {
+	if c.snapshot != nil {
+		return c.snapshot.filterConfigs, c.snapshot.filterErr
+	}
+	ndmConfig, err := c.getNDMConfig()
+	if err != nil {
+		return nil, err
+	}
+	if ndmConfig != nil {
+		return FilterConfigsFromRules(ndmConfig.Spec.Filters), nil
+	}
+
+	filtersYAML, err := c.getConfigMapValue(FiltersConfigKey)
+	if err != nil {
+		return nil, err
+	}
+	filterConfigs, err := c.ParseFilterConfigs(filtersYAML)
+	if err != nil {
+		return nil, &parseError{err: err}
+	}
+	return filterConfigs, nil
+}
+
+// getAutoProvisionConfigs returns the auto-provision configurations of the NodeDiskManagerConfig.
+// The ConfigMap is used if the NodeDiskManagerConfig doesn't exist, and a NotFound
+// error is returned if neither the NodeDiskManagerConfig nor the ConfigMap key exists.
+func (c *ConfigMapLoader) getAutoProvisionConfigs() ([]AutoProvisionConfig, error) {
+	if c.snapshot != nil {
+		return c.snapshot.autoProvConfigs, c.snapshot.autoProvErr
+	}
+	ndmConfig, err := c.getNDMConfig()
+	if err != nil {
+		return nil, err
+	}
+	if ndmConfig != nil {
+		return AutoProvisionConfigsFromRules(ndmConfig.Spec.AutoProvision), nil
+	}
+
+	autoProvYAML, err := c.getConfigMapValue(AutoProvisionConfigKey)
+	if err != nil {
+		return nil, err
+	}
+	autoProvConfigs, err := c.ParseAutoProvisionConfigs(autoProvYAML)
+	if err != nil {
+		return nil, &parseError{err: err}
+	}
+	return autoProvConfigs, nil
+}
+
+// getNDMConfig returns the default NodeDiskManagerConfig,
+// or nil if either the config or the cache doesn't exist
+func (c *ConfigMapLoader) getNDMConfig() (*diskv1.NodeDiskManagerConfig, error) {
+	if c.ndmConfigCache == nil {
+		return nil, nil
+	}
+	ndmConfig, err := c.ndmConfigCache.Get(DefaultNDMConfigName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get NodeDiskManagerConfig: %w", err)
+	}
+	return ndmConfig, nil
+}
+
+// getConfigMapValue returns the value of the key in the ConfigMap, or
+// a NotFound error if either the ConfigMap or the key doesn't exist
+func (c *ConfigMapLoader) getConfigMapValue(key string) (string, error) {
+	if c.configMapCache == nil {
+		return "", errors.NewNotFound(corev1.Resource("configmaps"), c.configMapName)
+	}
+	configMap, err := c.configMapCache.Get(c.namespace, c.configMapName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logrus.Infof("ConfigMap %s/%s not found, will fallback to environment variables", c.namespace, c.configMapName)
+			return "", err
+		}
+		return "", fmt.Errorf("failed to get ConfigMap: %w", err)
+	}
+	value, exists := configMap.Data[key]
+	if !exists {
+		logrus.Infof("ConfigMap %s/%s exists but missing %s key, will fallback to environment variables", c.namespace, c.configMapName, key)
+		return "", errors.NewNotFound(corev1.Resource("configmaps"), c.configMapName)
+	}
+	return value, nil
+}
+
+// parseError is returned when the ConfigMap content can't be parsed
+type parseError struct {
+	err error
+}
+
+func (e *parseError) Error() string {
+	return e.err.Error()
+}
+
+func (e *parseError) Unwrap() error {
+	return e.err
+}
+
 // ParseFilterConfigs parses the filters YAML content
 func (c *ConfigMapLoader) ParseFilterConfigs(yamlContent string) ([]FilterConfig, error) {
 	var configs []FilterConfig
@@ -189,7 +505,7 @@ func (c *ConfigMapLoader) mergeFilterConfigs(configs []FilterConfig) (deviceFilt
 	var devices, vendors, paths, labels []string
 
 	for _, config := range configs {
-		if c.matchesHostname(config.Hostname, c.nodeName) {
+		if c.matchesNode(config.Hostname, config.NodeSelector) {
 			devices = append(devices, config.ExcludeDevices...)
 			vendors = append(vendors, config.ExcludeVendors...)
 			paths = append(paths, config.ExcludePaths...)
@@ -200,12 +516,44 @@ func (c *ConfigMapLoader) mergeFilterConfigs(configs []FilterConfig) (deviceFilt
 	return strings.Join(devices, ","), strings.Join(vendors, ","), strings.Join(paths, ","), strings.Join(labels, ",")
 }
 
+// mergeExcludeExpressions merges global and node-specific exclude expressions
+func (c *ConfigMapLoader) mergeExcludeExpressions(configs []FilterConfig) []string {
+	var expressions []string
+
+	for _, config := range configs {
+		if c.matchesNode(config.Hostname, config.NodeSelector) {
+			expressions = append(expressions, config.ExcludeExpressions...)
+		}
+	}
+
+	return expressions
+}
+
+// mergeIncludeConfigs merges global and node-specific include rules
+func (c *ConfigMapLoader) mergeIncludeConfigs(configs []FilterConfig) IncludeRules {
+	rules := IncludeRules{}
+
+	for _, config := range configs {
+		if c.matchesNode(config.Hostname, config.NodeSelector) {
+			rules.WWNs = append(rules.WWNs, config.IncludeWWNs...)
+			rules.Serials = append(rules.Serials, config.IncludeSerials...)
+			rules.Models = append(rules.Models, config.IncludeModels...)
+			rules.BusPaths = append(rules.BusPaths, config.IncludeBusPaths...)
+			if config.IncludeSizeRange != nil {
+				rules.SizeRanges = append(rules.SizeRanges, *config.IncludeSizeRange)
+			}
+		}
+	}
+
+	return rules
+}
+
 // mergeAutoProvisionConfigs merges global and node-specific auto-provision configurations
 func (c *ConfigMapLoader) mergeAutoProvisionConfigs(configs []AutoProvisionConfig) string {
 	var devices []string
 
 	for _, config := range configs {
-		if c.matchesHostname(config.Hostname, c.nodeName) {
+		if c.matchesNode(config.Hostname, config.NodeSelector) {
 			devices = append(devices, config.Devices...)
 		}
 	}
@@ -213,6 +561,127 @@ func (c *ConfigMapLoader) mergeAutoProvisionConfigs(configs []AutoProvisionConfi
 	return strings.Join(devices, ",")
 }
 
+// mergeAutoProvisionExpressions merges global and node-specific auto-provision expressions
+func (c *ConfigMapLoader) mergeAutoProvisionExpressions(configs []AutoProvisionConfig) []string {
+	var expressions []string
+
+	for _, config := range configs {
+		if c.matchesNode(config.Hostname, config.NodeSelector) {
+			expressions = append(expressions, config.Expressions...)
+		}
+	}
+
+	return expressions
+}
+
+// mergeUdevConfigs returns the matchers of the valid blocks matching the node
+func (c *ConfigMapLoader) mergeUdevConfigs(configs []UdevConfig) []diskv1.UdevMatcher {
+	var matchers []diskv1.UdevMatcher
+
+	for _, config := range configs {
+		if config.Validate() != nil {
+			continue
+		}
+		if c.matchesNode(config.Hostname, config.NodeSelector) {
+			matchers = append(matchers, config.UdevMatcher)
+		}
+	}
+
+	return matchers
+}
+
+// mergeMountRootConfigs returns the path of the last valid block matching the node
+func (c *ConfigMapLoader) mergeMountRootConfigs(configs []MountRootConfig) string {
+	var mountRoot string
+
+	for _, config := range configs {
+		if config.Validate() != nil {
+			continue
+		}
+		if c.matchesNode(config.Hostname, config.NodeSelector) {
+			mountRoot = config.Path
+		}
+	}
+
+	return mountRoot
+}
+
+// mergeTrimConfigs returns the schedules of the valid blocks matching the node
+func (c *ConfigMapLoader) mergeTrimConfigs(configs []TrimConfig) []diskv1.TrimSchedule {
+	var schedules []diskv1.TrimSchedule
+
+	for _, config := range configs {
+		if config.Validate() != nil {
+			continue
+		}
+		if c.matchesNode(config.Hostname, config.NodeSelector) {
+			schedules = append(schedules, config.TrimSchedule)
+		}
+	}
+
+	return schedules
+}
+
+// mergeTuningConfigs returns the profiles of the valid blocks matching the node
+func (c *ConfigMapLoader) mergeTuningConfigs(configs []TuningConfig) []diskv1.TuningProfile {
+	var profiles []diskv1.TuningProfile
+
+	for _, config := range configs {
+		if config.Validate() != nil {
+			continue
+		}
+		if c.matchesNode(config.Hostname, config.NodeSelector) {
+			profiles = append(profiles, config.TuningProfile)
+		}
+	}
+
+	return profiles
+}
+
+// matchesNode checks if the config block applies to the current node. Both the
+// hostname pattern and the node selector must match when they are set, and at
+// least one of them is required.
+func (c *ConfigMapLoader) matchesNode(pattern string, nodeSelector *NodeSelector) bool {
+	if pattern == "" && nodeSelector == nil {
+		logrus.Warnf("Empty hostname pattern is not allowed without node selector, ignoring this configuration")
+		return false
+	}
+
+	if pattern != "" && !c.matchesHostname(pattern, c.nodeName) {
+		return false
+	}
+
+	if nodeSelector == nil {
+		return true
+	}
+
+	selector, err := nodeSelector.AsSelector()
+	if err != nil {
+		logrus.Warnf("Invalid node selector %+v: %v, ignoring this configuration", *nodeSelector, err)
+		return false
+	}
+	return selector.Matches(labels.Set(c.nodeLabels))
+}
+
+// forNode returns a copy of the loader holding the current labels of the node,
+// so the labels are read once for all the node selectors of a load
+func (c *ConfigMapLoader) forNode() (*ConfigMapLoader, error) {
+	if c.snapshot != nil && c.snapshot.nodeErr != nil {
+		return nil, c.snapshot.nodeErr
+	}
+	if c.nodeCache == nil {
+		return c, nil
+	}
+	node, err := c.nodeCache.Get(c.nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node %s for evaluating node selectors: %v", c.nodeName, err)
+	}
+	loader := *c
+	loader.nodeCache = nil
+	loader.nodeLabels = node.Labels
+	return &loader, nil
+}
+
 // matchesHostname checks if the hostname pattern matches the node name
 // Supports wildcard "*" (global match), glob patterns, and exact match
 // Empty string hostname is treated as invalid and ignored
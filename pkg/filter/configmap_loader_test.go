@@ -3,6 +3,7 @@ package filter
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -10,6 +11,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	corefake "k8s.io/client-go/kubernetes/fake"
 
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	diskfake "github.com/harvester/node-disk-manager/pkg/generated/clientset/versioned/fake"
 	fakeclient "github.com/harvester/node-disk-manager/pkg/utils/fake"
 )
 
@@ -242,7 +245,7 @@ func TestLoadFiltersFromConfigMap(t *testing.T) {
 			require.NoError(t, err)
 
 			loader := NewConfigMapLoader(
-				fakeclient.FakeConfigMapClient(fakeClientset.CoreV1().ConfigMaps),
+				fakeclient.FakeConfigMapCache(fakeClientset.CoreV1().ConfigMaps),
 				tt.nodeName,
 				"", "", "", "", // env filters
 			)
@@ -481,7 +484,7 @@ func TestLoadAutoProvisionFromConfigMap(t *testing.T) {
 			require.NoError(t, err)
 
 			loader := NewConfigMapLoader(
-				fakeclient.FakeConfigMapClient(fakeClientset.CoreV1().ConfigMaps),
+				fakeclient.FakeConfigMapCache(fakeClientset.CoreV1().ConfigMaps),
 				tt.nodeName,
 				"", "", "", "", // env filters
 			)
@@ -517,7 +520,7 @@ func TestLoadFiltersFromConfigMap_WithMissingConfigMapKey(t *testing.T) {
 	require.NoError(t, err)
 
 	loader := NewConfigMapLoader(
-		fakeclient.FakeConfigMapClient(fakeClientset.CoreV1().ConfigMaps),
+		fakeclient.FakeConfigMapCache(fakeClientset.CoreV1().ConfigMaps),
 		"harvester1",
 		"", "", "", "",
 	)
@@ -549,7 +552,7 @@ func TestLoadAutoProvisionFromConfigMap_WithMissingConfigMapKey(t *testing.T) {
 	require.NoError(t, err)
 
 	loader := NewConfigMapLoader(
-		fakeclient.FakeConfigMapClient(fakeClientset.CoreV1().ConfigMaps),
+		fakeclient.FakeConfigMapCache(fakeClientset.CoreV1().ConfigMaps),
 		"harvester1",
 		"", "", "", "",
 	)
@@ -559,6 +562,44 @@ func TestLoadAutoProvisionFromConfigMap_WithMissingConfigMapKey(t *testing.T) {
 	assert.Equal(t, "", devices)
 }
 
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	fakeClientset := corefake.NewClientset()
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      DefaultConfigMapName,
+			Namespace: DefaultConfigMapNamespace,
+		},
+		Data: map[string]string{
+			FiltersConfigKey:       "- hostname: \"*\"\n  excludeDevices: [\"/dev/sdb\"]\n  excludeExpressions: [\"disk.isRemovable\"]\n",
+			AutoProvisionConfigKey: "- hostname: \"*\"\n  devices: [\"/dev/sdc\"]\n",
+		},
+	}
+	require.NoError(t, fakeClientset.Tracker().Add(cm))
+
+	loader := NewConfigMapLoader(
+		fakeclient.FakeConfigMapCache(fakeClientset.CoreV1().ConfigMaps),
+		"harvester1",
+		"", "", "", "",
+	).Snapshot()
+
+	// the loads of the snapshot aren't affected by a later change of the ConfigMap
+	cm = cm.DeepCopy()
+	cm.Data[FiltersConfigKey] = "- hostname: \"*\"\n  excludeDevices: [\"/dev/sdd\"]\n"
+	cm.Data[AutoProvisionConfigKey] = "invalid: ["
+	require.NoError(t, fakeClientset.Tracker().Update(corev1.SchemeGroupVersion.WithResource("configmaps"), cm, cm.Namespace))
+
+	device, _, _, _, err := loader.LoadFiltersFromConfigMap(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/sdb", device)
+	expressions, err := loader.LoadExcludeExpressionsFromConfigMap(ctx)
+	require.NoError(t, err)
+	assert.Len(t, expressions, 1)
+	devPaths, err := loader.LoadAutoProvisionFromConfigMap(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/sdc", devPaths)
+}
+
 func TestLoadFiltersFromConfigMap_WithInvalidYAML(t *testing.T) {
 	ctx := context.Background()
 	fakeClientset := corefake.NewClientset()
@@ -577,7 +618,7 @@ func TestLoadFiltersFromConfigMap_WithInvalidYAML(t *testing.T) {
 	require.NoError(t, err)
 
 	loader := NewConfigMapLoader(
-		fakeclient.FakeConfigMapClient(fakeClientset.CoreV1().ConfigMaps),
+		fakeclient.FakeConfigMapCache(fakeClientset.CoreV1().ConfigMaps),
 		"harvester1",
 		"", "", "", "",
 	)
@@ -609,7 +650,7 @@ func TestLoadAutoProvisionFromConfigMap_WithInvalidYAML(t *testing.T) {
 	require.NoError(t, err)
 
 	loader := NewConfigMapLoader(
-		fakeclient.FakeConfigMapClient(fakeClientset.CoreV1().ConfigMaps),
+		fakeclient.FakeConfigMapCache(fakeClientset.CoreV1().ConfigMaps),
 		"harvester1",
 		"", "", "", "",
 	)
@@ -627,7 +668,7 @@ func TestLoadFiltersFromConfigMap_ConfigMapNotFound(t *testing.T) {
 	// No ConfigMap added to the tracker
 
 	loader := NewConfigMapLoader(
-		fakeclient.FakeConfigMapClient(fakeClientset.CoreV1().ConfigMaps),
+		fakeclient.FakeConfigMapCache(fakeClientset.CoreV1().ConfigMaps),
 		"harvester1",
 		"", "", "", "",
 	)
@@ -648,7 +689,7 @@ func TestLoadAutoProvisionFromConfigMap_ConfigMapNotFound(t *testing.T) {
 	// No ConfigMap added to the tracker
 
 	loader := NewConfigMapLoader(
-		fakeclient.FakeConfigMapClient(fakeClientset.CoreV1().ConfigMaps),
+		fakeclient.FakeConfigMapCache(fakeClientset.CoreV1().ConfigMaps),
 		"harvester1",
 		"", "", "", "",
 	)
@@ -658,3 +699,286 @@ func TestLoadAutoProvisionFromConfigMap_ConfigMapNotFound(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "", devices)
 }
+
+func TestLoadFiltersFromNDMConfig(t *testing.T) {
+	ctx := context.Background()
+	fakeClientset := corefake.NewClientset()
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      DefaultConfigMapName,
+			Namespace: DefaultConfigMapNamespace,
+		},
+		Data: map[string]string{
+			FiltersConfigKey: `- hostname: "*"
+  excludeVendors: ["longhorn"]`,
+			AutoProvisionConfigKey: `- hostname: "*"
+  devices: ["/dev/sdc"]`,
+		},
+	}
+	require.NoError(t, fakeClientset.Tracker().Add(cm))
+
+	ndmConfig := &diskv1.NodeDiskManagerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultNDMConfigName},
+		Spec: diskv1.NodeDiskManagerConfigSpec{
+			Filters: []diskv1.DiskFilterRule{
+				{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, ExcludeLabels: []string{"COS_*"}},
+				{NodeTarget: diskv1.NodeTarget{NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"rack": "r1"}}}, ExcludeDevices: []string{"/dev/sdb"}},
+			},
+			AutoProvision: []diskv1.AutoProvisionRule{
+				{NodeTarget: diskv1.NodeTarget{Hostname: "harvester1"}, Devices: []string{"/dev/sdd"}},
+			},
+		},
+	}
+	ndmClientset := diskfake.NewSimpleClientset(ndmConfig)
+
+	loader := NewConfigMapLoader(
+		fakeclient.FakeConfigMapCache(fakeClientset.CoreV1().ConfigMaps),
+		"harvester1",
+		"", "", "", "",
+	)
+	loader.nodeLabels = map[string]string{"rack": "r1"}
+
+	// the ConfigMap is used until the NodeDiskManagerConfig cache is set
+	_, vendor, _, _, err := loader.LoadFiltersFromConfigMap(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "longhorn", vendor)
+
+	loader.SetNDMConfigCache(fakeclient.FakeNodeDiskManagerConfigCache(ndmClientset.HarvesterhciV1beta1().NodeDiskManagerConfigs))
+	device, vendor, path, label, err := loader.LoadFiltersFromConfigMap(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "/dev/sdb", device)
+	assert.Equal(t, "", vendor)
+	assert.Equal(t, "", path)
+	assert.Equal(t, "COS_*", label)
+
+	devices, err := loader.LoadAutoProvisionFromConfigMap(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "/dev/sdd", devices)
+}
+
+func TestNodeConfigStatus(t *testing.T) {
+	ndmConfig := &diskv1.NodeDiskManagerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultNDMConfigName, Generation: 3},
+		Spec: diskv1.NodeDiskManagerConfigSpec{
+			Filters: []diskv1.DiskFilterRule{
+				{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, ExcludeLabels: []string{"COS_*", "HARV_*"}},
+				{NodeTarget: diskv1.NodeTarget{Hostname: "harvester1"}, ExcludeVendors: []string{"longhorn"}, IncludeSizeRange: &diskv1.DiskSizeRange{Min: "1Ti"}},
+				{NodeTarget: diskv1.NodeTarget{Hostname: "harvester2"}, ExcludeVendors: []string{"samsung"}},
+				{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, ExcludeExpressions: []string{"disk.sizeBytes <"}},
+			},
+			AutoProvision: []diskv1.AutoProvisionRule{
+				{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, Devices: []string{"/dev/sdc"}, Expressions: []string{"disk.isRemovable"}},
+			},
+		},
+	}
+
+	loader := NewConfigMapLoader(nil, "harvester1", "", "", "", "")
+	status, err := loader.NodeConfigStatus(ndmConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "harvester1", status.NodeName)
+	assert.Equal(t, int64(3), status.ObservedGeneration)
+	assert.Equal(t, "COS_*,HARV_*", status.Filters.ExcludeLabels)
+	assert.Equal(t, "longhorn", status.Filters.ExcludeVendors)
+	assert.Equal(t, []string{"disk.sizeBytes <"}, status.Filters.ExcludeExpressions)
+	assert.Equal(t, []diskv1.DiskSizeRange{{Min: "1Ti"}}, status.Filters.IncludeSizeRanges)
+	assert.Equal(t, "/dev/sdc", status.AutoProvision.Devices)
+	assert.Equal(t, []string{"disk.isRemovable"}, status.AutoProvision.Expressions)
+	require.Len(t, status.Errors, 1)
+	assert.Contains(t, status.Errors[0], "filter rule at index 3 has invalid excludeExpressions")
+}
+
+func TestLoadMountRoot(t *testing.T) {
+	ndmConfig := &diskv1.NodeDiskManagerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultNDMConfigName},
+		Spec: diskv1.NodeDiskManagerConfigSpec{
+			MountRoots: []diskv1.MountRootRule{
+				{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, Path: "/mnt/extra-disks"},
+				{NodeTarget: diskv1.NodeTarget{NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"rack": "r1"}}}, Path: "/data/disks"},
+				{NodeTarget: diskv1.NodeTarget{Hostname: "harvester2"}, Path: "/srv/disks"},
+				{NodeTarget: diskv1.NodeTarget{Hostname: "harvester1"}, Path: "relative/disks"},
+			},
+		},
+	}
+
+	loader := NewConfigMapLoader(nil, "harvester1", "", "", "", "")
+	loader.nodeLabels = map[string]string{"rack": "r1"}
+
+	// no NodeDiskManagerConfig cache means the default mount root
+	mountRoot, err := loader.LoadMountRoot()
+	assert.NoError(t, err)
+	assert.Equal(t, "", mountRoot)
+
+	ndmClientset := diskfake.NewSimpleClientset(ndmConfig)
+	loader.SetNDMConfigCache(fakeclient.FakeNodeDiskManagerConfigCache(ndmClientset.HarvesterhciV1beta1().NodeDiskManagerConfigs))
+	mountRoot, err = loader.LoadMountRoot()
+	assert.NoError(t, err)
+	assert.Equal(t, "/data/disks", mountRoot)
+
+	status, err := loader.NodeConfigStatus(ndmConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "/data/disks", status.MountRoot)
+	require.Len(t, status.Errors, 1)
+	assert.Contains(t, status.Errors[0], "mountRoots rule at index 3 has path \"relative/disks\" is not a clean absolute path")
+}
+
+func TestLoadTrimSchedules(t *testing.T) {
+	weekly := metav1.Duration{Duration: 168 * time.Hour}
+	ndmConfig := &diskv1.NodeDiskManagerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultNDMConfigName},
+		Spec: diskv1.NodeDiskManagerConfigSpec{
+			Trim: []diskv1.TrimRule{
+				{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, TrimSchedule: diskv1.TrimSchedule{Interval: weekly}},
+				{NodeTarget: diskv1.NodeTarget{Hostname: "harvester2"}, TrimSchedule: diskv1.TrimSchedule{Interval: weekly, Tags: []string{"fast"}}},
+				{NodeTarget: diskv1.NodeTarget{Hostname: "harvester1"}, TrimSchedule: diskv1.TrimSchedule{BlockDevices: []string{"disk-a"}}},
+				{NodeTarget: diskv1.NodeTarget{Hostname: "harvester1"}, TrimSchedule: diskv1.TrimSchedule{Interval: metav1.Duration{Duration: time.Minute}}},
+			},
+		},
+	}
+
+	loader := NewConfigMapLoader(nil, "harvester1", "", "", "", "")
+	ndmClientset := diskfake.NewSimpleClientset(ndmConfig)
+	loader.SetNDMConfigCache(fakeclient.FakeNodeDiskManagerConfigCache(ndmClientset.HarvesterhciV1beta1().NodeDiskManagerConfigs))
+	schedules, err := loader.LoadTrimSchedules()
+	assert.NoError(t, err)
+	assert.Equal(t, []diskv1.TrimSchedule{{Interval: weekly}, {BlockDevices: []string{"disk-a"}}}, schedules)
+
+	status, err := loader.NodeConfigStatus(ndmConfig)
+	require.NoError(t, err)
+	assert.Equal(t, schedules, status.Trim)
+	require.Len(t, status.Errors, 1)
+	assert.Contains(t, status.Errors[0], "trim rule at index 3 has interval 1m0s is shorter than 1h0m0s")
+}
+
+func TestLoadTuningProfiles(t *testing.T) {
+	readAhead, noRequests := int64(4096), int64(0)
+	ndmConfig := &diskv1.NodeDiskManagerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultNDMConfigName},
+		Spec: diskv1.NodeDiskManagerConfigSpec{
+			Tuning: []diskv1.TuningRule{
+				{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, TuningProfile: diskv1.TuningProfile{Scheduler: "none"}},
+				{NodeTarget: diskv1.NodeTarget{Hostname: "harvester2"}, TuningProfile: diskv1.TuningProfile{Scheduler: "bfq"}},
+				{NodeTarget: diskv1.NodeTarget{Hostname: "harvester1"}, TuningProfile: diskv1.TuningProfile{Tags: []string{"hdd"}, ReadAheadKB: &readAhead}},
+				{NodeTarget: diskv1.NodeTarget{Hostname: "harvester1"}, TuningProfile: diskv1.TuningProfile{NrRequests: &noRequests}},
+				{NodeTarget: diskv1.NodeTarget{Hostname: "harvester1"}, TuningProfile: diskv1.TuningProfile{WriteCache: "write back"}},
+			},
+		},
+	}
+
+	loader := NewConfigMapLoader(nil, "harvester1", "", "", "", "")
+	ndmClientset := diskfake.NewSimpleClientset(ndmConfig)
+	loader.SetNDMConfigCache(fakeclient.FakeNodeDiskManagerConfigCache(ndmClientset.HarvesterhciV1beta1().NodeDiskManagerConfigs))
+	profiles, err := loader.LoadTuningProfiles()
+	assert.NoError(t, err)
+	assert.Equal(t, []diskv1.TuningProfile{{Scheduler: "none"}, {Tags: []string{"hdd"}, ReadAheadKB: &readAhead}}, profiles)
+
+	status, err := loader.NodeConfigStatus(ndmConfig)
+	require.NoError(t, err)
+	assert.Equal(t, profiles, status.Tuning)
+	require.Len(t, status.Errors, 2)
+	assert.Contains(t, status.Errors[0], "tuning rule at index 3 has nrRequests 0 is not positive")
+	assert.Contains(t, status.Errors[1], "tuning rule at index 4 has writeCache must be WriteBack or WriteThrough")
+}
+
+func TestLoadWithNodeCache(t *testing.T) {
+	ctx := context.Background()
+	ndmConfig := &diskv1.NodeDiskManagerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultNDMConfigName},
+		Spec: diskv1.NodeDiskManagerConfigSpec{
+			Filters: []diskv1.DiskFilterRule{
+				{NodeTarget: diskv1.NodeTarget{NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"rack": "r1"}}}, ExcludeVendors: []string{"longhorn"}},
+				{NodeTarget: diskv1.NodeTarget{NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"rack": "r2"}}}, ExcludeVendors: []string{"samsung"}},
+			},
+			MountRoots: []diskv1.MountRootRule{
+				{NodeTarget: diskv1.NodeTarget{NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"rack": "r1"}}}, Path: "/data/disks"},
+			},
+		},
+	}
+	ndmClientset := diskfake.NewSimpleClientset(ndmConfig)
+
+	loader := NewConfigMapLoader(nil, "harvester1", "", "", "", "")
+	loader.SetNDMConfigCache(fakeclient.FakeNodeDiskManagerConfigCache(ndmClientset.HarvesterhciV1beta1().NodeDiskManagerConfigs))
+	loader.SetNodeCache(fakeclient.NewNodeCache([]*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "harvester1", Labels: map[string]string{"rack": "r1"}}},
+	}))
+
+	_, vendor, _, _, err := loader.LoadFiltersFromConfigMap(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "longhorn", vendor)
+	mountRoot, err := loader.LoadMountRoot()
+	require.NoError(t, err)
+	assert.Equal(t, "/data/disks", mountRoot)
+
+	// the load fails rather than matching the rules against no labels
+	loader.SetNodeCache(fakeclient.NewNodeCache(nil))
+	_, _, _, _, err = loader.LoadFiltersFromConfigMap(ctx)
+	assert.ErrorContains(t, err, "failed to get node harvester1")
+	_, err = loader.LoadIncludeFilterFromConfigMap(ctx)
+	assert.Error(t, err)
+	_, err = loader.LoadMountRoot()
+	assert.Error(t, err)
+	_, err = loader.NodeConfigStatus(ndmConfig)
+	assert.Error(t, err)
+}
+
+func TestLoadUdevRules(t *testing.T) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultConfigMapName, Namespace: DefaultConfigMapNamespace},
+		Data:       map[string]string{UdevRulesConfigKey: `{"rules": [{"env": {"DEVNAME": "^/dev/sd"}}]}`},
+	}
+	ndmConfig := &diskv1.NodeDiskManagerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: DefaultNDMConfigName},
+		Spec: diskv1.NodeDiskManagerConfigSpec{
+			UdevRules: []diskv1.UdevRule{
+				{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, UdevMatcher: diskv1.UdevMatcher{Env: map[string]string{"DEVNAME": "^/dev/nvme"}}},
+				{NodeTarget: diskv1.NodeTarget{Hostname: "harvester2"}, UdevMatcher: diskv1.UdevMatcher{Env: map[string]string{"DEVNAME": "^/dev/vd"}}},
+				{NodeTarget: diskv1.NodeTarget{Hostname: "harvester1"}, UdevMatcher: diskv1.UdevMatcher{Action: "add|remove", Env: map[string]string{"DM_UUID": "^mpath-"}}},
+				{NodeTarget: diskv1.NodeTarget{Hostname: "harvester1"}, UdevMatcher: diskv1.UdevMatcher{Env: map[string]string{"DEVNAME": "^/dev/(sd"}}},
+			},
+		},
+	}
+	ruleEnvs := func(t *testing.T, loader *ConfigMapLoader) []map[string]string {
+		rules, err := loader.LoadUdevRules()
+		require.NoError(t, err)
+		if rules == nil {
+			return nil
+		}
+		envs := []map[string]string{}
+		for _, rule := range rules.Rules {
+			envs = append(envs, rule.Env)
+		}
+		return envs
+	}
+
+	clientset := corefake.NewSimpleClientset(configMap)
+	loader := NewConfigMapLoader(fakeclient.FakeConfigMapCache(clientset.CoreV1().ConfigMaps), "harvester1", "", "", "", "")
+	assert.Equal(t, []map[string]string{{"DEVNAME": "^/dev/sd"}}, ruleEnvs(t, loader), "the rules of the ConfigMap apply without config")
+
+	ndmClientset := diskfake.NewSimpleClientset(ndmConfig)
+	loader.SetNDMConfigCache(fakeclient.FakeNodeDiskManagerConfigCache(ndmClientset.HarvesterhciV1beta1().NodeDiskManagerConfigs))
+	assert.Equal(t, []map[string]string{{"DEVNAME": "^/dev/nvme"}, {"DM_UUID": "^mpath-"}}, ruleEnvs(t, loader), "the config takes precedence over the ConfigMap")
+
+	status, err := loader.NodeConfigStatus(ndmConfig)
+	require.NoError(t, err)
+	assert.Equal(t, []diskv1.UdevMatcher{
+		{Env: map[string]string{"DEVNAME": "^/dev/nvme"}},
+		{Action: "add|remove", Env: map[string]string{"DM_UUID": "^mpath-"}},
+	}, status.UdevRules)
+	require.Len(t, status.Errors, 1)
+	assert.Contains(t, status.Errors[0], "udev rule at index 3 has invalid regular expression")
+
+	// a config without rules for the node doesn't fall back to the ConfigMap
+	loader = NewConfigMapLoader(fakeclient.FakeConfigMapCache(clientset.CoreV1().ConfigMaps), "harvester3", "", "", "", "")
+	loader.SetNDMConfigCache(fakeclient.FakeNodeDiskManagerConfigCache(ndmClientset.HarvesterhciV1beta1().NodeDiskManagerConfigs))
+	ndmConfig.Spec.UdevRules = ndmConfig.Spec.UdevRules[1:3]
+	_, err = ndmClientset.HarvesterhciV1beta1().NodeDiskManagerConfigs().Update(context.Background(), ndmConfig, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, ruleEnvs(t, loader))
+
+	// invalid rules of the ConfigMap are reported
+	configMap.Data[UdevRulesConfigKey] = `{"rules": []}`
+	_, err = clientset.CoreV1().ConfigMaps(DefaultConfigMapNamespace).Update(context.Background(), configMap, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	loader = NewConfigMapLoader(fakeclient.FakeConfigMapCache(clientset.CoreV1().ConfigMaps), "harvester1", "", "", "", "")
+	_, err = loader.LoadUdevRules()
+	assert.ErrorContains(t, err, "no rules provided")
+}
@@ -26,6 +26,7 @@ func SetExcludeFilters(deviceString, vendorString, pathString, labelString strin
 	logrus.Info("register exclude filters")
 
 	driveTypeFilter := RegisterDriveTypeFilter()
+	zonedFilter := RegisterZonedFilter()
 
 	devices := strings.Split(deviceString, ",")
 	deviceFilter := RegisterDevicePathFilter(devices...)
@@ -43,7 +44,7 @@ func SetExcludeFilters(deviceString, vendorString, pathString, labelString strin
 
 	partTypeFilters := RegisterPartTypeFilter(defaultExcludedPartTypes...)
 
-	return []*Filter{driveTypeFilter, deviceFilter, vendorFilter, pathFilter, labelFilter, partTypeFilters}
+	return []*Filter{driveTypeFilter, zonedFilter, deviceFilter, vendorFilter, pathFilter, labelFilter, partTypeFilters}
 }
 
 type DiskFilter interface {
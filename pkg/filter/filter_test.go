@@ -482,3 +482,216 @@ func Test_driveTypeFilter(t *testing.T) {
 		})
 	}
 }
+
+func Test_zonedFilter(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		given    string
+		expected bool
+	}{
+		{
+			name:     "not zoned",
+			given:    "none",
+			expected: false,
+		},
+		{
+			name:     "host-aware",
+			given:    "host-aware",
+			expected: false,
+		},
+		{
+			name:     "host-managed",
+			given:    "host-managed",
+			expected: true,
+		},
+		{
+			name:     "unknown",
+			given:    "",
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filter := RegisterZonedFilter()
+			assert.Equal(t, tc.expected, filter.ApplyDiskFilter(&block.Disk{ZonedModel: tc.given}))
+			assert.False(t, filter.ApplyPartFilter(&block.Partition{}))
+		})
+	}
+}
+
+func Test_includeFilter(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		disk     *block.Disk
+		rules    IncludeRules
+		expected bool
+	}{
+		{
+			name:     "no rules",
+			disk:     &block.Disk{WWN: "0x5000c500a1b2c3d4"},
+			rules:    IncludeRules{},
+			expected: false,
+		},
+		{
+			name:     "matched wwn",
+			disk:     &block.Disk{WWN: "0x5000C500A1B2C3D4"},
+			rules:    IncludeRules{WWNs: []string{"0x5000c500*"}},
+			expected: false,
+		},
+		{
+			name:     "unmatched wwn",
+			disk:     &block.Disk{WWN: "0x5002538e40a1b2c3"},
+			rules:    IncludeRules{WWNs: []string{"0x5000c500*"}},
+			expected: true,
+		},
+		{
+			name:     "matched serial",
+			disk:     &block.Disk{SerialNumber: "S3Z8NB0K123456"},
+			rules:    IncludeRules{WWNs: []string{"0x5000c500*"}, Serials: []string{"S3Z8*"}},
+			expected: false,
+		},
+		{
+			name:     "matched model",
+			disk:     &block.Disk{Model: "ST4000NM0035"},
+			rules:    IncludeRules{Models: []string{"ST4000*"}},
+			expected: false,
+		},
+		{
+			name:     "matched bus path",
+			disk:     &block.Disk{BusPath: "pci-0000:3b:00.0-sas-phy2-lun-0"},
+			rules:    IncludeRules{BusPaths: []string{"pci-0000:3b:00.0-*"}},
+			expected: false,
+		},
+		{
+			name:     "size within range",
+			disk:     &block.Disk{SizeBytes: 2 << 40},
+			rules:    IncludeRules{SizeRanges: []SizeRange{{Min: "1Ti", Max: "4Ti"}}},
+			expected: false,
+		},
+		{
+			name:     "size below range",
+			disk:     &block.Disk{SizeBytes: 512 << 30},
+			rules:    IncludeRules{SizeRanges: []SizeRange{{Min: "1Ti", Max: "4Ti"}}},
+			expected: true,
+		},
+		{
+			name:     "size with unbounded max",
+			disk:     &block.Disk{SizeBytes: 16 << 40},
+			rules:    IncludeRules{SizeRanges: []SizeRange{{Min: "1Ti"}}},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := RegisterIncludeFilter(tc.rules)
+			assert.NoError(t, err)
+			if filter == nil {
+				assert.False(t, tc.expected)
+				return
+			}
+			assert.Equal(t, tc.expected, filter.ApplyDiskFilter(tc.disk))
+		})
+	}
+}
+
+func Test_includeFilterInvalidSizeRange(t *testing.T) {
+	_, err := RegisterIncludeFilter(IncludeRules{SizeRanges: []SizeRange{{Min: "4Ti", Max: "1Ti"}}})
+	assert.Error(t, err)
+	_, err = RegisterIncludeFilter(IncludeRules{SizeRanges: []SizeRange{{Min: "lots"}}})
+	assert.Error(t, err)
+	_, err = RegisterIncludeFilter(IncludeRules{SizeRanges: []SizeRange{{Min: "-1Ti", Max: "1Ti"}}})
+	assert.Error(t, err)
+	// a negative max would wrap around to a huge size
+	_, err = RegisterIncludeFilter(IncludeRules{SizeRanges: []SizeRange{{Max: "-1"}}})
+	assert.Error(t, err)
+}
+
+func Test_celFilter(t *testing.T) {
+	disk := &block.Disk{
+		Name:        "sdb",
+		SizeBytes:   960 << 30,
+		DriveType:   ghwblock.DRIVE_TYPE_SSD,
+		IsRemovable: false,
+		Vendor:      "Samsung",
+		Partitions: []*block.Partition{
+			{Name: "sdb1", Label: "COS_PERSISTENT"},
+		},
+		LogicalBlockSizeBytes: 4096,
+		DiscardMaxBytes:       2 << 30,
+		ZonedModel:            "none",
+	}
+	var testCases = []struct {
+		name       string
+		expression string
+		expected   bool
+	}{
+		{
+			name:       "large non-removable SSD",
+			expression: `disk.driveType == "SSD" && disk.sizeBytes > 500*GiB && !disk.isRemovable`,
+			expected:   true,
+		},
+		{
+			name:       "size above limit",
+			expression: `disk.sizeBytes > 1*TiB`,
+			expected:   false,
+		},
+		{
+			name:       "device path glob",
+			expression: `disk.devPath.startsWith("/dev/sd")`,
+			expected:   true,
+		},
+		{
+			name:       "partition label",
+			expression: `disk.partitions.exists(p, p.label.startsWith("COS_"))`,
+			expected:   true,
+		},
+		{
+			name:       "vendor regex",
+			expression: `disk.vendor.matches("^(?i)intel")`,
+			expected:   false,
+		},
+		{
+			name:       "4Kn disk supporting discard",
+			expression: `disk.logicalBlockSizeBytes == 4*KiB && disk.discardMaxBytes > 0 && disk.zonedModel == "none"`,
+			expected:   true,
+		},
+		{
+			name:       "partition mount point",
+			expression: `disk.partitions.exists(p, p.fileSystem.mountPoint == "/oem")`,
+			expected:   false,
+		},
+		{
+			name:       "rotational disk",
+			expression: `disk.rotationRateRPM >= 7200`,
+			expected:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := RegisterCELFilter(tc.expression)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, filter.ApplyDiskFilter(disk))
+			assert.Equal(t, "expression: "+tc.expression, filter.DiskFilter.Details())
+		})
+	}
+}
+
+func Test_celFilterInvalid(t *testing.T) {
+	for _, expression := range []string{
+		`disk.driveType ==`,
+		`disk.sizeBytes + 1`,
+		`disk.driveTyp == "SSD"`,
+		`unknown.sizeBytes > 0`,
+		`disk.sizeBytes`,
+		// the fields and types are checked when compiling, even for the disks without partitions
+		`disk.partitions.exists(p, p.mountPoint == "/")`,
+		`disk.vendor == 1`,
+		`disk.isRemovable ? true : "no"`,
+	} {
+		_, err := RegisterCELFilter(expression)
+		assert.Error(t, err, expression)
+	}
+}
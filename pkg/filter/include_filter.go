@@ -0,0 +1,164 @@
+package filter
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/harvester/node-disk-manager/pkg/block"
+)
+
+const (
+	includeFilterName = "include filter"
+)
+
+// SizeRange is an inclusive range of disk sizes, e.g. {min: 1Ti, max: 4Ti}.
+// Either bound can be omitted.
+type SizeRange struct {
+	Min string `yaml:"min,omitempty"`
+	Max string `yaml:"max,omitempty"`
+}
+
+// sizeBounds is a parsed SizeRange, a zero max means unbounded
+type sizeBounds struct {
+	min uint64
+	max uint64
+}
+
+// IncludeRules is the allow-list of disks. A disk is included if it matches
+// any of the rules.
+type IncludeRules struct {
+	WWNs       []string
+	Serials    []string
+	Models     []string
+	BusPaths   []string
+	SizeRanges []SizeRange
+}
+
+// IsEmpty returns true if there is no rule, i.e. every disk is included
+func (r *IncludeRules) IsEmpty() bool {
+	return len(r.WWNs) == 0 && len(r.Serials) == 0 && len(r.Models) == 0 && len(r.BusPaths) == 0 && len(r.SizeRanges) == 0
+}
+
+// includeFilter excludes the disks which match none of the include rules.
+// It has no partition filter since the allow-list only applies to disks.
+type includeFilter struct {
+	rules  IncludeRules
+	bounds []sizeBounds
+}
+
+// RegisterIncludeFilter returns a filter matching the disks outside of the
+// allow-list, or nil if the allow-list is empty. Since it is registered as an
+// exclude filter, it only applies to the disks not already excluded.
+func RegisterIncludeFilter(rules IncludeRules) (*Filter, error) {
+	if rules.IsEmpty() {
+		return nil, nil
+	}
+	f := &includeFilter{rules: rules}
+	for _, sizeRange := range rules.SizeRanges {
+		bounds, err := sizeRange.parse()
+		if err != nil {
+			return nil, err
+		}
+		f.bounds = append(f.bounds, bounds)
+	}
+	return &Filter{
+		Name:       includeFilterName,
+		DiskFilter: f,
+	}, nil
+}
+
+// Validate checks that the bounds are valid non-negative quantities and min is not above max
+func (r *SizeRange) Validate() error {
+	_, err := r.parse()
+	return err
+}
+
+func (r *SizeRange) parse() (sizeBounds, error) {
+	bounds := sizeBounds{}
+	if r.Min != "" {
+		q, err := resource.ParseQuantity(r.Min)
+		if err != nil {
+			return bounds, fmt.Errorf("invalid min size %q: %w", r.Min, err)
+		}
+		if q.Sign() < 0 {
+			return bounds, fmt.Errorf("invalid min size %q: the size must not be negative", r.Min)
+		}
+		bounds.min = uint64(q.Value())
+	}
+	if r.Max != "" {
+		q, err := resource.ParseQuantity(r.Max)
+		if err != nil {
+			return bounds, fmt.Errorf("invalid max size %q: %w", r.Max, err)
+		}
+		if q.Sign() < 0 {
+			return bounds, fmt.Errorf("invalid max size %q: the size must not be negative", r.Max)
+		}
+		bounds.max = uint64(q.Value())
+		if bounds.max < bounds.min {
+			return bounds, fmt.Errorf("min size %s is larger than max size %s", r.Min, r.Max)
+		}
+	}
+	return bounds, nil
+}
+
+// Match returns true if the disk matches none of the include rules.
+func (f *includeFilter) Match(disk *block.Disk) bool {
+	if matchPatternsIgnoredCase(f.rules.WWNs, disk.WWN) ||
+		matchPatternsIgnoredCase(f.rules.Serials, disk.SerialNumber) ||
+		matchPatternsIgnoredCase(f.rules.Models, disk.Model) ||
+		matchPatternsIgnoredCase(f.rules.BusPaths, disk.BusPath) {
+		return false
+	}
+	for _, bounds := range f.bounds {
+		if disk.SizeBytes >= bounds.min && (bounds.max == 0 || disk.SizeBytes <= bounds.max) {
+			return false
+		}
+	}
+	return true
+}
+
+// Details returns the allow-list
+func (f *includeFilter) Details() string {
+	details := []string{}
+	if len(f.rules.WWNs) > 0 {
+		details = append(details, "wwns: ["+strings.Join(f.rules.WWNs, ", ")+"]")
+	}
+	if len(f.rules.Serials) > 0 {
+		details = append(details, "serials: ["+strings.Join(f.rules.Serials, ", ")+"]")
+	}
+	if len(f.rules.Models) > 0 {
+		details = append(details, "models: ["+strings.Join(f.rules.Models, ", ")+"]")
+	}
+	if len(f.rules.BusPaths) > 0 {
+		details = append(details, "bus paths: ["+strings.Join(f.rules.BusPaths, ", ")+"]")
+	}
+	for _, sizeRange := range f.rules.SizeRanges {
+		details = append(details, fmt.Sprintf("size range: [%s, %s]", sizeRange.Min, sizeRange.Max))
+	}
+	return "exclude disks not matching any of " + strings.Join(details, ", ")
+}
+
+// matchPatternsIgnoredCase returns true if the value matches one of the glob patterns with case-insensitive
+func matchPatternsIgnoredCase(patterns []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		ok, err := filepath.Match(strings.ToLower(pattern), strings.ToLower(value))
+		if err != nil {
+			logrus.Errorf("failed to perform include matching on %s for pattern %s: %s", value, pattern, err.Error())
+			continue
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,382 @@
+package filter
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/provisioner"
+)
+
+// schedulerNamePattern matches the names of the I/O schedulers listed in /sys/block/<disk>/queue/scheduler
+var schedulerNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// FilterConfigsFromRules converts the filter rules of the NodeDiskManagerConfig
+func FilterConfigsFromRules(rules []diskv1.DiskFilterRule) []FilterConfig {
+	configs := make([]FilterConfig, 0, len(rules))
+	for _, rule := range rules {
+		config := FilterConfig{
+			Hostname:           rule.Hostname,
+			NodeSelector:       nodeSelectorFromLabelSelector(rule.NodeSelector),
+			ExcludeDevices:     rule.ExcludeDevices,
+			ExcludeLabels:      rule.ExcludeLabels,
+			ExcludeVendors:     rule.ExcludeVendors,
+			ExcludePaths:       rule.ExcludePaths,
+			ExcludeExpressions: rule.ExcludeExpressions,
+			IncludeWWNs:        rule.IncludeWWNs,
+			IncludeSerials:     rule.IncludeSerials,
+			IncludeModels:      rule.IncludeModels,
+			IncludeBusPaths:    rule.IncludeBusPaths,
+		}
+		if rule.IncludeSizeRange != nil {
+			config.IncludeSizeRange = &SizeRange{Min: rule.IncludeSizeRange.Min, Max: rule.IncludeSizeRange.Max}
+		}
+		configs = append(configs, config)
+	}
+	return configs
+}
+
+// AutoProvisionConfigsFromRules converts the auto-provision rules of the NodeDiskManagerConfig
+func AutoProvisionConfigsFromRules(rules []diskv1.AutoProvisionRule) []AutoProvisionConfig {
+	configs := make([]AutoProvisionConfig, 0, len(rules))
+	for _, rule := range rules {
+		config := AutoProvisionConfig{
+			Hostname:     rule.Hostname,
+			NodeSelector: nodeSelectorFromLabelSelector(rule.NodeSelector),
+			Devices:      rule.Devices,
+			Expressions:  rule.Expressions,
+			Provisioner:  rule.Provisioner,
+			Params:       rule.Params,
+		}
+		if config.Provisioner == "" {
+			config.Provisioner = provisioner.TypeLonghornV1
+		}
+		configs = append(configs, config)
+	}
+	return configs
+}
+
+// UdevConfig matches the udev events handled on the matching nodes
+type UdevConfig struct {
+	Hostname     string
+	NodeSelector *NodeSelector
+	diskv1.UdevMatcher
+}
+
+// UdevConfigsFromRules converts the udev rules of the NodeDiskManagerConfig
+func UdevConfigsFromRules(rules []diskv1.UdevRule) []UdevConfig {
+	configs := make([]UdevConfig, 0, len(rules))
+	for _, rule := range rules {
+		configs = append(configs, UdevConfig{
+			Hostname:     rule.Hostname,
+			NodeSelector: nodeSelectorFromLabelSelector(rule.NodeSelector),
+			UdevMatcher:  rule.UdevMatcher,
+		})
+	}
+	return configs
+}
+
+// MountRootConfig sets the directory the LonghornV1 disks of the matching nodes are mounted under
+type MountRootConfig struct {
+	Hostname     string
+	NodeSelector *NodeSelector
+	Path         string
+}
+
+// MountRootConfigsFromRules converts the mount root rules of the NodeDiskManagerConfig
+func MountRootConfigsFromRules(rules []diskv1.MountRootRule) []MountRootConfig {
+	configs := make([]MountRootConfig, 0, len(rules))
+	for _, rule := range rules {
+		configs = append(configs, MountRootConfig{
+			Hostname:     rule.Hostname,
+			NodeSelector: nodeSelectorFromLabelSelector(rule.NodeSelector),
+			Path:         rule.Path,
+		})
+	}
+	return configs
+}
+
+// TrimConfig schedules fstrim on the disks of the matching nodes
+type TrimConfig struct {
+	Hostname     string
+	NodeSelector *NodeSelector
+	diskv1.TrimSchedule
+}
+
+// TrimConfigsFromRules converts the trim rules of the NodeDiskManagerConfig
+func TrimConfigsFromRules(rules []diskv1.TrimRule) []TrimConfig {
+	configs := make([]TrimConfig, 0, len(rules))
+	for _, rule := range rules {
+		configs = append(configs, TrimConfig{
+			Hostname:     rule.Hostname,
+			NodeSelector: nodeSelectorFromLabelSelector(rule.NodeSelector),
+			TrimSchedule: rule.TrimSchedule,
+		})
+	}
+	return configs
+}
+
+// TuningConfig tunes the block layer of the disks of the matching nodes
+type TuningConfig struct {
+	Hostname     string
+	NodeSelector *NodeSelector
+	diskv1.TuningProfile
+}
+
+// TuningConfigsFromRules converts the tuning rules of the NodeDiskManagerConfig
+func TuningConfigsFromRules(rules []diskv1.TuningRule) []TuningConfig {
+	configs := make([]TuningConfig, 0, len(rules))
+	for _, rule := range rules {
+		configs = append(configs, TuningConfig{
+			Hostname:      rule.Hostname,
+			NodeSelector:  nodeSelectorFromLabelSelector(rule.NodeSelector),
+			TuningProfile: rule.TuningProfile,
+		})
+	}
+	return configs
+}
+
+// FilterRulesFromConfigs converts the filters.yaml configurations into NodeDiskManagerConfig rules
+func FilterRulesFromConfigs(configs []FilterConfig) []diskv1.DiskFilterRule {
+	rules := make([]diskv1.DiskFilterRule, 0, len(configs))
+	for _, config := range configs {
+		rule := diskv1.DiskFilterRule{
+			NodeTarget: diskv1.NodeTarget{
+				Hostname:     config.Hostname,
+				NodeSelector: config.NodeSelector.labelSelector(),
+			},
+			ExcludeDevices:     config.ExcludeDevices,
+			ExcludeLabels:      config.ExcludeLabels,
+			ExcludeVendors:     config.ExcludeVendors,
+			ExcludePaths:       config.ExcludePaths,
+			ExcludeExpressions: config.ExcludeExpressions,
+			IncludeWWNs:        config.IncludeWWNs,
+			IncludeSerials:     config.IncludeSerials,
+			IncludeModels:      config.IncludeModels,
+			IncludeBusPaths:    config.IncludeBusPaths,
+		}
+		if config.IncludeSizeRange != nil {
+			rule.IncludeSizeRange = &diskv1.DiskSizeRange{Min: config.IncludeSizeRange.Min, Max: config.IncludeSizeRange.Max}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// AutoProvisionRulesFromConfigs converts the autoprovision.yaml configurations into NodeDiskManagerConfig rules
+func AutoProvisionRulesFromConfigs(configs []AutoProvisionConfig) []diskv1.AutoProvisionRule {
+	rules := make([]diskv1.AutoProvisionRule, 0, len(configs))
+	for _, config := range configs {
+		rules = append(rules, diskv1.AutoProvisionRule{
+			NodeTarget: diskv1.NodeTarget{
+				Hostname:     config.Hostname,
+				NodeSelector: config.NodeSelector.labelSelector(),
+			},
+			Devices:     config.Devices,
+			Expressions: config.Expressions,
+			Provisioner: config.Provisioner,
+			Params:      config.Params,
+		})
+	}
+	return rules
+}
+
+// validateNodeTarget checks that a block selects nodes by hostname or node
+// selector, and that the node selector is valid
+func validateNodeTarget(hostname string, selector *NodeSelector) error {
+	if hostname == "" && selector == nil {
+		return fmt.Errorf("empty hostname and no nodeSelector, which is not allowed")
+	}
+	if selector != nil {
+		if _, err := selector.AsSelector(); err != nil {
+			return fmt.Errorf("invalid nodeSelector: %w", err)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the block selects nodes by hostname or node selector,
+// and that the node selector, size range and expressions are valid
+func (config *FilterConfig) Validate() error {
+	if err := validateNodeTarget(config.Hostname, config.NodeSelector); err != nil {
+		return err
+	}
+	if config.IncludeSizeRange != nil {
+		if err := config.IncludeSizeRange.Validate(); err != nil {
+			return fmt.Errorf("invalid includeSizeRange: %w", err)
+		}
+	}
+	if _, err := RegisterCELFilters(config.ExcludeExpressions...); err != nil {
+		return fmt.Errorf("invalid excludeExpressions: %w", err)
+	}
+	return nil
+}
+
+// Validate checks that the block selects nodes by hostname or node selector,
+// and that the node selector and expressions are valid
+func (config *AutoProvisionConfig) Validate() error {
+	if err := validateNodeTarget(config.Hostname, config.NodeSelector); err != nil {
+		return err
+	}
+	if _, err := RegisterCELFilters(config.Expressions...); err != nil {
+		return fmt.Errorf("invalid expressions: %w", err)
+	}
+	return nil
+}
+
+// Validate checks that the block selects nodes by hostname or node selector,
+// and that the action and env entries are valid regular expressions
+func (config *UdevConfig) Validate() error {
+	if err := validateNodeTarget(config.Hostname, config.NodeSelector); err != nil {
+		return err
+	}
+	rule := udevRuleDefinition(config.UdevMatcher)
+	if err := rule.Compile(); err != nil {
+		return fmt.Errorf("invalid regular expression: %w", err)
+	}
+	return nil
+}
+
+// Validate checks that the block selects nodes by hostname or node selector,
+// and that the path is a clean absolute path other than the root directory
+func (config *MountRootConfig) Validate() error {
+	if err := validateNodeTarget(config.Hostname, config.NodeSelector); err != nil {
+		return err
+	}
+	if !filepath.IsAbs(config.Path) || filepath.Clean(config.Path) != config.Path {
+		return fmt.Errorf("path %q is not a clean absolute path", config.Path)
+	}
+	if config.Path == "/" {
+		return fmt.Errorf("path must not be the root directory")
+	}
+	return nil
+}
+
+// Validate checks that the block selects nodes by hostname or node selector,
+// and that the interval is either zero or at least the minimum trim interval
+func (config *TrimConfig) Validate() error {
+	if err := validateNodeTarget(config.Hostname, config.NodeSelector); err != nil {
+		return err
+	}
+	if interval := config.Interval.Duration; interval != 0 && interval < MinTrimInterval {
+		return fmt.Errorf("interval %s is shorter than %s", interval, MinTrimInterval)
+	}
+	return nil
+}
+
+// Validate checks that the block selects nodes by hostname or node selector,
+// and that the queue attributes are valid. WriteThrough only applies to named disks.
+func (config *TuningConfig) Validate() error {
+	if err := validateNodeTarget(config.Hostname, config.NodeSelector); err != nil {
+		return err
+	}
+	if config.Scheduler != "" && !schedulerNamePattern.MatchString(config.Scheduler) {
+		return fmt.Errorf("invalid scheduler %q", config.Scheduler)
+	}
+	if config.ReadAheadKB != nil && *config.ReadAheadKB < 0 {
+		return fmt.Errorf("readAheadKB %d is negative", *config.ReadAheadKB)
+	}
+	if config.NrRequests != nil && *config.NrRequests < 1 {
+		return fmt.Errorf("nrRequests %d is not positive", *config.NrRequests)
+	}
+	switch config.WriteCache {
+	case "", diskv1.WriteCacheBack:
+	case diskv1.WriteCacheThrough:
+		// the kernel stops flushing the cache of the disk, which loses the acknowledged
+		// writes on a power loss unless the cache is non-volatile, so the disks are named
+		if len(config.BlockDevices) == 0 {
+			return fmt.Errorf("writeCache %s requires the blockDevices with a non-volatile cache to be named", diskv1.WriteCacheThrough)
+		}
+	default:
+		return fmt.Errorf("writeCache must be %s or %s", diskv1.WriteCacheBack, diskv1.WriteCacheThrough)
+	}
+	return nil
+}
+
+// NodeConfigStatus computes the rules of the NodeDiskManagerConfig effective on the current node.
+// The invalid blocks matching the node are reported as errors.
+func (c *ConfigMapLoader) NodeConfigStatus(ndmConfig *diskv1.NodeDiskManagerConfig) (diskv1.NodeConfigStatus, error) {
+	loader, err := c.forNode()
+	if err != nil {
+		return diskv1.NodeConfigStatus{}, err
+	}
+	filterConfigs := FilterConfigsFromRules(ndmConfig.Spec.Filters)
+	autoProvConfigs := AutoProvisionConfigsFromRules(ndmConfig.Spec.AutoProvision)
+	udevConfigs := UdevConfigsFromRules(ndmConfig.Spec.UdevRules)
+	mountRootConfigs := MountRootConfigsFromRules(ndmConfig.Spec.MountRoots)
+	trimConfigs := TrimConfigsFromRules(ndmConfig.Spec.Trim)
+	tuningConfigs := TuningConfigsFromRules(ndmConfig.Spec.Tuning)
+
+	status := diskv1.NodeConfigStatus{
+		NodeName:           c.nodeName,
+		ObservedGeneration: ndmConfig.Generation,
+	}
+	for i, config := range filterConfigs {
+		if err := config.Validate(); err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("filter rule at index %d has %v", i, err))
+		}
+	}
+	for i, config := range autoProvConfigs {
+		if err := config.Validate(); err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("autoProvision rule at index %d has %v", i, err))
+		}
+	}
+	for i, config := range udevConfigs {
+		if err := config.Validate(); err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("udev rule at index %d has %v", i, err))
+		}
+	}
+	for i, config := range mountRootConfigs {
+		if err := config.Validate(); err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("mountRoots rule at index %d has %v", i, err))
+		}
+	}
+	for i, config := range trimConfigs {
+		if err := config.Validate(); err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("trim rule at index %d has %v", i, err))
+		}
+	}
+	for i, config := range tuningConfigs {
+		if err := config.Validate(); err != nil {
+			status.Errors = append(status.Errors, fmt.Sprintf("tuning rule at index %d has %v", i, err))
+		}
+	}
+
+	filters := &status.Filters
+	filters.ExcludeDevices, filters.ExcludeVendors, filters.ExcludePaths, filters.ExcludeLabels = loader.mergeFilterConfigs(filterConfigs)
+	filters.ExcludeExpressions = loader.mergeExcludeExpressions(filterConfigs)
+	includeRules := loader.mergeIncludeConfigs(filterConfigs)
+	filters.IncludeWWNs = includeRules.WWNs
+	filters.IncludeSerials = includeRules.Serials
+	filters.IncludeModels = includeRules.Models
+	filters.IncludeBusPaths = includeRules.BusPaths
+	for _, sizeRange := range includeRules.SizeRanges {
+		filters.IncludeSizeRanges = append(filters.IncludeSizeRanges, diskv1.DiskSizeRange{Min: sizeRange.Min, Max: sizeRange.Max})
+	}
+
+	status.AutoProvision.Devices = loader.mergeAutoProvisionConfigs(autoProvConfigs)
+	status.AutoProvision.Expressions = loader.mergeAutoProvisionExpressions(autoProvConfigs)
+	status.MountRoot = loader.mergeMountRootConfigs(mountRootConfigs)
+	status.Trim = loader.mergeTrimConfigs(trimConfigs)
+	status.Tuning = loader.mergeTuningConfigs(tuningConfigs)
+	status.UdevRules = loader.mergeUdevConfigs(udevConfigs)
+	return status, nil
+}
+
+func nodeSelectorFromLabelSelector(selector *metav1.LabelSelector) *NodeSelector {
+	if selector == nil {
+		return nil
+	}
+	nodeSelector := &NodeSelector{MatchLabels: selector.MatchLabels}
+	for _, req := range selector.MatchExpressions {
+		nodeSelector.MatchExpressions = append(nodeSelector.MatchExpressions, NodeSelectorRequirement{
+			Key:      req.Key,
+			Operator: string(req.Operator),
+			Values:   req.Values,
+		})
+	}
+	return nodeSelector
+}
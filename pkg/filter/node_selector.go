@@ -0,0 +1,43 @@
+package filter
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// NodeSelector selects the nodes a config block applies to by their labels.
+// It has the same semantics as a Kubernetes label selector.
+type NodeSelector struct {
+	MatchLabels      map[string]string         `yaml:"matchLabels,omitempty"`
+	MatchExpressions []NodeSelectorRequirement `yaml:"matchExpressions,omitempty"`
+}
+
+// NodeSelectorRequirement is a label selector requirement, e.g.
+// {key: rack, operator: In, values: [r1, r2]}
+type NodeSelectorRequirement struct {
+	Key      string   `yaml:"key"`
+	Operator string   `yaml:"operator"`
+	Values   []string `yaml:"values,omitempty"`
+}
+
+// AsSelector converts the node selector into a label selector
+func (s *NodeSelector) AsSelector() (labels.Selector, error) {
+	return metav1.LabelSelectorAsSelector(s.labelSelector())
+}
+
+func (s *NodeSelector) labelSelector() *metav1.LabelSelector {
+	if s == nil {
+		return nil
+	}
+	selector := &metav1.LabelSelector{
+		MatchLabels: s.MatchLabels,
+	}
+	for _, req := range s.MatchExpressions {
+		selector.MatchExpressions = append(selector.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      req.Key,
+			Operator: metav1.LabelSelectorOperator(req.Operator),
+			Values:   req.Values,
+		})
+	}
+	return selector
+}
@@ -0,0 +1,84 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pilebones/go-udev/netlink"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+// ParseUdevRules parses and compiles the udev matcher rules, a JSON object like
+// {"rules": [{"action": "add|remove", "env": {"DEVNAME": "^/dev/sd"}}]}.
+// The rules are OR'ed, an event is handled if it matches any of them. Nil is
+// returned for empty content, which means every event is handled.
+func ParseUdevRules(content string) (*netlink.RuleDefinitions, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, nil
+	}
+
+	var rules netlink.RuleDefinitions
+	if err := json.Unmarshal([]byte(content), &rules); err != nil {
+		return nil, fmt.Errorf("wrong rule syntax: %w", err)
+	}
+	if len(rules.Rules) == 0 {
+		return nil, fmt.Errorf("no rules provided, which would drop every udev event")
+	}
+	// compile the rules in place, so they aren't compiled again for every event
+	for i := range rules.Rules {
+		if err := rules.Rules[i].Compile(); err != nil {
+			return nil, fmt.Errorf("rule at index %d has invalid regular expression: %w", i, err)
+		}
+	}
+	return &rules, nil
+}
+
+// UdevRulesFromMatchers compiles the matchers of the NodeDiskManagerConfig, nil is
+// returned without matchers, which means every event is handled.
+func UdevRulesFromMatchers(matchers []diskv1.UdevMatcher) (*netlink.RuleDefinitions, error) {
+	if len(matchers) == 0 {
+		return nil, nil
+	}
+
+	rules := &netlink.RuleDefinitions{}
+	for i, matcher := range matchers {
+		rule := udevRuleDefinition(matcher)
+		if err := rule.Compile(); err != nil {
+			return nil, fmt.Errorf("rule at index %d has invalid regular expression: %w", i, err)
+		}
+		rules.AddRule(rule)
+	}
+	return rules, nil
+}
+
+// UdevRulesFromDefinitions converts the rules of udevrules.json into NodeDiskManagerConfig
+// rules, which apply to every node like the rules of the ConfigMap
+func UdevRulesFromDefinitions(definitions *netlink.RuleDefinitions) []diskv1.UdevRule {
+	if definitions == nil {
+		return nil
+	}
+	rules := make([]diskv1.UdevRule, 0, len(definitions.Rules))
+	for _, definition := range definitions.Rules {
+		rule := diskv1.UdevRule{
+			NodeTarget:  diskv1.NodeTarget{Hostname: "*"},
+			UdevMatcher: diskv1.UdevMatcher{Env: definition.Env},
+		}
+		if definition.Action != nil {
+			rule.Action = *definition.Action
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// udevRuleDefinition converts the matcher, an empty action matches every action
+func udevRuleDefinition(matcher diskv1.UdevMatcher) netlink.RuleDefinition {
+	rule := netlink.RuleDefinition{Env: matcher.Env}
+	if matcher.Action != "" {
+		action := matcher.Action
+		rule.Action = &action
+	}
+	return rule
+}
@@ -0,0 +1,111 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/pilebones/go-udev/netlink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+func newUEvent(action netlink.KObjAction, env map[string]string) netlink.UEvent {
+	return netlink.UEvent{Action: action, Env: env}
+}
+
+func TestParseUdevRules(t *testing.T) {
+	disk := newUEvent(netlink.ADD, map[string]string{"DEVNAME": "/dev/sdb"})
+	loop := newUEvent(netlink.ADD, map[string]string{"DEVNAME": "/dev/loop0"})
+	multipath := newUEvent(netlink.CHANGE, map[string]string{"DEVNAME": "/dev/dm-0", "DM_UUID": "mpath-3600"})
+
+	tests := []struct {
+		name     string
+		content  string
+		errorMsg string
+		noRules  bool
+		handled  []netlink.UEvent
+		dropped  []netlink.UEvent
+	}{
+		{
+			name:    "empty content handles every event",
+			content: " \n",
+			noRules: true,
+		},
+		{
+			name:    "the rules are OR'ed",
+			content: `{"rules": [{"env": {"DEVNAME": "^/dev/(sd|vd|nvme)"}}, {"env": {"DM_UUID": "^mpath-"}}]}`,
+			handled: []netlink.UEvent{disk, multipath},
+			dropped: []netlink.UEvent{loop},
+		},
+		{
+			name:    "the action and every env entry of a rule must match",
+			content: `{"rules": [{"action": "add", "env": {"DEVNAME": "^/dev/", "DM_UUID": "^mpath-"}}]}`,
+			dropped: []netlink.UEvent{disk, multipath, newUEvent(netlink.CHANGE, map[string]string{"DEVNAME": "/dev/dm-0", "DM_UUID": "mpath-3600"})},
+			handled: []netlink.UEvent{newUEvent(netlink.ADD, map[string]string{"DEVNAME": "/dev/dm-0", "DM_UUID": "mpath-3600"})},
+		},
+		{
+			name:     "invalid JSON",
+			content:  `{"rules": [{"env": "DEVNAME"}]}`,
+			errorMsg: "wrong rule syntax",
+		},
+		{
+			name:     "no rules would drop every event",
+			content:  `{"rules": []}`,
+			errorMsg: "no rules provided",
+		},
+		{
+			name:     "invalid regular expression",
+			content:  `{"rules": [{"env": {"DEVNAME": "^/dev/sd"}}, {"action": "(add"}]}`,
+			errorMsg: "rule at index 1 has invalid regular expression",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseUdevRules(tt.content)
+			if tt.errorMsg != "" {
+				assert.ErrorContains(t, err, tt.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			if tt.noRules {
+				assert.Nil(t, rules)
+				return
+			}
+			for _, uevent := range tt.handled {
+				assert.True(t, rules.Evaluate(uevent), "event %+v is handled", uevent)
+			}
+			for _, uevent := range tt.dropped {
+				assert.False(t, rules.Evaluate(uevent), "event %+v is dropped", uevent)
+			}
+		})
+	}
+}
+
+func TestUdevRulesFromMatchers(t *testing.T) {
+	rules, err := UdevRulesFromMatchers(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, rules, "every event is handled without matchers")
+
+	rules, err = UdevRulesFromMatchers([]diskv1.UdevMatcher{
+		{Env: map[string]string{"DEVNAME": "^/dev/nvme"}},
+		{Action: "remove"},
+	})
+	require.NoError(t, err)
+	assert.True(t, rules.Evaluate(newUEvent(netlink.ADD, map[string]string{"DEVNAME": "/dev/nvme0n1"})))
+	assert.True(t, rules.Evaluate(newUEvent(netlink.REMOVE, map[string]string{"DEVNAME": "/dev/sdb"})))
+	assert.False(t, rules.Evaluate(newUEvent(netlink.ADD, map[string]string{"DEVNAME": "/dev/sdb"})))
+
+	_, err = UdevRulesFromMatchers([]diskv1.UdevMatcher{{Action: "add"}, {Env: map[string]string{"DEVNAME": "^/dev/(sd"}}})
+	assert.ErrorContains(t, err, "rule at index 1 has invalid regular expression")
+}
+
+func TestUdevRulesFromDefinitions(t *testing.T) {
+	definitions, err := ParseUdevRules(`{"rules": [{"action": "add|change", "env": {"DEVNAME": "^/dev/sd"}}, {"env": {"DM_UUID": "^mpath-"}}]}`)
+	require.NoError(t, err)
+	assert.Equal(t, []diskv1.UdevRule{
+		{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, UdevMatcher: diskv1.UdevMatcher{Action: "add|change", Env: map[string]string{"DEVNAME": "^/dev/sd"}}},
+		{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, UdevMatcher: diskv1.UdevMatcher{Env: map[string]string{"DM_UUID": "^mpath-"}}},
+	}, UdevRulesFromDefinitions(definitions))
+	assert.Nil(t, UdevRulesFromDefinitions(nil))
+}
@@ -0,0 +1,183 @@
+package filter
+
+import (
+	"strings"
+
+	ghwblock "github.com/jaypipes/ghw/pkg/block"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/block"
+	"github.com/harvester/node-disk-manager/pkg/utils"
+)
+
+var knownDriveTypes = []ghwblock.DriveType{
+	ghwblock.DRIVE_TYPE_HDD,
+	ghwblock.DRIVE_TYPE_FDD,
+	ghwblock.DRIVE_TYPE_ODD,
+	ghwblock.DRIVE_TYPE_SSD,
+}
+
+var knownStorageControllers = []ghwblock.StorageController{
+	ghwblock.STORAGE_CONTROLLER_IDE,
+	ghwblock.STORAGE_CONTROLLER_SCSI,
+	ghwblock.STORAGE_CONTROLLER_NVME,
+	ghwblock.STORAGE_CONTROLLER_VIRTIO,
+	ghwblock.STORAGE_CONTROLLER_MMC,
+}
+
+// FirstMatch returns the first filter matching the disk, or nil if none matches.
+func FirstMatch(filters []*Filter, disk *block.Disk) *Filter {
+	for _, filter := range filters {
+		if filter.ApplyDiskFilter(disk) {
+			return filter
+		}
+	}
+	return nil
+}
+
+// NewFilteredDisk records the disk properties used by the disk filters and the expressions
+func NewFilteredDisk(disk *block.Disk) diskv1.FilteredDisk {
+	filtered := diskv1.FilteredDisk{
+		Vendor:                 disk.Vendor,
+		Model:                  disk.Model,
+		SerialNumber:           disk.SerialNumber,
+		WWN:                    disk.WWN,
+		SizeBytes:              disk.SizeBytes,
+		BusPath:                disk.BusPath,
+		DriveType:              disk.DriveType.String(),
+		IsRemovable:            disk.IsRemovable,
+		Label:                  disk.Label,
+		MountPoint:             disk.FileSystemInfo.MountPoint,
+		FileSystem:             disk.FileSystemInfo.Type,
+		IsReadOnly:             disk.FileSystemInfo.IsReadOnly,
+		PhysicalBlockSizeBytes: disk.PhysicalBlockSizeBytes,
+		LogicalBlockSizeBytes:  disk.LogicalBlockSizeBytes,
+		StorageController:      disk.StorageController.String(),
+		UUID:                   disk.UUID,
+		PtUUID:                 disk.PtUUID,
+		FirmwareRevision:       disk.FirmwareRevision,
+		RotationRateRPM:        disk.RotationRateRPM,
+		LinkSpeed:              disk.LinkSpeed,
+		DiscardGranularity:     disk.DiscardGranularity,
+		DiscardMaxBytes:        disk.DiscardMaxBytes,
+		ZonedModel:             disk.ZonedModel,
+		WriteCacheEnabled:      disk.WriteCacheEnabled,
+	}
+	for _, part := range disk.Partitions {
+		filtered.Partitions = append(filtered.Partitions, diskv1.FilteredPartition{
+			Name:       part.Name,
+			Label:      part.Label,
+			PartType:   part.PartType,
+			MountPoint: part.FileSystemInfo.MountPoint,
+			SizeBytes:  part.SizeBytes,
+			UUID:       part.UUID,
+			FsUUID:     part.FsUUID,
+			FileSystem: part.FileSystemInfo.Type,
+			IsReadOnly: part.FileSystemInfo.IsReadOnly,
+		})
+	}
+	return filtered
+}
+
+// DiskFromFiltered rebuilds a disk from the recorded properties, so that the
+// filters can be evaluated again without access to the node.
+func DiskFromFiltered(devPath string, filtered diskv1.FilteredDisk) *block.Disk {
+	driveType := ghwblock.DRIVE_TYPE_UNKNOWN
+	for _, t := range knownDriveTypes {
+		if t.String() == filtered.DriveType {
+			driveType = t
+		}
+	}
+	storageController := ghwblock.STORAGE_CONTROLLER_UNKNOWN
+	for _, c := range knownStorageControllers {
+		if c.String() == filtered.StorageController {
+			storageController = c
+		}
+	}
+	disk := &block.Disk{
+		Name:         strings.TrimPrefix(devPath, "/dev/"),
+		Vendor:       filtered.Vendor,
+		Model:        filtered.Model,
+		SerialNumber: filtered.SerialNumber,
+		WWN:          filtered.WWN,
+		SizeBytes:    filtered.SizeBytes,
+		BusPath:      filtered.BusPath,
+		DriveType:    driveType,
+		IsRemovable:  filtered.IsRemovable,
+		Label:        filtered.Label,
+		FileSystemInfo: block.FileSystemInfo{
+			Type:       filtered.FileSystem,
+			IsReadOnly: filtered.IsReadOnly,
+			MountPoint: filtered.MountPoint,
+		},
+		PhysicalBlockSizeBytes: filtered.PhysicalBlockSizeBytes,
+		LogicalBlockSizeBytes:  filtered.LogicalBlockSizeBytes,
+		StorageController:      storageController,
+		UUID:                   filtered.UUID,
+		PtUUID:                 filtered.PtUUID,
+		FirmwareRevision:       filtered.FirmwareRevision,
+		RotationRateRPM:        filtered.RotationRateRPM,
+		LinkSpeed:              filtered.LinkSpeed,
+		DiscardGranularity:     filtered.DiscardGranularity,
+		DiscardMaxBytes:        filtered.DiscardMaxBytes,
+		ZonedModel:             filtered.ZonedModel,
+		WriteCacheEnabled:      filtered.WriteCacheEnabled,
+	}
+	for _, part := range filtered.Partitions {
+		disk.Partitions = append(disk.Partitions, &block.Partition{
+			Disk:              disk,
+			Name:              part.Name,
+			Label:             part.Label,
+			SizeBytes:         part.SizeBytes,
+			UUID:              part.UUID,
+			FsUUID:            part.FsUUID,
+			PartType:          part.PartType,
+			DriveType:         driveType,
+			StorageController: storageController,
+			FileSystemInfo: block.FileSystemInfo{
+				Type:       part.FileSystem,
+				IsReadOnly: part.IsReadOnly,
+				MountPoint: part.MountPoint,
+			},
+		})
+	}
+	return disk
+}
+
+// PreviewExclusions evaluates the filter configurations against the verdicts
+// published by the given node, and returns the verdicts of the disks which are
+// accepted now but would be excluded by the new configurations.
+func PreviewExclusions(configs []FilterConfig, nodeName string, nodeLabels map[string]string, verdicts []diskv1.DiskFilterVerdict) ([]diskv1.DiskFilterVerdict, error) {
+	loader := &ConfigMapLoader{nodeName: nodeName, nodeLabels: nodeLabels}
+	filters := SetExcludeFilters(loader.mergeFilterConfigs(configs))
+	expressionFilters, err := RegisterCELFilters(loader.mergeExcludeExpressions(configs)...)
+	if err != nil {
+		return nil, err
+	}
+	filters = append(filters, expressionFilters...)
+	includeFilter, err := RegisterIncludeFilter(loader.mergeIncludeConfigs(configs))
+	if err != nil {
+		return nil, err
+	}
+	if includeFilter != nil {
+		filters = append(filters, includeFilter)
+	}
+
+	excluded := []diskv1.DiskFilterVerdict{}
+	for _, verdict := range verdicts {
+		if verdict.Excluded {
+			continue
+		}
+		disk := DiskFromFiltered(verdict.DevPath, verdict.Disk)
+		if filter := FirstMatch(filters, disk); filter != nil {
+			excluded = append(excluded, diskv1.DiskFilterVerdict{
+				DevPath:  utils.GetFullDevPath(disk.Name),
+				Excluded: true,
+				Filter:   filter.Name,
+				Rules:    filter.DiskFilter.Details(),
+				Disk:     verdict.Disk,
+			})
+		}
+	}
+	return excluded, nil
+}
@@ -0,0 +1,143 @@
+package filter
+
+import (
+	"testing"
+
+	ghwblock "github.com/jaypipes/ghw/pkg/block"
+	"github.com/stretchr/testify/assert"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/block"
+)
+
+func TestFilteredDiskRoundTrip(t *testing.T) {
+	disk := &block.Disk{
+		Name:                   "sda",
+		Vendor:                 "ATA",
+		Model:                  "QEMU HARDDISK",
+		SerialNumber:           "QM00001",
+		WWN:                    "0x5000c500a1b2c3d4",
+		SizeBytes:              500 * 1024 * 1024 * 1024,
+		BusPath:                "pci-0000:00:1f.2-ata-1",
+		DriveType:              ghwblock.DRIVE_TYPE_SSD,
+		IsRemovable:            true,
+		Label:                  "DATA",
+		PhysicalBlockSizeBytes: 4096,
+		LogicalBlockSizeBytes:  512,
+		StorageController:      ghwblock.STORAGE_CONTROLLER_SCSI,
+		UUID:                   "6c1f9d2e-5a3b-4c7d-8e9f-0a1b2c3d4e5f",
+		PtUUID:                 "0b4c8f3a-1d2e-4f5a-9b8c-7d6e5f4a3b2c",
+		FirmwareRevision:       "2.5+",
+		RotationRateRPM:        7200,
+		LinkSpeed:              "6.0 Gbps",
+		DiscardGranularity:     4096,
+		DiscardMaxBytes:        2147450880,
+		ZonedModel:             "host-aware",
+		WriteCacheEnabled:      true,
+		FileSystemInfo:         block.FileSystemInfo{Type: "ext4", IsReadOnly: true, MountPoint: "/data"},
+		Partitions: []*block.Partition{
+			{
+				Name:           "sda1",
+				Label:          "COS_OEM",
+				SizeBytes:      64 * 1024 * 1024,
+				UUID:           "3f2a1b0c-9d8e-4f7a-6b5c-4d3e2f1a0b9c",
+				FsUUID:         "a1b2c3d4-e5f6-4a7b-8c9d-0e1f2a3b4c5d",
+				PartType:       "0fc63daf-8483-4772-8e79-3d69d8477de4",
+				FileSystemInfo: block.FileSystemInfo{Type: "ext4", MountPoint: "/oem"},
+			},
+		},
+	}
+
+	rebuilt := DiskFromFiltered("/dev/sda", NewFilteredDisk(disk))
+	// every property the expressions can read survives the round trip
+	assert.Equal(t, celDiskView(disk), celDiskView(rebuilt))
+	assert.Equal(t, disk.SerialNumber, rebuilt.SerialNumber)
+	assert.Len(t, rebuilt.Partitions, 1)
+	assert.Equal(t, ghwblock.DRIVE_TYPE_SSD, rebuilt.Partitions[0].DriveType)
+}
+
+func TestPreviewExclusions(t *testing.T) {
+	verdicts := []diskv1.DiskFilterVerdict{
+		{
+			DevPath: "/dev/sda",
+			Disk:    diskv1.FilteredDisk{Vendor: "ATA", DriveType: "HDD"},
+		},
+		{
+			DevPath: "/dev/sdb",
+			Disk:    diskv1.FilteredDisk{Vendor: "Samsung", Model: "MZ7LH960", DriveType: "SSD"},
+		},
+		{
+			DevPath:  "/dev/sdc",
+			Excluded: true,
+			Filter:   "vendor filter",
+			Disk:     diskv1.FilteredDisk{Vendor: "longhorn", DriveType: "HDD"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		nodeName   string
+		nodeLabels map[string]string
+		configs    []FilterConfig
+		expected   []string
+	}{
+		{
+			name:     "no new exclusion",
+			nodeName: "harvester1",
+			configs:  []FilterConfig{{Hostname: "*", ExcludeVendors: []string{"longhorn"}}},
+			expected: []string{},
+		},
+		{
+			name:     "newly excluded by vendor",
+			nodeName: "harvester1",
+			configs:  []FilterConfig{{Hostname: "*", ExcludeVendors: []string{"samsung"}}},
+			expected: []string{"/dev/sdb"},
+		},
+		{
+			name:     "newly excluded by device path on matching node",
+			nodeName: "harvester1",
+			configs:  []FilterConfig{{Hostname: "harvester*", ExcludeDevices: []string{"/dev/sd*"}}},
+			expected: []string{"/dev/sda", "/dev/sdb"},
+		},
+		{
+			name:     "newly excluded by include rules",
+			nodeName: "harvester1",
+			configs:  []FilterConfig{{Hostname: "*", IncludeModels: []string{"MZ7*"}}, {Hostname: "harvester1", IncludeSizeRange: &SizeRange{Min: "1Ti"}}},
+			expected: []string{"/dev/sda"},
+		},
+		{
+			name:       "newly excluded on node selected by labels",
+			nodeName:   "harvester1",
+			nodeLabels: map[string]string{"rack": "r1"},
+			configs:    []FilterConfig{{NodeSelector: &NodeSelector{MatchLabels: map[string]string{"rack": "r1"}}, ExcludeVendors: []string{"ata"}}},
+			expected:   []string{"/dev/sda"},
+		},
+		{
+			name:       "node selector not matching",
+			nodeName:   "harvester1",
+			nodeLabels: map[string]string{"rack": "r2"},
+			configs:    []FilterConfig{{Hostname: "*", NodeSelector: &NodeSelector{MatchLabels: map[string]string{"rack": "r1"}}, ExcludeVendors: []string{"ata"}}},
+			expected:   []string{},
+		},
+		{
+			name:     "config for another node",
+			nodeName: "harvester1",
+			configs:  []FilterConfig{{Hostname: "harvester2", ExcludeDevices: []string{"/dev/sd*"}}},
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			devPaths := []string{}
+			preview, err := PreviewExclusions(tt.configs, tt.nodeName, tt.nodeLabels, verdicts)
+			assert.NoError(t, err)
+			for _, verdict := range preview {
+				assert.True(t, verdict.Excluded)
+				assert.NotEmpty(t, verdict.Filter)
+				devPaths = append(devPaths, verdict.DevPath)
+			}
+			assert.Equal(t, tt.expected, devPaths)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package filter
+
+import (
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/block"
+)
+
+const (
+	zonedFilterName = "zoned filter"
+)
+
+// diskZonedFilter filters host-managed zoned disks, which only accept sequential writes
+// in their zones, so they can't be formatted with ext4 or used by LVM.
+type diskZonedFilter struct{}
+
+func RegisterZonedFilter() *Filter {
+	return &Filter{
+		Name:       zonedFilterName,
+		DiskFilter: &diskZonedFilter{},
+	}
+}
+
+// Match returns true if the disk is a host-managed zoned device.
+func (f *diskZonedFilter) Match(disk *block.Disk) bool {
+	return disk.ZonedModel == diskv1.ZonedModelHostManaged
+}
+
+// Details returns the zoned filter criteria
+func (f *diskZonedFilter) Details() string {
+	return "exclude: host-managed zoned drives"
+}
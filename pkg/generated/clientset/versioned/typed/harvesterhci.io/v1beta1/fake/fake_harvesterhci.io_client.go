@@ -36,6 +36,14 @@ func (c *FakeHarvesterhciV1beta1) LVMVolumeGroups(namespace string) v1beta1.LVMV
 	return newFakeLVMVolumeGroups(c, namespace)
 }
 
+func (c *FakeHarvesterhciV1beta1) NodeDiskInventories() v1beta1.NodeDiskInventoryInterface {
+	return newFakeNodeDiskInventories(c)
+}
+
+func (c *FakeHarvesterhciV1beta1) NodeDiskManagerConfigs() v1beta1.NodeDiskManagerConfigInterface {
+	return newFakeNodeDiskManagerConfigs(c)
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeHarvesterhciV1beta1) RESTClient() rest.Interface {
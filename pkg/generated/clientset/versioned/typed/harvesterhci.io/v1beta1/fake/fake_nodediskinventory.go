@@ -0,0 +1,52 @@
+/*
+Copyright 2026 SUSE, LLC.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by main. DO NOT EDIT.
+
+package fake
+
+import (
+	v1beta1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	harvesterhciiov1beta1 "github.com/harvester/node-disk-manager/pkg/generated/clientset/versioned/typed/harvesterhci.io/v1beta1"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// fakeNodeDiskInventories implements NodeDiskInventoryInterface
+type fakeNodeDiskInventories struct {
+	*gentype.FakeClientWithList[*v1beta1.NodeDiskInventory, *v1beta1.NodeDiskInventoryList]
+	Fake *FakeHarvesterhciV1beta1
+}
+
+func newFakeNodeDiskInventories(fake *FakeHarvesterhciV1beta1) harvesterhciiov1beta1.NodeDiskInventoryInterface {
+	return &fakeNodeDiskInventories{
+		gentype.NewFakeClientWithList[*v1beta1.NodeDiskInventory, *v1beta1.NodeDiskInventoryList](
+			fake.Fake,
+			"",
+			v1beta1.SchemeGroupVersion.WithResource("nodediskinventories"),
+			v1beta1.SchemeGroupVersion.WithKind("NodeDiskInventory"),
+			func() *v1beta1.NodeDiskInventory { return &v1beta1.NodeDiskInventory{} },
+			func() *v1beta1.NodeDiskInventoryList { return &v1beta1.NodeDiskInventoryList{} },
+			func(dst, src *v1beta1.NodeDiskInventoryList) { dst.ListMeta = src.ListMeta },
+			func(list *v1beta1.NodeDiskInventoryList) []*v1beta1.NodeDiskInventory {
+				return gentype.ToPointerSlice(list.Items)
+			},
+			func(list *v1beta1.NodeDiskInventoryList, items []*v1beta1.NodeDiskInventory) {
+				list.Items = gentype.FromPointerSlice(items)
+			},
+		),
+		fake,
+	}
+}
@@ -21,3 +21,7 @@ package v1beta1
 type BlockDeviceExpansion interface{}
 
 type LVMVolumeGroupExpansion interface{}
+
+type NodeDiskInventoryExpansion interface{}
+
+type NodeDiskManagerConfigExpansion interface{}
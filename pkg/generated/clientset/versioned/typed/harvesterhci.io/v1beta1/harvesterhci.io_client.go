@@ -30,6 +30,8 @@ type HarvesterhciV1beta1Interface interface {
 	RESTClient() rest.Interface
 	BlockDevicesGetter
 	LVMVolumeGroupsGetter
+	NodeDiskInventoriesGetter
+	NodeDiskManagerConfigsGetter
 }
 
 // HarvesterhciV1beta1Client is used to interact with features provided by the harvesterhci.io group.
@@ -45,6 +47,14 @@ func (c *HarvesterhciV1beta1Client) LVMVolumeGroups(namespace string) LVMVolumeG
 	return newLVMVolumeGroups(c, namespace)
 }
 
+func (c *HarvesterhciV1beta1Client) NodeDiskInventories() NodeDiskInventoryInterface {
+	return newNodeDiskInventories(c)
+}
+
+func (c *HarvesterhciV1beta1Client) NodeDiskManagerConfigs() NodeDiskManagerConfigInterface {
+	return newNodeDiskManagerConfigs(c)
+}
+
 // NewForConfig creates a new HarvesterhciV1beta1Client for the given config.
 // NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
 // where httpClient was generated with rest.HTTPClientFor(c).
@@ -0,0 +1,72 @@
+/*
+Copyright 2026 SUSE, LLC.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by main. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	context "context"
+
+	harvesterhciiov1beta1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	scheme "github.com/harvester/node-disk-manager/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	gentype "k8s.io/client-go/gentype"
+)
+
+// NodeDiskInventoriesGetter has a method to return a NodeDiskInventoryInterface.
+// A group's client should implement this interface.
+type NodeDiskInventoriesGetter interface {
+	NodeDiskInventories() NodeDiskInventoryInterface
+}
+
+// NodeDiskInventoryInterface has methods to work with NodeDiskInventory resources.
+type NodeDiskInventoryInterface interface {
+	Create(ctx context.Context, nodeDiskInventory *harvesterhciiov1beta1.NodeDiskInventory, opts v1.CreateOptions) (*harvesterhciiov1beta1.NodeDiskInventory, error)
+	Update(ctx context.Context, nodeDiskInventory *harvesterhciiov1beta1.NodeDiskInventory, opts v1.UpdateOptions) (*harvesterhciiov1beta1.NodeDiskInventory, error)
+	// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+	UpdateStatus(ctx context.Context, nodeDiskInventory *harvesterhciiov1beta1.NodeDiskInventory, opts v1.UpdateOptions) (*harvesterhciiov1beta1.NodeDiskInventory, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*harvesterhciiov1beta1.NodeDiskInventory, error)
+	List(ctx context.Context, opts v1.ListOptions) (*harvesterhciiov1beta1.NodeDiskInventoryList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *harvesterhciiov1beta1.NodeDiskInventory, err error)
+	NodeDiskInventoryExpansion
+}
+
+// nodeDiskInventories implements NodeDiskInventoryInterface
+type nodeDiskInventories struct {
+	*gentype.ClientWithList[*harvesterhciiov1beta1.NodeDiskInventory, *harvesterhciiov1beta1.NodeDiskInventoryList]
+}
+
+// newNodeDiskInventories returns a NodeDiskInventories
+func newNodeDiskInventories(c *HarvesterhciV1beta1Client) *nodeDiskInventories {
+	return &nodeDiskInventories{
+		gentype.NewClientWithList[*harvesterhciiov1beta1.NodeDiskInventory, *harvesterhciiov1beta1.NodeDiskInventoryList](
+			"nodediskinventories",
+			c.RESTClient(),
+			scheme.ParameterCodec,
+			"",
+			func() *harvesterhciiov1beta1.NodeDiskInventory { return &harvesterhciiov1beta1.NodeDiskInventory{} },
+			func() *harvesterhciiov1beta1.NodeDiskInventoryList {
+				return &harvesterhciiov1beta1.NodeDiskInventoryList{}
+			},
+		),
+	}
+}
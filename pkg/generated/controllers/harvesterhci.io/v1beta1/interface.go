@@ -33,6 +33,8 @@ func init() {
 type Interface interface {
 	BlockDevice() BlockDeviceController
 	LVMVolumeGroup() LVMVolumeGroupController
+	NodeDiskInventory() NodeDiskInventoryController
+	NodeDiskManagerConfig() NodeDiskManagerConfigController
 }
 
 func New(controllerFactory controller.SharedControllerFactory) Interface {
@@ -52,3 +54,11 @@ func (v *version) BlockDevice() BlockDeviceController {
 func (v *version) LVMVolumeGroup() LVMVolumeGroupController {
 	return generic.NewController[*v1beta1.LVMVolumeGroup, *v1beta1.LVMVolumeGroupList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "LVMVolumeGroup"}, "lvmvolumegroups", true, v.controllerFactory)
 }
+
+func (v *version) NodeDiskInventory() NodeDiskInventoryController {
+	return generic.NewNonNamespacedController[*v1beta1.NodeDiskInventory, *v1beta1.NodeDiskInventoryList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "NodeDiskInventory"}, "nodediskinventories", v.controllerFactory)
+}
+
+func (v *version) NodeDiskManagerConfig() NodeDiskManagerConfigController {
+	return generic.NewNonNamespacedController[*v1beta1.NodeDiskManagerConfig, *v1beta1.NodeDiskManagerConfigList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "NodeDiskManagerConfig"}, "nodediskmanagerconfigs", v.controllerFactory)
+}
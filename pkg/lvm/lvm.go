@@ -66,6 +66,10 @@ func DoPVCreate(devPath string) error {
 	return executeCommandWithNS("pvcreate", []string{devPath})
 }
 
+func DoPVResize(devPath string) error {
+	return executeCommandWithNS("pvresize", []string{devPath})
+}
+
 func DoVGCreate(devPath, vgName string) error {
 	return executeCommandWithNS("vgcreate", []string{vgName, devPath})
 }
@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/sirupsen/logrus"
+)
+
+const namespace = "ndm"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	trimRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fstrim_runs_total",
+		Help:      "Number of scheduled fstrim runs on a block device, by result",
+	}, []string{"blockdevice", "result"})
+	trimmedBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fstrim_trimmed_bytes_total",
+		Help:      "Bytes reported as trimmed by the scheduled fstrim runs on a block device",
+	}, []string{"blockdevice"})
+	lastTrim = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "fstrim_last_run_timestamp_seconds",
+		Help:      "Time of the last scheduled fstrim run on a block device",
+	}, []string{"blockdevice"})
+
+	ioStatsLabels  = []string{"blockdevice", "node", "tags", "provisioner"}
+	readsPerSecond = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "disk_reads_per_second",
+		Help:      "Reads completed per second by the disk of a block device over the last sampling window",
+	}, ioStatsLabels)
+	writesPerSecond = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "disk_writes_per_second",
+		Help:      "Writes completed per second by the disk of a block device over the last sampling window",
+	}, ioStatsLabels)
+	readBytesPerSecond = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "disk_read_bytes_per_second",
+		Help:      "Bytes read per second from the disk of a block device over the last sampling window",
+	}, ioStatsLabels)
+	writtenBytesPerSecond = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "disk_written_bytes_per_second",
+		Help:      "Bytes written per second to the disk of a block device over the last sampling window",
+	}, ioStatsLabels)
+	readLatency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "disk_read_latency_seconds",
+		Help:      "Average time of the reads completed by the disk of a block device over the last sampling window",
+	}, ioStatsLabels)
+	writeLatency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "disk_write_latency_seconds",
+		Help:      "Average time of the writes completed by the disk of a block device over the last sampling window",
+	}, ioStatsLabels)
+	utilization = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "disk_utilization_ratio",
+		Help:      "Fraction of the last sampling window the disk of a block device was busy with I/O",
+	}, ioStatsLabels)
+)
+
+func init() {
+	registry.MustRegister(trimRuns, trimmedBytes, lastTrim)
+	registry.MustRegister(readsPerSecond, writesPerSecond, readBytesPerSecond, writtenBytesPerSecond,
+		readLatency, writeLatency, utilization)
+}
+
+// IOStatsLabels are the labels of the I/O statistics of a block device
+type IOStatsLabels struct {
+	BlockDevice string
+	Node        string
+	// Tags are the comma-separated tags of the block device
+	Tags        string
+	Provisioner string
+}
+
+// IORates are the I/O statistics of a disk over a sampling window
+type IORates struct {
+	ReadsPerSecond        float64
+	WritesPerSecond       float64
+	ReadBytesPerSecond    float64
+	WrittenBytesPerSecond float64
+	ReadLatencySeconds    float64
+	WriteLatencySeconds   float64
+	// Utilization is the fraction of the window the disk was busy, from 0 to 1
+	Utilization float64
+}
+
+// ObserveIOStats records the I/O statistics of the disk of a block device. The series of the
+// block device with other labels, e.g. before its tags changed, must be deleted first.
+func ObserveIOStats(labels IOStatsLabels, rates IORates) {
+	values := []string{labels.BlockDevice, labels.Node, labels.Tags, labels.Provisioner}
+	readsPerSecond.WithLabelValues(values...).Set(rates.ReadsPerSecond)
+	writesPerSecond.WithLabelValues(values...).Set(rates.WritesPerSecond)
+	readBytesPerSecond.WithLabelValues(values...).Set(rates.ReadBytesPerSecond)
+	writtenBytesPerSecond.WithLabelValues(values...).Set(rates.WrittenBytesPerSecond)
+	readLatency.WithLabelValues(values...).Set(rates.ReadLatencySeconds)
+	writeLatency.WithLabelValues(values...).Set(rates.WriteLatencySeconds)
+	utilization.WithLabelValues(values...).Set(rates.Utilization)
+}
+
+// DeleteIOStats drops the I/O statistics series of a block device
+func DeleteIOStats(blockDevice string) {
+	labels := prometheus.Labels{"blockdevice": blockDevice}
+	for _, vec := range []*prometheus.GaugeVec{readsPerSecond, writesPerSecond, readBytesPerSecond,
+		writtenBytesPerSecond, readLatency, writeLatency, utilization} {
+		vec.DeletePartialMatch(labels)
+	}
+}
+
+// ObserveTrim records a scheduled fstrim run on the block device
+func ObserveTrim(blockDevice string, bytes int64, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	trimRuns.WithLabelValues(blockDevice, result).Inc()
+	trimmedBytes.WithLabelValues(blockDevice).Add(float64(bytes))
+	lastTrim.WithLabelValues(blockDevice).SetToCurrentTime()
+}
+
+// DeleteBlockDevice drops the series of a removed block device
+func DeleteBlockDevice(blockDevice string) {
+	labels := prometheus.Labels{"blockdevice": blockDevice}
+	trimRuns.DeletePartialMatch(labels)
+	trimmedBytes.DeletePartialMatch(labels)
+	lastTrim.DeletePartialMatch(labels)
+	DeleteIOStats(blockDevice)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		families, err := registry.Gather()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		format := expfmt.NewFormat(expfmt.TypeTextPlain)
+		w.Header().Set("Content-Type", string(format))
+		encoder := expfmt.NewEncoder(w, format)
+		for _, family := range families {
+			if err := encoder.Encode(family); err != nil {
+				logrus.Warnf("Failed to encode metric %s: %v", family.GetName(), err)
+				return
+			}
+		}
+	})
+}
+
+// Serve serves the metrics on /metrics until the context is done
+func Serve(ctx context.Context, address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	logrus.Infof("Serving metrics on %s/metrics", address)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logrus.Errorf("Failed to serve metrics: %v", err)
+	}
+}
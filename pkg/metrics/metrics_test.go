@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatherSeries returns the values of the series of the registry, keyed by
+// the metric name and the labels, e.g. ndm_fstrim_runs_total{bd,success}
+func gatherSeries(t *testing.T) map[string]float64 {
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	series := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := ""
+			for i, label := range metric.GetLabel() {
+				if i > 0 {
+					labels += ","
+				}
+				labels += label.GetValue()
+			}
+			value := metric.GetCounter().GetValue() + metric.GetGauge().GetValue()
+			series[fmt.Sprintf("%s{%s}", family.GetName(), labels)] = value
+		}
+	}
+	return series
+}
+
+func TestObserveTrim(t *testing.T) {
+	defer DeleteBlockDevice("bd")
+
+	ObserveTrim("bd", 1024, nil)
+	ObserveTrim("bd", 2048, nil)
+	ObserveTrim("bd", 0, fmt.Errorf("fstrim: the discard operation is not supported"))
+	series := gatherSeries(t)
+	assert.Equal(t, float64(2), series["ndm_fstrim_runs_total{bd,success}"])
+	assert.Equal(t, float64(1), series["ndm_fstrim_runs_total{bd,failure}"])
+	assert.Equal(t, float64(3072), series["ndm_fstrim_trimmed_bytes_total{bd}"])
+	assert.NotZero(t, series["ndm_fstrim_last_run_timestamp_seconds{bd}"])
+
+	DeleteBlockDevice("bd")
+	assert.Empty(t, gatherSeries(t))
+}
+
+func TestHandler(t *testing.T) {
+	defer DeleteBlockDevice("bd")
+	ObserveTrim("bd", 1024, nil)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, recorder.Body.String(), `ndm_fstrim_trimmed_bytes_total{blockdevice="bd"} 1024`)
+}
+
+func TestObserveIOStats(t *testing.T) {
+	defer DeleteBlockDevice("bd")
+	defer DeleteBlockDevice("other")
+
+	labels := IOStatsLabels{BlockDevice: "bd", Node: "node1", Tags: "fast,ssd", Provisioner: "LonghornV1"}
+	ObserveIOStats(labels, IORates{ReadsPerSecond: 10, WrittenBytesPerSecond: 4096, WriteLatencySeconds: 0.02, Utilization: 0.5})
+	ObserveIOStats(IOStatsLabels{BlockDevice: "other", Node: "node1"}, IORates{ReadsPerSecond: 1})
+	series := gatherSeries(t)
+	assert.Equal(t, float64(10), series["ndm_disk_reads_per_second{bd,node1,LonghornV1,fast,ssd}"])
+	assert.Equal(t, float64(4096), series["ndm_disk_written_bytes_per_second{bd,node1,LonghornV1,fast,ssd}"])
+	assert.Equal(t, 0.02, series["ndm_disk_write_latency_seconds{bd,node1,LonghornV1,fast,ssd}"])
+	assert.Equal(t, 0.5, series["ndm_disk_utilization_ratio{bd,node1,LonghornV1,fast,ssd}"])
+
+	DeleteIOStats("bd")
+	for name := range gatherSeries(t) {
+		assert.NotContains(t, name, "{bd,")
+	}
+	assert.Equal(t, float64(1), gatherSeries(t)["ndm_disk_reads_per_second{other,node1,,}"])
+}
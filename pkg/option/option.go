@@ -1,5 +1,7 @@
 package option
 
+import "time"
+
 type Option struct {
 	KubeConfig  string
 	Namespace   string
@@ -16,4 +18,15 @@ type Option struct {
 	AutoProvisionFilter    string
 	MaxConcurrentOps       uint
 	InjectUdevMonitorError bool
+	DryRun                 bool
+	RescanInterval         time.Duration
+	UdevRulesFile          string
+	CordonReadOnlyDisks    bool
+	PersistMounts          bool
+	MetricsAddress         string
+	// FilesystemPressureThreshold and InodePressureThreshold are percentages
+	FilesystemPressureThreshold uint
+	InodePressureThreshold      uint
+	// IOStatsWindow is the window the I/O statistics of the disks are sampled over
+	IOStatsWindow time.Duration
 }
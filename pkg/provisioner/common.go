@@ -33,6 +33,45 @@ const (
 	NeedMountUpdateUnmount
 )
 
+const (
+	// DeviceMountedReasonUnmounted is the reason of the Mounted condition of a
+	// provisioned disk found unmounted from its mount point
+	DeviceMountedReasonUnmounted = "UnexpectedUnmount"
+	// DeviceMountedReasonMoved is the reason of the Mounted condition of a
+	// provisioned disk found mounted at another mount point
+	DeviceMountedReasonMoved = "MountPointMoved"
+	// DeviceMountedReasonRemounted is the reason of the Mounted condition of a
+	// provisioned disk mounted back at its mount point
+	DeviceMountedReasonRemounted = "Remounted"
+
+	// FilesystemHealthyReasonReadOnly is the reason of the FilesystemHealthy condition
+	// of a provisioned disk whose filesystem was remounted read-only by the kernel
+	FilesystemHealthyReasonReadOnly = "ReadOnly"
+	// FilesystemHealthyReasonRecovered is the reason of the FilesystemHealthy condition
+	// of a provisioned disk whose filesystem is writable again
+	FilesystemHealthyReasonRecovered = "Recovered"
+	// FilesystemHealthyReasonRepaired is the reason of the FilesystemHealthy condition
+	// of a provisioned disk mounted back after the admin repaired its filesystem
+	FilesystemHealthyReasonRepaired = "Repaired"
+	// FilesystemHealthyReasonHealthy is the reason of the FilesystemHealthy condition
+	// once the Longhorn scheduling on a recovered disk was resumed
+	FilesystemHealthyReasonHealthy = "Healthy"
+
+	// MountPersistedReasonPersisted is the reason of the MountPersisted condition
+	// of a disk whose mount unit matches the block device
+	MountPersistedReasonPersisted = "Persisted"
+	// MountPersistedReasonDrifted is the reason of the MountPersisted condition
+	// of a disk whose mount unit drifted from the block device and was rewritten
+	MountPersistedReasonDrifted = "Drifted"
+	// MountPersistedReasonRemoved is the reason of the MountPersisted condition
+	// of a disk whose mount unit was removed as the disk was unmounted
+	MountPersistedReasonRemoved = "Removed"
+
+	// defaultExtraDiskMountRoot is the directory the LonghornV1 disks are mounted
+	// under when the NodeDiskManagerConfig doesn't set one for the node
+	defaultExtraDiskMountRoot = "/var/lib/harvester/extra-disks"
+)
+
 func (f NeedMountUpdateOP) Has(flag NeedMountUpdateOP) bool {
 	return f&flag != 0
 }
@@ -62,6 +101,11 @@ type Provisioner interface {
 	// Like tags on the longhorn nodes, ensure the vg active for LVM ...etc
 	// Return values: bool: isRequeueNeeded, error: error
 	Update() (bool, error)
+
+	// Resize grows the provisioned storage after the capacity of the device grew,
+	// e.g. the filesystem or the LVM physical volume
+	// Return values: bool: isRequeueNeeded, error: error
+	Resize(string) (bool, error)
 }
 
 type provisioner struct {
@@ -113,6 +157,23 @@ func SetCondDeviceFormattingFail(device *diskv1.BlockDevice, err error) {
 	diskv1.DeviceFormatting.SetStatusBool(device, false)
 }
 
+// NeedsRemount returns true if the provisioned disk was found unmounted from its
+// mount point, or mounted at another one
+func NeedsRemount(device *diskv1.BlockDevice) bool {
+	if !diskv1.DeviceMounted.IsFalse(device) {
+		return false
+	}
+	reason := diskv1.DeviceMounted.GetReason(device)
+	return reason == DeviceMountedReasonUnmounted || reason == DeviceMountedReasonMoved
+}
+
+// RepairInProgress returns true if the repair requested on the block device was
+// accepted and is about to run or running
+func RepairInProgress(device *diskv1.BlockDevice) bool {
+	repair := device.Status.DeviceStatus.FileSystem.LastRepair
+	return repair != nil && repair.InProgress
+}
+
 // DiskTags is a cache mechanism for the blockdevices Tags (spec.Tags), it only changed from Harvester side.
 type DiskTags struct {
 	diskTags    map[string][]string
@@ -183,8 +244,8 @@ func NewSemaphore(n uint) *Semaphore {
 	}
 }
 
-// acquire a semaphore to prevent concurrent update
-func (s *Semaphore) acquire() bool {
+// Acquire a semaphore to prevent concurrent update, false if it is full
+func (s *Semaphore) Acquire() bool {
 	logrus.Debugf("Pre-acquire channel stats: %d/%d", len(s.ch), cap(s.ch))
 	select {
 	case s.ch <- struct{}{}:
@@ -195,8 +256,8 @@ func (s *Semaphore) acquire() bool {
 	}
 }
 
-// release the semaphore
-func (s *Semaphore) release() bool {
+// Release the semaphore
+func (s *Semaphore) Release() bool {
 	select {
 	case <-s.ch:
 		return true
@@ -0,0 +1,53 @@
+package provisioner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+func TestNeedsRemount(t *testing.T) {
+	tests := []struct {
+		name    string
+		mounted *bool
+		reason  string
+		remount bool
+	}{
+		{
+			name: "no mount condition",
+		},
+		{
+			name:    "mounted",
+			mounted: &[]bool{true}[0],
+			reason:  DeviceMountedReasonRemounted,
+		},
+		{
+			name:    "unmounted from its mount point",
+			mounted: &[]bool{false}[0],
+			reason:  DeviceMountedReasonUnmounted,
+			remount: true,
+		},
+		{
+			name:    "mounted at another mount point",
+			mounted: &[]bool{false}[0],
+			reason:  DeviceMountedReasonMoved,
+			remount: true,
+		},
+		{
+			name:    "unmounted by NDM",
+			mounted: &[]bool{false}[0],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := newResizeDevice()
+			if tt.mounted != nil {
+				diskv1.DeviceMounted.SetStatusBool(device, *tt.mounted)
+				diskv1.DeviceMounted.Reason(device, tt.reason)
+			}
+			assert.Equal(t, tt.remount, NeedsRemount(device))
+		})
+	}
+}
@@ -3,8 +3,10 @@ package provisioner
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
 	"reflect"
 	"slices"
+	"strings"
 	"time"
 
 	gocommon "github.com/harvester/go-common/ds"
@@ -18,6 +20,12 @@ import (
 	"github.com/harvester/node-disk-manager/pkg/utils"
 )
 
+// resizeFilesystem grows the filesystem of a device, replaced by the tests
+var resizeFilesystem = utils.ResizeExt4
+
+// repairFilesystem repairs the filesystem of a device, replaced by the tests
+var repairFilesystem = utils.RepairFilesystem
+
 type LonghornV1Provisioner struct {
 	*provisioner
 	nodeObj          *longhornv1.Node
@@ -26,6 +34,10 @@ type LonghornV1Provisioner struct {
 
 	cacheDiskTags *DiskTags
 	semaphoreObj  *Semaphore
+	// cordonReadOnly disables the scheduling on the disk while its filesystem is read-only
+	cordonReadOnly bool
+	// persistMounts persists the mount of the disk in a systemd mount unit on the host
+	persistMounts bool
 }
 
 func NewLHV1Provisioner(
@@ -36,6 +48,8 @@ func NewLHV1Provisioner(
 	nodesClientCache ctllonghornv1.NodeCache,
 	cacheDiskTags *DiskTags,
 	semaphore *Semaphore,
+	cordonReadOnly bool,
+	persistMounts bool,
 ) (Provisioner, error) {
 	baseProvisioner := &provisioner{
 		name:      TypeLonghornV1,
@@ -49,6 +63,8 @@ func NewLHV1Provisioner(
 		nodesClientCache: nodesClientCache,
 		cacheDiskTags:    cacheDiskTags,
 		semaphoreObj:     semaphore,
+		cordonReadOnly:   cordonReadOnly,
+		persistMounts:    persistMounts,
 	}
 
 	if !cacheDiskTags.Initialized() {
@@ -74,7 +90,7 @@ func (p *LonghornV1Provisioner) Provision() (bool, error) {
 	}
 	diskSpec := longhornv1.DiskSpec{
 		Type:              longhornv1.DiskTypeFilesystem,
-		Path:              extraDiskMountPoint(p.device),
+		Path:              ExtraDiskMountPoint(p.device),
 		AllowScheduling:   true,
 		EvictionRequested: false,
 		StorageReserved:   0,
@@ -117,6 +133,8 @@ func (p *LonghornV1Provisioner) UnProvision() (bool, error) {
 	// inner functions
 	updateProvisionPhaseUnprovisioned := func() {
 		p.device.Spec.FileSystem.Provisioned = false
+		// the mount root configured at that time is used when the disk is provisioned again
+		p.device.Status.DeviceStatus.FileSystem.MountRoot = ""
 		msg := fmt.Sprintf("Disk not in longhorn node `%s`", p.nodeObj.Name)
 		setCondDiskAddedToNodeFalse(p.device, msg, diskv1.ProvisionPhaseUnprovisioned)
 	}
@@ -191,6 +209,10 @@ func (p *LonghornV1Provisioner) UnProvision() (bool, error) {
 }
 
 func (p *LonghornV1Provisioner) unmountTheBrokenDisk() error {
+	// an inactive disk has no mount left, but its mount unit would still be mounted on boot
+	if err := p.removeMountUnit(ExtraDiskMountPoint(p.device)); err != nil {
+		return err
+	}
 	filesystem := p.blockInfo.GetFileSystemInfoByDevPath(p.device.Status.DeviceStatus.DevPath)
 	if filesystem != nil && filesystem.MountPoint != "" {
 		if err := utils.ForceUmountWithTimeout(filesystem.MountPoint, 30*time.Second); err != nil {
@@ -241,6 +263,11 @@ func (p *LonghornV1Provisioner) Update() (bool, error) {
 		logrus.Warnf("disk %s not in disks of longhorn node, was it already provisioned?", p.device.Name)
 		return false, nil
 	}
+	if err := p.syncReadOnlyCordon(); err != nil {
+		return true, err
+	}
+	// the scheduling may have changed
+	targetDisk = p.nodeObj.Spec.Disks[p.device.Name]
 	DiskTagsSynced := gocommon.SliceContentCmp(p.device.Spec.Tags, p.cacheDiskTags.GetDiskTags(p.device.Name))
 	if !DiskTagsSynced || (DiskTagsSynced && DiskTagsOnNodeMissed(targetDisk)) {
 		// The final tags: DiskSpec.Tags - DiskCacheTags + Device.Spec.Tags
@@ -262,6 +289,19 @@ func (p *LonghornV1Provisioner) Update() (bool, error) {
 	return false, nil
 }
 
+// Resize grows the ext4 filesystem online, so Longhorn sees the new space
+func (p *LonghornV1Provisioner) Resize(devPath string) (bool, error) {
+	logrus.WithFields(logrus.Fields{
+		"provisioner": p.name,
+		"device":      p.device.Name,
+		"devPath":     devPath,
+	}).Info("Growing the filesystem of the device")
+	if err := resizeFilesystem(devPath); err != nil {
+		return true, err
+	}
+	return false, nil
+}
+
 func (p *LonghornV1Provisioner) Format(devPath string) (bool, bool, error) {
 	logrus.WithFields(logrus.Fields{
 		"provisioner": p.name,
@@ -275,6 +315,14 @@ func (p *LonghornV1Provisioner) Format(devPath string) (bool, bool, error) {
 	filesystem := p.blockInfo.GetFileSystemInfoByDevPath(devPath)
 	devPathStatus := convertFSInfoToString(filesystem)
 	logrus.Debugf("Get filesystem info from device %s, %s", devPath, devPathStatus)
+	if p.device.Spec.FileSystem.Repair || RepairInProgress(p.device) {
+		requeue, err = p.repairFS(devPath, filesystem)
+		return formatted, requeue, err
+	}
+	if NeedsRemount(p.device) {
+		requeue, err = p.remount(devPath, filesystem)
+		return formatted, requeue, err
+	}
 	if p.needFormat() {
 		logrus.Infof("Prepare to force format device %s", p.device.Name)
 		requeue, err = p.forceFormatFS(p.device, devPath, filesystem)
@@ -295,10 +343,273 @@ func (p *LonghornV1Provisioner) Format(devPath string) (bool, bool, error) {
 		}
 		return formatted, requeue, err
 	}
+	if needUpdateMountOptions(p.device, filesystem) {
+		options := p.device.Spec.FileSystem.MountOptions
+		var err error
+		if mountOptionsRemoved(p.device) {
+			// a remount keeps the options it isn't given, only a new mount drops them
+			logrus.Infof("Mount device %s again with the mount options %v", p.device.Name, options)
+			err = p.updateDeviceMount(p.device, devPath, filesystem, NeedMountUpdateUnmount|NeedMountUpdateMount)
+		} else {
+			logrus.Infof("Remount device %s with the mount options %v", p.device.Name, options)
+			err = utils.RemountDisk(filesystem.MountPoint, options)
+		}
+		if err != nil {
+			err := fmt.Errorf("failed to apply the mount options of device %s: %s", p.device.Name, err.Error())
+			diskv1.DeviceMounted.Message(p.device, err.Error())
+			return formatted, true, err
+		}
+		p.device.Status.DeviceStatus.FileSystem.MountOptions = options
+		diskv1.DeviceMounted.Message(p.device, "")
+	}
+	p.syncMountUnit(devPath, filesystem)
 	formatted = true
 	return formatted, false, nil
 }
 
+// repairFS runs the repair requested with spec.fileSystem.repair on the unmounted
+// filesystem, and reports the outcome in the status. On success the filesystem is no
+// longer flagged as corrupted, and the disk is mounted back by the next reconciliation.
+//
+// The request is first reset and the repair flagged as in progress, and the tool only
+// runs on the next reconciliation, so a write of the block device while the tool runs
+// doesn't lead to a second run. The repair runs in the handler and may take up to an
+// hour on a large disk, so it holds one of the controller workers and a slot of the
+// concurrent operations until it completes. The other block devices are reconciled by
+// the remaining workers.
+func (p *LonghornV1Provisioner) repairFS(devPath string, filesystem *block.FileSystemInfo) (bool, error) {
+	status := &diskv1.FilesystemRepairStatus{ExitCode: -1}
+	fsType := repairFSType(p.device, filesystem)
+	cmd, args, cmdErr := utils.RepairCommand(devPath, fsType)
+	switch {
+	case filesystem != nil && filesystem.MountPoint != "":
+		status.Output = fmt.Sprintf("filesystem is mounted at %s, unmount it first", filesystem.MountPoint)
+	case cmdErr != nil:
+		status.Output = cmdErr.Error()
+	case p.device.Spec.FileSystem.Repair:
+		logrus.Infof("Accept the repair of the %s filesystem of device %s", fsType, p.device.Name)
+		p.device.Spec.FileSystem.Repair = false
+		p.device.Status.DeviceStatus.FileSystem.LastRepair = &diskv1.FilesystemRepairStatus{
+			Time:       metav1.Now(),
+			InProgress: true,
+			Command:    strings.Join(append([]string{cmd}, args...), " "),
+			ExitCode:   -1,
+		}
+		return false, nil
+	default:
+		if !p.semaphoreObj.Acquire() {
+			logrus.Infof("Hit maximum concurrent count. Requeue device %s", p.device.Name)
+			return true, nil
+		}
+		defer p.semaphoreObj.Release()
+
+		logrus.Infof("Repair the %s filesystem of device %s", fsType, p.device.Name)
+		result, err := repairFilesystem(devPath, fsType)
+		if err != nil {
+			status.Command = strings.Join(append([]string{cmd}, args...), " ")
+			status.Output = err.Error()
+		} else {
+			status.Command = result.Command
+			status.ExitCode = result.ExitCode
+			status.Output = result.Output
+			status.Succeeded = result.Succeeded
+		}
+	}
+
+	status.Time = metav1.Now()
+	p.device.Status.DeviceStatus.FileSystem.LastRepair = status
+	// the request is served whatever the outcome, it can be set again to retry
+	p.device.Spec.FileSystem.Repair = false
+	if status.Succeeded {
+		p.device.Status.DeviceStatus.FileSystem.Corrupted = false
+		p.device.Spec.FileSystem.Repaired = true
+	}
+	return false, nil
+}
+
+// repairFSType returns the type of the filesystem to repair, the type found on the
+// device wins over the one last reported in the status
+func repairFSType(device *diskv1.BlockDevice, filesystem *block.FileSystemInfo) string {
+	if filesystem != nil && filesystem.Type != "" {
+		return filesystem.Type
+	}
+	return device.Status.DeviceStatus.FileSystem.Type
+}
+
+// remount mounts back a disk which was unmounted or moved behind NDM's back. The
+// Longhorn scheduling on the disk is suspended until the disk is back at its mount
+// point, so no replica lands on the root filesystem underneath.
+func (p *LonghornV1Provisioner) remount(devPath string, filesystem *block.FileSystemInfo) (bool, error) {
+	reason := diskv1.DeviceMounted.GetReason(p.device)
+	failed := func(err error) (bool, error) {
+		diskv1.DeviceMounted.SetStatusBool(p.device, false)
+		diskv1.DeviceMounted.Reason(p.device, reason)
+		diskv1.DeviceMounted.Message(p.device, fmt.Sprintf("Failed to remount: %v", err))
+		return true, err
+	}
+
+	// only the scheduling suspended here is resumed after the remount, the admin or
+	// the read-only cordon may have disabled it on purpose
+	if targetDisk, found := p.nodeObj.Spec.Disks[p.device.Name]; found && targetDisk.AllowScheduling {
+		if err := p.setDiskScheduling(false); err != nil {
+			return failed(err)
+		}
+		p.device.Status.SchedulingSuspended = true
+	}
+	if diskv1.FilesystemHealthy.IsFalse(p.device) && !p.device.Spec.FileSystem.Repaired {
+		// don't race with the admin repairing the unmounted filesystem
+		diskv1.DeviceMounted.Message(p.device, "Waiting for the filesystem repair, set spec.fileSystem.repair to repair it")
+		return false, nil
+	}
+	if filesystem == nil {
+		return failed(fmt.Errorf("failed to get filesystem info from devPath %s", devPath))
+	}
+
+	expectedMountPoint := ExtraDiskMountPoint(p.device)
+	if needMountUpdate := needUpdateMountPoint(p.device, filesystem); needMountUpdate != NeedMountUpdateNoOp {
+		// never stack the disk on top of another mount
+		source, err := utils.GetMountSource(expectedMountPoint)
+		if err != nil {
+			return failed(err)
+		}
+		if source != "" {
+			return failed(fmt.Errorf("mount point %s is already used by %s", expectedMountPoint, source))
+		}
+		if !p.semaphoreObj.Acquire() {
+			logrus.Infof("Hit maximum concurrent count. Requeue device %s", p.device.Name)
+			return true, nil
+		}
+		defer p.semaphoreObj.Release()
+		if err := p.updateDeviceMount(p.device, devPath, filesystem, needMountUpdate); err != nil {
+			return failed(err)
+		}
+	}
+
+	diskv1.DeviceMounted.SetStatusBool(p.device, true)
+	diskv1.DeviceMounted.Reason(p.device, DeviceMountedReasonRemounted)
+	diskv1.DeviceMounted.Message(p.device, fmt.Sprintf("Remounted at %s", expectedMountPoint))
+	if diskv1.FilesystemHealthy.IsFalse(p.device) {
+		diskv1.FilesystemHealthy.SetStatusBool(p.device, true)
+		diskv1.FilesystemHealthy.Reason(p.device, FilesystemHealthyReasonRepaired)
+		diskv1.FilesystemHealthy.Message(p.device, "Remounted after the filesystem repair")
+		// a later fault must be repaired again
+		p.device.Spec.FileSystem.Repaired = false
+		// the read-only cordon suspended the scheduling of the faulty filesystem
+		// and only resumes it on recovery, which is this remount
+		if p.cordonReadOnly {
+			p.device.Status.SchedulingSuspended = true
+		}
+	}
+	if p.device.Status.SchedulingSuspended {
+		if err := p.setDiskScheduling(true); err != nil {
+			return failed(err)
+		}
+		p.device.Status.SchedulingSuspended = false
+	}
+	return false, nil
+}
+
+// syncReadOnlyCordon disables the scheduling on the disk while its filesystem is
+// read-only, and allows it again once the filesystem recovered
+func (p *LonghornV1Provisioner) syncReadOnlyCordon() error {
+	if !p.cordonReadOnly {
+		return nil
+	}
+	if diskv1.FilesystemHealthy.IsFalse(p.device) {
+		return p.setDiskScheduling(false)
+	}
+	if diskv1.FilesystemHealthy.GetReason(p.device) != FilesystemHealthyReasonRecovered {
+		return nil
+	}
+	if err := p.setDiskScheduling(true); err != nil {
+		return err
+	}
+	// resume the scheduling only once, so a later change by the admin is kept
+	diskv1.FilesystemHealthy.Reason(p.device, FilesystemHealthyReasonHealthy)
+	return nil
+}
+
+// setDiskScheduling allows or suspends the scheduling of Longhorn replicas on the disk
+func (p *LonghornV1Provisioner) setDiskScheduling(allow bool) error {
+	targetDisk, found := p.nodeObj.Spec.Disks[p.device.Name]
+	if !found || targetDisk.AllowScheduling == allow {
+		return nil
+	}
+	if allow && (slices.Contains(targetDisk.Tags, utils.DiskRemoveTag) || NeedsRemount(p.device) ||
+		(p.cordonReadOnly && diskv1.FilesystemHealthy.IsFalse(p.device))) {
+		// the disk is being unprovisioned or is still faulty, keep it excluded
+		return nil
+	}
+
+	logrus.Infof("Set scheduling of disk %s on longhorn node %s to %v", p.device.Name, p.nodeObj.Name, allow)
+	targetDisk.AllowScheduling = allow
+	nodeCpy := p.nodeObj.DeepCopy()
+	nodeCpy.Spec.Disks[p.device.Name] = targetDisk
+	node, err := p.nodesClient.Update(nodeCpy)
+	if err != nil {
+		return err
+	}
+	p.nodeObj = node
+	return nil
+}
+
+// syncMountUnit persists the mount of the disk in a systemd mount unit on the host, so the
+// disk is mounted on boot before Longhorn starts. A unit which drifted from the block
+// device is rewritten, and the drift is reported in the MountPersisted condition.
+func (p *LonghornV1Provisioner) syncMountUnit(devPath string, filesystem *block.FileSystemInfo) {
+	if !p.persistMounts || !p.device.Spec.Provision || filesystem == nil || filesystem.MountPoint != ExtraDiskMountPoint(p.device) {
+		return
+	}
+	failed := func(err error) {
+		logrus.Warnf("Failed to persist the mount of device %s: %v", p.device.Name, err)
+		diskv1.MountPersisted.SetError(p.device, "", err)
+		diskv1.MountPersisted.SetStatusBool(p.device, false)
+	}
+
+	disk := p.blockInfo.GetDiskByDevPath(devPath)
+	if disk == nil || disk.UUID == "" {
+		failed(fmt.Errorf("no filesystem UUID found on %s", devPath))
+		return
+	}
+	unit := &utils.MountUnit{
+		UUID:       disk.UUID,
+		MountPoint: ExtraDiskMountPoint(p.device),
+		Options:    p.device.Status.DeviceStatus.FileSystem.MountOptions,
+	}
+	drift, err := utils.SyncMountUnit(unit)
+	if err != nil {
+		failed(fmt.Errorf("failed to persist the mount in %s: %w", unit.Name(), err))
+		return
+	}
+
+	diskv1.MountPersisted.SetError(p.device, "", nil)
+	diskv1.MountPersisted.SetStatusBool(p.device, true)
+	if len(drift) > 0 {
+		logrus.Infof("Rewrote the drifted mount unit %s of device %s: %v", unit.Name(), p.device.Name, drift)
+		diskv1.MountPersisted.Reason(p.device, MountPersistedReasonDrifted)
+		diskv1.MountPersisted.Message(p.device, fmt.Sprintf("Rewrote the drifted unit %s: %s", unit.Name(), strings.Join(drift, "; ")))
+		return
+	}
+	diskv1.MountPersisted.Reason(p.device, MountPersistedReasonPersisted)
+	diskv1.MountPersisted.Message(p.device, fmt.Sprintf("Persisted in %s", unit.Name()))
+}
+
+// removeMountUnit removes the mount unit of the disk, so the host no longer mounts it on boot
+func (p *LonghornV1Provisioner) removeMountUnit(mountPoint string) error {
+	if !p.persistMounts {
+		return nil
+	}
+	if err := utils.RemoveMountUnit(mountPoint); err != nil {
+		return fmt.Errorf("failed to remove the mount unit of %s: %w", mountPoint, err)
+	}
+	if diskv1.MountPersisted.GetStatus(p.device) != "" {
+		diskv1.MountPersisted.SetStatusBool(p.device, false)
+		diskv1.MountPersisted.Reason(p.device, MountPersistedReasonRemoved)
+		diskv1.MountPersisted.Message(p.device, fmt.Sprintf("Removed the unit %s", utils.MountUnitName(mountPoint)))
+	}
+	return nil
+}
+
 func (p *LonghornV1Provisioner) UnFormat() (bool, error) {
 	logrus.Infof("%s unformatting Longhorn block device %s", p.name, p.device.Name)
 	return false, nil
@@ -316,11 +627,15 @@ func (p *LonghornV1Provisioner) updateDeviceMount(device *diskv1.BlockDevice, de
 		}
 		diskv1.DeviceMounted.SetError(device, "", nil)
 		diskv1.DeviceMounted.SetStatusBool(device, false)
+		device.Status.DeviceStatus.FileSystem.MountOptions = nil
+		if err := p.removeMountUnit(filesystem.MountPoint); err != nil {
+			return err
+		}
 	}
 	if needMountUpdate.Has(NeedMountUpdateMount) {
-		expectedMountPoint := extraDiskMountPoint(device)
+		expectedMountPoint := ExtraDiskMountPoint(device)
 		logrus.Infof("Mount device %s to %s", device.Name, expectedMountPoint)
-		if err := utils.MountDisk(devPath, expectedMountPoint); err != nil {
+		if err := utils.MountDisk(devPath, expectedMountPoint, device.Spec.FileSystem.MountOptions); err != nil {
 			if utils.IsFSCorrupted(err) {
 				logrus.Errorf("Target device may be corrupted, update FS info.")
 				device.Status.DeviceStatus.FileSystem.Corrupted = true
@@ -330,6 +645,7 @@ func (p *LonghornV1Provisioner) updateDeviceMount(device *diskv1.BlockDevice, de
 		}
 		diskv1.DeviceMounted.SetError(device, "", nil)
 		diskv1.DeviceMounted.SetStatusBool(device, true)
+		device.Status.DeviceStatus.FileSystem.MountOptions = device.Spec.FileSystem.MountOptions
 	}
 	device.Status.DeviceStatus.FileSystem.Corrupted = false
 	return p.updateDeviceFileSystem(device, devPath)
@@ -345,12 +661,12 @@ func (p *LonghornV1Provisioner) needFormat() bool {
 // - umount the block device if it is mounted
 // - create ext4 filesystem on the block device
 func (p *LonghornV1Provisioner) forceFormatFS(device *diskv1.BlockDevice, devPath string, filesystem *block.FileSystemInfo) (bool, error) {
-	if !p.semaphoreObj.acquire() {
+	if !p.semaphoreObj.Acquire() {
 		logrus.Infof("Hit maximum concurrent count. Requeue device %s", device.Name)
 		return true, nil
 	}
 
-	defer p.semaphoreObj.release()
+	defer p.semaphoreObj.Release()
 
 	// before format, we need to unmount the device if it is mounted
 	if filesystem != nil && filesystem.MountPoint != "" {
@@ -358,6 +674,10 @@ func (p *LonghornV1Provisioner) forceFormatFS(device *diskv1.BlockDevice, devPat
 		if err := utils.UmountDisk(filesystem.MountPoint); err != nil {
 			return false, err
 		}
+		// the filesystem UUID changes
+		if err := p.removeMountUnit(filesystem.MountPoint); err != nil {
+			return false, err
+		}
 	}
 
 	// ***TODO***: we should let people to use ext4 or xfs, but now...
@@ -435,13 +755,18 @@ func (p *LonghornV1Provisioner) updateDeviceFileSystem(device *diskv1.BlockDevic
 	return nil
 }
 
-func extraDiskMountPoint(bd *diskv1.BlockDevice) string {
+// ExtraDiskMountPoint returns the path where a LonghornV1 disk is mounted
+func ExtraDiskMountPoint(bd *diskv1.BlockDevice) string {
 	// DEPRECATED: only for backward compatibility
 	if bd.Spec.FileSystem.MountPoint != "" {
 		return bd.Spec.FileSystem.MountPoint
 	}
 
-	return fmt.Sprintf("/var/lib/harvester/extra-disks/%s", bd.Name)
+	mountRoot := defaultExtraDiskMountRoot
+	if bd.Status.DeviceStatus.FileSystem != nil && bd.Status.DeviceStatus.FileSystem.MountRoot != "" {
+		mountRoot = bd.Status.DeviceStatus.FileSystem.MountRoot
+	}
+	return filepath.Join(mountRoot, bd.Name)
 }
 
 func convertFSInfoToString(fsInfo *block.FileSystemInfo) string {
@@ -452,6 +777,25 @@ func convertFSInfoToString(fsInfo *block.FileSystemInfo) string {
 	return fmt.Sprintf("mountpoint: %s, fsType: %s", fsInfo.MountPoint, fsInfo.Type)
 }
 
+// needUpdateMountOptions checks whether the mount options of the disk mounted at its
+// mount point differ from the requested ones
+func needUpdateMountOptions(bd *diskv1.BlockDevice, filesystem *block.FileSystemInfo) bool {
+	if filesystem == nil || !bd.Spec.Provision || filesystem.MountPoint != ExtraDiskMountPoint(bd) {
+		return false
+	}
+	return !slices.Equal(bd.Spec.FileSystem.MountOptions, bd.Status.DeviceStatus.FileSystem.MountOptions)
+}
+
+// mountOptionsRemoved tells whether an applied mount option was removed from the spec
+func mountOptionsRemoved(bd *diskv1.BlockDevice) bool {
+	for _, option := range bd.Status.DeviceStatus.FileSystem.MountOptions {
+		if !slices.Contains(bd.Spec.FileSystem.MountOptions, option) {
+			return true
+		}
+	}
+	return false
+}
+
 func needUpdateMountPoint(bd *diskv1.BlockDevice, filesystem *block.FileSystemInfo) NeedMountUpdateOP {
 	if filesystem == nil {
 		logrus.Debugf("Filesystem is not ready, skip the mount operation")
@@ -464,7 +808,7 @@ func needUpdateMountPoint(bd *diskv1.BlockDevice, filesystem *block.FileSystemIn
 		if filesystem.MountPoint == "" {
 			return NeedMountUpdateMount
 		}
-		if filesystem.MountPoint == extraDiskMountPoint(bd) {
+		if filesystem.MountPoint == ExtraDiskMountPoint(bd) {
 			logrus.Debugf("Already mounted, return no-op")
 			return NeedMountUpdateNoOp
 		}
@@ -0,0 +1,221 @@
+package provisioner
+
+import (
+	"fmt"
+	"testing"
+
+	longhornv1 "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/block"
+	ctllonghornv1 "github.com/harvester/node-disk-manager/pkg/generated/controllers/longhorn.io/v1beta2"
+	"github.com/harvester/node-disk-manager/pkg/utils"
+)
+
+// fakeLonghornNodes records the scheduling of the disk set by the updates of the Longhorn node
+type fakeLonghornNodes struct {
+	ctllonghornv1.NodeClient
+	scheduling []bool
+}
+
+func (f *fakeLonghornNodes) Update(node *longhornv1.Node) (*longhornv1.Node, error) {
+	f.scheduling = append(f.scheduling, node.Spec.Disks["bd"].AllowScheduling)
+	return node, nil
+}
+
+func newResizeDevice() *diskv1.BlockDevice {
+	return &diskv1.BlockDevice{ObjectMeta: metav1.ObjectMeta{Name: "bd", Namespace: "longhorn-system"}}
+}
+
+func TestLonghornV1Resize(t *testing.T) {
+	tests := []struct {
+		name      string
+		resizeErr error
+		requeue   bool
+	}{
+		{
+			name: "the filesystem is grown",
+		},
+		{
+			name:      "a failed resize is retried",
+			resizeErr: fmt.Errorf("resize2fs: Device or resource busy"),
+			requeue:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(resize func(string) error) { resizeFilesystem = resize }(resizeFilesystem)
+			resized := []string{}
+			resizeFilesystem = func(devPath string) error {
+				resized = append(resized, devPath)
+				return tt.resizeErr
+			}
+
+			p := &LonghornV1Provisioner{provisioner: &provisioner{name: TypeLonghornV1, device: newResizeDevice()}}
+			requeue, err := p.Resize("/dev/sdb")
+			assert.Equal(t, tt.requeue, requeue)
+			assert.Equal(t, tt.resizeErr, err)
+			assert.Equal(t, []string{"/dev/sdb"}, resized)
+		})
+	}
+}
+
+func TestLonghornV1RepairFS(t *testing.T) {
+	defer func(repair func(string, string) (*utils.FilesystemRepairResult, error)) { repairFilesystem = repair }(repairFilesystem)
+	repaired := []string{}
+	repairFilesystem = func(devPath, fsType string) (*utils.FilesystemRepairResult, error) {
+		repaired = append(repaired, devPath)
+		return &utils.FilesystemRepairResult{Command: "e2fsck -fy " + devPath, ExitCode: 1, Succeeded: true}, nil
+	}
+
+	device := newResizeDevice()
+	device.Spec.FileSystem = &diskv1.FilesystemInfo{Repair: true}
+	device.Status.DeviceStatus.FileSystem = &diskv1.FilesystemStatus{Type: "ext4", Corrupted: true}
+	p := &LonghornV1Provisioner{provisioner: &provisioner{name: TypeLonghornV1, device: device}, semaphoreObj: NewSemaphore(1)}
+	filesystem := &block.FileSystemInfo{Type: "ext4"}
+
+	// the request is reset and the repair flagged as in progress before the tool runs
+	requeue, err := p.repairFS("/dev/sdb", filesystem)
+	require.NoError(t, err)
+	assert.False(t, requeue)
+	assert.Empty(t, repaired)
+	assert.False(t, device.Spec.FileSystem.Repair)
+	assert.True(t, RepairInProgress(device))
+	assert.Equal(t, "e2fsck -fy /dev/sdb", device.Status.DeviceStatus.FileSystem.LastRepair.Command)
+
+	// the tool runs on the next reconciliation
+	requeue, err = p.repairFS("/dev/sdb", filesystem)
+	require.NoError(t, err)
+	assert.False(t, requeue)
+	assert.Equal(t, []string{"/dev/sdb"}, repaired)
+	assert.False(t, RepairInProgress(device))
+	assert.True(t, device.Status.DeviceStatus.FileSystem.LastRepair.Succeeded)
+	assert.False(t, device.Status.DeviceStatus.FileSystem.Corrupted)
+	assert.True(t, device.Spec.FileSystem.Repaired)
+}
+
+func TestLonghornV1Remount(t *testing.T) {
+	newProvisioner := func(allowScheduling bool) (*LonghornV1Provisioner, *fakeLonghornNodes) {
+		device := newResizeDevice()
+		device.Spec.Provision = true
+		device.Spec.FileSystem = &diskv1.FilesystemInfo{}
+		device.Status.DeviceStatus.FileSystem = &diskv1.FilesystemStatus{}
+		diskv1.DeviceMounted.SetStatusBool(device, false)
+		diskv1.DeviceMounted.Reason(device, DeviceMountedReasonUnmounted)
+		node := &longhornv1.Node{Spec: longhornv1.NodeSpec{Disks: map[string]longhornv1.DiskSpec{
+			"bd": {AllowScheduling: allowScheduling},
+		}}}
+		nodes := &fakeLonghornNodes{}
+		return &LonghornV1Provisioner{
+			provisioner:  &provisioner{name: TypeLonghornV1, device: device},
+			nodeObj:      node,
+			nodesClient:  nodes,
+			semaphoreObj: NewSemaphore(1),
+		}, nodes
+	}
+	// the disk is found mounted back at its mount point, so nothing is mounted
+	mounted := &block.FileSystemInfo{Type: "ext4", MountPoint: defaultExtraDiskMountRoot + "/bd"}
+
+	t.Run("the scheduling suspended for the remount is resumed", func(t *testing.T) {
+		p, nodes := newProvisioner(true)
+		requeue, err := p.remount("/dev/sdb", mounted)
+		require.NoError(t, err)
+		assert.False(t, requeue)
+		assert.Equal(t, []bool{false, true}, nodes.scheduling)
+		assert.False(t, p.device.Status.SchedulingSuspended)
+		assert.True(t, diskv1.DeviceMounted.IsTrue(p.device))
+	})
+
+	t.Run("the scheduling disabled by the admin is kept", func(t *testing.T) {
+		p, nodes := newProvisioner(false)
+		requeue, err := p.remount("/dev/sdb", mounted)
+		require.NoError(t, err)
+		assert.False(t, requeue)
+		assert.Empty(t, nodes.scheduling)
+		assert.False(t, p.device.Status.SchedulingSuspended)
+	})
+
+	t.Run("the scheduling stays suspended until the repaired disk is remounted", func(t *testing.T) {
+		p, nodes := newProvisioner(true)
+		diskv1.FilesystemHealthy.SetStatusBool(p.device, false)
+		_, err := p.remount("/dev/sdb", mounted)
+		require.NoError(t, err)
+		assert.Equal(t, []bool{false}, nodes.scheduling)
+		assert.True(t, p.device.Status.SchedulingSuspended)
+		assert.False(t, diskv1.DeviceMounted.IsTrue(p.device))
+
+		p.device.Spec.FileSystem.Repaired = true
+		_, err = p.remount("/dev/sdb", mounted)
+		require.NoError(t, err)
+		assert.Equal(t, []bool{false, true}, nodes.scheduling)
+		assert.False(t, p.device.Status.SchedulingSuspended)
+	})
+}
+
+func TestExtraDiskMountPoint(t *testing.T) {
+	device := newResizeDevice()
+	device.Spec.FileSystem = &diskv1.FilesystemInfo{}
+	assert.Equal(t, "/var/lib/harvester/extra-disks/bd", ExtraDiskMountPoint(device))
+
+	device.Status.DeviceStatus.FileSystem = &diskv1.FilesystemStatus{MountRoot: "/data/disks"}
+	assert.Equal(t, "/data/disks/bd", ExtraDiskMountPoint(device))
+
+	// the deprecated mount point of the spec still wins
+	device.Spec.FileSystem.MountPoint = "/mnt/bd"
+	assert.Equal(t, "/mnt/bd", ExtraDiskMountPoint(device))
+}
+
+func TestNeedUpdateMountOptions(t *testing.T) {
+	tests := []struct {
+		name       string
+		provision  bool
+		mountPoint string
+		requested  []string
+		applied    []string
+		update     bool
+	}{
+		{
+			name:       "no mount options",
+			provision:  true,
+			mountPoint: "/var/lib/harvester/extra-disks/bd",
+		},
+		{
+			name:       "the mount options are applied",
+			provision:  true,
+			mountPoint: "/var/lib/harvester/extra-disks/bd",
+			requested:  []string{"noatime", "discard"},
+			applied:    []string{"noatime", "discard"},
+		},
+		{
+			name:       "new mount options",
+			provision:  true,
+			mountPoint: "/var/lib/harvester/extra-disks/bd",
+			requested:  []string{"noatime", "commit=30"},
+			applied:    []string{"noatime"},
+			update:     true,
+		},
+		{
+			name:       "the disk isn't mounted at its mount point",
+			provision:  true,
+			mountPoint: "/mnt/bd",
+			requested:  []string{"noatime"},
+		},
+		{
+			name:       "the disk isn't provisioned",
+			mountPoint: "/var/lib/harvester/extra-disks/bd",
+			requested:  []string{"noatime"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := newResizeDevice()
+			device.Spec.Provision = tt.provision
+			device.Spec.FileSystem = &diskv1.FilesystemInfo{MountOptions: tt.requested}
+			device.Status.DeviceStatus.FileSystem = &diskv1.FilesystemStatus{MountOptions: tt.applied}
+			assert.Equal(t, tt.update, needUpdateMountOptions(device, &block.FileSystemInfo{MountPoint: tt.mountPoint}))
+		})
+	}
+}
@@ -157,6 +157,15 @@ func (p *LonghornV2Provisioner) Update() (isRequeueNeeded bool, err error) {
 	return
 }
 
+// Resize is a no-op, Longhorn uses the whole device without any filesystem
+func (p *LonghornV2Provisioner) Resize(_ string) (isRequeueNeeded bool, err error) {
+	logrus.WithFields(logrus.Fields{
+		"provisioner": p.name,
+		"device":      p.device.Name,
+	}).Info("Nothing to grow for the device")
+	return false, nil
+}
+
 // resolveLonghornV2DevPath will return a BDF path if possible for virtio or
 // NVMe devices, then will fall back to /dev/disk/by-id (which requires the
 // disk to have a WWN).  For details on BDF pathing, see
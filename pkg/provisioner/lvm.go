@@ -16,6 +16,9 @@ import (
 	"github.com/harvester/node-disk-manager/pkg/lvm"
 )
 
+// resizePhysicalVolume grows the physical volume of a device, replaced by the tests
+var resizePhysicalVolume = lvm.DoPVResize
+
 type LVMProvisioner struct {
 	*provisioner
 	vgName   string
@@ -177,6 +180,20 @@ func (l *LVMProvisioner) Update() (requeue bool, err error) {
 	return
 }
 
+// Resize grows the physical volume, so the volume group gets the new space
+func (l *LVMProvisioner) Resize(devPath string) (requeue bool, err error) {
+	logrus.WithFields(logrus.Fields{
+		"provisioner": l.name,
+		"device":      l.device.Name,
+		"vgName":      l.vgName,
+		"devPath":     devPath,
+	}).Info("Growing the physical volume of the device")
+	if err := resizePhysicalVolume(devPath); err != nil {
+		return true, err
+	}
+	return false, nil
+}
+
 func (l *LVMProvisioner) addDevOrCreateLVMVgCRD(lvmVG *diskv1.LVMVolumeGroup, found bool) (requeue bool, err error) {
 	logrus.Infof("addDevOrCreateLVMVgCRD: %v, found: %v", lvmVG, found)
 	requeue = false
@@ -0,0 +1,41 @@
+package provisioner
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLVMResize(t *testing.T) {
+	tests := []struct {
+		name      string
+		resizeErr error
+		requeue   bool
+	}{
+		{
+			name: "the physical volume is grown",
+		},
+		{
+			name:      "a failed resize is retried",
+			resizeErr: fmt.Errorf("execute command 'pvresize' with args '[/dev/sdb]' failed: exit status 5"),
+			requeue:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(resize func(string) error) { resizePhysicalVolume = resize }(resizePhysicalVolume)
+			resized := []string{}
+			resizePhysicalVolume = func(devPath string) error {
+				resized = append(resized, devPath)
+				return tt.resizeErr
+			}
+
+			l := &LVMProvisioner{provisioner: &provisioner{name: TypeLVM, device: newResizeDevice()}, vgName: "vg01"}
+			requeue, err := l.Resize("/dev/sdb")
+			assert.Equal(t, tt.requeue, requeue)
+			assert.Equal(t, tt.resizeErr, err)
+			assert.Equal(t, []string{"/dev/sdb"}, resized)
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package provisioner
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	gocommon "github.com/harvester/go-common/ds"
+	longhornv1 "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/lvm"
+	"github.com/harvester/node-disk-manager/pkg/utils"
+)
+
+// Planner describes the changes the Provisioner operations would make
+// without applying them. It is used by the dry-run mode, so the
+// implementations must only read the current state.
+type Planner interface {
+	PlanFormat(devPath string) ([]string, error)
+	PlanProvision() ([]string, error)
+	PlanUnProvision() ([]string, error)
+	PlanUpdate() ([]string, error)
+}
+
+func (p *LonghornV1Provisioner) PlanFormat(devPath string) ([]string, error) {
+	filesystem := p.blockInfo.GetFileSystemInfoByDevPath(devPath)
+	if p.device.Spec.FileSystem.Repair || RepairInProgress(p.device) {
+		if filesystem != nil && filesystem.MountPoint != "" {
+			return []string{fmt.Sprintf("refuse to repair %s as it is mounted at %s", devPath, filesystem.MountPoint)}, nil
+		}
+		cmd, args, err := utils.RepairCommand(devPath, repairFSType(p.device, filesystem))
+		if err != nil {
+			return []string{fmt.Sprintf("fail to repair %s: %v", devPath, err)}, nil
+		}
+		return []string{fmt.Sprintf("run %s", strings.Join(append([]string{cmd}, args...), " "))}, nil
+	}
+	if p.needFormat() {
+		plan := []string{}
+		if filesystem != nil && filesystem.MountPoint != "" {
+			plan = append(plan, fmt.Sprintf("unmount %s from %s", devPath, filesystem.MountPoint))
+		}
+		return append(plan, fmt.Sprintf("wipe and format %s with ext4", devPath)), nil
+	}
+
+	plan := []string{}
+	needMountUpdate := needUpdateMountPoint(p.device, filesystem)
+	if needMountUpdate.Has(NeedMountUpdateUnmount) {
+		plan = append(plan, fmt.Sprintf("unmount %s from %s", devPath, filesystem.MountPoint))
+		if p.persistMounts {
+			plan = append(plan, fmt.Sprintf("remove the mount unit %s", utils.MountUnitName(filesystem.MountPoint)))
+		}
+	}
+	if needMountUpdate.Has(NeedMountUpdateMount) {
+		plan = append(plan, fmt.Sprintf("mount %s at %s", devPath, ExtraDiskMountPoint(p.device)))
+		if p.persistMounts {
+			plan = append(plan, fmt.Sprintf("persist the mount in the mount unit %s", utils.MountUnitName(ExtraDiskMountPoint(p.device))))
+		}
+	}
+	if needMountUpdate != NeedMountUpdateNoOp {
+		return plan, nil
+	}
+	if needUpdateMountOptions(p.device, filesystem) {
+		options := p.device.Spec.FileSystem.MountOptions
+		if mountOptionsRemoved(p.device) {
+			plan = append(plan, fmt.Sprintf("unmount %s from %s and mount it again with the mount options %v", devPath, filesystem.MountPoint, options))
+		} else {
+			plan = append(plan, fmt.Sprintf("remount %s at %s with the mount options %v", devPath, filesystem.MountPoint, options))
+		}
+	}
+	return plan, nil
+}
+
+func (p *LonghornV1Provisioner) PlanProvision() ([]string, error) {
+	tags := []string{}
+	if p.device.Spec.Tags != nil {
+		tags = p.device.Spec.Tags
+	}
+	diskSpec := longhornv1.DiskSpec{
+		Type:              longhornv1.DiskTypeFilesystem,
+		Path:              ExtraDiskMountPoint(p.device),
+		AllowScheduling:   true,
+		EvictionRequested: false,
+		StorageReserved:   0,
+		Tags:              tags,
+	}
+	if disk, found := p.nodeObj.Spec.Disks[p.device.Name]; found && reflect.DeepEqual(disk, diskSpec) {
+		return nil, nil
+	}
+	return []string{fmt.Sprintf("add filesystem disk %s at %s with tags %v to longhorn node %s",
+		p.device.Name, diskSpec.Path, tags, p.nodeObj.Name)}, nil
+}
+
+func (p *LonghornV1Provisioner) PlanUnProvision() ([]string, error) {
+	diskToRemove, ok := p.nodeObj.Spec.Disks[p.device.Name]
+	if !ok {
+		return nil, nil
+	}
+
+	if !slices.Contains(p.device.Status.Tags, utils.DiskRemoveTag) {
+		return []string{fmt.Sprintf("disable scheduling and request eviction of disk %s on longhorn node %s",
+			p.device.Name, p.nodeObj.Name)}, nil
+	}
+
+	if !diskToRemove.AllowScheduling &&
+		(p.device.Status.State == diskv1.BlockDeviceInactive || p.device.Status.DeviceStatus.FileSystem.Corrupted) {
+		return []string{fmt.Sprintf("force unmount and remove inactive or corrupted disk %s from longhorn node %s",
+			p.device.Name, p.nodeObj.Name)}, nil
+	}
+
+	if status, ok := p.nodeObj.Status.DiskStatus[p.device.Name]; ok && len(status.ScheduledReplica) == 0 {
+		return []string{fmt.Sprintf("remove disk %s from longhorn node %s", p.device.Name, p.nodeObj.Name)}, nil
+	}
+	return []string{fmt.Sprintf("wait for the replicas to be evicted from disk %s", p.device.Name)}, nil
+}
+
+func (p *LonghornV1Provisioner) PlanUpdate() ([]string, error) {
+	targetDisk, found := p.nodeObj.Spec.Disks[p.device.Name]
+	if !found {
+		return nil, nil
+	}
+	respectedTags := []string{}
+	for _, tag := range targetDisk.Tags {
+		if !slices.Contains(p.cacheDiskTags.GetDiskTags(p.device.Name), tag) {
+			respectedTags = append(respectedTags, tag)
+		}
+	}
+	tags := gocommon.SliceDedupe(append(respectedTags, p.device.Spec.Tags...))
+	if gocommon.SliceContentCmp(tags, targetDisk.Tags) {
+		return nil, nil
+	}
+	return []string{fmt.Sprintf("update tags of disk %s on longhorn node %s from %v to %v",
+		p.device.Name, p.nodeObj.Name, targetDisk.Tags, tags)}, nil
+}
+
+func (p *LonghornV2Provisioner) PlanFormat(devPath string) ([]string, error) {
+	if p.device.Status.ProvisionPhase == diskv1.ProvisionPhaseProvisioned {
+		return nil, nil
+	}
+	return []string{fmt.Sprintf("wipe existing filesystem and LVM signatures on %s", devPath)}, nil
+}
+
+func (p *LonghornV2Provisioner) PlanProvision() ([]string, error) {
+	devPath, err := resolveLonghornV2DevPath(p.device)
+	if err != nil {
+		return nil, err
+	}
+	disk, found := p.nodeObj.Spec.Disks[p.device.Name]
+	if found && disk.Type == longhornv1.DiskTypeBlock && disk.Path == devPath {
+		return nil, nil
+	}
+	return []string{fmt.Sprintf("add block disk %s at %s with disk driver %s to longhorn node %s",
+		p.device.Name, devPath, p.device.Spec.Provisioner.Longhorn.DiskDriver, p.nodeObj.Name)}, nil
+}
+
+func (p *LonghornV2Provisioner) PlanUpdate() ([]string, error) {
+	plan, err := p.LonghornV1Provisioner.PlanUpdate()
+	if err != nil {
+		return nil, err
+	}
+	targetDisk, found := p.nodeObj.Spec.Disks[p.device.Name]
+	if found && targetDisk.DiskDriver != p.device.Spec.Provisioner.Longhorn.DiskDriver {
+		plan = append(plan, fmt.Sprintf("update disk driver of disk %s on longhorn node %s from %s to %s",
+			p.device.Name, p.nodeObj.Name, targetDisk.DiskDriver, p.device.Spec.Provisioner.Longhorn.DiskDriver))
+	}
+	return plan, nil
+}
+
+func (l *LVMProvisioner) PlanFormat(devPath string) ([]string, error) {
+	pvResult, err := lvm.GetPVScanResult()
+	if err != nil {
+		return nil, err
+	}
+	if vg, found := pvResult[devPath]; found && vg == l.vgName {
+		if _, err := l.getTargetLVMVG(); err == nil {
+			return nil, nil
+		} else if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	return []string{fmt.Sprintf("wipe existing filesystem and LVM signatures on %s", devPath)}, nil
+}
+
+func (l *LVMProvisioner) PlanProvision() ([]string, error) {
+	lvmvg, err := l.getTargetLVMVG()
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return []string{fmt.Sprintf("create volume group %s on node %s with device %s",
+				l.vgName, l.nodeName, l.device.Status.DeviceStatus.DevPath)}, nil
+		}
+		return nil, err
+	}
+	if _, found := lvmvg.Spec.Devices[l.device.Name]; found {
+		return nil, nil
+	}
+	return []string{fmt.Sprintf("add device %s to volume group %s", l.device.Status.DeviceStatus.DevPath, l.vgName)}, nil
+}
+
+func (l *LVMProvisioner) PlanUnProvision() ([]string, error) {
+	lvmvg, err := l.getTargetLVMVG()
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if _, found := lvmvg.Spec.Devices[l.device.Name]; !found {
+		return nil, nil
+	}
+	return []string{fmt.Sprintf("remove device %s from volume group %s", l.device.Status.DeviceStatus.DevPath, l.vgName)}, nil
+}
+
+func (l *LVMProvisioner) PlanUpdate() ([]string, error) {
+	lvmvg, err := l.getTargetLVMVG()
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if lvmvg.Spec.DesiredState == diskv1.VGStateEnabled && (lvmvg.Status == nil || lvmvg.Status.Status != diskv1.VGStatusActive) {
+		return []string{fmt.Sprintf("activate volume group %s", l.vgName)}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,332 @@
+package provisioner
+
+import (
+	"testing"
+
+	longhornv1 "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/block"
+	"github.com/harvester/node-disk-manager/pkg/utils"
+)
+
+// fakeFSInfo serves the filesystem of a single device, the other methods are never called
+type fakeFSInfo struct {
+	block.Info
+	filesystem *block.FileSystemInfo
+}
+
+func (i *fakeFSInfo) GetFileSystemInfoByDevPath(string) *block.FileSystemInfo {
+	return i.filesystem
+}
+
+func TestLonghornV1PlanFormatRepair(t *testing.T) {
+	tests := []struct {
+		name       string
+		filesystem *block.FileSystemInfo
+		statusType string
+		inProgress bool
+		plan       []string
+	}{
+		{
+			name:       "ext4",
+			filesystem: &block.FileSystemInfo{Type: "ext4"},
+			plan:       []string{"run e2fsck -fy /dev/sdb"},
+		},
+		{
+			name:       "the type last reported is used when the device reports none",
+			filesystem: &block.FileSystemInfo{},
+			statusType: "xfs",
+			plan:       []string{"run xfs_repair /dev/sdb"},
+		},
+		{
+			name:       "the accepted repair is planned until it ran",
+			filesystem: &block.FileSystemInfo{Type: "ext4"},
+			inProgress: true,
+			plan:       []string{"run e2fsck -fy /dev/sdb"},
+		},
+		{
+			name:       "a mounted filesystem isn't repaired",
+			filesystem: &block.FileSystemInfo{Type: "ext4", MountPoint: "/var/lib/harvester/extra-disks/bd"},
+			plan:       []string{"refuse to repair /dev/sdb as it is mounted at /var/lib/harvester/extra-disks/bd"},
+		},
+		{
+			name:       "a filesystem which can't be identified isn't repaired",
+			filesystem: &block.FileSystemInfo{},
+			plan:       []string{"fail to repair /dev/sdb: unknown filesystem type, refusing to repair"},
+		},
+		{
+			name:       "unsupported filesystem",
+			filesystem: &block.FileSystemInfo{Type: "btrfs"},
+			plan:       []string{"fail to repair /dev/sdb: unsupported filesystem type btrfs"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := newResizeDevice()
+			device.Spec.FileSystem = &diskv1.FilesystemInfo{Repair: !tt.inProgress, ForceFormatted: true}
+			device.Status.DeviceStatus.FileSystem = &diskv1.FilesystemStatus{Type: tt.statusType, Corrupted: true}
+			if tt.inProgress {
+				device.Status.DeviceStatus.FileSystem.LastRepair = &diskv1.FilesystemRepairStatus{InProgress: true}
+			}
+			p := &LonghornV1Provisioner{provisioner: &provisioner{
+				name:      TypeLonghornV1,
+				device:    device,
+				blockInfo: &fakeFSInfo{filesystem: tt.filesystem},
+			}}
+
+			// the repair is served before the force format of a corrupted filesystem
+			plan, err := p.PlanFormat("/dev/sdb")
+			require.NoError(t, err)
+			assert.Equal(t, tt.plan, plan)
+		})
+	}
+}
+
+func TestLonghornV1PlanFormatMountOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested []string
+		applied   []string
+		plan      []string
+	}{
+		{
+			name:      "the mount options are applied",
+			requested: []string{"noatime"},
+			applied:   []string{"noatime"},
+			plan:      []string{},
+		},
+		{
+			name:      "an added option is applied by a remount",
+			requested: []string{"noatime", "commit=30"},
+			applied:   []string{"noatime"},
+			plan:      []string{"remount /dev/sdb at /var/lib/harvester/extra-disks/bd with the mount options [noatime commit=30]"},
+		},
+		{
+			name:      "a removed option requires a new mount",
+			requested: []string{"commit=30"},
+			applied:   []string{"noatime", "commit=30"},
+			plan:      []string{"unmount /dev/sdb from /var/lib/harvester/extra-disks/bd and mount it again with the mount options [commit=30]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := newResizeDevice()
+			device.Spec.Provision = true
+			device.Spec.FileSystem = &diskv1.FilesystemInfo{MountOptions: tt.requested}
+			device.Status.DeviceStatus.FileSystem = &diskv1.FilesystemStatus{MountOptions: tt.applied, LastFormattedAt: &metav1.Time{}}
+			p := &LonghornV1Provisioner{provisioner: &provisioner{
+				name:      TypeLonghornV1,
+				device:    device,
+				blockInfo: &fakeFSInfo{filesystem: &block.FileSystemInfo{Type: "ext4", MountPoint: "/var/lib/harvester/extra-disks/bd"}},
+			}}
+
+			plan, err := p.PlanFormat("/dev/sdb")
+			require.NoError(t, err)
+			assert.Equal(t, tt.plan, plan)
+		})
+	}
+}
+
+func TestLonghornV1PlanFormat(t *testing.T) {
+	tests := []struct {
+		name          string
+		corrupted     bool
+		formatted     bool
+		filesystem    *block.FileSystemInfo
+		persistMounts bool
+		plan          []string
+	}{
+		{
+			name:       "a new disk is formatted",
+			filesystem: &block.FileSystemInfo{},
+			plan:       []string{"wipe and format /dev/sdb with ext4"},
+		},
+		{
+			name:       "a corrupted filesystem is unmounted and formatted again",
+			corrupted:  true,
+			formatted:  true,
+			filesystem: &block.FileSystemInfo{Type: "ext4", MountPoint: "/mnt/bd"},
+			plan:       []string{"unmount /dev/sdb from /mnt/bd", "wipe and format /dev/sdb with ext4"},
+		},
+		{
+			name:       "a formatted disk is mounted",
+			formatted:  true,
+			filesystem: &block.FileSystemInfo{Type: "ext4"},
+			plan:       []string{"mount /dev/sdb at /var/lib/harvester/extra-disks/bd"},
+		},
+		{
+			name:          "a disk mounted elsewhere is moved and its mounts persisted",
+			formatted:     true,
+			filesystem:    &block.FileSystemInfo{Type: "ext4", MountPoint: "/mnt/bd"},
+			persistMounts: true,
+			plan: []string{
+				"unmount /dev/sdb from /mnt/bd",
+				"remove the mount unit mnt-bd.mount",
+				"mount /dev/sdb at /var/lib/harvester/extra-disks/bd",
+				"persist the mount in the mount unit var-lib-harvester-extra\\x2ddisks-bd.mount",
+			},
+		},
+		{
+			name:       "a mounted disk is left as is",
+			formatted:  true,
+			filesystem: &block.FileSystemInfo{Type: "ext4", MountPoint: "/var/lib/harvester/extra-disks/bd"},
+			plan:       []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := newResizeDevice()
+			device.Spec.Provision = true
+			device.Spec.FileSystem = &diskv1.FilesystemInfo{ForceFormatted: true}
+			device.Status.DeviceStatus.FileSystem = &diskv1.FilesystemStatus{Corrupted: tt.corrupted}
+			if tt.formatted {
+				device.Status.DeviceStatus.FileSystem.LastFormattedAt = &metav1.Time{}
+			}
+			p := &LonghornV1Provisioner{
+				provisioner: &provisioner{
+					name:      TypeLonghornV1,
+					device:    device,
+					blockInfo: &fakeFSInfo{filesystem: tt.filesystem},
+				},
+				persistMounts: tt.persistMounts,
+			}
+
+			plan, err := p.PlanFormat("/dev/sdb")
+			require.NoError(t, err)
+			assert.Equal(t, tt.plan, plan)
+		})
+	}
+}
+
+func newPlanNode(disks map[string]longhornv1.DiskSpec, replicas map[string]int64) *longhornv1.Node {
+	node := &longhornv1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1", Namespace: "longhorn-system"},
+		Spec:       longhornv1.NodeSpec{Disks: disks},
+	}
+	if replicas != nil {
+		node.Status.DiskStatus = map[string]*longhornv1.DiskStatus{"bd": {ScheduledReplica: replicas}}
+	}
+	return node
+}
+
+func TestLonghornV1PlanProvision(t *testing.T) {
+	device := newResizeDevice()
+	device.Spec.FileSystem = &diskv1.FilesystemInfo{}
+	device.Spec.Tags = []string{"ssd"}
+	device.Status.DeviceStatus.FileSystem = &diskv1.FilesystemStatus{}
+	p := &LonghornV1Provisioner{
+		provisioner: &provisioner{name: TypeLonghornV1, device: device},
+		nodeObj:     newPlanNode(nil, nil),
+	}
+
+	plan, err := p.PlanProvision()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"add filesystem disk bd at /var/lib/harvester/extra-disks/bd with tags [ssd] to longhorn node node1"}, plan)
+
+	p.nodeObj = newPlanNode(map[string]longhornv1.DiskSpec{"bd": {
+		Type:            longhornv1.DiskTypeFilesystem,
+		Path:            "/var/lib/harvester/extra-disks/bd",
+		AllowScheduling: true,
+		Tags:            []string{"ssd"},
+	}}, nil)
+	plan, err = p.PlanProvision()
+	require.NoError(t, err)
+	assert.Empty(t, plan, "the disk is already added")
+}
+
+func TestLonghornV1PlanUnProvision(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     *longhornv1.Node
+		removing bool
+		inactive bool
+		plan     []string
+	}{
+		{
+			name: "the disk is not on the node",
+			node: newPlanNode(nil, nil),
+		},
+		{
+			name: "the scheduling is disabled first",
+			node: newPlanNode(map[string]longhornv1.DiskSpec{"bd": {AllowScheduling: true}}, nil),
+			plan: []string{"disable scheduling and request eviction of disk bd on longhorn node node1"},
+		},
+		{
+			name:     "an inactive disk is removed at once",
+			node:     newPlanNode(map[string]longhornv1.DiskSpec{"bd": {}}, map[string]int64{"replica": 1}),
+			removing: true,
+			inactive: true,
+			plan:     []string{"force unmount and remove inactive or corrupted disk bd from longhorn node node1"},
+		},
+		{
+			name:     "the replicas are evicted before the removal",
+			node:     newPlanNode(map[string]longhornv1.DiskSpec{"bd": {}}, map[string]int64{"replica": 1}),
+			removing: true,
+			plan:     []string{"wait for the replicas to be evicted from disk bd"},
+		},
+		{
+			name:     "a disk without replicas is removed",
+			node:     newPlanNode(map[string]longhornv1.DiskSpec{"bd": {}}, map[string]int64{}),
+			removing: true,
+			plan:     []string{"remove disk bd from longhorn node node1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := newResizeDevice()
+			device.Status.State = diskv1.BlockDeviceActive
+			if tt.inactive {
+				device.Status.State = diskv1.BlockDeviceInactive
+			}
+			device.Status.DeviceStatus.FileSystem = &diskv1.FilesystemStatus{}
+			if tt.removing {
+				device.Status.Tags = []string{utils.DiskRemoveTag}
+			}
+			p := &LonghornV1Provisioner{provisioner: &provisioner{name: TypeLonghornV1, device: device}, nodeObj: tt.node}
+
+			plan, err := p.PlanUnProvision()
+			require.NoError(t, err)
+			assert.Equal(t, tt.plan, plan)
+		})
+	}
+}
+
+func TestLonghornV1PlanUpdate(t *testing.T) {
+	device := newResizeDevice()
+	device.Spec.Tags = []string{"ssd", "fast"}
+	diskTags := NewLonghornDiskTags()
+	// the tags set on the Longhorn disk by NDM before, the others were set by the admin
+	diskTags.UpdateDiskTags("bd", []string{"ssd", "slow"})
+	p := &LonghornV1Provisioner{
+		provisioner:   &provisioner{name: TypeLonghornV1, device: device},
+		nodeObj:       newPlanNode(map[string]longhornv1.DiskSpec{"bd": {Tags: []string{"ssd", "slow", "admin"}}}, nil),
+		cacheDiskTags: diskTags,
+	}
+
+	plan, err := p.PlanUpdate()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"update tags of disk bd on longhorn node node1 from [ssd slow admin] to [admin ssd fast]"}, plan)
+
+	p.nodeObj = newPlanNode(map[string]longhornv1.DiskSpec{"bd": {Tags: []string{"admin", "ssd", "fast"}}}, nil)
+	plan, err = p.PlanUpdate()
+	require.NoError(t, err)
+	assert.Empty(t, plan, "the tags are up to date")
+}
+
+func TestLonghornV2PlanFormat(t *testing.T) {
+	device := newResizeDevice()
+	p := &LonghornV2Provisioner{LonghornV1Provisioner: &LonghornV1Provisioner{provisioner: &provisioner{name: TypeLonghornV2, device: device}}}
+
+	plan, err := p.PlanFormat("/dev/sdb")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"wipe existing filesystem and LVM signatures on /dev/sdb"}, plan)
+
+	device.Status.ProvisionPhase = diskv1.ProvisionPhaseProvisioned
+	plan, err = p.PlanFormat("/dev/sdb")
+	require.NoError(t, err)
+	assert.Empty(t, plan, "a provisioned disk is never wiped")
+}
@@ -1,6 +1,7 @@
 package udev
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/harvester/node-disk-manager/pkg/block"
@@ -31,6 +32,8 @@ const (
 	UdevVendor         = "ID_VENDOR"
 	UdevWWN            = "ID_WWN"
 	UdevDMWWN          = "DM_WWN" // multipath device
+	UdevRevision       = "ID_REVISION"
+	UdevRotationRate   = "ID_ATA_ROTATION_RATE_RPM"
 )
 
 type Device map[string]string
@@ -67,6 +70,13 @@ func (device Device) UpdateDiskFromUdev(disk *block.Disk) {
 	} else if len(device[UdevDMWWN]) > 0 {
 		disk.WWN = device[UdevDMWWN]
 	}
+
+	if len(device[UdevRevision]) > 0 {
+		disk.FirmwareRevision = device[UdevRevision]
+	}
+	if rpm, err := strconv.ParseUint(device[UdevRotationRate], 10, 32); err == nil {
+		disk.RotationRateRPM = uint32(rpm)
+	}
 }
 
 // IsDisk check if device is a disk
@@ -0,0 +1,30 @@
+package udev
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/harvester/node-disk-manager/pkg/block"
+)
+
+func TestUpdateDiskFromUdevHardwareMetadata(t *testing.T) {
+	disk := &block.Disk{FirmwareRevision: "SN02", RotationRateRPM: 5400}
+	InitUdevDevice(map[string]string{
+		UdevRevision:     "SN03",
+		UdevRotationRate: "7200",
+	}).UpdateDiskFromUdev(disk)
+	assert.Equal(t, "SN03", disk.FirmwareRevision)
+	assert.Equal(t, uint32(7200), disk.RotationRateRPM)
+
+	// an SSD reports a rotation rate of 0
+	InitUdevDevice(map[string]string{UdevRotationRate: "0"}).UpdateDiskFromUdev(disk)
+	assert.Equal(t, "SN03", disk.FirmwareRevision)
+	assert.Zero(t, disk.RotationRateRPM)
+
+	// the properties udev doesn't report are kept
+	disk = &block.Disk{FirmwareRevision: "GDC5302Q", RotationRateRPM: 7200}
+	InitUdevDevice(map[string]string{UdevRotationRate: "unknown"}).UpdateDiskFromUdev(disk)
+	assert.Equal(t, "GDC5302Q", disk.FirmwareRevision)
+	assert.Equal(t, uint32(7200), disk.RotationRateRPM)
+}
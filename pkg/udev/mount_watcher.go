@@ -6,11 +6,12 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"os"
+	"sort"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
-
-	"github.com/harvester/node-disk-manager/pkg/utils"
 )
 
 // NDM mounts the host's /proc at /host/proc (see daemonset.yaml), so we watch
@@ -20,6 +21,8 @@ const procMountInfo = "/host/proc/1/mountinfo"
 
 // watchMounts monitors mount table changes by polling /proc/self/mountinfo for POLLERR.
 // The kernel raises POLLERR on this fd whenever any mount or umount occurs.
+// Only the block devices whose mount entries changed are handed to the scanner, so
+// the mounts of containers and pseudo filesystems don't cause any scan.
 // On any unrecoverable error it sends to errChan so spawnMountWatcher can respawn it.
 func (u *Udev) watchMounts(ctx context.Context, errChan chan error) {
 	logrus.Debugf("mount watcher: opening %s", procMountInfo)
@@ -40,6 +43,12 @@ func (u *Udev) watchMounts(ctx context.Context, errChan chan error) {
 	}
 	fdInt32 := int32(fd) //nolint:gosec // fd is guaranteed non-negative by Open and bounded by math.MaxInt32 check above
 
+	mounts, err := readDeviceMounts()
+	if err != nil {
+		errChan <- err
+		return
+	}
+
 	// POLLERR fires when the kernel marks /proc/self/mountinfo dirty (mount/umount)
 	fds := []unix.PollFd{{Fd: fdInt32, Events: unix.POLLERR}}
 
@@ -69,10 +78,66 @@ func (u *Udev) watchMounts(ctx context.Context, errChan chan error) {
 		}
 
 		logrus.Debugf("mount watcher: POLLERR received (revents=0x%x), mount table changed", fds[0].Revents)
-		utils.CallerWithCondLock(u.scanner.Cond, func() any {
-			logrus.Info("mount watcher: mount change detected, waking scanner")
-			u.scanner.Cond.Signal()
-			return nil
-		})
+		current, err := readDeviceMounts()
+		if err != nil {
+			logrus.Warnf("mount watcher: %v, waking scanner for a full scan", err)
+			u.scanner.RequestScan("mount table changed")
+			continue
+		}
+		devPaths := diffDeviceMounts(mounts, current)
+		mounts = current
+		if len(devPaths) == 0 {
+			logrus.Debug("mount watcher: no block device mount changed")
+			continue
+		}
+		logrus.Infof("mount watcher: mounts of %v changed, waking scanner", devPaths)
+		u.scanner.RequestDeviceScan("mount table changed", devPaths...)
+	}
+}
+
+// readDeviceMounts returns the mount entries of the block devices, mapped to their
+// mount source. The whole line is the key, so a remount changes the entry too.
+func readDeviceMounts() (map[string]string, error) {
+	content, err := os.ReadFile(procMountInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", procMountInfo, err)
+	}
+
+	mounts := make(map[string]string)
+	for _, line := range strings.Split(string(content), "\n") {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+		// the optional fields are terminated by "-", which is followed by fstype and source
+		fields := strings.Fields(line)
+		for i, field := range fields {
+			if field == "-" && i+2 < len(fields) {
+				if source := fields[i+2]; strings.HasPrefix(source, "/dev/") {
+					mounts[line] = source
+				}
+				break
+			}
+		}
+	}
+	return mounts, nil
+}
+
+// diffDeviceMounts returns the mount sources of the entries which were added or removed
+func diffDeviceMounts(previous, current map[string]string) []string {
+	changed := make(map[string]struct{})
+	for line, source := range current {
+		if _, ok := previous[line]; !ok {
+			changed[source] = struct{}{}
+		}
+	}
+	for line, source := range previous {
+		if _, ok := current[line]; !ok {
+			changed[source] = struct{}{}
+		}
+	}
+
+	devPaths := make([]string, 0, len(changed))
+	for devPath := range changed {
+		devPaths = append(devPaths, devPath)
 	}
+	sort.Strings(devPaths)
+	return devPaths
 }
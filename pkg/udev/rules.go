@@ -0,0 +1,136 @@
+package udev
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pilebones/go-udev/netlink"
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/filter"
+	ctldiskv1 "github.com/harvester/node-disk-manager/pkg/generated/controllers/harvesterhci.io/v1beta1"
+)
+
+const (
+	udevRulesHandlerName = "harvester-node-disk-manager-udev-rules-handler"
+	// rulesFileCheckInterval is the period of checking the rules file for changes
+	rulesFileCheckInterval = 30 * time.Second
+)
+
+// Register reloads the udev matcher rules when the NodeDiskManagerConfig or the NDM ConfigMap changes
+func (u *Udev) Register(ctx context.Context, configMaps ctlcorev1.ConfigMapController, ndmConfigs ctldiskv1.NodeDiskManagerConfigController) {
+	configMaps.OnChange(ctx, udevRulesHandlerName, u.OnConfigMapChange)
+	ndmConfigs.OnChange(ctx, udevRulesHandlerName, u.OnNDMConfigChange)
+}
+
+// OnConfigMapChange reloads the udev matcher rules when the NDM ConfigMap changes
+func (u *Udev) OnConfigMapChange(key string, cm *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	if key != fmt.Sprintf("%s/%s", filter.DefaultConfigMapNamespace, filter.DefaultConfigMapName) {
+		return cm, nil
+	}
+	u.loadConfigRules()
+	return cm, nil
+}
+
+// OnNDMConfigChange reloads the udev matcher rules when the NodeDiskManagerConfig
+// changes, which includes the changes of the node labels
+func (u *Udev) OnNDMConfigChange(key string, ndmConfig *diskv1.NodeDiskManagerConfig) (*diskv1.NodeDiskManagerConfig, error) {
+	if key != filter.DefaultNDMConfigName {
+		return ndmConfig, nil
+	}
+	u.loadConfigRules()
+	return ndmConfig, nil
+}
+
+// loadConfigRules loads the udev matcher rules of the NodeDiskManagerConfig, or of the
+// ConfigMap. Invalid rules are rejected by the webhook, if any get through, or the rules
+// can't be loaded, the current rules are kept.
+func (u *Udev) loadConfigRules() {
+	rules, err := u.configLoader.LoadUdevRules()
+	if err != nil {
+		logrus.Errorf("Ignoring the udev matcher rules of the config, error: %s", err.Error())
+		return
+	}
+	// the compiled rules can't be compared, their JSON form is
+	content, err := json.Marshal(rules)
+	if err != nil {
+		logrus.Errorf("Ignoring the udev matcher rules of the config, error: %s", err.Error())
+		return
+	}
+
+	u.rulesLock.Lock()
+	defer u.rulesLock.Unlock()
+	if bytes.Equal(content, u.configRulesContent) {
+		return
+	}
+	previous := u.configRules
+	u.configRules = rules
+	u.configRulesContent = content
+	if rules != nil {
+		logrus.Infof("Loaded the udev matcher rules of the config:\n%s", rules.String())
+	} else if previous != nil {
+		logrus.Info("Removed the udev matcher rules of the config")
+	}
+}
+
+// matchesRules returns true if the udev event should be handled. The rules of the
+// NodeDiskManagerConfig or the ConfigMap take precedence over the rules file, every
+// event is handled without rules.
+func (u *Udev) matchesRules(uevent netlink.UEvent) bool {
+	u.rulesLock.RLock()
+	defer u.rulesLock.RUnlock()
+	rules := u.configRules
+	if rules == nil {
+		rules = u.fileRules
+	}
+	return rules == nil || rules.Evaluate(uevent)
+}
+
+// watchRulesFile reloads the rules file when its content changes. The current rules
+// are kept if the file can't be read or is invalid.
+func (u *Udev) watchRulesFile(ctx context.Context) {
+	ticker := time.NewTicker(rulesFileCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := u.loadRulesFile(); err != nil {
+				logrus.Errorf("Failed to reload the udev rules file %s, error: %s", u.rulesFile, err.Error())
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// loadRulesFile parses and loads the rules file if its content changed
+func (u *Udev) loadRulesFile() error {
+	content, err := os.ReadFile(u.rulesFile)
+	if err != nil {
+		return err
+	}
+
+	u.rulesLock.Lock()
+	defer u.rulesLock.Unlock()
+	if u.fileRules != nil && bytes.Equal(content, u.fileRulesContent) {
+		return nil
+	}
+	rules, err := filter.ParseUdevRules(string(content))
+	if err != nil {
+		return err
+	}
+	if rules == nil {
+		return fmt.Errorf("empty, no rules provided in %q", u.rulesFile)
+	}
+	u.fileRules = rules
+	u.fileRulesContent = content
+	logrus.Infof("Loaded the udev matcher rules of %s:\n%s", u.rulesFile, rules.String())
+	return nil
+}
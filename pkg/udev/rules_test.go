@@ -0,0 +1,120 @@
+package udev
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pilebones/go-udev/netlink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corefake "k8s.io/client-go/kubernetes/fake"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/filter"
+	diskfake "github.com/harvester/node-disk-manager/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/node-disk-manager/pkg/utils/fake"
+)
+
+const configMapKey = filter.DefaultConfigMapNamespace + "/" + filter.DefaultConfigMapName
+
+// handledDevices returns which of the sd, vd and nvme disks the udev events are handled for
+func handledDevices(u *Udev) []string {
+	handled := []string{}
+	for _, devName := range []string{"/dev/sdb", "/dev/vdb", "/dev/nvme0n1"} {
+		if u.matchesRules(netlink.UEvent{Action: netlink.ADD, Env: map[string]string{"DEVNAME": devName}}) {
+			handled = append(handled, devName)
+		}
+	}
+	return handled
+}
+
+func TestRulesPrecedence(t *testing.T) {
+	ctx := context.Background()
+	rulesFile := filepath.Join(t.TempDir(), "udevrules.json")
+	require.NoError(t, os.WriteFile(rulesFile, []byte(`{"rules": [{"env": {"DEVNAME": "^/dev/sd"}}]}`), 0644))
+
+	clientset := corefake.NewSimpleClientset()
+	ndmClientset := diskfake.NewSimpleClientset()
+	loader := filter.NewConfigMapLoader(fake.FakeConfigMapCache(clientset.CoreV1().ConfigMaps), "node1", "", "", "", "")
+	loader.SetNDMConfigCache(fake.FakeNodeDiskManagerConfigCache(ndmClientset.HarvesterhciV1beta1().NodeDiskManagerConfigs))
+	u := &Udev{rulesFile: rulesFile, configLoader: loader}
+	assert.Equal(t, []string{"/dev/sdb", "/dev/vdb", "/dev/nvme0n1"}, handledDevices(u), "every event is handled without rules")
+
+	require.NoError(t, u.loadRulesFile())
+	assert.Equal(t, []string{"/dev/sdb"}, handledDevices(u), "the rules file applies without config")
+
+	// the rules of the ConfigMap take precedence over the file
+	configMap, err := clientset.CoreV1().ConfigMaps(filter.DefaultConfigMapNamespace).Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: filter.DefaultConfigMapName, Namespace: filter.DefaultConfigMapNamespace},
+		Data:       map[string]string{filter.UdevRulesConfigKey: `{"rules": [{"env": {"DEVNAME": "^/dev/nvme"}}]}`},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = u.OnConfigMapChange("default/other", configMap)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/dev/sdb"}, handledDevices(u), "other ConfigMaps are ignored")
+	_, err = u.OnConfigMapChange(configMapKey, configMap)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/dev/nvme0n1"}, handledDevices(u))
+
+	// invalid rules which got through the webhook don't replace the current rules
+	configMap.Data[filter.UdevRulesConfigKey] = `{"rules": [{"env": {"DEVNAME": "^/dev/(sd"}}]}`
+	configMap, err = clientset.CoreV1().ConfigMaps(filter.DefaultConfigMapNamespace).Update(ctx, configMap, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	_, err = u.OnConfigMapChange(configMapKey, configMap)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/dev/nvme0n1"}, handledDevices(u))
+
+	// the rules of the config take precedence over the ConfigMap
+	ndmConfig, err := ndmClientset.HarvesterhciV1beta1().NodeDiskManagerConfigs().Create(ctx, &diskv1.NodeDiskManagerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: filter.DefaultNDMConfigName},
+		Spec: diskv1.NodeDiskManagerConfigSpec{UdevRules: []diskv1.UdevRule{
+			{NodeTarget: diskv1.NodeTarget{Hostname: "node1"}, UdevMatcher: diskv1.UdevMatcher{Env: map[string]string{"DEVNAME": "^/dev/vd"}}},
+		}},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = u.OnNDMConfigChange(filter.DefaultNDMConfigName, ndmConfig)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/dev/vdb"}, handledDevices(u))
+
+	// the rules file applies again once the config sets no rules for the node
+	ndmConfig.Spec.UdevRules[0].Hostname = "node2"
+	ndmConfig, err = ndmClientset.HarvesterhciV1beta1().NodeDiskManagerConfigs().Update(ctx, ndmConfig, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	_, err = u.OnNDMConfigChange(filter.DefaultNDMConfigName, ndmConfig)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/dev/sdb"}, handledDevices(u))
+
+	// the ConfigMap applies again once the config is deleted
+	configMap.Data[filter.UdevRulesConfigKey] = `{"rules": [{"env": {"DEVNAME": "^/dev/(vd|nvme)"}}]}`
+	_, err = clientset.CoreV1().ConfigMaps(filter.DefaultConfigMapNamespace).Update(ctx, configMap, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, ndmClientset.HarvesterhciV1beta1().NodeDiskManagerConfigs().Delete(ctx, filter.DefaultNDMConfigName, metav1.DeleteOptions{}))
+	_, err = u.OnNDMConfigChange(filter.DefaultNDMConfigName, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/dev/vdb", "/dev/nvme0n1"}, handledDevices(u))
+}
+
+func TestLoadRulesFile(t *testing.T) {
+	rulesFile := filepath.Join(t.TempDir(), "udevrules.json")
+	u := &Udev{rulesFile: rulesFile}
+	assert.Error(t, u.loadRulesFile(), "a missing file is an error")
+
+	require.NoError(t, os.WriteFile(rulesFile, []byte(`{"rules": [{"env": {"DEVNAME": "^/dev/sd"}}]}`), 0644))
+	require.NoError(t, u.loadRulesFile())
+	assert.Equal(t, []string{"/dev/sdb"}, handledDevices(u))
+
+	require.NoError(t, os.WriteFile(rulesFile, []byte(`{"rules": [{"env": {"DEVNAME": "^/dev/nvme"}}]}`), 0644))
+	require.NoError(t, u.loadRulesFile())
+	assert.Equal(t, []string{"/dev/nvme0n1"}, handledDevices(u), "the changed file is reloaded")
+
+	// the current rules are kept if the file turns invalid or empty
+	require.NoError(t, os.WriteFile(rulesFile, []byte(`{"rules": [{"env": {"DEVNAME": "^/dev/(sd"}}]}`), 0644))
+	assert.ErrorContains(t, u.loadRulesFile(), "invalid regular expression")
+	require.NoError(t, os.WriteFile(rulesFile, []byte(""), 0644))
+	assert.ErrorContains(t, u.loadRulesFile(), "no rules provided")
+	assert.Equal(t, []string{"/dev/nvme0n1"}, handledDevices(u))
+}
@@ -2,17 +2,15 @@ package udev
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"os"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/harvester/node-disk-manager/pkg/block"
 	"github.com/harvester/node-disk-manager/pkg/controller/blockdevice"
+	"github.com/harvester/node-disk-manager/pkg/filter"
 	"github.com/harvester/node-disk-manager/pkg/option"
-	"github.com/harvester/node-disk-manager/pkg/utils"
 	"github.com/pilebones/go-udev/netlink"
 	"github.com/sirupsen/logrus"
 )
@@ -23,16 +21,34 @@ type Udev struct {
 	startOnce   sync.Once
 	scanner     *blockdevice.Scanner
 	injectError bool
+
+	// the rules matching the udev events to handle, see matchesRules
+	rulesLock          sync.RWMutex
+	rulesFile          string
+	fileRules          *netlink.RuleDefinitions
+	fileRulesContent   []byte
+	configLoader       *filter.ConfigMapLoader
+	configRules        *netlink.RuleDefinitions
+	configRulesContent []byte
 }
 
-func NewUdev(opt *option.Option, scanner *blockdevice.Scanner) *Udev {
-	return &Udev{
-		startOnce:   sync.Once{},
-		namespace:   opt.Namespace,
-		nodeName:    opt.NodeName,
-		scanner:     scanner,
-		injectError: opt.InjectUdevMonitorError,
+// NewUdev returns an error if the udev rules file is set but can't be loaded
+func NewUdev(opt *option.Option, scanner *blockdevice.Scanner) (*Udev, error) {
+	u := &Udev{
+		startOnce:    sync.Once{},
+		namespace:    opt.Namespace,
+		nodeName:     opt.NodeName,
+		scanner:      scanner,
+		injectError:  opt.InjectUdevMonitorError,
+		rulesFile:    opt.UdevRulesFile,
+		configLoader: scanner.ConfigMapLoader,
+	}
+	if u.rulesFile != "" {
+		if err := u.loadRulesFile(); err != nil {
+			return nil, fmt.Errorf("failed to load udev rules file %s: %w", u.rulesFile, err)
+		}
 	}
+	return u, nil
 }
 
 func (u *Udev) Monitor(ctx context.Context) {
@@ -42,6 +58,9 @@ func (u *Udev) Monitor(ctx context.Context) {
 	go u.spawnMonitor(ctx, udevErrChan)
 	mountErrChan := make(chan error)
 	go u.spawnMountWatcher(ctx, mountErrChan)
+	if u.rulesFile != "" {
+		go u.watchRulesFile(ctx)
+	}
 }
 
 func (u *Udev) spawnMonitor(ctx context.Context, errChan chan error) {
@@ -73,11 +92,6 @@ func (u *Udev) spawnMountWatcher(ctx context.Context, errChan chan error) {
 func (u *Udev) monitor(ctx context.Context, errors chan error) {
 	logrus.Infoln("Start monitoring udev processed events")
 
-	matcher, err := getOptionalMatcher(nil)
-	if err != nil {
-		logrus.Fatalf("Failed to get udev config, error: %s", err.Error())
-	}
-
 	conn := new(netlink.UEventConn)
 	if err := conn.Connect(netlink.UdevEvent); err != nil {
 		logrus.Fatalf("Unable to connect to Netlink Kobject UEvent socket, error: %s", err.Error())
@@ -86,7 +100,8 @@ func (u *Udev) monitor(ctx context.Context, errors chan error) {
 
 	uqueue := make(chan netlink.UEvent)
 	errChan := make(chan error)
-	quit := conn.Monitor(uqueue, errChan, matcher)
+	// the events are matched in the loop below, so the rules can be reloaded
+	quit := conn.Monitor(uqueue, errChan, nil)
 	defer close(quit)
 
 	// simulator the error from udev monitor
@@ -100,6 +115,10 @@ func (u *Udev) monitor(ctx context.Context, errors chan error) {
 	for {
 		select {
 		case uevent := <-uqueue:
+			if !u.matchesRules(uevent) {
+				logrus.Tracef("Dropping udev %s event of %s not matching the udev rules", uevent.Action, uevent.Env["DEVNAME"])
+				continue
+			}
 			u.ActionHandler(uevent)
 		case err := <-errChan:
 			errors <- err
@@ -123,7 +142,7 @@ func (u *Udev) ActionHandler(uevent netlink.UEvent) {
 	devPath := udevDevice.GetDevName()
 	var disk *block.Disk
 
-	if strings.Contains(devPath, "dm-") {
+	if strings.Contains(devPath, "dm-") && uevent.Action != netlink.CHANGE {
 		// wait for rebuilding the multipath device
 		time.Sleep(1 * time.Second)
 	}
@@ -147,7 +166,9 @@ func (u *Udev) ActionHandler(uevent netlink.UEvent) {
 		return
 	}
 
-	if uevent.Action != netlink.ADD {
+	// CHANGE is emitted for a whole disk when its capacity or its media changed,
+	// e.g. after growing a virtual disk or a SAN LUN
+	if uevent.Action != netlink.ADD && uevent.Action != netlink.CHANGE {
 		return
 	}
 
@@ -157,42 +178,17 @@ func (u *Udev) ActionHandler(uevent netlink.UEvent) {
 		return
 	}
 
-	// just wake up scanner to check if the disk is added, do no-op internally
+	// just wake up scanner to check if the disk is added or changed, do no-op internally
 	u.wakeUpScanner(uevent, devPath, u.namespace)
 }
 
 func (u *Udev) wakeUpScanner(uevent netlink.UEvent, devPath string, namespace string) {
-	utils.CallerWithCondLock(u.scanner.Cond, func() any {
-		logrus.WithFields(logrus.Fields{
-			"namespace":  namespace,
-			"kind":       "BlockDevice",
-			"udevAction": uevent.Action,
-			"device":     devPath,
-		}).Info("udev action triggering scanner wake")
-		u.scanner.Cond.Signal()
-		return nil
-	})
-}
-
-// getOptionalMatcher Parse and load config file which contains rules for matching
-func getOptionalMatcher(filePath *string) (matcher netlink.Matcher, err error) {
-	if filePath == nil || *filePath == "" {
-		return nil, nil
-	}
-
-	stream, err := os.ReadFile(*filePath)
-	if err != nil {
-		return nil, err
-	}
-
-	if stream == nil {
-		return nil, fmt.Errorf("empty, no rules provided in \"%s\", err: %w", *filePath, err)
-	}
-
-	var rules netlink.RuleDefinitions
-	if err := json.Unmarshal(stream, &rules); err != nil {
-		return nil, fmt.Errorf("wrong rule syntax, err: %v", err)
-	}
-
-	return &rules, nil
+	logrus.WithFields(logrus.Fields{
+		"namespace":  namespace,
+		"kind":       "BlockDevice",
+		"udevAction": uevent.Action,
+		"device":     devPath,
+	}).Info("udev action triggering scanner wake")
+	// only the device of the event is re-probed
+	u.scanner.RequestDeviceScan(fmt.Sprintf("udev %s event", uevent.Action), devPath)
 }
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	ghwcontext "github.com/jaypipes/ghw/pkg/context"
+	"github.com/jaypipes/ghw/pkg/linuxpath"
+)
+
+const (
+	QueueAttrScheduler   = "scheduler"
+	QueueAttrReadAheadKB = "read_ahead_kb"
+	QueueAttrNrRequests  = "nr_requests"
+	QueueAttrWriteCache  = "write_cache"
+)
+
+// QueueSettings are the tunable attributes of /sys/block/<disk>/queue, as read from sysfs
+type QueueSettings struct {
+	Scheduler   string
+	ReadAheadKB int64
+	NrRequests  int64
+	// WriteCache is either "write back" or "write through"
+	WriteCache string
+}
+
+// ReadQueueSettings reads the queue attributes of the disk from sysfs
+func ReadQueueSettings(disk string) (*QueueSettings, error) {
+	var settings QueueSettings
+	scheduler, err := readQueueAttribute(disk, QueueAttrScheduler)
+	if err != nil {
+		return nil, err
+	}
+	settings.Scheduler = activeScheduler(scheduler)
+	if settings.WriteCache, err = readQueueAttribute(disk, QueueAttrWriteCache); err != nil {
+		return nil, err
+	}
+	for attribute, value := range map[string]*int64{
+		QueueAttrReadAheadKB: &settings.ReadAheadKB,
+		QueueAttrNrRequests:  &settings.NrRequests,
+	} {
+		content, err := readQueueAttribute(disk, attribute)
+		if err != nil {
+			return nil, err
+		}
+		if *value, err = strconv.ParseInt(content, 10, 64); err != nil {
+			return nil, fmt.Errorf("failed to parse %s of disk %s: %w", attribute, disk, err)
+		}
+	}
+	return &settings, nil
+}
+
+// WriteQueueAttribute sets a queue attribute of the disk in sysfs, the kernel
+// rejects the values it doesn't support, e.g. a scheduler which isn't loaded
+func WriteQueueAttribute(disk, attribute, value string) error {
+	path := queueAttributePath(disk, attribute)
+	if err := os.WriteFile(path, []byte(value), 0644); err != nil {
+		return fmt.Errorf("failed to set %s of disk %s to %q: %w", attribute, disk, value, err)
+	}
+	return nil
+}
+
+func readQueueAttribute(disk, attribute string) (string, error) {
+	content, err := os.ReadFile(queueAttributePath(disk, attribute))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// queueAttributePath resolves /sys/block like pkg/block does, i.e. below GHW_CHROOT when it is set
+func queueAttributePath(disk, attribute string) string {
+	paths := linuxpath.New(ghwcontext.New())
+	return filepath.Join(paths.SysBlock, disk, "queue", attribute)
+}
+
+// activeScheduler returns the scheduler in brackets, e.g. mq-deadline for "none [mq-deadline] kyber"
+func activeScheduler(schedulers string) string {
+	for _, scheduler := range strings.Fields(schedulers) {
+		if strings.HasPrefix(scheduler, "[") && strings.HasSuffix(scheduler, "]") {
+			return strings.Trim(scheduler, "[]")
+		}
+	}
+	return schedulers
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActiveScheduler(t *testing.T) {
+	assert.Equal(t, "mq-deadline", activeScheduler("none [mq-deadline] kyber bfq"))
+	assert.Equal(t, "none", activeScheduler("[none] mq-deadline"))
+	// a device without a choice of scheduler
+	assert.Equal(t, "none", activeScheduler("none"))
+}
+
+func TestQueueAttributesUnderChroot(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("GHW_CHROOT", root)
+	queue := filepath.Join(root, "sys", "block", "sdb", "queue")
+	require.NoError(t, os.MkdirAll(queue, 0755))
+	for attribute, value := range map[string]string{
+		QueueAttrScheduler:   "none [mq-deadline]",
+		QueueAttrReadAheadKB: "128",
+		QueueAttrNrRequests:  "64",
+		QueueAttrWriteCache:  "write back",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(queue, attribute), []byte(value+"\n"), 0644))
+	}
+
+	require.NoError(t, WriteQueueAttribute("sdb", QueueAttrReadAheadKB, "4096"))
+	settings, err := ReadQueueSettings("sdb")
+	require.NoError(t, err)
+	assert.Equal(t, &QueueSettings{Scheduler: "mq-deadline", ReadAheadKB: 4096, NrRequests: 64, WriteCache: "write back"}, settings)
+}
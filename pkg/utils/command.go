@@ -43,22 +43,35 @@ func (exec *Executor) SetTimeout(timeout time.Duration) {
 }
 
 func (exec *Executor) Execute(cmd string, args []string) (string, error) {
-	command := cmd
-	cmdArgs := args
-	if exec.namespace != "" {
-		cmdArgs = make([]string, 0, len(args)+4)
-		cmdArgs = append(cmdArgs,
-			"--mount="+filepath.Join(exec.namespace, "mnt"),
-			"--net="+filepath.Join(exec.namespace, "net"),
-			"--ipc="+filepath.Join(exec.namespace, "ipc"),
-			cmd,
-		)
-		cmdArgs = append(cmdArgs, args...)
-		command = NSBinary
-	}
+	command, cmdArgs := exec.command(cmd, args)
 	return execute(command, cmdArgs, exec.cmdTimeout)
 }
 
+// ExecuteWithExitCode is like Execute, but a command which exits with a non-zero code
+// is not an error. The exit code and the combined stdout and stderr are returned, so
+// the caller can interpret the code, e.g. of fsck. The error is only set if the
+// command could not run or timed out.
+func (exec *Executor) ExecuteWithExitCode(cmd string, args []string) (string, int, error) {
+	command, cmdArgs := exec.command(cmd, args)
+	return executeWithExitCode(command, cmdArgs, exec.cmdTimeout)
+}
+
+// command returns the command and its arguments, prefixed by nsenter for the namespace
+func (exec *Executor) command(cmd string, args []string) (string, []string) {
+	if exec.namespace == "" {
+		return cmd, args
+	}
+	cmdArgs := make([]string, 0, len(args)+4)
+	cmdArgs = append(cmdArgs,
+		"--mount="+filepath.Join(exec.namespace, "mnt"),
+		"--net="+filepath.Join(exec.namespace, "net"),
+		"--ipc="+filepath.Join(exec.namespace, "ipc"),
+		cmd,
+	)
+	cmdArgs = append(cmdArgs, args...)
+	return NSBinary, cmdArgs
+}
+
 func execute(command string, args []string, timeout time.Duration) (string, error) {
 	cmd := exec.Command(command, args...)
 
@@ -87,3 +100,32 @@ func execute(command string, args []string, timeout time.Duration) (string, erro
 
 	return output.String(), nil
 }
+
+func executeWithExitCode(command string, args []string, timeout time.Duration) (string, int, error) {
+	cmd := exec.Command(command, args...)
+
+	var output bytes.Buffer
+	cmdTimeout := false
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	timer := time.NewTimer(cmdTimeoutNone)
+	if timeout != cmdTimeoutNone {
+		// add timer to kill the process if timeout
+		timer = time.AfterFunc(timeout, func() {
+			cmdTimeout = true
+			cmd.Process.Kill() //nolint:errcheck
+		})
+	}
+	defer timer.Stop()
+
+	err := cmd.Run()
+	if cmdTimeout {
+		return output.String(), -1, errors.Errorf("timeout after %v: %v %v", timeout, command, args)
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return output.String(), -1, errors.Wrapf(err, "failed to execute: %v %v", command, args)
+	}
+	return output.String(), cmd.ProcessState.ExitCode(), nil
+}
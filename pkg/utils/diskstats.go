@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DiskStatsSectorSize is the size of the sectors counted in /proc/diskstats, whatever the disk
+const DiskStatsSectorSize = 512
+
+// DiskStats are the cumulative I/O counters of a disk in /proc/diskstats
+type DiskStats struct {
+	ReadsCompleted  uint64
+	SectorsRead     uint64
+	ReadTimeMs      uint64
+	WritesCompleted uint64
+	SectorsWritten  uint64
+	WriteTimeMs     uint64
+	// IOTimeMs is the time the disk had I/O in flight
+	IOTimeMs uint64
+}
+
+// ReadDiskStats returns the I/O counters of the devices of the host, by device name
+func ReadDiskStats() (map[string]DiskStats, error) {
+	procPath := ProcPath
+	isHostProcMounted, err := IsHostProcMounted()
+	if err != nil {
+		return nil, err
+	}
+	if isHostProcMounted {
+		procPath = HostProcPath
+	}
+	file, err := os.Open(filepath.Join(procPath, "diskstats"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseDiskStats(file)
+}
+
+// parseDiskStats parses the I/O counters in the format of /proc/diskstats
+func parseDiskStats(r io.Reader) (map[string]DiskStats, error) {
+	stats := make(map[string]DiskStats)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// major minor name reads merged sectors ms writes merged sectors ms in-flight io-ms weighted-ms ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 13 {
+			continue
+		}
+		var counters [10]uint64
+		for i := range counters {
+			var err error
+			if counters[i], err = strconv.ParseUint(fields[i+3], 10, 64); err != nil {
+				return nil, fmt.Errorf("failed to parse the diskstats of %s: %w", fields[2], err)
+			}
+		}
+		stats[fields[2]] = DiskStats{
+			ReadsCompleted:  counters[0],
+			SectorsRead:     counters[2],
+			ReadTimeMs:      counters[3],
+			WritesCompleted: counters[4],
+			SectorsWritten:  counters[6],
+			WriteTimeMs:     counters[7],
+			IOTimeMs:        counters[9],
+		}
+	}
+	return stats, scanner.Err()
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDiskStats(t *testing.T) {
+	stats, err := parseDiskStats(strings.NewReader(`   8       0 sda 12020 3125 1139502 6411 35224 24386 1533440 53790 0 41260 61042 0 0 0 0 1543 841
+   8       1 sda1 240 0 12714 41 2 0 2 0 0 60 41 0 0 0 0 0 0
+ 259       0 nvme0n1 4471 0 307962 1003 1230 47 81296 411 0 2744 1414
+   7       0 loop0 0 0 0 0
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]DiskStats{
+		"sda": {
+			ReadsCompleted:  12020,
+			SectorsRead:     1139502,
+			ReadTimeMs:      6411,
+			WritesCompleted: 35224,
+			SectorsWritten:  1533440,
+			WriteTimeMs:     53790,
+			IOTimeMs:        41260,
+		},
+		"sda1": {ReadsCompleted: 240, SectorsRead: 12714, ReadTimeMs: 41, WritesCompleted: 2, SectorsWritten: 2, IOTimeMs: 60},
+		// the kernels before 4.18 have no discard counters
+		"nvme0n1": {
+			ReadsCompleted:  4471,
+			SectorsRead:     307962,
+			ReadTimeMs:      1003,
+			WritesCompleted: 1230,
+			SectorsWritten:  81296,
+			WriteTimeMs:     411,
+			IOTimeMs:        2744,
+		},
+	}, stats, "the truncated lines are skipped")
+
+	_, err = parseDiskStats(strings.NewReader("   8       0 sda 12020 3125 -1 6411 35224 24386 1533440 53790 0 41260 61042\n"))
+	assert.ErrorContains(t, err, "failed to parse the diskstats of sda")
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+
+	ndmscheme "github.com/harvester/node-disk-manager/pkg/generated/clientset/versioned/scheme"
+)
+
+const (
+	// EventComponent is the source component of the events recorded by NDM
+	EventComponent = "harvester-node-disk-manager"
+	// EventReasonDryRun is the reason of the events describing what NDM would do in dry-run mode
+	EventReasonDryRun = "DryRun"
+	// EventReasonDiskExcluded is the reason of the events about a disk getting excluded by the filters
+	EventReasonDiskExcluded = "DiskExcluded"
+	// EventReasonDiskIncluded is the reason of the events about a disk no longer excluded by the filters
+	EventReasonDiskIncluded = "DiskIncluded"
+	// EventReasonCapacityChanged is the reason of the events about a change of the device capacity
+	EventReasonCapacityChanged = "CapacityChanged"
+	// EventReasonResized is the reason of the events about the provisioned storage grown to the device capacity
+	EventReasonResized = "Resized"
+	// EventReasonResizeFailed is the reason of the events about a failure to grow the provisioned storage
+	EventReasonResizeFailed = "ResizeFailed"
+	// EventReasonUnexpectedUnmount is the reason of the events about a provisioned disk unmounted or moved behind NDM's back
+	EventReasonUnexpectedUnmount = "UnexpectedUnmount"
+	// EventReasonRemounted is the reason of the events about a provisioned disk mounted back at its mount point
+	EventReasonRemounted = "Remounted"
+	// EventReasonRemountFailed is the reason of the events about a failure to mount back a provisioned disk
+	EventReasonRemountFailed = "RemountFailed"
+	// EventReasonFilesystemReadOnly is the reason of the events about a provisioned disk whose filesystem turned read-only
+	EventReasonFilesystemReadOnly = "FilesystemReadOnly"
+	// EventReasonFilesystemRecovered is the reason of the events about a provisioned disk whose filesystem is writable again
+	EventReasonFilesystemRecovered = "FilesystemRecovered"
+	// EventReasonRepaired is the reason of the events about a successful filesystem repair
+	EventReasonRepaired = "Repaired"
+	// EventReasonRepairFailed is the reason of the events about a failed filesystem repair
+	EventReasonRepairFailed = "RepairFailed"
+	// EventReasonMountDrift is the reason of the events about a persisted mount which drifted from the block device
+	EventReasonMountDrift = "MountDrift"
+	// EventReasonPersistMountFailed is the reason of the events about a failure to persist the mount of a disk on the host
+	EventReasonPersistMountFailed = "PersistMountFailed"
+	// EventReasonTrimmed is the reason of the events about a scheduled fstrim of a disk
+	EventReasonTrimmed = "Trimmed"
+	// EventReasonTrimFailed is the reason of the events about a failed scheduled fstrim of a disk
+	EventReasonTrimFailed = "TrimFailed"
+	// EventReasonFilesystemPressure is the reason of the events about a provisioned disk running low on space or inodes
+	EventReasonFilesystemPressure = "FilesystemPressure"
+	// EventReasonTuningDrift is the reason of the events about the tuning of a disk re-applied after it drifted
+	EventReasonTuningDrift = "TuningDrift"
+	// EventReasonTuningFailed is the reason of the events about the tuning of a disk which could not be applied
+	EventReasonTuningFailed = "TuningFailed"
+)
+
+// NewEventRecorder returns an event recorder which records the events from NDM on the given node.
+func NewEventRecorder(kubeConfig *rest.Config, nodeName string) (record.EventRecorder, error) {
+	client, err := typedcorev1.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(ndmscheme.AddToScheme(scheme))
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.Events("")})
+	return broadcaster.NewRecorder(scheme, corev1.EventSource{Component: EventComponent, Host: nodeName}), nil
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/rancher/wrangler/v3/pkg/generic"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	corev1type "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -50,3 +51,22 @@ func (c FakeConfigMapClient) Patch(_, _ string, _ types.PatchType, _ []byte, _ .
 func (c FakeConfigMapClient) WithImpersonation(_ rest.ImpersonationConfig) (generic.ClientInterface[*corev1.ConfigMap, *corev1.ConfigMapList], error) {
 	panic("implement me")
 }
+
+// Fake ConfigMapCache implementation for testing
+type FakeConfigMapCache func(namespace string) corev1type.ConfigMapInterface
+
+func (c FakeConfigMapCache) Get(namespace, name string) (*corev1.ConfigMap, error) {
+	return c(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+func (c FakeConfigMapCache) List(_ string, _ labels.Selector) ([]*corev1.ConfigMap, error) {
+	panic("implement me")
+}
+
+func (c FakeConfigMapCache) AddIndexer(_ string, _ generic.Indexer[*corev1.ConfigMap]) {
+	panic("implement me")
+}
+
+func (c FakeConfigMapCache) GetByIndex(_, _ string) ([]*corev1.ConfigMap, error) {
+	panic("implement me")
+}
@@ -0,0 +1,47 @@
+package fake
+
+import (
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type FakeNodeCache struct {
+	nodes []*corev1.Node
+}
+
+func NewNodeCache(nodesToServe []*corev1.Node) ctlcorev1.NodeCache {
+	return &FakeNodeCache{
+		nodes: nodesToServe,
+	}
+}
+
+func (c *FakeNodeCache) AddIndexer(indexName string, indexer generic.Indexer[*corev1.Node]) {
+	panic("unimplemented")
+}
+
+func (c *FakeNodeCache) Get(name string) (*corev1.Node, error) {
+	for _, node := range c.nodes {
+		if node.Name == name {
+			return node.DeepCopy(), nil
+		}
+	}
+	return nil, errors.NewNotFound(schema.GroupResource{}, name)
+}
+
+func (c *FakeNodeCache) GetByIndex(indexName, key string) ([]*corev1.Node, error) {
+	panic("unimplemented")
+}
+
+func (c *FakeNodeCache) List(selector labels.Selector) ([]*corev1.Node, error) {
+	var matching []*corev1.Node
+	for _, node := range c.nodes {
+		if selector.Matches(labels.Set(node.GetLabels())) {
+			matching = append(matching, node.DeepCopy())
+		}
+	}
+	return matching, nil
+}
@@ -0,0 +1,39 @@
+package fake
+
+import (
+	"context"
+
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	diskv1type "github.com/harvester/node-disk-manager/pkg/generated/clientset/versioned/typed/harvesterhci.io/v1beta1"
+)
+
+// Fake NodeDiskManagerConfigCache implementation for testing
+type FakeNodeDiskManagerConfigCache func() diskv1type.NodeDiskManagerConfigInterface
+
+func (c FakeNodeDiskManagerConfigCache) Get(name string) (*diskv1.NodeDiskManagerConfig, error) {
+	return c().Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+func (c FakeNodeDiskManagerConfigCache) List(selector labels.Selector) ([]*diskv1.NodeDiskManagerConfig, error) {
+	list, err := c().List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*diskv1.NodeDiskManagerConfig, 0, len(list.Items))
+	for i := range list.Items {
+		result = append(result, &list.Items[i])
+	}
+	return result, nil
+}
+
+func (c FakeNodeDiskManagerConfigCache) AddIndexer(_ string, _ generic.Indexer[*diskv1.NodeDiskManagerConfig]) {
+	panic("implement me")
+}
+
+func (c FakeNodeDiskManagerConfigCache) GetByIndex(_, _ string) ([]*diskv1.NodeDiskManagerConfig, error) {
+	panic("implement me")
+}
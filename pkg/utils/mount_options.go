@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// remountableMountOptions are the extra ext4 mount options a disk accepts. They can
+// all be changed by a remount, the options taking a value require a positive integer.
+var remountableMountOptions = map[string]bool{
+	"noatime":              false,
+	"relatime":             false,
+	"strictatime":          false,
+	"nodiratime":           false,
+	"diratime":             false,
+	"lazytime":             false,
+	"nolazytime":           false,
+	"discard":              false,
+	"nodiscard":            false,
+	"commit":               true,
+	"inode_readahead_blks": true,
+	"max_batch_time":       true,
+	"min_batch_time":       true,
+}
+
+// atimeMountFlags are the mount options passed as flags instead of data to mount(2)
+var atimeMountFlags = map[string]uintptr{
+	"noatime":     unix.MS_NOATIME,
+	"relatime":    unix.MS_RELATIME,
+	"strictatime": unix.MS_STRICTATIME,
+	"nodiratime":  unix.MS_NODIRATIME,
+	"lazytime":    unix.MS_LAZYTIME,
+}
+
+// ValidateMountOptions checks the extra ext4 mount options of a disk
+func ValidateMountOptions(options []string) error {
+	for _, option := range options {
+		name, value, hasValue := strings.Cut(option, "=")
+		needsValue, ok := remountableMountOptions[name]
+		if !ok {
+			return fmt.Errorf("mount option %q is not supported", option)
+		}
+		if needsValue != hasValue {
+			if needsValue {
+				return fmt.Errorf("mount option %q requires a value", option)
+			}
+			return fmt.Errorf("mount option %q does not take a value", option)
+		}
+		if hasValue {
+			if n, err := strconv.ParseUint(value, 10, 32); err != nil || n == 0 {
+				return fmt.Errorf("mount option %q requires a positive integer", option)
+			}
+		}
+	}
+	return nil
+}
+
+// RemountDisk applies the extra mount options to the ext4 filesystem mounted at the mount point.
+// The options not listed are kept, so the disk must be mounted again to drop an option.
+func RemountDisk(mountPoint string, options []string) error {
+	isHostProcMounted, err := IsHostProcMounted()
+	if err != nil {
+		return err
+	}
+	if isHostProcMounted {
+		opts := strings.Join(append([]string{"remount"}, options...), ",")
+		_, err := executeOnHostNamespace("mount", []string{"-o", opts, mountPoint})
+		return err
+	}
+
+	flags, data := ext4MountFlagsAndData(options)
+	err = syscall.Mount("", mountPoint, "ext4", flags|syscall.MS_REMOUNT, data)
+	return os.NewSyscallError("mount", err)
+}
+
+// ext4MountFlagsAndData splits the default and extra ext4 mount options into the flags and data of mount(2)
+func ext4MountFlagsAndData(options []string) (uintptr, string) {
+	flags := uintptr(syscall.MS_RELATIME)
+	data := []string{ext4MountOptions}
+	for _, option := range options {
+		switch option {
+		case "noatime", "strictatime":
+			flags &^= unix.MS_RELATIME | unix.MS_NOATIME | unix.MS_STRICTATIME
+			flags |= atimeMountFlags[option]
+		case "relatime":
+			flags &^= unix.MS_NOATIME | unix.MS_STRICTATIME
+			flags |= unix.MS_RELATIME
+		case "diratime":
+			flags &^= unix.MS_NODIRATIME
+		case "nolazytime":
+			flags &^= unix.MS_LAZYTIME
+		case "nodiratime", "lazytime":
+			flags |= atimeMountFlags[option]
+		default:
+			data = append(data, option)
+		}
+	}
+	return flags, strings.Join(data, ",")
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestValidateMountOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []string
+		err     string
+	}{
+		{
+			name: "no mount options",
+		},
+		{
+			name:    "supported mount options",
+			options: []string{"noatime", "nodiscard", "commit=30", "inode_readahead_blks=64"},
+		},
+		{
+			name:    "unsupported mount option",
+			options: []string{"noatime", "data=writeback"},
+			err:     `mount option "data=writeback" is not supported`,
+		},
+		{
+			name:    "missing value",
+			options: []string{"commit"},
+			err:     `mount option "commit" requires a value`,
+		},
+		{
+			name:    "unexpected value",
+			options: []string{"noatime=1"},
+			err:     `mount option "noatime=1" does not take a value`,
+		},
+		{
+			name:    "zero value",
+			options: []string{"commit=0"},
+			err:     `mount option "commit=0" requires a positive integer`,
+		},
+		{
+			name:    "negative value",
+			options: []string{"max_batch_time=-1"},
+			err:     `mount option "max_batch_time=-1" requires a positive integer`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMountOptions(tt.options)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestExt4MountFlagsAndData(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []string
+		flags   uintptr
+		data    string
+	}{
+		{
+			name:  "default mount options",
+			flags: unix.MS_RELATIME,
+			data:  ext4MountOptions,
+		},
+		{
+			name:    "noatime replaces relatime",
+			options: []string{"noatime"},
+			flags:   unix.MS_NOATIME,
+			data:    ext4MountOptions,
+		},
+		{
+			name:    "the last atime option wins",
+			options: []string{"noatime", "relatime"},
+			flags:   unix.MS_RELATIME,
+			data:    ext4MountOptions,
+		},
+		{
+			name:    "flags and data",
+			options: []string{"lazytime", "nodiratime", "discard", "commit=30"},
+			flags:   unix.MS_RELATIME | unix.MS_LAZYTIME | unix.MS_NODIRATIME,
+			data:    ext4MountOptions + ",discard,commit=30",
+		},
+		{
+			name:    "the opposite options clear the flags",
+			options: []string{"lazytime", "nolazytime", "nodiratime", "diratime"},
+			flags:   unix.MS_RELATIME,
+			data:    ext4MountOptions,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, data := ext4MountFlagsAndData(tt.options)
+			assert.Equal(t, tt.flags, flags)
+			assert.Equal(t, tt.data, data)
+		})
+	}
+}
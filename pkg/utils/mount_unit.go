@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	systemdUnitDir = "/etc/systemd/system"
+	// mountUnitTarget is the target wanting the mount units, so the disks are mounted on boot
+	mountUnitTarget = "local-fs.target"
+	// mountUnitHeader marks the units written by NDM, the other units are never touched
+	mountUnitHeader = "# Managed by harvester-node-disk-manager, local changes are overwritten"
+	// mountUnitDeviceTimeout bounds how long the boot waits for a missing disk
+	mountUnitDeviceTimeout = "30s"
+)
+
+// MountUnit is a systemd mount unit persisting the mount of a disk on the host,
+// so the disk is mounted on boot without waiting for NDM
+type MountUnit struct {
+	// UUID is the filesystem UUID of the disk
+	UUID       string
+	MountPoint string
+	// Options are the extra ext4 mount options of the disk
+	Options []string
+}
+
+// Name returns the unit name systemd requires for the mount point, e.g.
+// var-lib-harvester-extra\x2ddisks-foo.mount
+func (u *MountUnit) Name() string {
+	return MountUnitName(u.MountPoint)
+}
+
+// Render returns the content of the unit file
+func (u *MountUnit) Render() string {
+	// a missing or failed disk must not drop the host into emergency mode on boot
+	options := ext4MountOptions + ",relatime,nofail,x-systemd.device-timeout=" + mountUnitDeviceTimeout
+	if len(u.Options) > 0 {
+		options = options + "," + strings.Join(u.Options, ",")
+	}
+	return fmt.Sprintf(`%s
+[Unit]
+Description=Harvester extra disk %s
+
+[Mount]
+What=/dev/disk/by-uuid/%s
+Where=%s
+Type=ext4
+Options=%s
+
+[Install]
+WantedBy=%s
+`, mountUnitHeader, u.MountPoint, u.UUID, u.MountPoint, options, mountUnitTarget)
+}
+
+// MountUnitName escapes the mount point like `systemd-escape --path --suffix=mount`
+func MountUnitName(mountPoint string) string {
+	path := strings.Trim(filepath.Clean(mountPoint), "/")
+	if path == "" {
+		return "-.mount"
+	}
+	var sb strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '/':
+			sb.WriteByte('-')
+		case c == '.' && i == 0,
+			!(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == ':' || c == '_' || c == '.'):
+			fmt.Fprintf(&sb, `\x%02x`, c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String() + ".mount"
+}
+
+// SyncMountUnit writes and enables the mount unit on the host unless it is already in place.
+// It returns the differences found in a unit which drifted from the expected one, a unit
+// written for the first time is not a drift.
+func SyncMountUnit(unit *MountUnit) ([]string, error) {
+	root, err := mountUnitRoot()
+	if err != nil {
+		return nil, err
+	}
+	unitPath := filepath.Join(root, systemdUnitDir, unit.Name())
+	wantsPath := filepath.Join(root, systemdUnitDir, mountUnitTarget+".wants", unit.Name())
+	expected := unit.Render()
+
+	var drift []string
+	content, err := os.ReadFile(unitPath)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	case string(content) != expected:
+		if drift = mountUnitDrift(string(content), expected); len(drift) == 0 {
+			drift = []string{"the unit file was modified"}
+		}
+	default:
+		if _, err := os.Lstat(wantsPath); err == nil {
+			return nil, nil
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		drift = []string{"the unit is not enabled"}
+	}
+
+	if err := writeFileAtomic(unitPath, []byte(expected), 0644); err != nil {
+		return nil, err
+	}
+	if err := systemctl("daemon-reload"); err != nil {
+		return nil, err
+	}
+	return drift, systemctl("enable", unit.Name())
+}
+
+// RemoveMountUnit disables and removes the mount unit written by NDM for the mount point,
+// the mount itself is left as is
+func RemoveMountUnit(mountPoint string) error {
+	root, err := mountUnitRoot()
+	if err != nil {
+		return err
+	}
+	name := MountUnitName(mountPoint)
+	unitPath := filepath.Join(root, systemdUnitDir, name)
+	content, err := os.ReadFile(unitPath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(string(content), mountUnitHeader) {
+		return nil
+	}
+
+	if err := systemctl("disable", name); err != nil {
+		return err
+	}
+	if err := os.Remove(unitPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return systemctl("daemon-reload")
+}
+
+// mountUnitDrift lists the [Mount] settings of the existing unit which differ from the expected one
+func mountUnitDrift(existing, expected string) []string {
+	existingSettings, expectedSettings := mountUnitSettings(existing), mountUnitSettings(expected)
+	var drift []string
+	for _, key := range []string{"What", "Where", "Type", "Options"} {
+		if got, want := existingSettings[key], expectedSettings[key]; got != want {
+			drift = append(drift, fmt.Sprintf("%s is %q instead of %q", key, got, want))
+		}
+	}
+	return drift
+}
+
+func mountUnitSettings(content string) map[string]string {
+	settings := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "="); ok {
+			settings[key] = value
+		}
+	}
+	return settings
+}
+
+// mountUnitRoot returns the host root directory seen through the host's init process.
+// The mount units are only managed on the host, so the host /proc must be mounted,
+// otherwise the units would be written and enabled inside the container.
+func mountUnitRoot() (string, error) {
+	isHostProcMounted, err := IsHostProcMounted()
+	if err != nil {
+		return "", err
+	}
+	if !isHostProcMounted {
+		return "", fmt.Errorf("the host /proc is not mounted at %s, the mount units can't be managed", HostProcPath)
+	}
+	return HostProcPath + "/1/root", nil
+}
+
+// writeFileAtomic writes the file through a temporary file renamed over it,
+// so the file is never seen partially written
+func writeFileAtomic(path string, content []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// hostRootPath returns the path of the host root directory, seen
+// through the host's init process when the host /proc is mounted
+func hostRootPath() (string, error) {
+	isHostProcMounted, err := IsHostProcMounted()
+	if err != nil {
+		return "", err
+	}
+	if isHostProcMounted {
+		return HostProcPath + "/1/root", nil
+	}
+	return "/", nil
+}
+
+// systemctl runs systemctl in the host namespace, the callers ensure the host /proc is mounted
+func systemctl(args ...string) error {
+	_, err := executeOnHostNamespace("systemctl", args)
+	return err
+}
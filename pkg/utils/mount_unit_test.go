@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMountUnitName(t *testing.T) {
+	tests := []struct {
+		mountPoint string
+		name       string
+	}{
+		{
+			mountPoint: "/var/lib/harvester/extra-disks/0c4b0a8c1a0b5b7ae6e0b0e3b5a9c3f1",
+			name:       `var-lib-harvester-extra\x2ddisks-0c4b0a8c1a0b5b7ae6e0b0e3b5a9c3f1.mount`,
+		},
+		{
+			mountPoint: "/data//disks/",
+			name:       "data-disks.mount",
+		},
+		{
+			mountPoint: "/mnt/.hidden/disk.1",
+			name:       `mnt-.hidden-disk.1.mount`,
+		},
+		{
+			mountPoint: "/mnt/my disk",
+			name:       `mnt-my\x20disk.mount`,
+		},
+		{
+			mountPoint: "/",
+			name:       "-.mount",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mountPoint, func(t *testing.T) {
+			assert.Equal(t, tt.name, MountUnitName(tt.mountPoint))
+		})
+	}
+}
+
+func TestMountUnitRender(t *testing.T) {
+	unit := &MountUnit{UUID: "5ae7e0d6-7e88-4e8f-9d3c-5b0c6c8a1f2e", MountPoint: "/var/lib/harvester/extra-disks/bd"}
+	content := unit.Render()
+	assert.True(t, strings.HasPrefix(content, mountUnitHeader+"\n"))
+	settings := mountUnitSettings(content)
+	assert.Equal(t, "/dev/disk/by-uuid/5ae7e0d6-7e88-4e8f-9d3c-5b0c6c8a1f2e", settings["What"])
+	assert.Equal(t, "/var/lib/harvester/extra-disks/bd", settings["Where"])
+	assert.Equal(t, "ext4", settings["Type"])
+	assert.Equal(t, ext4MountOptions+",relatime,nofail,x-systemd.device-timeout=30s", settings["Options"])
+	assert.Equal(t, mountUnitTarget, settings["WantedBy"])
+
+	unit.Options = []string{"noatime", "commit=30"}
+	assert.Equal(t, ext4MountOptions+",relatime,nofail,x-systemd.device-timeout=30s,noatime,commit=30", mountUnitSettings(unit.Render())["Options"])
+}
+
+func TestMountUnitDrift(t *testing.T) {
+	unit := &MountUnit{UUID: "5ae7e0d6-7e88-4e8f-9d3c-5b0c6c8a1f2e", MountPoint: "/var/lib/harvester/extra-disks/bd"}
+	expected := unit.Render()
+	assert.Empty(t, mountUnitDrift(expected, expected))
+
+	// only the mount settings are compared
+	assert.Empty(t, mountUnitDrift(strings.Replace(expected, "Description=", "Description=Edited ", 1), expected))
+
+	formatted := &MountUnit{UUID: "11111111-2222-3333-4444-555555555555", MountPoint: unit.MountPoint, Options: []string{"noatime"}}
+	assert.Equal(t, []string{
+		`What is "/dev/disk/by-uuid/11111111-2222-3333-4444-555555555555" instead of "/dev/disk/by-uuid/5ae7e0d6-7e88-4e8f-9d3c-5b0c6c8a1f2e"`,
+		`Options is "` + ext4MountOptions + `,relatime,nofail,x-systemd.device-timeout=30s,noatime" instead of "` + ext4MountOptions + `,relatime,nofail,x-systemd.device-timeout=30s"`,
+	}, mountUnitDrift(formatted.Render(), expected))
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "var-lib-harvester-extra\\x2ddisks-bd.mount")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0600))
+
+	require.NoError(t, writeFileAtomic(path, []byte("new"), 0644))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	// the temporary file is gone
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"sync"
 	"syscall"
@@ -28,6 +29,23 @@ const (
 
 var CmdTimeoutError error
 
+// fsRepairTimeout bounds the filesystem repair, which may read the whole device
+const fsRepairTimeout = time.Hour
+
+// fsRepairOutputLines is the number of the last lines of the repair output kept as summary
+const fsRepairOutputLines = 10
+
+// fstrimTimeout bounds a scheduled trim, which could take a while on a large disk
+const fstrimTimeout = 30 * time.Minute
+
+// FilesystemRepairResult is the outcome of a filesystem repair which ran
+type FilesystemRepairResult struct {
+	Command   string
+	ExitCode  int
+	Output    string
+	Succeeded bool
+}
+
 var ext4MountOptions = strings.Join([]string{
 	"journal_checksum",
 	"journal_ioprio=0",
@@ -88,8 +106,31 @@ func MakeExt4DiskFormatting(devPath, uuid string) error {
 	return nil
 }
 
-// MountDisk mounts the specified ext4 volume device to the specified path
-func MountDisk(devPath, mountPoint string) error {
+// ResizeExt4 grows the ext4 filesystem of the device to the size of the device.
+// A mounted filesystem is grown online.
+func ResizeExt4(devPath string) error {
+	isHostProcMounted, err := IsHostProcMounted()
+	if err != nil {
+		return err
+	}
+	if isHostProcMounted {
+		// the filesystem is mounted in the host namespace
+		if _, err := executeOnHostNamespace("resize2fs", []string{devPath}); err != nil {
+			return fmt.Errorf("failed to resize %s: %w", devPath, err)
+		}
+		return nil
+	}
+
+	cmd := exec.Command("resize2fs", devPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to resize %s. %v: %s", devPath, err,
+			strings.ReplaceAll(strings.TrimSpace(string(output)), "\n", " "))
+	}
+	return nil
+}
+
+// MountDisk mounts the specified ext4 volume device to the specified path with the extra mount options
+func MountDisk(devPath, mountPoint string, options []string) error {
 	var needMkdir bool
 	if _, err := os.Stat(mountPoint); err != nil && !os.IsNotExist(err) {
 		return err
@@ -115,10 +156,10 @@ func MountDisk(devPath, mountPoint string) error {
 	}
 
 	if isHostProcMounted {
-		return mountExt4OnHostNamespace(devPath, mountPoint, false)
+		return mountExt4OnHostNamespace(devPath, mountPoint, false, options)
 	}
 
-	return mountExt4(devPath, mountPoint, false)
+	return mountExt4(devPath, mountPoint, false, options)
 }
 
 // UmountDisk unmounts the specified volume device to the specified path
@@ -135,6 +176,45 @@ func UmountDisk(path string) error {
 	return os.NewSyscallError("umount", err)
 }
 
+// GetMountSource returns the source of the mount at the given path in the host mount
+// namespace, or an empty string if nothing is mounted there
+func GetMountSource(mountPoint string) (string, error) {
+	mountInfo := ProcPath + "/self/mountinfo"
+	isHostProcMounted, err := IsHostProcMounted()
+	if err != nil {
+		return "", err
+	}
+	if isHostProcMounted {
+		mountInfo = HostProcPath + "/1/mountinfo"
+	}
+
+	content, err := os.ReadFile(mountInfo)
+	if err != nil {
+		return "", err
+	}
+	return parseMountSource(string(content), mountPoint), nil
+}
+
+// parseMountSource returns the source of the mount at the given path in the mountinfo content
+func parseMountSource(mountInfo, mountPoint string) string {
+	source := ""
+	for _, line := range strings.Split(mountInfo, "\n") {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+		fields := strings.Fields(line)
+		if len(fields) < 5 || fields[4] != mountPoint {
+			continue
+		}
+		for i, field := range fields {
+			if field == "-" && i+2 < len(fields) {
+				// the last entry wins, as it hides the earlier ones
+				source = fields[i+2]
+				break
+			}
+		}
+	}
+	return source
+}
+
 // ForceUmountWithTimeout umounts the specific device with timeout to the specified path
 func ForceUmountWithTimeout(path string, timeout time.Duration) error {
 	isHostProcMounted, err := IsHostProcMounted()
@@ -150,29 +230,32 @@ func ForceUmountWithTimeout(path string, timeout time.Duration) error {
 	return os.NewSyscallError("umount", err)
 }
 
-// mountExt4 mount the ext4 volume device to the specified path with readonly option
-func mountExt4(device, path string, readonly bool) error {
-	var flags uintptr
-	flags = syscall.MS_RELATIME
+// mountExt4 mount the ext4 volume device to the specified path with readonly and the extra options
+func mountExt4(device, path string, readonly bool, options []string) error {
+	flags, data := ext4MountFlagsAndData(options)
 	if readonly {
 		flags |= syscall.MS_RDONLY
 	}
-	err := syscall.Mount(device, path, "ext4", flags, ext4MountOptions)
+	err := syscall.Mount(device, path, "ext4", flags, data)
 	return os.NewSyscallError("mount", err)
 }
 
 // mountExt4OnHostNamespace provides the same functionality as mountExt4 but on host namespace.
-func mountExt4OnHostNamespace(device, path string, readonly bool) error {
+func mountExt4OnHostNamespace(device, path string, readonly bool, options []string) error {
 	ns := common.GetHostNamespacePath(HostProcPath)
 	executor, err := NewExecutorWithNS(ns)
 	if err != nil {
 		return err
 	}
 
+	// the extra options come last, so they override the defaults, e.g. noatime over relatime
 	opts := ext4MountOptions + ",relatime"
 	if readonly {
 		opts = opts + ",ro"
 	}
+	if len(options) > 0 {
+		opts = opts + "," + strings.Join(options, ",")
+	}
 
 	_, err = executor.Execute("mount", []string{"-t", "ext4", "-o", opts, device, path})
 	return err
@@ -201,6 +284,71 @@ func executeOnHostNamespaceWithTimeout(cmd string, args []string, cmdTimeout tim
 	return executor.Execute(cmd, args)
 }
 
+// RepairFilesystem checks and repairs the unmounted filesystem of the device with
+// e2fsck, or xfs_repair for xfs. The error is only set if the tool could not run,
+// the result tells whether the filesystem was repaired.
+func RepairFilesystem(devPath, fsType string) (*FilesystemRepairResult, error) {
+	cmd, args, err := RepairCommand(devPath, fsType)
+	if err != nil {
+		return nil, err
+	}
+
+	executor := NewExecutor()
+	isHostProcMounted, err := IsHostProcMounted()
+	if err != nil {
+		return nil, err
+	}
+	if isHostProcMounted {
+		if executor, err = NewExecutorWithNS(common.GetHostNamespacePath(HostProcPath)); err != nil {
+			return nil, err
+		}
+	}
+	executor.SetTimeout(fsRepairTimeout)
+
+	output, exitCode, err := executor.ExecuteWithExitCode(cmd, args)
+	if err != nil {
+		return nil, err
+	}
+	return newFilesystemRepairResult(cmd, args, output, exitCode), nil
+}
+
+// RepairCommand returns the command RepairFilesystem runs on the device for the filesystem type.
+// An unknown type is refused, as a filesystem which can't be identified may be of any type.
+func RepairCommand(devPath, fsType string) (string, []string, error) {
+	switch fsType {
+	case "":
+		return "", nil, fmt.Errorf("unknown filesystem type, refusing to repair")
+	case "ext4":
+		// -f checks even a clean filesystem, -y answers yes to every fix
+		return "e2fsck", []string{"-fy", devPath}, nil
+	case "xfs":
+		return "xfs_repair", []string{devPath}, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported filesystem type %s", fsType)
+	}
+}
+
+// newFilesystemRepairResult interprets the exit code of the repair tool, and keeps
+// the last lines of its output as summary
+func newFilesystemRepairResult(cmd string, args []string, output string, exitCode int) *FilesystemRepairResult {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > fsRepairOutputLines {
+		lines = lines[len(lines)-fsRepairOutputLines:]
+	}
+	result := &FilesystemRepairResult{
+		Command:  strings.Join(append([]string{cmd}, args...), " "),
+		ExitCode: exitCode,
+		Output:   strings.Join(lines, "\n"),
+	}
+	if cmd == "e2fsck" {
+		// 1 and 2 mean that errors were corrected, 4 and above that some were left
+		result.Succeeded = exitCode >= 0 && exitCode < 4
+	} else {
+		result.Succeeded = exitCode == 0
+	}
+	return result
+}
+
 // IsFSCorrupted checks if the error is caused by a corrupted filesystem
 func IsFSCorrupted(err error) bool {
 	errMsg := err.Error()
@@ -295,3 +443,68 @@ func GetMapperDeviceFromDM(dmDevice string) (string, error) {
 	logrus.Debugf("Mapper name for device %s: %s", dmDevice, mapperName)
 	return fmt.Sprintf("/dev/mapper/%s", mapperName), nil
 }
+
+// Fstrim discards the unused blocks of the filesystem mounted at the mount point,
+// and returns the bytes fstrim reported as trimmed
+func Fstrim(mountPoint string) (int64, error) {
+	executor := NewExecutor()
+	isHostProcMounted, err := IsHostProcMounted()
+	if err != nil {
+		return 0, err
+	}
+	if isHostProcMounted {
+		if executor, err = NewExecutorWithNS(common.GetHostNamespacePath(HostProcPath)); err != nil {
+			return 0, err
+		}
+	}
+	executor.SetTimeout(fstrimTimeout)
+
+	// e.g. "/var/lib/harvester/extra-disks/foo: 10 GiB (10737418240 bytes) trimmed"
+	output, err := executor.Execute("fstrim", []string{"-v", mountPoint})
+	if err != nil {
+		return 0, err
+	}
+	return parseFstrimOutput(output)
+}
+
+// parseFstrimOutput returns the bytes reported as trimmed in the output of fstrim -v
+func parseFstrimOutput(output string) (int64, error) {
+	_, after, found := strings.Cut(output, "(")
+	if !found {
+		return 0, fmt.Errorf("unexpected fstrim output %q", strings.TrimSpace(output))
+	}
+	var trimmed int64
+	if _, err := fmt.Sscanf(after, "%d bytes", &trimmed); err != nil {
+		return 0, fmt.Errorf("unexpected fstrim output %q", strings.TrimSpace(output))
+	}
+	return trimmed, nil
+}
+
+// FilesystemUsage is the usage of a mounted filesystem as reported by statfs
+type FilesystemUsage struct {
+	TotalBytes     int64
+	UsedBytes      int64
+	AvailableBytes int64
+	TotalInodes    int64
+	UsedInodes     int64
+}
+
+// GetFilesystemUsage returns the usage of the filesystem mounted at the mount point of the host
+func GetFilesystemUsage(mountPoint string) (*FilesystemUsage, error) {
+	root, err := hostRootPath()
+	if err != nil {
+		return nil, err
+	}
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(filepath.Join(root, mountPoint), &stat); err != nil {
+		return nil, os.NewSyscallError("statfs", err)
+	}
+	blockSize := int64(stat.Bsize)
+	return &FilesystemUsage{
+		TotalBytes:     int64(stat.Blocks) * blockSize,
+		UsedBytes:      int64(stat.Blocks-stat.Bfree) * blockSize,
+		AvailableBytes: int64(stat.Bavail) * blockSize,
+		TotalInodes:    int64(stat.Files),
+		UsedInodes:     int64(stat.Files - stat.Ffree),
+	}, nil
+}
@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMountSource(t *testing.T) {
+	mountInfo := `22 1 8:3 / / rw,relatime shared:1 - ext4 /dev/sda3 rw
+98 22 8:16 / /var/lib/harvester/extra-disks/abc rw,relatime shared:40 - ext4 /dev/sdb rw,errors=remount-ro
+99 22 8:32 / /var/lib/harvester/extra-disks/abcd rw,relatime - ext4 /dev/sdc rw
+120 22 8:48 / /mnt/moved rw,relatime shared:50 master:2 - ext4 /dev/sdd rw
+121 120 8:64 / /mnt/moved rw,relatime shared:51 - ext4 /dev/sde rw
+130 22 0:50 / /mnt/broken rw,relatime -
+`
+
+	tests := []struct {
+		name       string
+		mountPoint string
+		source     string
+	}{
+		{
+			name:       "mounted disk",
+			mountPoint: "/var/lib/harvester/extra-disks/abc",
+			source:     "/dev/sdb",
+		},
+		{
+			name:       "mount point which only shares a prefix",
+			mountPoint: "/var/lib/harvester/extra-disks/ab",
+		},
+		{
+			name:       "mount without optional fields",
+			mountPoint: "/var/lib/harvester/extra-disks/abcd",
+			source:     "/dev/sdc",
+		},
+		{
+			name:       "the last mount hides the earlier ones",
+			mountPoint: "/mnt/moved",
+			source:     "/dev/sde",
+		},
+		{
+			name:       "truncated line",
+			mountPoint: "/mnt/broken",
+		},
+		{
+			name:       "nothing mounted",
+			mountPoint: "/mnt/none",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.source, parseMountSource(mountInfo, tt.mountPoint))
+		})
+	}
+}
+
+func TestNewFilesystemRepairResult(t *testing.T) {
+	tests := []struct {
+		name      string
+		cmd       string
+		exitCode  int
+		succeeded bool
+	}{
+		{
+			name:      "clean ext4 filesystem",
+			cmd:       "e2fsck",
+			succeeded: true,
+		},
+		{
+			name:      "ext4 errors corrected",
+			cmd:       "e2fsck",
+			exitCode:  1,
+			succeeded: true,
+		},
+		{
+			name:      "ext4 errors corrected, reboot advised",
+			cmd:       "e2fsck",
+			exitCode:  2,
+			succeeded: true,
+		},
+		{
+			name:     "ext4 errors left uncorrected",
+			cmd:      "e2fsck",
+			exitCode: 4,
+		},
+		{
+			name:     "e2fsck operational error",
+			cmd:      "e2fsck",
+			exitCode: 8,
+		},
+		{
+			name:      "xfs repaired",
+			cmd:       "xfs_repair",
+			succeeded: true,
+		},
+		{
+			name:     "xfs with a dirty log",
+			cmd:      "xfs_repair",
+			exitCode: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := newFilesystemRepairResult(tt.cmd, []string{"/dev/sdb"}, "done\n", tt.exitCode)
+			assert.Equal(t, tt.cmd+" /dev/sdb", result.Command)
+			assert.Equal(t, tt.exitCode, result.ExitCode)
+			assert.Equal(t, "done", result.Output)
+			assert.Equal(t, tt.succeeded, result.Succeeded)
+		})
+	}
+}
+
+func TestRepairCommand(t *testing.T) {
+	cmd, args, err := RepairCommand("/dev/sdb", "ext4")
+	require.NoError(t, err)
+	assert.Equal(t, "e2fsck", cmd)
+	assert.Equal(t, []string{"-fy", "/dev/sdb"}, args)
+
+	cmd, args, err = RepairCommand("/dev/sdb", "xfs")
+	require.NoError(t, err)
+	assert.Equal(t, "xfs_repair", cmd)
+	assert.Equal(t, []string{"/dev/sdb"}, args)
+
+	_, _, err = RepairCommand("/dev/sdb", "")
+	assert.EqualError(t, err, "unknown filesystem type, refusing to repair")
+	_, _, err = RepairCommand("/dev/sdb", "btrfs")
+	assert.EqualError(t, err, "unsupported filesystem type btrfs")
+}
+
+func TestNewFilesystemRepairResultKeepsTheLastLines(t *testing.T) {
+	lines := make([]string, 0, fsRepairOutputLines+5)
+	for i := 0; i < fsRepairOutputLines+5; i++ {
+		lines = append(lines, fmt.Sprintf("Pass %d", i))
+	}
+	result := newFilesystemRepairResult("e2fsck", []string{"-fy", "/dev/sdb"}, strings.Join(lines, "\n"), 1)
+	assert.Equal(t, "e2fsck -fy /dev/sdb", result.Command)
+	assert.Equal(t, strings.Join(lines[5:], "\n"), result.Output)
+}
+
+func TestExecuteWithExitCode(t *testing.T) {
+	output, exitCode, err := executeWithExitCode("sh", []string{"-c", "echo out; echo err >&2; exit 3"}, cmdTimeoutNone)
+	require.NoError(t, err)
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, "out\nerr\n", output)
+
+	_, exitCode, err = executeWithExitCode("sh", []string{"-c", "sleep 5"}, 100*time.Millisecond)
+	assert.ErrorContains(t, err, "timeout after")
+	assert.Equal(t, -1, exitCode)
+
+	_, exitCode, err = executeWithExitCode("/nonexistent/fsck", nil, cmdTimeoutNone)
+	assert.Error(t, err)
+	assert.Equal(t, -1, exitCode)
+}
+
+func TestParseFstrimOutput(t *testing.T) {
+	trimmed, err := parseFstrimOutput("/var/lib/harvester/extra-disks/bd: 10 GiB (10737418240 bytes) trimmed\n")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10737418240), trimmed)
+
+	trimmed, err = parseFstrimOutput("/var/lib/harvester/extra-disks/bd: 0 B (0 bytes) trimmed\n")
+	require.NoError(t, err)
+	assert.Zero(t, trimmed)
+
+	_, err = parseFstrimOutput("")
+	assert.EqualError(t, err, `unexpected fstrim output ""`)
+
+	_, err = parseFstrimOutput("/mnt/(weird): trimmed\n")
+	assert.EqualError(t, err, `unexpected fstrim output "/mnt/(weird): trimmed"`)
+}
+
+func TestGetFilesystemUsage(t *testing.T) {
+	if isHostProcMounted, err := IsHostProcMounted(); err != nil || isHostProcMounted {
+		t.Skip("the host /proc is mounted")
+	}
+	usage, err := GetFilesystemUsage(t.TempDir())
+	require.NoError(t, err)
+	assert.Positive(t, usage.TotalBytes)
+	assert.LessOrEqual(t, usage.UsedBytes, usage.TotalBytes)
+	assert.LessOrEqual(t, usage.AvailableBytes, usage.TotalBytes-usage.UsedBytes)
+	assert.LessOrEqual(t, usage.UsedInodes, usage.TotalInodes)
+
+	_, err = GetFilesystemUsage("/nonexistent/mount/point")
+	assert.Error(t, err)
+}
@@ -18,6 +18,7 @@ import (
 
 	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
 	ctldiskv1 "github.com/harvester/node-disk-manager/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/provisioner"
 	"github.com/harvester/node-disk-manager/pkg/utils"
 )
 
@@ -64,6 +65,9 @@ func (v *Validator) Create(_ *admission.Request, newObj runtime.Object) error {
 	if err := v.validateProvisioner(bd); err != nil {
 		return err
 	}
+	if err := validateMountOptions(bd); err != nil {
+		return err
+	}
 	return v.validateLVMProvisioner(nil, bd)
 }
 
@@ -74,6 +78,15 @@ func (v *Validator) Update(_ *admission.Request, oldObj, newObj runtime.Object)
 	if err := v.validateProvisioner(newBd); err != nil {
 		return err
 	}
+	if err := validateIdentityConflict(oldBd, newBd); err != nil {
+		return err
+	}
+	if err := validateFilesystemRepair(oldBd, newBd); err != nil {
+		return err
+	}
+	if err := validateMountOptions(newBd); err != nil {
+		return err
+	}
 	if err := v.validateLVMProvisioner(oldBd, newBd); err != nil {
 		return err
 	}
@@ -81,6 +94,12 @@ func (v *Validator) Update(_ *admission.Request, oldObj, newObj runtime.Object)
 }
 
 func (v *Validator) validateProvisioner(bd *diskv1.BlockDevice) error {
+	// ext4, LVM and the Longhorn V2 engine all need random writes
+	if bd.Spec.Provision && bd.Status.DeviceStatus.Details.ZonedModel == diskv1.ZonedModelHostManaged {
+		return werror.NewBadRequest(fmt.Sprintf("Blockdevice %s is a host-managed zoned device, which the %s provisioner can't handle",
+			bd.Name, provisionerType(bd)))
+	}
+
 	if bd.Spec.Provisioner == nil {
 		return nil
 	}
@@ -91,6 +110,66 @@ func (v *Validator) validateProvisioner(bd *diskv1.BlockDevice) error {
 	return nil
 }
 
+// provisionerType returns the provisioner the controller would pick for the block device
+func provisionerType(bd *diskv1.BlockDevice) string {
+	if bd.Spec.Provisioner != nil {
+		if bd.Spec.Provisioner.LVM != nil {
+			return provisioner.TypeLVM
+		}
+		if lh := bd.Spec.Provisioner.Longhorn; lh != nil && lh.EngineVersion == provisioner.TypeLonghornV2 {
+			return provisioner.TypeLonghornV2
+		}
+	}
+	return provisioner.TypeLonghornV1
+}
+
+// validateIdentityConflict blocks provisioning a block device whose identity is also
+// claimed by another device, until the scanner reports the conflict as resolved.
+func validateIdentityConflict(oldBd, newBd *diskv1.BlockDevice) error {
+	if oldBd.Spec.Provision || !newBd.Spec.Provision {
+		return nil
+	}
+	if diskv1.IdentityConflict.IsTrue(newBd) {
+		return werror.NewBadRequest(fmt.Sprintf("Blockdevice %s has an identity conflict (%s), set spec.trustedIdentity and wait for the conflict to be resolved before provisioning it",
+			newBd.Name, diskv1.IdentityConflict.GetMessage(newBd)))
+	}
+	return nil
+}
+
+// validateFilesystemRepair only accepts a repair request for the unmounted filesystem
+// of a Longhorn V1 disk, and not together with a force format.
+func validateFilesystemRepair(oldBd, newBd *diskv1.BlockDevice) error {
+	newFS := newBd.Spec.FileSystem
+	if (oldBd.Spec.FileSystem != nil && oldBd.Spec.FileSystem.Repair) || newFS == nil || !newFS.Repair {
+		return nil
+	}
+	if newFS.ForceFormatted {
+		return werror.NewBadRequest("Blockdevice should not request a filesystem repair and a force format together")
+	}
+	if p := newBd.Spec.Provisioner; p != nil && (p.LVM != nil || (p.Longhorn != nil && p.Longhorn.EngineVersion == provisioner.TypeLonghornV2)) {
+		return werror.NewBadRequest(fmt.Sprintf("Blockdevice %s has no filesystem to repair", newBd.Name))
+	}
+	if fs := newBd.Status.DeviceStatus.FileSystem; fs != nil && fs.MountPoint != "" {
+		return werror.NewBadRequest(fmt.Sprintf("Blockdevice %s is mounted at %s, unmount it before requesting a filesystem repair", newBd.Name, fs.MountPoint))
+	}
+	return nil
+}
+
+// validateMountOptions only accepts the mount options which can be applied by a
+// remount, and only for a Longhorn V1 disk as the other provisioners mount nothing.
+func validateMountOptions(bd *diskv1.BlockDevice) error {
+	if bd.Spec.FileSystem == nil || len(bd.Spec.FileSystem.MountOptions) == 0 {
+		return nil
+	}
+	if p := bd.Spec.Provisioner; p != nil && (p.LVM != nil || (p.Longhorn != nil && p.Longhorn.EngineVersion == provisioner.TypeLonghornV2)) {
+		return werror.NewBadRequest(fmt.Sprintf("Blockdevice %s has no filesystem to mount with options", bd.Name))
+	}
+	if err := utils.ValidateMountOptions(bd.Spec.FileSystem.MountOptions); err != nil {
+		return werror.NewBadRequest(fmt.Sprintf("Blockdevice %s has invalid mount options: %v", bd.Name, err))
+	}
+	return nil
+}
+
 func (v *Validator) validateLHDisk(oldBd, newBd *diskv1.BlockDevice) error {
 	if oldBd.Spec.Provisioner == nil || newBd.Spec.Provisioner == nil {
 		return nil
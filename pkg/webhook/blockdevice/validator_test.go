@@ -140,6 +140,78 @@ func TestUpdate(t *testing.T) {
 			newBlockDeice:  newBlockDevice("blockdevice-to-remove", "node-1", false),
 			expectedErr:    false,
 		},
+		{
+			name:            "provisioning rejected with an identity conflict",
+			lhNodesToCache:  []*lhv1.Node{},
+			biToCache:       []*lhv1.BackingImage{},
+			replicasToCache: []*lhv1.Replica{},
+			oldBlockDevice:  newBlockDeviceWithIdentityConflict("blockdevice-conflict", "node-1", false, true),
+			newBlockDeice:   newBlockDeviceWithIdentityConflict("blockdevice-conflict", "node-1", true, true),
+			expectedErr:     true,
+		},
+		{
+			name:            "provisioning allowed with a resolved identity conflict",
+			lhNodesToCache:  []*lhv1.Node{},
+			biToCache:       []*lhv1.BackingImage{},
+			replicasToCache: []*lhv1.Replica{},
+			oldBlockDevice:  newBlockDeviceWithIdentityConflict("blockdevice-conflict", "node-1", false, false),
+			newBlockDeice:   newBlockDeviceWithIdentityConflict("blockdevice-conflict", "node-1", true, false),
+			expectedErr:     false,
+		},
+		{
+			name:            "filesystem repair of an unmounted device",
+			lhNodesToCache:  []*lhv1.Node{},
+			biToCache:       []*lhv1.BackingImage{},
+			replicasToCache: []*lhv1.Replica{},
+			oldBlockDevice:  newBlockDeviceWithRepair("corrupted-disk", "node-1", "", false, false),
+			newBlockDeice:   newBlockDeviceWithRepair("corrupted-disk", "node-1", "", true, false),
+			expectedErr:     false,
+		},
+		{
+			name:            "filesystem repair rejected for a mounted device",
+			lhNodesToCache:  []*lhv1.Node{},
+			biToCache:       []*lhv1.BackingImage{},
+			replicasToCache: []*lhv1.Replica{},
+			oldBlockDevice:  newBlockDeviceWithRepair("readonly-disk", "node-1", "/var/lib/harvester/extra-disks/readonly-disk", false, false),
+			newBlockDeice:   newBlockDeviceWithRepair("readonly-disk", "node-1", "/var/lib/harvester/extra-disks/readonly-disk", true, false),
+			expectedErr:     true,
+		},
+		{
+			name:            "filesystem repair rejected together with a force format",
+			lhNodesToCache:  []*lhv1.Node{},
+			biToCache:       []*lhv1.BackingImage{},
+			replicasToCache: []*lhv1.Replica{},
+			oldBlockDevice:  newBlockDeviceWithRepair("corrupted-disk", "node-1", "", false, false),
+			newBlockDeice:   newBlockDeviceWithRepair("corrupted-disk", "node-1", "", true, true),
+			expectedErr:     true,
+		},
+		{
+			name:            "remountable mount options",
+			lhNodesToCache:  []*lhv1.Node{},
+			biToCache:       []*lhv1.BackingImage{},
+			replicasToCache: []*lhv1.Replica{},
+			oldBlockDevice:  newBlockDeviceWithMountOptions("tuned-disk", "node-1", nil),
+			newBlockDeice:   newBlockDeviceWithMountOptions("tuned-disk", "node-1", []string{"noatime", "discard", "commit=30"}),
+			expectedErr:     false,
+		},
+		{
+			name:            "mount option changing the mount behaviour rejected",
+			lhNodesToCache:  []*lhv1.Node{},
+			biToCache:       []*lhv1.BackingImage{},
+			replicasToCache: []*lhv1.Replica{},
+			oldBlockDevice:  newBlockDeviceWithMountOptions("tuned-disk", "node-1", nil),
+			newBlockDeice:   newBlockDeviceWithMountOptions("tuned-disk", "node-1", []string{"noatime", "errors=continue"}),
+			expectedErr:     true,
+		},
+		{
+			name:            "mount option with an invalid value rejected",
+			lhNodesToCache:  []*lhv1.Node{},
+			biToCache:       []*lhv1.BackingImage{},
+			replicasToCache: []*lhv1.Replica{},
+			oldBlockDevice:  newBlockDeviceWithMountOptions("tuned-disk", "node-1", nil),
+			newBlockDeice:   newBlockDeviceWithMountOptions("tuned-disk", "node-1", []string{"commit=soon"}),
+			expectedErr:     true,
+		},
 	}
 
 	for _, test := range tests {
@@ -183,6 +255,101 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestValidateProvisioner(t *testing.T) {
+	tests := []struct {
+		name        string
+		provisioner *diskv1.ProvisionerInfo
+		zonedModel  string
+		expectedErr bool
+	}{
+		{
+			name:        "no provisioner",
+			provisioner: nil,
+			expectedErr: false,
+		},
+		{
+			name: "longhorn v1",
+			provisioner: &diskv1.ProvisionerInfo{
+				Longhorn: &diskv1.LonghornProvisionerInfo{EngineVersion: "LonghornV1"},
+			},
+			expectedErr: false,
+		},
+		{
+			name: "longhorn v2",
+			provisioner: &diskv1.ProvisionerInfo{
+				Longhorn: &diskv1.LonghornProvisionerInfo{EngineVersion: "LonghornV2", DiskDriver: lhv1.DiskDriverAio},
+			},
+			expectedErr: false,
+		},
+		{
+			// the block devices admitted before must still be accepted on update
+			name: "unknown longhorn engine version is left to the controller",
+			provisioner: &diskv1.ProvisionerInfo{
+				Longhorn: &diskv1.LonghornProvisionerInfo{EngineVersion: "LonghornV3"},
+			},
+			expectedErr: false,
+		},
+		{
+			name: "lvm",
+			provisioner: &diskv1.ProvisionerInfo{
+				LVM: &diskv1.LVMProvisionerInfo{VgName: "vg01"},
+			},
+			expectedErr: false,
+		},
+		{
+			name: "lvm without volume group is left to the controller",
+			provisioner: &diskv1.ProvisionerInfo{
+				LVM: &diskv1.LVMProvisionerInfo{},
+			},
+			expectedErr: false,
+		},
+		{
+			name: "multiple provisioners",
+			provisioner: &diskv1.ProvisionerInfo{
+				Longhorn: &diskv1.LonghornProvisionerInfo{EngineVersion: "LonghornV1"},
+				LVM:      &diskv1.LVMProvisionerInfo{VgName: "vg01"},
+			},
+			expectedErr: true,
+		},
+		{
+			name:        "host-managed zoned device",
+			provisioner: nil,
+			zonedModel:  "host-managed",
+			expectedErr: true,
+		},
+		{
+			name: "host-managed zoned device with lvm",
+			provisioner: &diskv1.ProvisionerInfo{
+				LVM: &diskv1.LVMProvisionerInfo{VgName: "vg01"},
+			},
+			zonedModel:  "host-managed",
+			expectedErr: true,
+		},
+		{
+			name: "host-aware zoned device",
+			provisioner: &diskv1.ProvisionerInfo{
+				Longhorn: &diskv1.LonghornProvisionerInfo{EngineVersion: "LonghornV1"},
+			},
+			zonedModel:  "host-aware",
+			expectedErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bd := newBlockDevice("bd-1", "node-1", true)
+			bd.Spec.Provisioner = tt.provisioner
+			bd.Status.DeviceStatus.Details.ZonedModel = tt.zonedModel
+			err := (&Validator{}).validateProvisioner(bd)
+			if tt.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func newBlockDevice(name, nodeName string, provision bool) *diskv1.BlockDevice {
 	return &diskv1.BlockDevice{
 		ObjectMeta: metav1.ObjectMeta{
@@ -198,6 +365,26 @@ func newBlockDevice(name, nodeName string, provision bool) *diskv1.BlockDevice {
 	}
 }
 
+func newBlockDeviceWithIdentityConflict(name, nodeName string, provision, conflict bool) *diskv1.BlockDevice {
+	bd := newBlockDevice(name, nodeName, provision)
+	diskv1.IdentityConflict.SetStatusBool(bd, conflict)
+	diskv1.IdentityConflict.Message(bd, "WWN 0x5000c500a0b1c2d3 is also reported by /dev/sdc")
+	return bd
+}
+
+func newBlockDeviceWithRepair(name, nodeName, mountPoint string, repair, forceFormatted bool) *diskv1.BlockDevice {
+	bd := newBlockDevice(name, nodeName, true)
+	bd.Spec.FileSystem = &diskv1.FilesystemInfo{Repair: repair, ForceFormatted: forceFormatted}
+	bd.Status.DeviceStatus.FileSystem = &diskv1.FilesystemStatus{MountPoint: mountPoint, Type: "ext4"}
+	return bd
+}
+
+func newBlockDeviceWithMountOptions(name, nodeName string, options []string) *diskv1.BlockDevice {
+	bd := newBlockDevice(name, nodeName, true)
+	bd.Spec.FileSystem = &diskv1.FilesystemInfo{MountOptions: options}
+	return bd
+}
+
 func newLHNode(name string, disks map[string]string) *lhv1.Node {
 	diskStatus := make(map[string]*lhv1.DiskStatus)
 	for bdName, uuid := range disks {
@@ -7,9 +7,11 @@ import (
 	"github.com/harvester/webhook/pkg/server/admission"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/harvester/node-disk-manager/pkg/filter"
+	ctldiskv1 "github.com/harvester/node-disk-manager/pkg/generated/controllers/harvesterhci.io/v1beta1"
 )
 
 const (
@@ -19,15 +21,19 @@ const (
 type Validator struct {
 	admission.DefaultValidator
 
-	loader *filter.ConfigMapLoader
+	loader         *filter.ConfigMapLoader
+	ndmConfigCache ctldiskv1.NodeDiskManagerConfigCache
 }
 
-func NewConfigMapValidator() *Validator {
+// NewConfigMapValidator returns the validator of the NDM ConfigMap. The NodeDiskManagerConfig cache
+// tells whether filters.yaml and autoprovision.yaml are ignored, they are always used when it is nil.
+func NewConfigMapValidator(ndmConfigCache ctldiskv1.NodeDiskManagerConfigCache) *Validator {
 	// Create a loader instance for parsing YAML
-	// The nil configMapClient and empty strings are fine since we only use the parse methods
+	// The nil configMapCache and empty strings are fine since we only use the parse methods
 	loader := filter.NewConfigMapLoader(nil, "", "", "", "", "")
 	return &Validator{
-		loader: loader,
+		loader:         loader,
+		ndmConfigCache: ndmConfigCache,
 	}
 }
 
@@ -47,6 +53,16 @@ func (v *Validator) validateConfigMap(cm *corev1.ConfigMap) error {
 		return nil
 	}
 
+	ignored, err := v.rulesIgnored()
+	if err != nil {
+		return werror.NewInternalError(err.Error())
+	}
+	if ignored {
+		// the rules of the ConfigMap are neither validated nor used while the
+		// NodeDiskManagerConfig exists, the agents log that they are ignored
+		return nil
+	}
+
 	// Validate filters.yaml if present
 	if filtersYAML, exists := cm.Data[filter.FiltersConfigKey]; exists && filtersYAML != "" {
 		if err := v.validateFiltersYAML(filtersYAML); err != nil {
@@ -61,12 +77,20 @@ func (v *Validator) validateConfigMap(cm *corev1.ConfigMap) error {
 		}
 	}
 
+	// Validate udevrules.json if present
+	if udevRules, exists := cm.Data[filter.UdevRulesConfigKey]; exists && udevRules != "" {
+		if _, err := filter.ParseUdevRules(udevRules); err != nil {
+			return werror.NewBadRequest(fmt.Sprintf("invalid %s: %v", filter.UdevRulesConfigKey, err))
+		}
+	}
+
 	return nil
 }
 
 // validateFiltersYAML validates the filters.yaml content
 // First pass: ensure it can be parsed
-// Second pass: ensure no hostname is empty string
+// Second pass: ensure every block selects nodes by hostname or nodeSelector, and the
+// node selectors, size ranges and expressions are valid
 func (v *Validator) validateFiltersYAML(yamlContent string) error {
 	// First pass: try to parse the YAML
 	configs, err := v.loader.ParseFilterConfigs(yamlContent)
@@ -74,19 +98,36 @@ func (v *Validator) validateFiltersYAML(yamlContent string) error {
 		return fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
-	// Second pass: validate no empty hostname
+	// Second pass: validate the node selection, size ranges and expressions
 	for i, config := range configs {
-		if config.Hostname == "" {
-			return fmt.Errorf("filter config at index %d has empty hostname, which is not allowed", i)
+		if err := config.Validate(); err != nil {
+			return fmt.Errorf("filter config at index %d has %w", i, err)
 		}
 	}
 
 	return nil
 }
 
+// rulesIgnored tells whether the NodeDiskManagerConfig exists, in which case NDM
+// ignores the filters.yaml, autoprovision.yaml and udevrules.json of the ConfigMap
+func (v *Validator) rulesIgnored() (bool, error) {
+	if v.ndmConfigCache == nil {
+		return false, nil
+	}
+	_, err := v.ndmConfigCache.Get(filter.DefaultNDMConfigName)
+	if err == nil {
+		return true, nil
+	}
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to get NodeDiskManagerConfig %s: %w", filter.DefaultNDMConfigName, err)
+}
+
 // validateAutoProvisionYAML validates the autoprovision.yaml content
 // First pass: ensure it can be parsed
-// Second pass: ensure no hostname is empty string
+// Second pass: ensure every block selects nodes by hostname or nodeSelector, and the
+// node selectors and expressions are valid
 func (v *Validator) validateAutoProvisionYAML(yamlContent string) error {
 	// First pass: try to parse the YAML
 	configs, err := v.loader.ParseAutoProvisionConfigs(yamlContent)
@@ -94,10 +135,10 @@ func (v *Validator) validateAutoProvisionYAML(yamlContent string) error {
 		return fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
-	// Second pass: validate no empty hostname
+	// Second pass: validate the node selection and expressions
 	for i, config := range configs {
-		if config.Hostname == "" {
-			return fmt.Errorf("autoprovision config at index %d has empty hostname, which is not allowed", i)
+		if err := config.Validate(); err != nil {
+			return fmt.Errorf("autoprovision config at index %d has %w", i, err)
 		}
 	}
 
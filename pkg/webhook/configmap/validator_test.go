@@ -7,11 +7,14 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
 	"github.com/harvester/node-disk-manager/pkg/filter"
+	diskfake "github.com/harvester/node-disk-manager/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/node-disk-manager/pkg/utils/fake"
 )
 
 func TestValidateFiltersYAML(t *testing.T) {
-	validator := NewConfigMapValidator()
+	validator := NewConfigMapValidator(nil)
 
 	tests := []struct {
 		name        string
@@ -48,12 +51,62 @@ func TestValidateFiltersYAML(t *testing.T) {
 			expectError: true,
 			errorMsg:    "filter config at index 1 has empty hostname",
 		},
+		{
+			name: "valid filters with node selector and no hostname",
+			yamlContent: `- nodeSelector:
+    matchLabels:
+      storage: "true"
+  excludeVendors: ["longhorn"]`,
+			expectError: false,
+		},
+		{
+			name: "invalid: node selector with unknown operator",
+			yamlContent: `- nodeSelector:
+    matchExpressions:
+    - key: rack
+      operator: Near
+      values: ["r1"]
+  excludeVendors: ["longhorn"]`,
+			expectError: true,
+			errorMsg:    "filter config at index 0 has invalid nodeSelector",
+		},
 		{
 			name:        "invalid: malformed YAML",
 			yamlContent: `invalid: yaml: content: [[[`,
 			expectError: true,
 			errorMsg:    "failed to parse YAML",
 		},
+		{
+			name: "valid filters with include rules",
+			yamlContent: `- hostname: "*"
+  includeWWNs: ["0x5000c500*"]
+  includeSizeRange:
+    min: 1Ti
+    max: 4Ti`,
+			expectError: false,
+		},
+		{
+			name: "valid filters with exclude expressions",
+			yamlContent: `- hostname: "*"
+  excludeExpressions: ["disk.isRemovable || disk.sizeBytes < 10*GiB"]`,
+			expectError: false,
+		},
+		{
+			name: "invalid: exclude expression does not compile",
+			yamlContent: `- hostname: "*"
+  excludeExpressions: ["disk.sizeBytes <"]`,
+			expectError: true,
+			errorMsg:    "filter config at index 0 has invalid excludeExpressions",
+		},
+		{
+			name: "invalid: include size range min above max",
+			yamlContent: `- hostname: "*"
+  includeSizeRange:
+    min: 4Ti
+    max: 1Ti`,
+			expectError: true,
+			errorMsg:    "filter config at index 0 has invalid includeSizeRange",
+		},
 		{
 			name:        "valid: empty content",
 			yamlContent: "",
@@ -77,7 +130,7 @@ func TestValidateFiltersYAML(t *testing.T) {
 }
 
 func TestValidateAutoProvisionYAML(t *testing.T) {
-	validator := NewConfigMapValidator()
+	validator := NewConfigMapValidator(nil)
 
 	tests := []struct {
 		name        string
@@ -93,6 +146,21 @@ func TestValidateAutoProvisionYAML(t *testing.T) {
     - "/dev/sdd"`,
 			expectError: false,
 		},
+		{
+			name: "valid autoprovision with expressions",
+			yamlContent: `- hostname: "*"
+  expressions:
+    - 'disk.driveType == "SSD" && disk.sizeBytes > 500*GiB && !disk.isRemovable'`,
+			expectError: false,
+		},
+		{
+			name: "invalid: expression is not a bool",
+			yamlContent: `- hostname: "*"
+  expressions:
+    - 'disk.sizeBytes * 2'`,
+			expectError: true,
+			errorMsg:    "autoprovision config at index 0 has invalid expressions",
+		},
 		{
 			name: "valid autoprovision with specific hostname",
 			yamlContent: `- hostname: "harvester1"
@@ -148,7 +216,7 @@ func TestValidateAutoProvisionYAML(t *testing.T) {
 }
 
 func TestValidateConfigMap(t *testing.T) {
-	validator := NewConfigMapValidator()
+	validator := NewConfigMapValidator(nil)
 
 	tests := []struct {
 		name        string
@@ -202,6 +270,47 @@ func TestValidateConfigMap(t *testing.T) {
 			expectError: true,
 			errorMsg:    "invalid autoprovision.yaml",
 		},
+		{
+			name: "valid udevrules.json",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "harvester-node-disk-manager",
+					Namespace: "harvester-system",
+				},
+				Data: map[string]string{
+					filter.UdevRulesConfigKey: `{"rules": [{"env": {"DEVNAME": "^/dev/(sd|vd|nvme)"}}, {"env": {"DM_UUID": "^mpath-"}}]}`,
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid udevrules.json with bad regular expression",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "harvester-node-disk-manager",
+					Namespace: "harvester-system",
+				},
+				Data: map[string]string{
+					filter.UdevRulesConfigKey: `{"rules": [{"env": {"DEVNAME": "^/dev/(sd"}}]}`,
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid udevrules.json",
+		},
+		{
+			name: "invalid udevrules.json without rules",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "harvester-node-disk-manager",
+					Namespace: "harvester-system",
+				},
+				Data: map[string]string{
+					filter.UdevRulesConfigKey: `{"rules": []}`,
+				},
+			},
+			expectError: true,
+			errorMsg:    "would drop every udev event",
+		},
 		{
 			name: "non-target ConfigMap should be ignored",
 			configMap: &corev1.ConfigMap{
@@ -242,3 +351,22 @@ func TestValidateConfigMap(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateConfigMapIgnoredRules(t *testing.T) {
+	ndmClientset := diskfake.NewSimpleClientset(&diskv1.NodeDiskManagerConfig{ObjectMeta: metav1.ObjectMeta{Name: filter.DefaultNDMConfigName}})
+	validator := NewConfigMapValidator(fake.FakeNodeDiskManagerConfigCache(ndmClientset.HarvesterhciV1beta1().NodeDiskManagerConfigs))
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: harvesterNodeDiskManagerConfigMap, Namespace: "harvester-system"},
+		Data: map[string]string{
+			filter.FiltersConfigKey: `- hostname: ""
+  excludeVendors: ["longhorn"]`,
+		},
+	}
+	// the ignored filters.yaml isn't validated
+	assert.NoError(t, validator.validateConfigMap(cm))
+
+	// neither are the udev rules
+	cm.Data = map[string]string{filter.UdevRulesConfigKey: `{"rules": [`}
+	assert.NoError(t, validator.validateConfigMap(cm))
+}
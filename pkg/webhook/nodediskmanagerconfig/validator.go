@@ -0,0 +1,84 @@
+package nodediskmanagerconfig
+
+import (
+	"fmt"
+
+	werror "github.com/harvester/webhook/pkg/error"
+	"github.com/harvester/webhook/pkg/server/admission"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/node-disk-manager/pkg/filter"
+)
+
+type Validator struct {
+	admission.DefaultValidator
+}
+
+func NewNodeDiskManagerConfigValidator() *Validator {
+	return &Validator{}
+}
+
+func (v *Validator) Create(_ *admission.Request, newObj runtime.Object) error {
+	ndmConfig := newObj.(*diskv1.NodeDiskManagerConfig)
+	if ndmConfig.Name != filter.DefaultNDMConfigName {
+		return werror.NewBadRequest(fmt.Sprintf("only the NodeDiskManagerConfig named %s is supported", filter.DefaultNDMConfigName))
+	}
+	return v.validateSpec(ndmConfig)
+}
+
+func (v *Validator) Update(_ *admission.Request, _ runtime.Object, newObj runtime.Object) error {
+	ndmConfig := newObj.(*diskv1.NodeDiskManagerConfig)
+	return v.validateSpec(ndmConfig)
+}
+
+// validateSpec ensures every rule selects nodes by hostname or nodeSelector,
+// and the node selectors, size ranges, expressions, mount roots, trim intervals, tuning profiles and udev rules are valid
+func (v *Validator) validateSpec(ndmConfig *diskv1.NodeDiskManagerConfig) error {
+	for i, config := range filter.FilterConfigsFromRules(ndmConfig.Spec.Filters) {
+		if err := config.Validate(); err != nil {
+			return werror.NewBadRequest(fmt.Sprintf("filter rule at index %d has %v", i, err))
+		}
+	}
+	for i, config := range filter.AutoProvisionConfigsFromRules(ndmConfig.Spec.AutoProvision) {
+		if err := config.Validate(); err != nil {
+			return werror.NewBadRequest(fmt.Sprintf("autoProvision rule at index %d has %v", i, err))
+		}
+	}
+	for i, config := range filter.UdevConfigsFromRules(ndmConfig.Spec.UdevRules) {
+		if err := config.Validate(); err != nil {
+			return werror.NewBadRequest(fmt.Sprintf("udev rule at index %d has %v", i, err))
+		}
+	}
+	for i, config := range filter.MountRootConfigsFromRules(ndmConfig.Spec.MountRoots) {
+		if err := config.Validate(); err != nil {
+			return werror.NewBadRequest(fmt.Sprintf("mountRoots rule at index %d has %v", i, err))
+		}
+	}
+	for i, config := range filter.TrimConfigsFromRules(ndmConfig.Spec.Trim) {
+		if err := config.Validate(); err != nil {
+			return werror.NewBadRequest(fmt.Sprintf("trim rule at index %d has %v", i, err))
+		}
+	}
+	for i, config := range filter.TuningConfigsFromRules(ndmConfig.Spec.Tuning) {
+		if err := config.Validate(); err != nil {
+			return werror.NewBadRequest(fmt.Sprintf("tuning rule at index %d has %v", i, err))
+		}
+	}
+	return nil
+}
+
+func (v *Validator) Resource() admission.Resource {
+	return admission.Resource{
+		Names:      []string{"nodediskmanagerconfigs"},
+		Scope:      admissionregv1.ClusterScope,
+		APIGroup:   diskv1.SchemeGroupVersion.Group,
+		APIVersion: diskv1.SchemeGroupVersion.Version,
+		ObjectType: &diskv1.NodeDiskManagerConfig{},
+		OperationTypes: []admissionregv1.OperationType{
+			admissionregv1.Create,
+			admissionregv1.Update,
+		},
+	}
+}
@@ -0,0 +1,205 @@
+package nodediskmanagerconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	diskv1 "github.com/harvester/node-disk-manager/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+func TestValidateNodeDiskManagerConfig(t *testing.T) {
+	validator := NewNodeDiskManagerConfigValidator()
+
+	tests := []struct {
+		name        string
+		configName  string
+		spec        diskv1.NodeDiskManagerConfigSpec
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:       "valid config",
+			configName: "default",
+			spec: diskv1.NodeDiskManagerConfigSpec{
+				Filters: []diskv1.DiskFilterRule{
+					{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, ExcludeLabels: []string{"COS_*", "HARV_*"}},
+					{NodeTarget: diskv1.NodeTarget{NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"rack": "r1"}}}, IncludeSizeRange: &diskv1.DiskSizeRange{Min: "1Ti"}},
+				},
+				AutoProvision: []diskv1.AutoProvisionRule{
+					{NodeTarget: diskv1.NodeTarget{Hostname: "harvester1"}, Expressions: []string{`disk.driveType == "SSD"`}},
+				},
+				MountRoots: []diskv1.MountRootRule{
+					{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, Path: "/mnt/extra-disks"},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:        "invalid: config not named default",
+			configName:  "custom",
+			expectError: true,
+			errorMsg:    "only the NodeDiskManagerConfig named default is supported",
+		},
+		{
+			name:       "invalid: filter rule without node target",
+			configName: "default",
+			spec: diskv1.NodeDiskManagerConfigSpec{
+				Filters: []diskv1.DiskFilterRule{
+					{ExcludeVendors: []string{"longhorn"}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "filter rule at index 0 has empty hostname and no nodeSelector",
+		},
+		{
+			name:       "invalid: filter rule with min size above max size",
+			configName: "default",
+			spec: diskv1.NodeDiskManagerConfigSpec{
+				Filters: []diskv1.DiskFilterRule{
+					{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, IncludeSizeRange: &diskv1.DiskSizeRange{Min: "4Ti", Max: "1Ti"}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "filter rule at index 0 has invalid includeSizeRange",
+		},
+		{
+			name:       "invalid: auto-provision expression does not compile",
+			configName: "default",
+			spec: diskv1.NodeDiskManagerConfigSpec{
+				AutoProvision: []diskv1.AutoProvisionRule{
+					{NodeTarget: diskv1.NodeTarget{Hostname: "*"}},
+					{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, Expressions: []string{"disk.sizeBytes <"}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "autoProvision rule at index 1 has invalid expressions",
+		},
+		{
+			name:       "valid: udev rules for disks and multipath devices",
+			configName: "default",
+			spec: diskv1.NodeDiskManagerConfigSpec{
+				UdevRules: []diskv1.UdevRule{
+					{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, UdevMatcher: diskv1.UdevMatcher{Env: map[string]string{"DEVNAME": "^/dev/(sd|vd|nvme)"}}},
+					{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, UdevMatcher: diskv1.UdevMatcher{Action: "add|change", Env: map[string]string{"DM_UUID": "^mpath-"}}},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:       "invalid: udev rule with bad regular expression",
+			configName: "default",
+			spec: diskv1.NodeDiskManagerConfigSpec{
+				UdevRules: []diskv1.UdevRule{
+					{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, UdevMatcher: diskv1.UdevMatcher{Env: map[string]string{"DEVNAME": "^/dev/(sd"}}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "udev rule at index 0 has invalid regular expression",
+		},
+		{
+			name:       "invalid: relative mount root",
+			configName: "default",
+			spec: diskv1.NodeDiskManagerConfigSpec{
+				MountRoots: []diskv1.MountRootRule{
+					{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, Path: "mnt/extra-disks"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "mountRoots rule at index 0 has path \"mnt/extra-disks\" is not a clean absolute path",
+		},
+		{
+			name:       "invalid: root directory as mount root",
+			configName: "default",
+			spec: diskv1.NodeDiskManagerConfigSpec{
+				MountRoots: []diskv1.MountRootRule{
+					{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, Path: "/"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "mountRoots rule at index 0 has path must not be the root directory",
+		},
+		{
+			name:       "valid: trim disabled for tagged disks",
+			configName: "default",
+			spec: diskv1.NodeDiskManagerConfigSpec{
+				Trim: []diskv1.TrimRule{
+					{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, TrimSchedule: diskv1.TrimSchedule{Interval: metav1.Duration{Duration: 168 * time.Hour}}},
+					{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, TrimSchedule: diskv1.TrimSchedule{Tags: []string{"archive"}}},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:       "invalid: trim interval too short",
+			configName: "default",
+			spec: diskv1.NodeDiskManagerConfigSpec{
+				Trim: []diskv1.TrimRule{
+					{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, TrimSchedule: diskv1.TrimSchedule{Interval: metav1.Duration{Duration: 5 * time.Minute}}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "trim rule at index 0 has interval 5m0s is shorter than 1h0m0s",
+		},
+		{
+			name:       "valid: tuning profile for tagged disks",
+			configName: "default",
+			spec: diskv1.NodeDiskManagerConfigSpec{
+				Tuning: []diskv1.TuningRule{
+					{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, TuningProfile: diskv1.TuningProfile{Tags: []string{"nvme"}, Scheduler: "none", WriteCache: diskv1.WriteCacheBack}},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:       "valid: write-through for named disks",
+			configName: "default",
+			spec: diskv1.NodeDiskManagerConfigSpec{
+				Tuning: []diskv1.TuningRule{
+					{NodeTarget: diskv1.NodeTarget{Hostname: "harvester1"}, TuningProfile: diskv1.TuningProfile{BlockDevices: []string{"bd-plp"}, WriteCache: diskv1.WriteCacheThrough}},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name:       "invalid: write-through for tagged disks",
+			configName: "default",
+			spec: diskv1.NodeDiskManagerConfigSpec{
+				Tuning: []diskv1.TuningRule{
+					{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, TuningProfile: diskv1.TuningProfile{Tags: []string{"nvme"}, WriteCache: diskv1.WriteCacheThrough}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "tuning rule at index 0 has writeCache WriteThrough requires the blockDevices",
+		},
+		{
+			name:       "invalid: tuning scheduler",
+			configName: "default",
+			spec: diskv1.NodeDiskManagerConfigSpec{
+				Tuning: []diskv1.TuningRule{
+					{NodeTarget: diskv1.NodeTarget{Hostname: "*"}, TuningProfile: diskv1.TuningProfile{Scheduler: "[mq-deadline]"}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "tuning rule at index 0 has invalid scheduler \"[mq-deadline]\"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ndmConfig := &diskv1.NodeDiskManagerConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: tt.configName},
+				Spec:       tt.spec,
+			}
+			err := validator.Create(nil, ndmConfig)
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}